@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+func newAddCmd() *cobra.Command {
+	var (
+		name     string
+		addr     string
+		user     string
+		port     int
+		identity string
+		proxy    string
+		group    string
+		tags     []string
+		policy   string
+		stdin    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add [name] [[user@]host[:port]]",
+		Short: "Add a host",
+		Long: `Add a host, given as arguments or entirely through flags for provisioning
+scripts. --user and --port override the values in the target argument.
+
+With --stdin hosts are read as JSON Lines (one object per line) or YAML
+documents separated by "---", using the field names of "sshm show -o json".
+Each record is validated on its own: valid hosts are added in a single write
+and every invalid record is reported with its position. --identity, --proxy,
+--group and --tag fill in fields the records leave empty.`,
+		Example: `  sshm add web1 deploy@10.0.0.4
+  sshm add db1 admin@db.example.com:2222 --group prod --tag db
+  sshm add --name web1 --host 10.0.0.4 --user deploy --port 2222 --tag prod --identity ~/.ssh/deploy
+  terraform output -json | jq -c '.hosts.value[]' | sshm add --stdin --group prod`,
+		Args: cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if stdin {
+				for _, f := range []string{"name", "host", "user", "port"} {
+					if cmd.Flags().Changed(f) {
+						return fmt.Errorf("--stdin cannot be combined with --%s", f)
+					}
+				}
+				if len(args) > 0 {
+					return fmt.Errorf("--stdin does not take arguments")
+				}
+				defaults := models.Host{Identity: identity, Proxy: proxy, Group: group, Tags: tags}
+				return addFromReader(cmd, cmd.InOrStdin(), defaults)
+			}
+
+			var host models.Host
+			if len(args) > 0 {
+				host.Name = args[0]
+			}
+			if len(args) > 1 {
+				var err error
+				if host.User, host.Host, host.Port, err = parseTarget(args[1]); err != nil {
+					return err
+				}
+			}
+
+			flags := cmd.Flags()
+			if flags.Changed("name") {
+				if host.Name != "" {
+					return fmt.Errorf("give the name either as an argument or with --name, not both")
+				}
+				host.Name = name
+			}
+			if flags.Changed("host") {
+				if host.Host != "" {
+					return fmt.Errorf("give the address either as an argument or with --host, not both")
+				}
+				host.Host = addr
+			}
+			if flags.Changed("user") {
+				host.User = user
+			}
+			if flags.Changed("port") {
+				host.Port = port
+			}
+			host.Identity = identity
+			host.Proxy = proxy
+			host.Group = group
+			host.Tags = tags
+			host.HostKeyPolicy = models.HostKeyPolicy(policy)
+
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host = s.ApplyDefaults(host)
+			if err := host.Validate(); err != nil {
+				return err
+			}
+			if _, err := s.GetHostByName(host.Name); err == nil {
+				return fmt.Errorf("host %q already exists", host.Name)
+			}
+			if err := s.AddHost(host); err != nil {
+				return fmt.Errorf("failed to add host: %w", err)
+			}
+
+			fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Added %s\n", host.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "display name")
+	cmd.Flags().StringVar(&addr, "host", "", "IP address or hostname")
+	cmd.Flags().StringVarP(&user, "user", "u", "", "SSH username")
+	cmd.Flags().IntVarP(&port, "port", "p", 22, "SSH port")
+	cmd.Flags().StringVarP(&identity, "identity", "i", "", "path to the private key")
+	cmd.Flags().StringVarP(&proxy, "proxy", "J", "", "jump host ([user@]host[:port])")
+	cmd.Flags().StringVarP(&group, "group", "g", "", "group name")
+	cmd.Flags().StringSliceVarP(&tags, "tag", "t", nil, "tag (repeatable or comma separated)")
+	cmd.Flags().StringVar(&policy, "host-key-policy", "", "check host keys strict, tofu or insecure (default from the settings)")
+	cmd.Flags().BoolVar(&stdin, "stdin", false, "read hosts as JSON Lines or YAML documents from stdin")
+
+	return cmd
+}
+
+// hostRecord is one host read by add --stdin
+type hostRecord struct {
+	pos  string // "line 3" or "document 2", for error messages
+	host models.Host
+	err  error
+}
+
+// addFromReader adds every valid host record in r, reporting invalid ones
+// Fields missing from a record are taken from defaults.
+func addFromReader(cmd *cobra.Command, r io.Reader, defaults models.Host) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+	records := parseHostRecords(data)
+	if len(records) == 0 {
+		return fmt.Errorf("no hosts on stdin")
+	}
+
+	s, err := openStore()
+	if err != nil {
+		return err
+	}
+	existing := s.ListHosts()
+	names := make(map[string]bool)
+	ids := make(map[string]bool)
+	for _, h := range existing {
+		names[h.Name] = true
+		ids[h.ID] = true
+	}
+
+	var added []models.Host
+	errOut := cmd.ErrOrStderr()
+	failed := 0
+	for _, rec := range records {
+		h := rec.host
+		err := rec.err
+		if err == nil {
+			fillHostDefaults(&h, defaults)
+			h = s.ApplyDefaults(h)
+			err = h.Validate()
+		}
+		if err == nil && names[h.Name] {
+			err = fmt.Errorf("host %q already exists", h.Name)
+		}
+		if err == nil && h.ID != "" && ids[h.ID] {
+			err = fmt.Errorf("host ID %q already exists", h.ID)
+		}
+		if err != nil {
+			fmt.Fprintf(errOut, "%s: %v\n", rec.pos, err)
+			failed++
+			continue
+		}
+		names[h.Name] = true
+		if h.ID != "" {
+			ids[h.ID] = true
+		}
+		added = append(added, h)
+	}
+
+	if len(added) > 0 {
+		if err := s.ReplaceHosts(append(existing, added...)); err != nil {
+			return fmt.Errorf("failed to add hosts: %w", err)
+		}
+	}
+
+	status := statusWriter(cmd.OutOrStdout())
+	fmt.Fprintf(status, "Added %d hosts", len(added))
+	if failed > 0 {
+		fmt.Fprintf(status, ", %d failed", failed)
+	}
+	fmt.Fprintln(status)
+	if failed > 0 {
+		return &exitCodeError{code: exitError}
+	}
+	return nil
+}
+
+// parseHostRecords splits data into JSON Lines records when it starts with
+// "{", and into YAML documents otherwise
+func parseHostRecords(data []byte) []hostRecord {
+	var records []hostRecord
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for n := 1; scanner.Scan(); n++ {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			rec := hostRecord{pos: fmt.Sprintf("line %d", n)}
+			dec := json.NewDecoder(bytes.NewReader(line))
+			dec.DisallowUnknownFields()
+			rec.err = dec.Decode(&rec.host)
+			records = append(records, rec)
+		}
+		return records
+	}
+
+	// Split documents by hand so one malformed document doesn't stop the rest
+	var docs []string
+	var current strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		if trimmed := strings.TrimRight(line, " \t\r"); trimmed == "---" || trimmed == "..." {
+			docs = append(docs, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+	docs = append(docs, current.String())
+
+	n := 0
+	for _, doc := range docs {
+		if strings.TrimSpace(stripYAMLComments(doc)) == "" {
+			continue
+		}
+		n++
+		rec := hostRecord{pos: fmt.Sprintf("document %d", n)}
+		dec := yaml.NewDecoder(strings.NewReader(doc))
+		dec.KnownFields(true)
+		rec.err = dec.Decode(&rec.host)
+		records = append(records, rec)
+	}
+	return records
+}
+
+// stripYAMLComments drops full-line comments so comment-only documents are skipped
+func stripYAMLComments(doc string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(doc, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// fillHostDefaults sets any empty identity, proxy, group or tags from
+// defaults; the store's defaults block fills in the rest
+func fillHostDefaults(h *models.Host, defaults models.Host) {
+	h.Online = nil
+	if h.Identity == "" {
+		h.Identity = defaults.Identity
+	}
+	if h.Proxy == "" {
+		h.Proxy = defaults.Proxy
+	}
+	if h.Group == "" {
+		h.Group = defaults.Group
+	}
+	if len(h.Tags) == 0 {
+		h.Tags = defaults.Tags
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+func TestAddCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	run := func(args ...string) error {
+		root := newRootCmd()
+		root.SetOut(io.Discard)
+		root.SetArgs(append([]string{"--config", path, "add"}, args...))
+		return root.Execute()
+	}
+
+	if err := run("--name", "web1", "--host", "10.0.0.4", "--user", "deploy", "--port", "2222", "--tag", "prod", "--identity", "/keys/deploy"); err != nil {
+		t.Fatalf("add with flags failed: %v", err)
+	}
+	if err := run("db1", "admin@10.0.0.5:2200", "--port", "5432"); err != nil {
+		t.Fatalf("add with arguments failed: %v", err)
+	}
+
+	s := openTestStore(t, path)
+	web, err := s.GetHostByName("web1")
+	if err != nil {
+		t.Fatalf("web1 not stored: %v", err)
+	}
+	if web.Host != "10.0.0.4" || web.User != "deploy" || web.Port != 2222 || web.Identity != "/keys/deploy" || len(web.Tags) != 1 {
+		t.Errorf("unexpected host from flags: %+v", web)
+	}
+	if db, _ := s.GetHostByName("db1"); db.User != "admin" || db.Port != 5432 {
+		t.Errorf("expected --port to override the target port, got %+v", db)
+	}
+
+	for _, args := range [][]string{
+		{"--host", "10.0.0.6"},                  // no name
+		{"app1"},                                // no address
+		{"app1", "10.0.0.6", "--name", "app2"},  // name twice
+		{"app1", "10.0.0.6", "--port", "70000"}, // invalid port
+		{"web1", "10.0.0.6"},                    // duplicate
+	} {
+		if err := run(args...); err == nil {
+			t.Errorf("expected add %v to fail", args)
+		}
+	}
+}
+
+func TestParseHostRecords(t *testing.T) {
+	jsonl := []byte(`{"name":"web1","host":"10.0.0.1"}
+
+{"name":"web2","host":"10.0.0.2","port":2222}
+{"name":"bad","hots":"typo"}
+`)
+	records := parseHostRecords(jsonl)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 JSON records, got %d", len(records))
+	}
+	if records[1].host.Port != 2222 || records[1].pos != "line 3" {
+		t.Errorf("unexpected second record %+v", records[1])
+	}
+	if records[2].err == nil || records[2].pos != "line 4" {
+		t.Errorf("expected unknown field on line 4 to fail, got %+v", records[2])
+	}
+
+	docs := []byte(`# inventory
+---
+name: db1
+host: 10.0.1.1
+tags: [db]
+---
+name: [broken
+---
+name: db2
+host: 10.0.1.2
+`)
+	records = parseHostRecords(docs)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 YAML records, got %d", len(records))
+	}
+	if records[0].host.Name != "db1" || records[0].host.Tags[0] != "db" {
+		t.Errorf("unexpected first document %+v", records[0])
+	}
+	if records[1].err == nil || records[1].pos != "document 2" {
+		t.Errorf("expected document 2 to fail, got %+v", records[1])
+	}
+	if records[2].err != nil || records[2].host.Name != "db2" {
+		t.Errorf("expected documents after a broken one to parse, got %+v", records[2])
+	}
+}
+
+func TestAddFromStdin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	openTestStore(t, path).AddHost(models.Host{Name: "web1", Host: "10.0.0.1", Port: 22})
+
+	var stderr bytes.Buffer
+	root := newRootCmd()
+	root.SetOut(io.Discard)
+	root.SetErr(&stderr)
+	root.SetIn(strings.NewReader(`{"name":"web2","host":"10.0.0.2"}
+{"name":"web1","host":"10.0.0.9"}
+{"name":"web3"}
+{"name":"web4","host":"10.0.0.4","group":"edge"}
+`))
+	root.SetArgs([]string{"--config", path, "add", "--stdin", "--group", "prod"})
+
+	if err := root.Execute(); exitCode(err) != exitError {
+		t.Errorf("expected exit code 1 when records fail, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "line 2: host \"web1\" already exists") || !strings.Contains(stderr.String(), "line 3: web3: host is required") {
+		t.Errorf("expected per-record errors, got:\n%s", stderr.String())
+	}
+
+	s := openTestStore(t, path)
+	if s.Count() != 3 {
+		t.Errorf("expected the 2 valid hosts to be added, got %d hosts", s.Count())
+	}
+	if h, _ := s.GetHostByName("web2"); h.Group != "prod" || h.Port != 22 || h.ID == "" {
+		t.Errorf("expected defaults to be applied, got %+v", h)
+	}
+	if h, _ := s.GetHostByName("web4"); h.Group != "edge" {
+		t.Errorf("expected the record's own group to win, got %q", h.Group)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/config"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the settings and hosts files",
+		Example: `  sshm config validate
+  sshm config migrate --dry-run`,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(newConfigValidateCmd(), newConfigMigrateCmd())
+
+	return cmd
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate [file...]",
+		Short: "Check the settings and hosts files for mistakes",
+		Long: `Check the settings and hosts files (or the given files) more strictly than
+loading them does, along with the files they include. Unknown fields, values of the wrong type, duplicate host
+names, invalid ports and auth types are errors; identity files missing on
+this machine are warnings. Each problem is printed with its line and column.
+
+sshm exits non-zero when there are errors; warnings alone pass.`,
+		Example: `  sshm config validate
+  sshm config validate ~/team/hosts.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := args
+			if len(files) == 0 {
+				files = configFiles()
+			}
+			var problems []config.Problem
+			for _, file := range files {
+				found, err := config.ValidateFile(file)
+				if err != nil {
+					return err
+				}
+				problems = append(problems, found...)
+
+				included, err := includedFiles(file)
+				problems = append(problems, includeProblems(file, err)...)
+				for _, inc := range included {
+					found, err := config.ValidateFile(inc.Path)
+					if err != nil {
+						return err
+					}
+					problems = append(problems, found...)
+				}
+			}
+			if errors := writeProblems(cmd.OutOrStdout(), problems); errors > 0 {
+				return &exitCodeError{code: exitError}
+			}
+			return nil
+		},
+	}
+}
+
+func newConfigMigrateCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate [file...]",
+		Short: "Rewrite the settings and hosts files in the current layout",
+		Long: `Rewrite the settings and hosts files (or the given files) in the current
+layout. sshm reads older layouts, such as a bare list of hosts, hosts keyed by
+name or field names like hostname and username, by upgrading them in memory;
+this saves the upgrade, along with the IDs of hosts that have none.
+
+Each file keeps its format and is backed up as <file>.bak first. Files already
+in the current layout are left alone.`,
+		Example: `  sshm config migrate --dry-run
+  sshm config migrate ~/team/hosts.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := args
+			if len(files) == 0 {
+				files = configFiles()
+			}
+			out := cmd.OutOrStdout()
+			migrated := 0
+			for _, file := range files {
+				changes, err := config.MigrateFile(file, dryRun)
+				if err != nil {
+					return err
+				}
+				if len(changes) == 0 {
+					continue
+				}
+				migrated++
+				for _, c := range changes {
+					fmt.Fprintf(out, "%s:%d:%d: %s\n", file, c.Line, c.Column, c.Message)
+				}
+				if dryRun {
+					fmt.Fprintf(statusWriter(out), "Would rewrite %s\n", file)
+				} else {
+					fmt.Fprintf(statusWriter(out), "Rewrote %s (backup in %s.bak)\n", file, file)
+				}
+			}
+			if migrated == 0 {
+				fmt.Fprintln(statusWriter(out), "Nothing to migrate")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes without writing the files")
+
+	return cmd
+}
+
+// offerMigration points out settings and hosts files in an older layout
+func offerMigration(w io.Writer) {
+	for _, file := range configFiles() {
+		changes, err := config.CheckUpgrade(file)
+		if err != nil || len(changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(statusWriter(w), "%s uses an older layout (%s); run \"sshm config migrate\" to rewrite it\n", file, changes[0].Message)
+	}
+}
+
+// configFiles returns the settings and hosts files in use, once each
+func configFiles() []string {
+	paths := resolvePaths()
+	if paths.Config == paths.Hosts {
+		return []string{paths.Config}
+	}
+	return []string{paths.Config, paths.Hosts}
+}
+
+// includedFiles returns the files included by the file at path, directly
+// or through other included files
+func includedFiles(path string) ([]config.IncludedFile, error) {
+	_, include, err := config.ReadHosts(path)
+	if err != nil {
+		// Validation reports unreadable files itself
+		return nil, nil
+	}
+	return config.LoadIncludes(path, include)
+}
+
+// includeProblems turns the errors of following includes into problems
+func includeProblems(path string, err error) []config.Problem {
+	if err == nil {
+		return nil
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	problems := make([]config.Problem, 0, len(errs))
+	for _, e := range errs {
+		problems = append(problems, config.Problem{Path: path, Message: e.Error()})
+	}
+	return problems
+}
+
+// writeProblems prints problems and a summary and returns the error count
+func writeProblems(w io.Writer, problems []config.Problem) int {
+	errors, warnings := 0, 0
+	for _, p := range problems {
+		fmt.Fprintln(w, p)
+		if p.Warning {
+			warnings++
+		} else {
+			errors++
+		}
+	}
+	if len(problems) == 0 {
+		fmt.Fprintln(statusWriter(w), "No problems found")
+	} else {
+		fmt.Fprintf(statusWriter(w), "%d errors, %d warnings\n", errors, warnings)
+	}
+	return errors
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigValidateCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.yaml")
+	data := "hosts:\n  - name: web1\n    host: 10.0.0.1\n    prot: 22\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write hosts: %v", err)
+	}
+
+	var out bytes.Buffer
+	root := newRootCmd()
+	root.SetOut(&out)
+	root.SetArgs([]string{"--config", path, "config", "validate"})
+	err := root.Execute()
+	if exitCode(err) != exitError {
+		t.Errorf("expected exit code %d, got %d (%v)", exitError, exitCode(err), err)
+	}
+	if !strings.Contains(out.String(), path+`:4:5: unknown field "prot" in hosts`) {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestConfigMigrateCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "web1", "hostname": "10.0.0.1"}]`), 0600); err != nil {
+		t.Fatalf("failed to write hosts: %v", err)
+	}
+
+	var out bytes.Buffer
+	root := newRootCmd()
+	root.SetOut(&out)
+	root.SetArgs([]string{"--config", path, "config", "migrate"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if !strings.Contains(out.String(), `"hostname" is now "host"`) || !strings.Contains(out.String(), "Rewrote "+path) {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+	if _, err := os.Stat(path + ".bak"); err != nil {
+		t.Errorf("expected a backup: %v", err)
+	}
+
+	out.Reset()
+	root = newRootCmd()
+	root.SetOut(&out)
+	root.SetArgs([]string{"--config", path, "config", "migrate"})
+	if err := root.Execute(); err != nil || !strings.Contains(out.String(), "Nothing to migrate") {
+		t.Errorf("expected a second run to change nothing, got %v:\n%s", err, out.String())
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/ssh"
+	"github.com/sshm/sshm/internal/store"
+	"github.com/sshm/sshm/internal/tui"
+	"golang.org/x/term"
+)
+
+func newConnectCmd() *cobra.Command {
+	var first, exact, yes bool
+
+	cmd := &cobra.Command{
+		Use:   "connect <name|->",
+		Short: "Open an interactive session to a host",
+		Long: `Open an interactive session to a host.
+
+The host is resolved by exact name first, then by unique name prefix and
+finally by fuzzy match, so "sshm connect pdb" finds "prod-database".
+A selector such as "tag:db AND group:eu" works too when it picks exactly one
+host. "sshm connect -" reconnects to the most recently used host, like "cd -".
+sshm exits with the exit status of the remote shell.
+
+When the name matches several hosts a numbered list is shown to choose from
+on a terminal. Scripts can use --first to take the best match or --exact to
+accept only an exact name or ID; otherwise an ambiguous name is an error.
+
+Hosts with a guarded tag are confirmed first; --yes skips the question, and
+without a terminal to ask on it is required.`,
+		Example: `  sshm connect web1
+  sshm connect pdb
+  sshm connect web --first
+  sshm connect -`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			var host models.Host
+			switch {
+			case args[0] == "-":
+				host, err = lastHost(s)
+			case exact:
+				host, err = findHost(s, args[0])
+			default:
+				host, err = resolveHost(s, args[0])
+				var ambiguous *store.AmbiguousError
+				if errors.As(err, &ambiguous) {
+					if first {
+						host, err = ambiguous.Matches[0], nil
+					} else if in := cmd.InOrStdin(); in == os.Stdin && term.IsTerminal(int(os.Stdin.Fd())) {
+						host, err = chooseHost(in, cmd.ErrOrStderr(), ambiguous)
+					}
+				}
+			}
+			if err != nil {
+				return err
+			}
+			return connectHost(cmd, s, host, yes)
+		},
+	}
+
+	cmd.Flags().BoolVar(&first, "first", false, "connect to the best match when the name is ambiguous")
+	cmd.Flags().BoolVar(&exact, "exact", false, "only accept an exact host name or ID")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "connect to guarded hosts without asking")
+	cmd.MarkFlagsMutuallyExclusive("first", "exact")
+
+	return cmd
+}
+
+// chooseHost lists the matches of an ambiguous name and reads a number
+// An empty answer or end of input cancels.
+func chooseHost(in io.Reader, out io.Writer, ambiguous *store.AmbiguousError) (models.Host, error) {
+	width := 0
+	for _, h := range ambiguous.Matches {
+		width = max(width, len(h.Name))
+	}
+	fmt.Fprintf(out, "%q matches several hosts:\n", ambiguous.Query)
+	for i, h := range ambiguous.Matches {
+		fmt.Fprintf(out, "  %d) %-*s  %s@%s:%d\n", i+1, width, h.Name, h.User, h.Host, h.Port)
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Fprintf(out, "Choose 1-%d (Enter to cancel): ", len(ambiguous.Matches))
+		line, err := reader.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			if err != nil {
+				fmt.Fprintln(out)
+			}
+			return models.Host{}, errors.New("cancelled")
+		}
+		if n, convErr := strconv.Atoi(answer); convErr == nil && n >= 1 && n <= len(ambiguous.Matches) {
+			return ambiguous.Matches[n-1], nil
+		}
+		fmt.Fprintf(out, "%q is not a number between 1 and %d\n", answer, len(ambiguous.Matches))
+		if err != nil {
+			return models.Host{}, errors.New("cancelled")
+		}
+	}
+}
+
+// confirmGuarded asks before connecting to a host with a guarded tag
+// Without a terminal to ask on, yes is required.
+func confirmGuarded(cmd *cobra.Command, cfg *config.Config, host models.Host, yes bool) error {
+	tag := cfg.GuardedTag(host)
+	if tag == "" || yes {
+		return nil
+	}
+	if in := cmd.InOrStdin(); in != os.Stdin || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("%s is guarded (tagged %q), use --yes to connect without a terminal", host.Name, tag)
+	}
+	return confirmHost(os.Stdin, cmd.ErrOrStderr(), host, tag)
+}
+
+// confirmHost asks whether to connect to a guarded host, its name in red
+// Anything but y or yes cancels.
+func confirmHost(in io.Reader, out io.Writer, host models.Host, tag string) error {
+	name := lipgloss.NewRenderer(out).NewStyle().Foreground(lipgloss.Color("1")).Bold(true).Render(host.Name)
+	fmt.Fprintf(out, "Connect to %s (tagged %q)? [y/N] ", name, tag)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil {
+		fmt.Fprintln(out)
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return nil
+	}
+	return errors.New("cancelled")
+}
+
+// connectHost runs an interactive session, ended when the command's context
+// is done, and records it in the history
+// A non-zero remote exit status is passed through as the exit code.
+func connectHost(cmd *cobra.Command, s *store.FileStore, host models.Host, yes bool) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	profile, err := connectProfile(cfg, host)
+	if err != nil {
+		return err
+	}
+	if err := confirmGuarded(cmd, cfg, host, yes); err != nil {
+		return err
+	}
+	start := time.Now()
+	session := ssh.NewSession(host, profile)
+	session.SetContext(ctx)
+	if path := cfg.RecordingPath(host, start); path != "" {
+		session.Record(path, cfg.RecordingOptions())
+	}
+	err = session.Run()
+
+	code, remote := ssh.ExitStatus(err)
+	errMsg := ""
+	if err != nil && !remote {
+		errMsg = err.Error()
+	}
+	tui.RecordConnection(store.NewHistoryStore(""), s, models.ConnectionHistory{
+		HostID:         host.ID,
+		Success:        errMsg == "",
+		Error:          errMsg,
+		Duration:       time.Since(start).Milliseconds(),
+		Warnings:       session.Warnings,
+		HostKeyChanges: session.HostKeyChanges,
+	})
+	if remote {
+		if code == 0 {
+			return nil
+		}
+		return &exitCodeError{code: code}
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/store"
+)
+
+func TestChooseHost(t *testing.T) {
+	ambiguous := &store.AmbiguousError{Query: "web", Matches: []models.Host{
+		{Name: "web-1", User: "deploy", Host: "10.0.0.1", Port: 22},
+		{Name: "web-22", User: "root", Host: "10.0.0.2", Port: 2222},
+	}}
+
+	var out bytes.Buffer
+	host, err := chooseHost(strings.NewReader("7\n2\n"), &out, ambiguous)
+	if err != nil || host.Name != "web-22" {
+		t.Fatalf("expected web-22 after a retry, got %q, %v", host.Name, err)
+	}
+	for _, want := range []string{"  1) web-1   deploy@10.0.0.1:22\n", "  2) web-22  root@10.0.0.2:2222\n", `"7" is not a number between 1 and 2`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("chooser output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	for _, input := range []string{"\n", "", "x"} {
+		if _, err := chooseHost(strings.NewReader(input), &out, ambiguous); err == nil {
+			t.Errorf("expected input %q to cancel", input)
+		}
+	}
+}
+
+func TestConnectAmbiguousAndExact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	s := openTestStore(t, path)
+	s.AddHost(models.Host{Name: "web-1", Host: "10.0.0.1", Port: 22})
+	s.AddHost(models.Host{Name: "web-2", Host: "10.0.0.2", Port: 22})
+
+	run := func(args ...string) error {
+		root := newRootCmd()
+		root.SetIn(strings.NewReader(""))
+		root.SetOut(&bytes.Buffer{})
+		root.SetErr(&bytes.Buffer{})
+		root.SetArgs(append([]string{"--config", path, "connect"}, args...))
+		return root.Execute()
+	}
+
+	// Without a terminal an ambiguous name stays an error
+	var ambiguous *store.AmbiguousError
+	if err := run("web"); !errors.As(err, &ambiguous) {
+		t.Errorf("expected an ambiguous error, got %v", err)
+	}
+	if err := run("web", "--exact"); !errors.Is(err, errNotFound) {
+		t.Errorf("expected --exact to reject a prefix, got %v", err)
+	}
+	if err := run("web", "--exact", "--first"); err == nil {
+		t.Error("expected --exact and --first to be mutually exclusive")
+	}
+}
+
+func TestConnectBastionPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sshm.yaml")
+	data := `guarded_tags: [production]
+bastion_policy:
+  guarded: true
+defaults:
+  proxy: jump
+hosts:
+  - name: web1
+    host: 10.0.0.1
+    proxy: none
+    tags: [production]
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"connect", "web1"}, {"exec", "web1", "--", "true"}} {
+		root := newRootCmd()
+		root.SetIn(strings.NewReader(""))
+		root.SetOut(&bytes.Buffer{})
+		root.SetErr(&bytes.Buffer{})
+		root.SetArgs(append([]string{"--config", path}, args...))
+		if err := root.Execute(); !errors.Is(err, config.ErrBastionRequired) {
+			t.Errorf("%s: expected the direct connection to be refused, got %v", args[0], err)
+		}
+	}
+}
+
+func TestConfirmGuarded(t *testing.T) {
+	host := models.Host{Name: "db1", Host: "10.0.0.1", Port: 22, Tags: []string{"production"}}
+
+	var out bytes.Buffer
+	if err := confirmHost(strings.NewReader("y\n"), &out, host, "production"); err != nil {
+		t.Errorf("expected y to confirm, got %v", err)
+	}
+	if !strings.Contains(out.String(), `Connect to db1 (tagged "production")? [y/N] `) {
+		t.Errorf("unexpected question %q", out.String())
+	}
+	for _, input := range []string{"\n", "", "n\n", "yep\n"} {
+		if err := confirmHost(strings.NewReader(input), &out, host, "production"); err == nil {
+			t.Errorf("expected input %q to cancel", input)
+		}
+	}
+
+	// Without a terminal a guarded host needs --yes
+	path := filepath.Join(t.TempDir(), "sshm.yaml")
+	data := "guarded_tags: [production]\nhosts:\n  - name: db1\n    host: 10.0.0.1\n    tags: [production]\n    aliases:\n      up: uptime\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"connect", "db1"}, {"run", "db1", "up"}} {
+		root := newRootCmd()
+		root.SetIn(strings.NewReader("y\n"))
+		root.SetOut(&bytes.Buffer{})
+		root.SetErr(&bytes.Buffer{})
+		root.SetArgs(append([]string{"--config", path}, args...))
+		if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "--yes") {
+			t.Errorf("%s: expected the guarded host to need --yes, got %v", args[0], err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/ssh"
+	"golang.org/x/term"
+)
+
+func newCopyIDCmd() *cobra.Command {
+	var (
+		keyPath       string
+		forcePassword bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "copy-id <host>",
+		Short: "Install a public key in a host's authorized_keys",
+		Long: `Install a public key in a host's authorized_keys, like ssh-copy-id.
+
+Without -i the host's identity with .pub appended is used, falling back to
+~/.ssh/id_ed25519.pub, id_ecdsa.pub and id_rsa.pub. If the host does not
+accept any configured key yet, the password is asked for on the terminal.`,
+		Example: `  sshm copy-id web1
+  sshm copy-id web1 -i ~/.ssh/deploy.pub`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, args[0])
+			if err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			key, path, err := ssh.ReadPublicKey(keyPath, host)
+			if err != nil {
+				return err
+			}
+
+			profile, err := connectProfile(cfg, host)
+			if err != nil {
+				return err
+			}
+			var added bool
+			if !forcePassword {
+				added, err = ssh.CopyID(host, profile, key)
+			}
+			if forcePassword || ssh.IsAuthError(err) {
+				// Bootstrap with the account password
+				password, perr := readPassword(host)
+				if perr != nil {
+					return perr
+				}
+				host.Password = password
+				host.AuthType = models.AuthTypePassword
+				added, err = ssh.CopyID(host, profile, key)
+			}
+			if err != nil {
+				return err
+			}
+
+			if added {
+				fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Installed %s on %s\n", path, host.Name)
+			} else {
+				fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "%s is already installed on %s\n", path, host.Name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyPath, "identity", "i", "", "public key to install")
+	cmd.Flags().BoolVar(&forcePassword, "password", false, "authenticate with a password right away")
+
+	return cmd
+}
+
+// readPassword asks for the host's password on the terminal
+func readPassword(host models.Host) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("a password is required for %s but stdin is not a terminal", host.Name)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s@%s's password: ", host.User, host.Host)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return string(password), nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/ssh"
+	"golang.org/x/term"
+)
+
+// copyPath is one side of a cp command: a local path or host:path
+type copyPath struct {
+	host string
+	path string
+}
+
+func (p copyPath) remote() bool { return p.host != "" }
+
+// parseCopyPath splits "host:path" from a local path. Anything with a slash
+// before the first colon, or a single letter drive such as C:, is local.
+func parseCopyPath(arg string) copyPath {
+	i := strings.Index(arg, ":")
+	if i <= 0 || strings.ContainsAny(arg[:i], `/\`) || (i == 1 && len(arg) > 2 && (arg[2] == '\\' || arg[2] == '/')) {
+		return copyPath{path: arg}
+	}
+	path := arg[i+1:]
+	if path == "" {
+		path = "."
+	}
+	return copyPath{host: arg[:i], path: path}
+}
+
+func newCpCmd() *cobra.Command {
+	var recursive, resume bool
+
+	cmd := &cobra.Command{
+		Use:   "cp <source> <destination>",
+		Short: "Copy files to or from a host over SFTP",
+		Long: `Copy files to or from a host over SFTP.
+
+Exactly one of source and destination is written as host:path; the host is
+resolved like "sshm connect". Remote paths are relative to the home
+directory, and "host:" alone means the home directory. When the destination
+is an existing directory the source is copied into it.
+
+With --resume a destination file that is smaller than the source is
+continued from where it stopped rather than copied again.`,
+		Example: `  sshm cp web1:/var/log/nginx/access.log .
+  sshm cp -r ./site web1:/srv/www
+  sshm cp --resume backup.tar.gz db1:backups/`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, dst := parseCopyPath(args[0]), parseCopyPath(args[1])
+			if src.remote() == dst.remote() {
+				return fmt.Errorf("exactly one of source and destination must be host:path")
+			}
+			remote := src
+			if dst.remote() {
+				remote = dst
+			}
+
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, remote.host)
+			if err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			profile, err := connectProfile(cfg, host)
+			if err != nil {
+				return err
+			}
+			transfer, err := ssh.OpenTransfer(host, profile)
+			if err != nil {
+				return err
+			}
+			defer transfer.Close()
+
+			opts := ssh.TransferOptions{Recursive: recursive, Resume: resume}
+			if !quiet && term.IsTerminal(int(os.Stderr.Fd())) {
+				progress := &progressPrinter{w: os.Stderr}
+				opts.Progress = progress.update
+				defer progress.finish()
+			}
+
+			start := time.Now()
+			if dst.remote() {
+				err = transfer.Upload(cmd.Context(), src.path, dst.path, opts)
+			} else {
+				err = transfer.Download(cmd.Context(), src.path, dst.path, opts)
+			}
+			notifyFinished(cfg, fmt.Sprintf("Copy of %s to %s", args[0], args[1]), start, err)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Copy directories recursively")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Continue partially copied files")
+	return cmd
+}
+
+// progressPrinter redraws a single progress line per file
+type progressPrinter struct {
+	w       io.Writer
+	current string
+	last    int
+}
+
+func (p *progressPrinter) update(name string, done, total int64) {
+	percent := 100
+	if total > 0 {
+		percent = int(done * 100 / total)
+	}
+	if name == p.current && percent == p.last && done != total {
+		return
+	}
+	if name != p.current && p.current != "" {
+		fmt.Fprintln(p.w)
+	}
+	p.current, p.last = name, percent
+	fmt.Fprintf(p.w, "\r%s %3d%% %s/%s", name, percent, formatBytes(done), formatBytes(total))
+}
+
+func (p *progressPrinter) finish() {
+	if p.current != "" {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestParseCopyPath(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want copyPath
+	}{
+		{"web1:/var/log/syslog", copyPath{host: "web1", path: "/var/log/syslog"}},
+		{"web1:", copyPath{host: "web1", path: "."}},
+		{"web1:backups/", copyPath{host: "web1", path: "backups/"}},
+		{"./file", copyPath{path: "./file"}},
+		{"./odd:name", copyPath{path: "./odd:name"}},
+		{"/tmp/a:b", copyPath{path: "/tmp/a:b"}},
+		{`C:\Users\me`, copyPath{path: `C:\Users\me`}},
+		{":file", copyPath{path: ":file"}},
+	}
+	for _, tt := range tests {
+		if got := parseCopyPath(tt.arg); got != tt.want {
+			t.Errorf("parseCopyPath(%q) = %+v, want %+v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0B",
+		1023:            "1023B",
+		1024:            "1.0KiB",
+		1536:            "1.5KiB",
+		5 * 1024 * 1024: "5.0MiB",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+// Documentation formats written by the docs command
+const (
+	docsMan      = "man"
+	docsMarkdown = "markdown"
+)
+
+func newDocsCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "docs <dir>",
+		Short: "Generate man pages or Markdown reference for all commands",
+		Long: `Generate man pages (section 1) or Markdown reference pages for sshm and
+every subcommand into dir, for packaging. The pages are built from the
+same help text, flags and examples as "sshm <command> --help". Set
+SOURCE_DATE_EPOCH for reproducible man page dates.`,
+		Example: `  sshm docs --format man ./man/man1
+  sshm docs --format markdown ./docs/cli`,
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := args[0]
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dir, err)
+			}
+			if err := writeDocs(cmd.Root(), format, dir); err != nil {
+				return err
+			}
+			fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Wrote %s pages to %s\n", format, filepath.Clean(dir))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", docsMan, "output format: man or markdown")
+	return cmd
+}
+
+// writeDocs renders the documentation for root and its subcommands
+func writeDocs(root *cobra.Command, format, dir string) error {
+	// Generated files should not change with the day they were built
+	root.DisableAutoGenTag = true
+
+	switch format {
+	case docsMan:
+		header := &doc.GenManHeader{
+			Title:   "SSHM",
+			Section: "1",
+			Source:  "sshm " + currentBuild().Version,
+			Manual:  "SSH Host Manager",
+		}
+		return doc.GenManTree(root, header, dir)
+	case docsMarkdown:
+		return doc.GenMarkdownTree(root, dir)
+	}
+	return fmt.Errorf("unknown format: %s (use man or markdown)", format)
+}
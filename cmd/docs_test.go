@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCommandsHaveExamples(t *testing.T) {
+	for _, cmd := range newRootCmd().Commands() {
+		if cmd.Hidden || cmd.Name() == "help" || cmd.Name() == "completion" {
+			continue
+		}
+		if cmd.Short == "" || cmd.Example == "" {
+			t.Errorf("%s: every command needs a short description and examples for the generated docs", cmd.Name())
+		}
+	}
+}
+
+func TestWriteDocs(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		file   string
+	}{
+		{docsMan, "sshm-connect.1"},
+		{docsMarkdown, "sshm_connect.md"},
+	} {
+		dir := t.TempDir()
+		if err := writeDocs(newRootCmd(), tt.format, dir); err != nil {
+			t.Fatalf("%s: writeDocs failed: %v", tt.format, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, tt.file)); err != nil {
+			t.Errorf("%s: expected %s to be generated: %v", tt.format, tt.file, err)
+		}
+	}
+
+	if err := writeDocs(&cobra.Command{Use: "sshm"}, "html", t.TempDir()); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
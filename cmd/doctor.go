@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/ssh"
+	"github.com/sshm/sshm/internal/store"
+)
+
+// doctorCheck is one line of the doctor report
+type doctorCheck struct {
+	name   string
+	detail string
+	ok     bool
+	warn   bool // worth a look, but not a failure
+}
+
+func newDoctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment and print a report for bug reports",
+		Long: `Check the environment and print a report for bug reports.
+
+The report includes the sshm version and build, the platform, the hosts
+file, the SSH agent and the ssh client, and flags hosts whose identity file
+is missing. It warns about DSA and short RSA keys, hosts whose last
+connection offered only deprecated key exchanges, host key algorithms or
+ciphers, and, with key_max_age in the settings, older keys. sshm exits
+non-zero when a check fails.`,
+		Example: `  sshm doctor
+  sshm doctor --config ~/work-hosts.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if failed := writeDoctorReport(cmd.OutOrStdout(), runDoctorChecks()); failed > 0 {
+				return &exitCodeError{code: exitError}
+			}
+			return nil
+		},
+	}
+}
+
+// runDoctorChecks gathers the environment report
+func runDoctorChecks() []doctorCheck {
+	info := currentBuild()
+	checks := []doctorCheck{
+		{name: "sshm", detail: info.String(), ok: true},
+	}
+
+	paths := resolvePaths()
+	switch _, err := os.Stat(paths.Config); {
+	case os.IsNotExist(err):
+		checks = append(checks, doctorCheck{name: "config", detail: paths.Config + " (not created yet)", ok: true})
+	case err != nil:
+		checks = append(checks, doctorCheck{name: "config", detail: err.Error()})
+	default:
+		if _, err := loadConfig(); err != nil {
+			checks = append(checks, doctorCheck{name: "config", detail: fmt.Sprintf("%s: %v", paths.Config, err)})
+		} else {
+			checks = append(checks, doctorCheck{name: "config", detail: paths.Config, ok: true})
+		}
+	}
+	// The checks below go through no hosts when the file can't be read
+	s, storeErr := openStore()
+	var hosts []models.Host
+	if storeErr == nil {
+		hosts = s.ListHosts()
+	}
+	switch _, err := os.Stat(paths.Hosts); {
+	case os.IsNotExist(err):
+		checks = append(checks, doctorCheck{name: "hosts", detail: paths.Hosts + " (not created yet)", ok: true})
+	case err != nil:
+		checks = append(checks, doctorCheck{name: "hosts", detail: err.Error()})
+	default:
+		if storeErr != nil {
+			checks = append(checks, doctorCheck{name: "hosts", detail: storeErr.Error()})
+		} else {
+			checks = append(checks, doctorCheck{name: "hosts", detail: fmt.Sprintf("%s (%d hosts)", paths.Hosts, s.Count()), ok: true})
+		}
+	}
+
+	errors := 0
+	for _, file := range configFiles() {
+		problems, _ := config.ValidateFile(file)
+		for _, p := range problems {
+			if !p.Warning {
+				errors++
+			}
+		}
+	}
+	if errors > 0 {
+		checks = append(checks, doctorCheck{name: "validation", detail: fmt.Sprintf("%d problems, see \"sshm config validate\"", errors)})
+	} else {
+		checks = append(checks, doctorCheck{name: "validation", detail: "no problems", ok: true})
+	}
+
+	var missing []string
+	for _, h := range hosts {
+		if !ssh.IdentityExists(h.Identity) {
+			missing = append(missing, h.Name)
+		}
+	}
+	if len(missing) > 0 {
+		checks = append(checks, doctorCheck{name: "identities", detail: "missing key file for " + strings.Join(missing, ", ")})
+	} else {
+		checks = append(checks, doctorCheck{name: "identities", detail: "all key files present", ok: true})
+	}
+
+	if cfg, err := loadConfig(); err == nil && cfg.KeyMaxAge > 0 {
+		now := time.Now()
+		var old []string
+		for _, k := range keyUses(cfg, hosts) {
+			if k.old(keyMaxAge(cfg), now) {
+				old = append(old, fmt.Sprintf("%s (%dd)", k.path, int(k.info.Age(now).Hours()/24)))
+			}
+		}
+		if len(old) > 0 {
+			checks = append(checks, doctorCheck{name: "key age", detail: fmt.Sprintf("older than %d days: %s, see \"sshm keys\"", cfg.KeyMaxAge, strings.Join(old, ", ")), ok: true, warn: true})
+		} else {
+			checks = append(checks, doctorCheck{name: "key age", detail: fmt.Sprintf("all keys younger than %d days", cfg.KeyMaxAge), ok: true})
+		}
+	}
+
+	if cfg, err := loadConfig(); err == nil {
+		var weak []string
+		for _, k := range keyUses(cfg, hosts) {
+			if k.err == nil && k.info.Weakness() != "" {
+				weak = append(weak, fmt.Sprintf("%s (%s)", k.path, k.info.Weakness()))
+			}
+		}
+		if len(weak) > 0 {
+			checks = append(checks, doctorCheck{name: "weak keys", detail: strings.Join(weak, ", ") + ", see \"sshm keys rotate\"", ok: true, warn: true})
+		} else {
+			checks = append(checks, doctorCheck{name: "weak keys", detail: "no DSA or short RSA keys", ok: true})
+		}
+	}
+
+	// Servers are only seen when connecting, so go by the last connections
+	history := store.NewHistoryStore("")
+	var weakHosts []string
+	for _, h := range hosts {
+		if recent := history.GetHistoryForHost(h.ID); len(recent) > 0 && len(recent[0].Warnings) > 0 {
+			weakHosts = append(weakHosts, h.Name)
+		}
+	}
+	if len(weakHosts) > 0 {
+		checks = append(checks, doctorCheck{name: "algorithms", detail: "weak keys or algorithms on the last connection to " + strings.Join(weakHosts, ", "), ok: true, warn: true})
+	} else {
+		checks = append(checks, doctorCheck{name: "algorithms", detail: "no warnings on the last connections", ok: true})
+	}
+
+	if agent := ssh.CheckAgent(); agent.Available {
+		checks = append(checks, doctorCheck{name: "agent", detail: strings.TrimPrefix(agent.String(), "agent: "), ok: true})
+	} else {
+		// Keys and passwords still work without an agent, so this is informational
+		checks = append(checks, doctorCheck{name: "agent", detail: fmt.Sprintf("unavailable (%v)", agent.Err), ok: true})
+	}
+
+	if client, err := exec.LookPath("ssh"); err == nil {
+		checks = append(checks, doctorCheck{name: "ssh client", detail: client, ok: true})
+	} else {
+		checks = append(checks, doctorCheck{name: "ssh client", detail: "not found in PATH (optional, sshm has a built-in client)", ok: true})
+	}
+
+	if sshfs, err := exec.LookPath("sshfs"); err == nil {
+		checks = append(checks, doctorCheck{name: "sshfs", detail: sshfs, ok: true})
+	} else {
+		checks = append(checks, doctorCheck{name: "sshfs", detail: "not found in PATH (optional, needed by sshm mount)", ok: true})
+	}
+
+	return checks
+}
+
+// writeDoctorReport prints the checks and returns how many failed
+func writeDoctorReport(w io.Writer, checks []doctorCheck) int {
+	failed := 0
+	for _, c := range checks {
+		mark := "✓"
+		if c.warn {
+			mark = "!"
+		}
+		if !c.ok {
+			mark = "✗"
+			failed++
+		}
+		fmt.Fprintf(w, "%s %-11s %s\n", mark, c.name, c.detail)
+	}
+	return failed
+}
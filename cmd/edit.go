@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/editor"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/store"
+)
+
+func newEditCmd() *cobra.Command {
+	var (
+		name     string
+		addr     string
+		user     string
+		port     int
+		identity string
+		proxy    string
+		group    string
+		tags     []string
+		policy   string
+		aliases  []string
+		unalias  []string
+		raw      bool
+		format   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "edit <name>",
+		Short: "Change fields of a host",
+		Long: `Change fields of a host. Only the flags given are updated; pass an empty value to clear a field.
+
+With --raw the host, or the whole inventory when no name is given, opens in
+$VISUAL or $EDITOR as YAML (or JSON with --format json). The result is
+validated when the editor exits and saved in a single write; invalid input
+can be corrected in the editor or discarded.`,
+		Example: `  sshm edit web1 --user root --port 2222
+  sshm edit web1 --tag web,prod --group ""
+  sshm edit web1 --alias logs="journalctl -f -u app" --unalias restart
+  sshm edit --raw
+  sshm edit web1 --raw --format json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if raw {
+				return cobra.MaximumNArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			if raw {
+				return editRaw(cmd, s, args, format)
+			}
+			host, err := findHost(s, args[0])
+			if err != nil {
+				return err
+			}
+
+			flags := cmd.Flags()
+			if flags.NFlag() == 0 {
+				return fmt.Errorf("nothing to change, see --help for the available flags")
+			}
+			if flags.Changed("name") {
+				if name == "" {
+					return fmt.Errorf("name cannot be empty")
+				}
+				if other, err := s.GetHostByName(name); err == nil && other.ID != host.ID {
+					return fmt.Errorf("host %q already exists", name)
+				}
+				host.Name = name
+			}
+			if flags.Changed("host") {
+				if addr == "" {
+					return fmt.Errorf("host cannot be empty")
+				}
+				host.Host = addr
+			}
+			if flags.Changed("port") {
+				if port < 1 || port > 65535 {
+					return fmt.Errorf("invalid port %d", port)
+				}
+				host.Port = port
+			}
+			if flags.Changed("user") {
+				host.User = user
+			}
+			if flags.Changed("identity") {
+				host.Identity = identity
+			}
+			if flags.Changed("proxy") {
+				host.Proxy = proxy
+			}
+			if flags.Changed("group") {
+				host.Group = group
+			}
+			if flags.Changed("tag") {
+				host.Tags = tags
+			}
+			if flags.Changed("host-key-policy") {
+				host.HostKeyPolicy = models.HostKeyPolicy(policy)
+				if err := host.Validate(); err != nil {
+					return err
+				}
+			}
+			if err := applyAliasFlags(&host, aliases, unalias); err != nil {
+				return err
+			}
+
+			if err := s.UpdateHost(host); err != nil {
+				return fmt.Errorf("failed to update host: %w", err)
+			}
+
+			fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Updated %s\n", host.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "new display name")
+	cmd.Flags().StringVar(&addr, "host", "", "IP address or hostname")
+	cmd.Flags().StringVarP(&user, "user", "u", "", "SSH username")
+	cmd.Flags().IntVarP(&port, "port", "p", 22, "SSH port")
+	cmd.Flags().StringVarP(&identity, "identity", "i", "", "path to the private key")
+	cmd.Flags().StringVarP(&proxy, "proxy", "J", "", "jump host ([user@]host[:port])")
+	cmd.Flags().StringVarP(&group, "group", "g", "", "group name")
+	cmd.Flags().StringSliceVarP(&tags, "tag", "t", nil, "replace tags (comma separated)")
+	cmd.Flags().StringVar(&policy, "host-key-policy", "", "check host keys strict, tofu or insecure; empty takes the default")
+	cmd.Flags().StringArrayVar(&aliases, "alias", nil, "set a command alias as name=command (repeatable)")
+	cmd.Flags().StringSliceVar(&unalias, "unalias", nil, "remove command aliases by name")
+	cmd.Flags().BoolVar(&raw, "raw", false, "edit the host or whole inventory in $EDITOR")
+	cmd.Flags().StringVar(&format, "format", editor.FormatYAML, "format for --raw: yaml or json")
+
+	return cmd
+}
+
+// applyAliasFlags removes the --unalias names and sets the --alias
+// name=command pairs on host
+func applyAliasFlags(host *models.Host, set, remove []string) error {
+	for _, name := range remove {
+		if _, ok := host.Aliases[name]; !ok {
+			return fmt.Errorf("alias %q %w on %s", name, errNotFound, host.Name)
+		}
+		delete(host.Aliases, name)
+	}
+	for _, pair := range set {
+		name, command, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(command) == "" {
+			return fmt.Errorf("invalid alias %q, use name=command", pair)
+		}
+		if err := models.ValidateAliasName(name); err != nil {
+			return err
+		}
+		if host.Aliases == nil {
+			host.Aliases = make(map[string]string)
+		}
+		host.Aliases[name] = command
+	}
+	if len(host.Aliases) == 0 {
+		host.Aliases = nil
+	}
+	return nil
+}
+
+// editRaw opens one host (args[0]) or the whole inventory in $EDITOR and
+// applies the edited result, offering to reopen the file when it is invalid
+func editRaw(cmd *cobra.Command, s *store.FileStore, args []string, format string) error {
+	for _, name := range []string{"name", "host", "port", "user", "identity", "proxy", "group", "tag", "host-key-policy", "alias", "unalias"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--raw cannot be combined with --%s", name)
+		}
+	}
+
+	hosts := s.ListHosts()
+	single := len(args) > 0
+	if single {
+		host, err := findHost(s, args[0])
+		if err != nil {
+			return err
+		}
+		hosts = []models.Host{host}
+	}
+
+	session, err := editor.NewSession(hosts, format, single)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	status := statusWriter(cmd.OutOrStdout())
+	in := bufio.NewReader(cmd.InOrStdin())
+	for {
+		if err := session.Cmd().Run(); err != nil {
+			return fmt.Errorf("editor failed: %w", err)
+		}
+
+		edited, changed, err := session.Result()
+		if !changed {
+			fmt.Fprintln(status, "No changes")
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%v\nEdit again? [Y/n] ", err)
+			answer, _ := in.ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); a == "n" || a == "no" {
+				return fmt.Errorf("changes discarded")
+			}
+			continue
+		}
+
+		if single {
+			// The ID ties the host to its history and can't be edited
+			edited[0].ID = hosts[0].ID
+			if other, err := s.GetHostByName(edited[0].Name); err == nil && other.ID != hosts[0].ID {
+				return fmt.Errorf("host %q already exists", edited[0].Name)
+			}
+			if err := s.UpdateHost(edited[0]); err != nil {
+				return fmt.Errorf("failed to update host: %w", err)
+			}
+			fmt.Fprintf(status, "Updated %s\n", edited[0].Name)
+			return nil
+		}
+
+		if err := s.ReplaceHosts(edited); err != nil {
+			return fmt.Errorf("failed to save inventory: %w", err)
+		}
+		fmt.Fprintf(status, "Saved %d hosts\n", len(edited))
+		return nil
+	}
+}
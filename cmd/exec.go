@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/notify"
+	"github.com/sshm/sshm/internal/ssh"
+	"golang.org/x/term"
+)
+
+func newExecCmd() *cobra.Command {
+	var (
+		concurrency int
+		timeout     time.Duration
+		failFast    bool
+		sudo        bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "exec <selector>... -- <command>",
+		Short: "Run a command on one or more hosts",
+		Long: `Run a command on one or more hosts.
+
+Targets are host names (resolved like connect), name patterns such as
+prod-web-*, tag:<tag>, group:<group> or user:<user>, combined with AND, OR,
+NOT and parentheses; separate targets are combined with OR.
+
+With several hosts every output line is prefixed with the host name as it
+arrives, failures are reported as they happen, a counter of the hosts done is
+kept on the terminal, and the command exits non-zero if any host failed. With
+a single host the output is passed through unchanged and the remote exit
+status is returned.
+
+--sudo runs the command as root through sudo. The sudo password is asked for
+once, the first time a host wants it, and reused for every other host; when
+stdin is not a terminal the first line of stdin is used instead.`,
+		Example: `  sshm exec web1 -- uptime
+  sshm exec tag:prod group:eu -- df -h /
+  sshm exec 'tag:db AND group:eu' -- df -h /
+  sshm exec 'prod-web-* AND NOT tag:canary' -- systemctl reload nginx
+  sshm exec tag:web --concurrency 4 --timeout 30s --fail-fast -- systemctl restart nginx
+  sshm exec group:eu --sudo -- apt-get update`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash == 0 {
+				return fmt.Errorf("at least one target is required before --")
+			}
+			if dash < 0 && len(args) < 2 || dash >= 0 && dash == len(args) {
+				return fmt.Errorf("a command is required, e.g. sshm exec web1 -- uptime")
+			}
+			return nil
+		},
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			targets, command := args[:1], args[1:]
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				targets, command = args[:dash], args[dash:]
+			}
+			if concurrency < 1 {
+				return fmt.Errorf("concurrency must be at least 1")
+			}
+
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			hosts, err := selectHosts(s, targets)
+			if err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			password := sudoPasswordOnce(cmd.InOrStdin())
+			start := time.Now()
+			run := func(ctx context.Context, h models.Host, stdout, stderr io.Writer) error {
+				if timeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, timeout)
+					defer cancel()
+				}
+				profile, err := connectProfile(cfg, h)
+				if err != nil {
+					return err
+				}
+				if sudo {
+					err = ssh.RunSudo(ctx, h, profile, strings.Join(command, " "), hostSudoPassword(h, password), stdout, stderr)
+				} else {
+					err = ssh.RunCommand(ctx, h, profile, strings.Join(command, " "), stdout, stderr)
+				}
+				if errors.Is(err, context.DeadlineExceeded) {
+					return &ssh.TimeoutError{After: timeout}
+				}
+				return err
+			}
+
+			if len(hosts) == 1 {
+				err := run(ctx, hosts[0], cmd.OutOrStdout(), cmd.ErrOrStderr())
+				notifyFinished(cfg, fmt.Sprintf("%s on %s", strings.Join(command, " "), hosts[0].Name), start, err)
+				if code, ok := ssh.ExitStatus(err); ok {
+					return &exitCodeError{code: code}
+				}
+				return err
+			}
+
+			// Count the hosts done on the terminal, below the output
+			var status io.Writer
+			if !quiet && cmd.ErrOrStderr() == os.Stderr && term.IsTerminal(int(os.Stderr.Fd())) {
+				status = os.Stderr
+			}
+			err = execOnHosts(ctx, hosts, run, concurrency, failFast, cmd.OutOrStdout(), cmd.ErrOrStderr(), status)
+			notifyFinished(cfg, fmt.Sprintf("%s on %d hosts", strings.Join(command, " "), len(hosts)), start, err)
+			return err
+		},
+	}
+
+	cmd.Flags().IntVarP(&concurrency, "concurrency", "c", 10, "number of hosts to run on at once")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "per-host time limit, e.g. 30s (0 for none)")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "stop starting new hosts after the first failure")
+	cmd.Flags().BoolVar(&sudo, "sudo", false, "run the command through sudo, asking for the password once")
+
+	return cmd
+}
+
+// notifyFinished announces on the desktop that job, started at start, ended
+// with err, when it ran long enough for notify in the settings
+// Notifications are best effort; failing to show one isn't reported.
+func notifyFinished(cfg *config.Config, job string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	if !cfg.Notify.Due(elapsed) {
+		return
+	}
+	text := fmt.Sprintf("%s finished after %s", job, elapsed.Round(time.Second))
+	if err != nil {
+		text = fmt.Sprintf("%s failed after %s: %v", job, elapsed.Round(time.Second), err)
+	}
+	notify.Send(text)
+}
+
+// sudoPasswordOnce returns a password source that asks the first time it is
+// called and hands the same answer to every later caller
+func sudoPasswordOnce(in io.Reader) ssh.PasswordFunc {
+	var (
+		once     sync.Once
+		password string
+		err      error
+	)
+	return func() (string, error) {
+		once.Do(func() {
+			password, err = readSudoPassword(in)
+		})
+		return password, err
+	}
+}
+
+// hostSudoPassword answers sudo with the host's password from the keyring,
+// if there is one, and from fallback otherwise
+func hostSudoPassword(h models.Host, fallback ssh.PasswordFunc) ssh.PasswordFunc {
+	if saved := ssh.KeyringPassword(h); saved != "" {
+		return func() (string, error) { return saved, nil }
+	}
+	return fallback
+}
+
+// readSudoPassword asks on the terminal, or reads the first line of in when
+// stdin is not a terminal so scripts can pipe the password in
+func readSudoPassword(in io.Reader) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if in == os.Stdin && term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "[sudo] password: ")
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read sudo password: %w", err)
+		}
+		return string(password), nil
+	}
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("sudo needs a password but none was given on stdin")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// execFunc runs the command on a single host
+type execFunc func(ctx context.Context, h models.Host, stdout, stderr io.Writer) error
+
+// execOnHosts runs fn on every host with bounded concurrency, streaming each
+// output line prefixed with the host name and each failure as it happens
+// With a status writer, a line at the bottom counts the hosts done.
+// Output is written as it arrives; a slow reader holds up the hosts.
+func execOnHosts(ctx context.Context, hosts []models.Host, fn execFunc, concurrency int, failFast bool, stdout, stderr, status io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	width := 0
+	for _, h := range hosts {
+		if len(h.Name) > width {
+			width = len(h.Name)
+		}
+	}
+
+	output := &execOutput{stderr: stderr, status: status, total: len(hosts)}
+	defer output.clear()
+	errs := make([]error, len(hosts))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, h := range hosts {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			errs[i] = errSkipped
+			output.finish(h, errSkipped)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, h models.Host) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			prefix := fmt.Sprintf("%-*s | ", width, h.Name)
+			out := newPrefixWriter(stdout, prefix, output)
+			errOut := newPrefixWriter(stderr, prefix, output)
+
+			err := fn(ctx, h, out, errOut)
+			out.Flush()
+			errOut.Flush()
+
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					err = errSkipped
+				}
+				errs[i] = err
+				if failFast {
+					cancel()
+				}
+			}
+			output.finish(h, err)
+		}(i, h)
+	}
+	wg.Wait()
+	output.clear()
+
+	if output.failed > 0 {
+		fmt.Fprintf(statusWriter(stderr), "%d of %d hosts failed\n", output.failed, len(hosts))
+		return &exitCodeError{code: commonExitCode(errs)}
+	}
+	return nil
+}
+
+// errSkipped marks hosts not run because of --fail-fast
+var errSkipped = errors.New("skipped after an earlier failure")
+
+// execOutput serializes the output of the hosts, so they don't interleave
+// within a line, and keeps the count of hosts done
+type execOutput struct {
+	mu     sync.Mutex
+	stderr io.Writer
+	status io.Writer // where the counter is drawn, nil for none
+	shown  bool      // the counter is on screen
+
+	total, ok, failed int
+}
+
+// write writes a line, moving the counter below it
+func (o *execOutput) write(w io.Writer, line string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.erase()
+	_, err := io.WriteString(w, line)
+	o.draw()
+	return err
+}
+
+// finish counts a host as done, reporting its failure right away
+func (o *execOutput) finish(h models.Host, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.erase()
+	if err == nil {
+		o.ok++
+	} else {
+		o.failed++
+		if code, ok := ssh.ExitStatus(err); ok {
+			fmt.Fprintf(o.stderr, "%s: exit status %d\n", h.Name, code)
+		} else {
+			fmt.Fprintf(o.stderr, "%s: %v\n", h.Name, err)
+		}
+	}
+	o.draw()
+}
+
+// clear removes the counter once all hosts are done
+func (o *execOutput) clear() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.erase()
+}
+
+func (o *execOutput) draw() {
+	if o.status == nil || o.ok+o.failed == o.total {
+		return
+	}
+	fmt.Fprintf(o.status, "[%d/%d] %d ok, %d failed", o.ok+o.failed, o.total, o.ok, o.failed)
+	o.shown = true
+}
+
+func (o *execOutput) erase() {
+	if o.shown {
+		io.WriteString(o.status, "\r\033[K")
+		o.shown = false
+	}
+}
+
+// maxPartialLine is how much of a line without a newline is held before it
+// is written anyway, for commands printing progress bars or binary data
+const maxPartialLine = 64 * 1024
+
+// prefixWriter prefixes every complete line written to it and writes it
+// to the underlying writer through the shared output
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	out    *execOutput
+	buf    bytes.Buffer
+}
+
+func newPrefixWriter(w io.Writer, prefix string, out *execOutput) *prefixWriter {
+	return &prefixWriter{w: w, prefix: prefix, out: out}
+}
+
+// Write buffers p and emits all complete lines, and the partial one once it
+// grows past maxPartialLine
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			if p.buf.Len() >= maxPartialLine {
+				return len(b), p.Flush()
+			}
+			break
+		}
+		line := string(p.buf.Next(i + 1))
+		if err := p.emit(line); err != nil {
+			return len(b), err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush emits a trailing partial line, if any
+func (p *prefixWriter) Flush() error {
+	if p.buf.Len() == 0 {
+		return nil
+	}
+	line := p.buf.String() + "\n"
+	p.buf.Reset()
+	return p.emit(line)
+}
+
+func (p *prefixWriter) emit(line string) error {
+	return p.out.write(p.w, p.prefix+line)
+}
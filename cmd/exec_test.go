@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newPrefixWriter(&buf, "web1 | ", &execOutput{})
+
+	fmt.Fprint(w, "up 3 days\nload")
+	fmt.Fprint(w, " 0.1\npartial")
+	w.Flush()
+
+	want := "web1 | up 3 days\nweb1 | load 0.1\nweb1 | partial\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	// A line without end is not held forever
+	buf.Reset()
+	w.Write(bytes.Repeat([]byte("."), maxPartialLine))
+	if buf.Len() != len("web1 | ")+maxPartialLine+1 {
+		t.Errorf("expected the long partial line written, got %d bytes", buf.Len())
+	}
+}
+
+func TestExecOnHosts(t *testing.T) {
+	hosts := []models.Host{{ID: "1", Name: "a"}, {ID: "2", Name: "bb"}, {ID: "3", Name: "c"}}
+
+	run := func(ctx context.Context, h models.Host, stdout, stderr io.Writer) error {
+		fmt.Fprintf(stdout, "hello from %s\n", h.Name)
+		if h.Name == "bb" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	var out, errOut, status bytes.Buffer
+	err := execOnHosts(context.Background(), hosts, run, 1, false, &out, &errOut, &status)
+
+	var exitErr *exitCodeError
+	if !errors.As(err, &exitErr) || exitErr.code != 1 {
+		t.Fatalf("expected exit code 1, got %v", err)
+	}
+	for _, line := range []string{"a  | hello from a", "bb | hello from bb", "c  | hello from c"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output missing %q:\n%s", line, out.String())
+		}
+	}
+	if !strings.Contains(errOut.String(), "bb: boom") || !strings.Contains(errOut.String(), "1 of 3 hosts failed") {
+		t.Errorf("unexpected error summary:\n%s", errOut.String())
+	}
+	if !strings.Contains(status.String(), "[2/3] 1 ok, 1 failed") || !strings.HasSuffix(status.String(), "\r\033[K") {
+		t.Errorf("expected a running counter, cleared at the end, got %q", status.String())
+	}
+
+	// With fail-fast and one host at a time, hosts after the failure are skipped
+	out.Reset()
+	errOut.Reset()
+	execOnHosts(context.Background(), hosts, run, 1, true, &out, &errOut, nil)
+	if strings.Contains(out.String(), "hello from c") {
+		t.Errorf("expected c to be skipped with fail-fast:\n%s", out.String())
+	}
+	if !strings.Contains(errOut.String(), "c: skipped") {
+		t.Errorf("expected c to be reported as skipped:\n%s", errOut.String())
+	}
+}
+
+func TestSudoPasswordOnce(t *testing.T) {
+	password := sudoPasswordOnce(strings.NewReader("s3cret\nsecond line\n"))
+
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = password()
+		}(i)
+	}
+	wg.Wait()
+
+	for i, got := range results {
+		if got != "s3cret" {
+			t.Errorf("call %d got %q, want the first line for every host", i, got)
+		}
+	}
+
+	if _, err := sudoPasswordOnce(strings.NewReader(""))(); err == nil {
+		t.Error("expected an error when stdin is empty")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+func newExportCmd() *cobra.Command {
+	var (
+		exportFormat string
+		outputFile   string
+		dryRun       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export [json|yaml|ssh-config|csv]",
+		Short: "Export hosts to JSON, YAML, SSH config or CSV format",
+		Example: `  sshm export ssh-config -o ~/.ssh/sshm_hosts
+  sshm export csv > hosts.csv
+  sshm export json -o backup.json --dry-run`,
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: []string{"json", "yaml", "ssh-config", "csv"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				exportFormat = args[0]
+			}
+
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			var output []byte
+
+			switch exportFormat {
+			case "json":
+				output, err = exportToJSON(cfg)
+			case "yaml":
+				output, err = exportToYAML(cfg)
+			case "ssh", "ssh-config":
+				output, err = exportToSSHConfig(cfg)
+			case "csv":
+				output, err = exportToCSV(cfg)
+			default:
+				return fmt.Errorf("unknown format: %s (use json, yaml, ssh-config or csv)", exportFormat)
+			}
+
+			if err != nil {
+				return fmt.Errorf("export failed: %w", err)
+			}
+
+			if outputFile != "" && dryRun {
+				fmt.Fprint(cmd.OutOrStdout(), string(output))
+				fmt.Fprintf(statusWriter(cmd.ErrOrStderr()), "Would write %d hosts to %s\n", len(cfg.Hosts), outputFile)
+				return nil
+			}
+
+			if outputFile != "" {
+				if err := os.WriteFile(outputFile, output, 0644); err != nil {
+					return fmt.Errorf("failed to write output: %w", err)
+				}
+				fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Exported to %s\n", outputFile)
+				return nil
+			}
+
+			fmt.Fprint(cmd.OutOrStdout(), string(output))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&exportFormat, "format", "json", "export format: json, yaml, ssh-config, csv")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "output file (stdout if empty)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print what would be written to the output file instead")
+
+	return cmd
+}
+
+func exportToJSON(cfg *config.Config) ([]byte, error) {
+	type exportConfig struct {
+		Hosts    []models.Host      `json:"hosts"`
+		Configs  []models.SSHConfig `json:"configs"`
+		Profiles []models.Profile   `json:"profiles"`
+	}
+
+	exp := exportConfig{
+		Hosts:    cfg.Hosts,
+		Configs:  cfg.Configs,
+		Profiles: cfg.Profiles,
+	}
+
+	return json.MarshalIndent(exp, "", "  ")
+}
+
+func exportToYAML(cfg *config.Config) ([]byte, error) {
+	type exportConfig struct {
+		Hosts    []models.Host      `yaml:"hosts"`
+		Configs  []models.SSHConfig `yaml:"configs"`
+		Profiles []models.Profile   `yaml:"profiles"`
+	}
+
+	exp := exportConfig{
+		Hosts:    cfg.Hosts,
+		Configs:  cfg.Configs,
+		Profiles: cfg.Profiles,
+	}
+
+	return yaml.Marshal(exp)
+}
+
+func exportToSSHConfig(cfg *config.Config) ([]byte, error) {
+	var lines []string
+
+	// Export hosts as SSH config
+	for _, host := range cfg.Hosts {
+		lines = append(lines, formatSSHHost(&host, cfg.ApplyConfigs(host, models.Profile{}))...)
+	}
+
+	return []byte(joinLines(lines)), nil
+}
+
+func exportToCSV(cfg *config.Config) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := config.FormatCSV(&buf, cfg.Hosts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatSSHHost writes a Host block with the options the configs matching
+// the host add to it
+func formatSSHHost(host *models.Host, options models.Profile) []string {
+	return append(host.SSHConfigLines(options), "") // Empty line between hosts
+}
+
+func joinLines(lines []string) string {
+	result := ""
+	for i, line := range lines {
+		if i > 0 {
+			result += "\n"
+		}
+		result += line
+	}
+	return result
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/models"
+)
+
+func newFzfCmd() *cobra.Command {
+	var (
+		finder    string
+		query     string
+		printOnly bool
+		yes       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "fzf",
+		Short: "Choose a host with fzf (or another fuzzy finder) and connect",
+		Long: `Pipe the inventory through an external fuzzy finder and connect to the selection.
+
+The finder is taken from --finder, then $SSHM_FINDER, and defaults to fzf. It
+receives one tab separated line per host (name, address, group, tags) on stdin
+and must print the chosen line. With fzf a preview of the host is shown.`,
+		Example: `  sshm fzf
+  sshm fzf --query prod
+  SSHM_FINDER=sk sshm fzf --print`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if finder == "" {
+				finder = os.Getenv("SSHM_FINDER")
+			}
+			if finder == "" {
+				finder = "fzf"
+			}
+
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			hosts := s.ListHosts()
+			if len(hosts) == 0 {
+				return fmt.Errorf("no hosts to choose from, add one with sshm add")
+			}
+
+			var input bytes.Buffer
+			if err := writeFinderLines(&input, hosts); err != nil {
+				return err
+			}
+
+			fields := strings.Fields(finder)
+			finderArgs := fields[1:]
+			if filepath.Base(fields[0]) == "fzf" {
+				finderArgs = append(finderArgs, fzfArgs(query)...)
+			}
+
+			var output bytes.Buffer
+			finderCmd := exec.Command(fields[0], finderArgs...)
+			finderCmd.Stdin = &input
+			finderCmd.Stdout = &output
+			finderCmd.Stderr = os.Stderr
+			if err := finderCmd.Run(); err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					// Cancelled or nothing matched
+					return &exitCodeError{code: exitErr.ExitCode()}
+				}
+				return fmt.Errorf("failed to run %s: %w", fields[0], err)
+			}
+
+			name, _, _ := strings.Cut(strings.TrimSpace(output.String()), "\t")
+			if name == "" {
+				return &exitCodeError{code: 1}
+			}
+			host, err := findHost(s, name)
+			if err != nil {
+				return err
+			}
+
+			if printOnly {
+				fmt.Fprintln(cmd.OutOrStdout(), host.Name)
+				return nil
+			}
+			return connectHost(cmd, s, host, yes)
+		},
+	}
+
+	cmd.Flags().StringVar(&finder, "finder", "", "fuzzy finder command (default $SSHM_FINDER or fzf)")
+	cmd.Flags().StringVarP(&query, "query", "q", "", "initial query (fzf only)")
+	cmd.Flags().BoolVar(&printOnly, "print", false, "print the chosen host name instead of connecting")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "connect to guarded hosts without asking")
+
+	return cmd
+}
+
+// fzfArgs returns the fzf options for the host lines: search all fields,
+// show a preview of the host and start with the given query
+func fzfArgs(query string) []string {
+	args := []string{"--delimiter=\t", "--prompt=sshm> ", "--height=40%", "--reverse"}
+	if self, err := os.Executable(); err == nil {
+		preview := fmt.Sprintf("%q show {1}", self)
+		if configPath != "" {
+			preview = fmt.Sprintf("%q --config %q show {1}", self, configPath)
+		}
+		args = append(args, "--preview="+preview, "--preview-window=right:50%")
+	}
+	if query != "" {
+		args = append(args, "--query="+query)
+	}
+	return args
+}
+
+// writeFinderLines writes one tab separated line per host, name first, so
+// finders can match on every field and scripts can cut out the name
+func writeFinderLines(w io.Writer, hosts []models.Host) error {
+	for _, h := range hosts {
+		addr := net.JoinHostPort(h.Host, strconv.Itoa(h.Port))
+		if h.User != "" {
+			addr = h.User + "@" + addr
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", h.Name, addr, h.Group, strings.Join(h.Tags, ",")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/ssh"
+	"github.com/sshm/sshm/internal/store"
+)
+
+// openStore opens the host store at the configured path
+func openStore() (*store.FileStore, error) {
+	paths := resolvePaths()
+	return store.NewFileStoreWithConfig(paths.Hosts, paths.Config)
+}
+
+// loadConfig loads the settings file
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig(resolveConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	// Connections made after loading the settings use their Vault and keyring
+	ssh.Vault = cfg.VaultClient()
+	ssh.KeyringPasswords = cfg.KeyringPasswords
+	ssh.WindowsKeys = cfg.WSL.WindowsKeys
+	ssh.WindowsAgentRelay = cfg.WSL.AgentRelay()
+	return cfg, nil
+}
+
+// connectProfile returns the profile to connect to host with, refusing the
+// direct connections bastion_policy forbids
+func connectProfile(cfg *config.Config, host models.Host) (models.Profile, error) {
+	if err := cfg.CheckBastion(host); err != nil {
+		return models.Profile{}, err
+	}
+	return cfg.GetProfile(host), nil
+}
+
+// findHost looks up a host by name or ID
+func findHost(s *store.FileStore, name string) (models.Host, error) {
+	host, err := s.GetHostByName(name)
+	if errors.Is(err, store.ErrHostNotFound) {
+		return host, fmt.Errorf("host %q %w", name, errNotFound)
+	}
+	return host, err
+}
+
+// resolveHost finds the single host meant by query, accepting exact names,
+// unique prefixes and fuzzy matches
+// A selector such as "tag:db AND group:eu" is accepted as long as it picks
+// exactly one host
+func resolveHost(s *store.FileStore, query string) (models.Host, error) {
+	var matches []models.Host
+	if store.IsSelector(query) {
+		var err error
+		if matches, err = s.SelectHosts(query); err != nil {
+			return models.Host{}, err
+		}
+	} else {
+		matches = s.ResolveHost(query)
+	}
+	switch len(matches) {
+	case 0:
+		return models.Host{}, fmt.Errorf("host %q %w", query, errNotFound)
+	case 1:
+		return matches[0], nil
+	}
+	return models.Host{}, &store.AmbiguousError{Query: query, Matches: matches}
+}
+
+// selectHosts expands targets into hosts using the selector syntax shared
+// with the TUI: host names (resolved like connect), name patterns such as
+// "prod-web-*", "tag:<tag>", "group:<group>" and "user:<user>", combined with
+// AND, OR, NOT and parentheses. Separate targets are combined with OR.
+// Hosts matched by several targets are returned once, in target order
+func selectHosts(s *store.FileStore, targets []string) ([]models.Host, error) {
+	expr := strings.Join(targets, " ")
+	hosts, err := s.SelectHosts(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no host matches %q: %w", expr, errNotFound)
+	}
+	return hosts, nil
+}
+
+// completeHostNames offers host names for shell completion of the first argument
+func completeHostNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 && cmd.Args != nil && cmd.Args(cmd, append(args, toComplete)) != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	s, err := openStore()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, h := range s.ListHosts() {
+		if strings.HasPrefix(h.Name, toComplete) {
+			names = append(names, h.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// parseTarget splits a "[user@]host[:port]" address into its parts
+// The port and user are left empty (0 and "") when not given
+func parseTarget(target string) (user, host string, port int, err error) {
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		user = target[:i]
+		target = target[i+1:]
+	}
+
+	host = target
+	// Bracketed IPv6 addresses may carry a port: [::1]:2222
+	if strings.HasPrefix(target, "[") {
+		end := strings.Index(target, "]")
+		if end < 0 {
+			return "", "", 0, fmt.Errorf("invalid address %q", target)
+		}
+		host = target[1:end]
+		target = target[end+1:]
+		if target == "" {
+			return user, host, port, nil
+		}
+		if !strings.HasPrefix(target, ":") {
+			return "", "", 0, fmt.Errorf("invalid address %q", target)
+		}
+		port, err = parsePort(target[1:])
+		return user, host, port, err
+	}
+
+	// A single colon separates the port; more than one means a bare IPv6 address
+	if strings.Count(target, ":") == 1 {
+		i := strings.Index(target, ":")
+		host = target[:i]
+		port, err = parsePort(target[i+1:])
+		if err != nil {
+			return "", "", 0, err
+		}
+	}
+
+	if host == "" {
+		return "", "", 0, fmt.Errorf("missing host in %q", target)
+	}
+	return user, host, port, nil
+}
+
+// parsePort parses a TCP port number
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
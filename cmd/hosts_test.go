@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/store"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		user    string
+		host    string
+		port    int
+		wantErr bool
+	}{
+		{"example.com", "", "example.com", 0, false},
+		{"deploy@10.0.0.4", "deploy", "10.0.0.4", 0, false},
+		{"admin@db.example.com:2222", "admin", "db.example.com", 2222, false},
+		{"[::1]:2200", "", "::1", 2200, false},
+		{"root@[fe80::1]", "root", "fe80::1", 0, false},
+		{"fe80::1", "", "fe80::1", 0, false},
+		{"host:notaport", "", "", 0, true},
+		{"host:70000", "", "", 0, true},
+		{"user@", "", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		user, host, port, err := parseTarget(tt.target)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTarget(%q) expected error", tt.target)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseTarget(%q) unexpected error: %v", tt.target, err)
+			continue
+		}
+		if user != tt.user || host != tt.host || port != tt.port {
+			t.Errorf("parseTarget(%q) = %q, %q, %d; want %q, %q, %d",
+				tt.target, user, host, port, tt.user, tt.host, tt.port)
+		}
+	}
+}
+
+func TestSelectHostsAndResolve(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	s.AddHost(models.Host{Name: "prod-web-1", Host: "10.0.0.1", Group: "eu", Tags: []string{"web"}})
+	s.AddHost(models.Host{Name: "prod-web-2", Host: "10.0.0.2", Group: "us", Tags: []string{"web"}})
+
+	hosts, err := selectHosts(s, []string{"prod-web-*", "AND", "group:us"})
+	if err != nil || len(hosts) != 1 || hosts[0].Name != "prod-web-2" {
+		t.Errorf("expected prod-web-2, got %v, %v", hosts, err)
+	}
+
+	if _, err := selectHosts(s, []string{"tag:web AND tag:db"}); exitCode(err) != exitNotFound {
+		t.Errorf("expected an unknown tag to exit with %d, got %v", exitNotFound, err)
+	}
+	if _, err := selectHosts(s, []string{"group:eu AND group:us"}); !errors.Is(err, errNotFound) {
+		t.Errorf("expected an empty selection to be not found, got %v", err)
+	}
+
+	// Single-host commands accept a selector that picks one host
+	if host, err := resolveHost(s, "prod-web-* AND group:eu"); err != nil || host.Name != "prod-web-1" {
+		t.Errorf("expected prod-web-1, got %v, %v", host.Name, err)
+	}
+	var ambiguous *store.AmbiguousError
+	if _, err := resolveHost(s, "tag:web"); !errors.As(err, &ambiguous) || len(ambiguous.Matches) != 2 {
+		t.Errorf("expected an ambiguous error listing both hosts, got %v", err)
+	}
+}
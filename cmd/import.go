@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/models"
+)
+
+func newImportCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "import <" + strings.Join(config.ImportFormats, "|") + "> [path]",
+		Short: "Import hosts from SSH config, Ansible inventories, CSV or PuTTY",
+		Long: `Import hosts from another tool. Hosts whose names already exist are skipped.
+
+  ssh-config  an OpenSSH client config (default ~/.ssh/config)
+  ansible     an Ansible inventory in INI or YAML form
+  csv         CSV with a header row: name,host,port,user,identity,proxy,group,tags
+  putty       a registry export of PuTTY sessions:
+              reg export HKCU\Software\SimonTatham\PuTTY\Sessions sessions.reg
+
+Use "-" as the path to read from standard input.`,
+		Example: `  sshm import ssh-config
+  sshm import ansible ./inventory.ini --dry-run
+  sshm import csv hosts.csv`,
+		Args:      cobra.RangeArgs(1, 2),
+		ValidArgs: config.ImportFormats,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format := args[0]
+			path := ""
+			if len(args) > 1 {
+				path = args[1]
+			}
+			if path == "" {
+				if format != config.ImportSSHConfig {
+					return fmt.Errorf("a path is required for %s imports", format)
+				}
+				home, err := os.UserHomeDir()
+				if err != nil {
+					return err
+				}
+				path = filepath.Join(home, ".ssh", "config")
+			}
+
+			data, err := readInput(cmd, path)
+			if err != nil {
+				return err
+			}
+			parsed, err := config.ParseImport(format, data)
+			if err != nil {
+				return err
+			}
+
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			hosts, skipped := config.NewHosts(s.ListHosts(), parsed)
+
+			out := cmd.OutOrStdout()
+			if dryRun {
+				var lines []diffLine
+				for _, h := range hosts {
+					lines = append(lines, diffLine{op: '+', host: h})
+				}
+				for _, name := range skipped {
+					lines = append(lines, diffLine{op: ' ', host: models.Host{Name: name}, note: "already exists, skipped"})
+				}
+				writeDiff(out, lines)
+				fmt.Fprintf(statusWriter(out), "Would import %d hosts, skip %d existing\n", len(hosts), len(skipped))
+				return nil
+			}
+
+			s.Batch()
+			for _, h := range hosts {
+				if err := s.AddHost(h); err != nil {
+					return fmt.Errorf("failed to add %s: %w", h.Name, err)
+				}
+			}
+			if err := s.Flush(); err != nil {
+				return err
+			}
+			status := statusWriter(out)
+			fmt.Fprintf(status, "Imported %d hosts", len(hosts))
+			if len(skipped) > 0 {
+				fmt.Fprintf(status, ", skipped %d existing", len(skipped))
+			}
+			fmt.Fprintln(status)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the hosts that would be added (+) or skipped without saving")
+	return cmd
+}
+
+// readInput reads a file, or standard input when path is "-"
+func readInput(cmd *cobra.Command, path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(cmd.InOrStdin())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
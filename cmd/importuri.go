@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/models"
+)
+
+func newImportURICmd() *cobra.Command {
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   "import-uri <sshm://...>...",
+		Short: "Add hosts shared as sshm:// links",
+		Long: `Add hosts shared as sshm:// links, which Q in the TUI shows with a QR code
+to scan. A link carries the address, user, port, jump host, group, tags and
+auth type of a host, never its password, identity file or host key policy.
+
+Use "-" to read links from standard input, one per line. Hosts whose names
+already exist are refused; --name gives a single host another name.`,
+		Example: `  sshm import-uri 'sshm://deploy@10.0.0.4:2222?name=web1&group=prod'
+  sshm import-uri 'sshm://10.0.0.5?name=db1' --name db1-staging
+  pbpaste | sshm import-uri -`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var links []string
+			for _, arg := range args {
+				if arg != "-" {
+					links = append(links, arg)
+					continue
+				}
+				data, err := readInput(cmd, arg)
+				if err != nil {
+					return err
+				}
+				for _, line := range strings.Split(string(data), "\n") {
+					if line = strings.TrimSpace(line); line != "" {
+						links = append(links, line)
+					}
+				}
+			}
+			if name != "" && len(links) != 1 {
+				return fmt.Errorf("--name needs exactly one link, got %d", len(links))
+			}
+
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			var hosts []models.Host
+			names := make(map[string]bool)
+			for _, link := range links {
+				host, err := models.ParseHostURI(link)
+				if err != nil {
+					return err
+				}
+				if ignored := models.IgnoredURIParams(link); len(ignored) > 0 {
+					fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s: ignored %s (links don't carry them)\n", host.Name, strings.Join(ignored, ", "))
+				}
+				if name != "" {
+					host.Name = name
+				}
+				host = s.ApplyDefaults(host)
+				if err := host.Validate(); err != nil {
+					return err
+				}
+				if _, err := s.GetHostByName(host.Name); err == nil || names[host.Name] {
+					return fmt.Errorf("host %q already exists (use --name to add it under another)", host.Name)
+				}
+				names[host.Name] = true
+				hosts = append(hosts, host)
+			}
+
+			s.Batch()
+			for _, h := range hosts {
+				if err := s.AddHost(h); err != nil {
+					return fmt.Errorf("failed to add %s: %w", h.Name, err)
+				}
+			}
+			if err := s.Flush(); err != nil {
+				return err
+			}
+			for _, h := range hosts {
+				fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Added %s\n", h.Name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "name of the host instead of the one in the link")
+	return cmd
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+func TestImportURICommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	run := func(stdin string, args ...string) error {
+		root := newRootCmd()
+		root.SetOut(io.Discard)
+		root.SetIn(strings.NewReader(stdin))
+		root.SetArgs(append([]string{"--config", path, "import-uri"}, args...))
+		return root.Execute()
+	}
+
+	shared := models.Host{Name: "web1", Host: "10.0.0.4", Port: 2222, User: "deploy", Group: "prod", Tags: []string{"web"}}
+	if err := run("", shared.URI()); err != nil {
+		t.Fatalf("import-uri failed: %v", err)
+	}
+	if err := run("sshm://10.0.0.5?name=db1\n\nsshm://admin@10.0.0.6\n", "-"); err != nil {
+		t.Fatalf("import-uri from stdin failed: %v", err)
+	}
+
+	s := openTestStore(t, path)
+	web, err := s.GetHostByName("web1")
+	if err != nil || web.Host != "10.0.0.4" || web.Port != 2222 || web.User != "deploy" || web.Group != "prod" || len(web.Tags) != 1 {
+		t.Errorf("unexpected host from the link: %+v (%v)", web, err)
+	}
+	if db, err := s.GetHostByName("db1"); err != nil || db.Port != 22 {
+		t.Errorf("expected db1 on the default port, got %+v (%v)", db, err)
+	}
+	if _, err := s.GetHostByName("10.0.0.6"); err != nil {
+		t.Errorf("expected a link without a name to be named after the address: %v", err)
+	}
+
+	if err := run("", shared.URI(), "--name", "web1-copy"); err != nil {
+		t.Fatalf("import-uri --name failed: %v", err)
+	}
+	for _, args := range [][]string{
+		{shared.URI()},     // duplicate
+		{"ssh://10.0.0.7"}, // not a link
+		{"sshm://10.0.0.7", "sshm://10.0.0.8", "--name", "x"}, // --name with two links
+	} {
+		if err := run("", args...); err == nil {
+			t.Errorf("expected import-uri %v to fail", args)
+		}
+	}
+	if got := len(openTestStore(t, path).ListHosts()); got != 4 {
+		t.Errorf("expected 4 hosts, got %d", got)
+	}
+
+	// A crafted link can't turn off host key checking
+	if err := run("", "sshm://10.0.0.9?name=lab&policy=insecure&identity=%2Ftmp%2Fkey"); err != nil {
+		t.Fatalf("import-uri failed: %v", err)
+	}
+	if lab, err := openTestStore(t, path).GetHostByName("lab"); err != nil || lab.HostKeyPolicy != "" || lab.Identity != "" {
+		t.Errorf("expected policy and identity to be ignored, got %+v (%v)", lab, err)
+	}
+}
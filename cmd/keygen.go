@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/ssh"
+	"golang.org/x/term"
+)
+
+func newKeygenCmd() *cobra.Command {
+	var (
+		keyType    string
+		bits       int
+		comment    string
+		passphrase bool
+		assign     []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "keygen [file]",
+		Short: "Create a new SSH key pair",
+		Long: `Create a new SSH key pair in OpenSSH format.
+
+The file defaults to ~/.ssh/id_<type>; a bare name is placed in ~/.ssh. The
+public key is written next to it with .pub appended. Existing files are never
+overwritten. With --assign the new key becomes the identity of the given
+hosts, chosen with the selectors of exec (names, patterns, tag:, group:).
+
+Keys protected with --passphrase must be loaded into the SSH agent
+(ssh-add) before sshm can use them.`,
+		Example: `  sshm keygen
+  sshm keygen deploy --assign tag:prod
+  sshm keygen -t rsa -b 4096 -C ci@example.com --passphrase`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if keyType == "" {
+				keyType = ssh.KeyTypeEd25519
+			}
+			path := "~/.ssh/id_" + keyType
+			if len(args) > 0 {
+				path = args[0]
+				if !strings.ContainsRune(path, filepath.Separator) && !strings.ContainsRune(path, '/') {
+					path = filepath.Join("~/.ssh", path)
+				}
+			}
+			if comment == "" {
+				comment = defaultKeyComment()
+			}
+
+			// Resolve hosts first so a typo does not leave an unused key behind
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			hosts, err := selectHosts(s, assign)
+			if err != nil {
+				return err
+			}
+
+			var secret []byte
+			if passphrase {
+				if secret, err = readNewPassphrase(); err != nil {
+					return err
+				}
+			}
+
+			kp, err := ssh.GenerateKey(keyType, bits, comment, secret)
+			if err != nil {
+				return err
+			}
+			if err := ssh.WriteKeyPair(kp, path); err != nil {
+				return err
+			}
+
+			out := statusWriter(cmd.OutOrStdout())
+			fingerprint, _ := ssh.Fingerprint(kp.Public)
+			fmt.Fprintf(out, "Created %s (%s)\n", path, fingerprint)
+
+			s.Batch()
+			for _, h := range hosts {
+				h.Identity = path
+				if err := s.UpdateHost(h); err != nil {
+					return fmt.Errorf("failed to assign key to %s: %w", h.Name, err)
+				}
+			}
+			if err := s.Flush(); err != nil {
+				return fmt.Errorf("failed to assign key: %w", err)
+			}
+			for _, h := range hosts {
+				fmt.Fprintf(out, "Assigned to %s\n", h.Name)
+			}
+			if len(hosts) > 0 {
+				fmt.Fprintln(out, "Install it on the hosts with: sshm copy-id <host>")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&keyType, "type", "t", ssh.KeyTypeEd25519, "key type: ed25519, rsa, ecdsa")
+	cmd.Flags().IntVarP(&bits, "bits", "b", 0, "RSA size (default 4096) or ECDSA curve (256, 384, 521)")
+	cmd.Flags().StringVarP(&comment, "comment", "C", "", "key comment (default user@hostname)")
+	cmd.Flags().BoolVar(&passphrase, "passphrase", false, "protect the private key with a passphrase")
+	cmd.Flags().StringSliceVar(&assign, "assign", nil, "hosts to use the new key as identity")
+
+	return cmd
+}
+
+// defaultKeyComment returns user@hostname like ssh-keygen
+func defaultKeyComment() string {
+	name := "sshm"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		return name + "@" + host
+	}
+	return name
+}
+
+// readNewPassphrase asks for a passphrase twice on the terminal
+func readNewPassphrase() ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("--passphrase needs a terminal to read from")
+	}
+
+	fmt.Fprint(os.Stderr, "Enter passphrase: ")
+	first, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprint(os.Stderr, "Enter same passphrase again: ")
+	second, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(first, second) {
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+	if len(first) == 0 {
+		return nil, fmt.Errorf("passphrase is empty")
+	}
+	return first, nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/ssh"
+)
+
+// defaultIdentities are the keys ssh tries for hosts without an identity
+var defaultIdentities = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+
+// keyUse is an identity file and the hosts using it
+type keyUse struct {
+	path  string // as written in the hosts file
+	info  ssh.KeyInfo
+	err   error // the key could not be read
+	hosts []models.Host
+}
+
+// old reports whether the key is older than maxAge; 0 never is
+func (k keyUse) old(maxAge time.Duration, now time.Time) bool {
+	return k.err == nil && maxAge > 0 && k.info.Age(now) > maxAge
+}
+
+func newKeysCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Show the age of identity files and rotate them",
+		Long: `Show the identity files used by hosts with their type and age, and rotate old
+ones onto the hosts using them.
+
+A key's age is that of its .pub file, or of the private key without one. With
+key_max_age (days) in the settings, older keys are flagged here and in
+"sshm doctor".`,
+		Example: `  sshm keys list
+  sshm keys rotate ~/.ssh/deploy`,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(newKeysListCmd(), newKeysRotateCmd())
+
+	return cmd
+}
+
+func newKeysListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Short:   "List the identity files of the hosts and the default keys",
+		Example: `  sshm keys list`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			keys := keyUses(cfg, s.ListHosts())
+			return writeKeyUses(cmd.OutOrStdout(), keys, keyMaxAge(cfg), time.Now())
+		},
+	}
+}
+
+func newKeysRotateCmd() *cobra.Command {
+	var (
+		to         string
+		keyType    string
+		bits       int
+		comment    string
+		passphrase bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rotate <key>",
+		Short: "Replace a key on the hosts that use it",
+		Long: `Replace a key on the hosts that use it, in three steps: generate a new key of
+the same type next to the old one (or at --to), install it on each host with
+the old key like copy-id, and make it the identity of the hosts where that
+worked.
+
+Hosts that could not be reached keep the old key; run rotate again for them
+once they are back, with --to pointing at the new key's path. The old key
+stays in the hosts' authorized_keys until you remove it.`,
+		Example: `  sshm keys rotate ~/.ssh/deploy
+  sshm keys rotate ~/.ssh/id_rsa -t ed25519 --to ~/.ssh/id_ed25519`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			var key *keyUse
+			for _, k := range keyUses(cfg, s.ListHosts()) {
+				if k.path == args[0] || (k.err == nil && k.info.Path == args[0]) {
+					key = &k
+					break
+				}
+			}
+			if key == nil || len(key.hosts) == 0 {
+				return fmt.Errorf("no host uses %s: %w", args[0], errNotFound)
+			}
+			if key.err != nil {
+				return key.err
+			}
+
+			if to == "" {
+				to = rotatedKeyPath(key.path, time.Now())
+			}
+			if keyType == "" {
+				keyType = ssh.GenerateKeyType(key.info.Type)
+			}
+			if comment == "" {
+				comment = defaultKeyComment()
+			}
+			out := statusWriter(cmd.OutOrStdout())
+
+			// An earlier, partly failed rotation left the new key behind
+			public, _, err := ssh.ReadPublicKey(to+".pub", models.Host{})
+			if err == nil {
+				fmt.Fprintf(out, "Using the existing %s\n", to)
+			} else {
+				var secret []byte
+				if passphrase {
+					if secret, err = readNewPassphrase(); err != nil {
+						return err
+					}
+				}
+				kp, err := ssh.GenerateKey(keyType, bits, comment, secret)
+				if err != nil {
+					return err
+				}
+				if err := ssh.WriteKeyPair(kp, to); err != nil {
+					return err
+				}
+				public = kp.Public
+				fingerprint, _ := ssh.Fingerprint(kp.Public)
+				fmt.Fprintf(out, "Created %s (%s)\n", to, fingerprint)
+			}
+
+			failed := 0
+			for _, host := range key.hosts {
+				profile, err := connectProfile(cfg, host)
+				if err == nil {
+					_, err = ssh.CopyID(host, profile, public)
+				}
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", host.Name, err)
+					failed++
+					continue
+				}
+				host.Identity = to
+				if err := s.UpdateHost(host); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s: installed, but not updated: %v\n", host.Name, err)
+					failed++
+					continue
+				}
+				fmt.Fprintf(out, "Rotated %s\n", host.Name)
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d hosts still use %s", failed, len(key.hosts), key.path)
+			}
+			fmt.Fprintf(out, "All hosts use %s now; remove the old key from their authorized_keys when you're done\n", to)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "path of the new key (default: the old one with the date appended)")
+	cmd.Flags().StringVarP(&keyType, "type", "t", "", "key type: ed25519, rsa, ecdsa (default: that of the old key)")
+	cmd.Flags().IntVarP(&bits, "bits", "b", 0, "RSA size (default 4096) or ECDSA curve (256, 384, 521)")
+	cmd.Flags().StringVarP(&comment, "comment", "C", "", "key comment (default user@hostname)")
+	cmd.Flags().BoolVar(&passphrase, "passphrase", false, "protect the new private key with a passphrase")
+
+	return cmd
+}
+
+// keyUses groups the hosts by identity file, falling back to the profile's,
+// and adds the default keys present in ~/.ssh
+func keyUses(cfg *config.Config, hosts []models.Host) []keyUse {
+	var keys []keyUse
+	index := make(map[string]int)
+	add := func(path string, host *models.Host) {
+		i, ok := index[path]
+		if !ok {
+			info, err := ssh.InspectKey(path)
+			// The same file may be written in different ways
+			if j, seen := index[info.Path]; err == nil && seen {
+				i, ok = j, true
+			}
+			if !ok {
+				i = len(keys)
+				keys = append(keys, keyUse{path: path, info: info, err: err})
+				if err == nil {
+					index[info.Path] = i
+				}
+			}
+			index[path] = i
+		}
+		if host != nil {
+			keys[i].hosts = append(keys[i].hosts, *host)
+		}
+	}
+
+	for _, h := range hosts {
+		identity := h.Identity
+		if identity == "" {
+			identity = cfg.GetProfile(h).IdentityFile
+		}
+		if identity != "" {
+			add(identity, &h)
+		}
+	}
+	for _, path := range append(defaultIdentities, ssh.WindowsIdentities()...) {
+		if _, ok := index[path]; !ok && ssh.IdentityExists(path) {
+			add(path, nil)
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].path < keys[j].path })
+	return keys
+}
+
+// keyMaxAge returns key_max_age as a duration, 0 when unset
+func keyMaxAge(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.KeyMaxAge) * 24 * time.Hour
+}
+
+// writeKeyUses lists the keys with their type, age and hosts
+func writeKeyUses(w io.Writer, keys []keyUse, maxAge time.Duration, now time.Time) error {
+	if len(keys) == 0 {
+		fmt.Fprintln(statusWriter(w), "No identity files")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tTYPE\tAGE\tHOSTS")
+	old := false
+	for _, k := range keys {
+		names := make([]string, len(k.hosts))
+		for i, h := range k.hosts {
+			names[i] = h.Name
+		}
+		hosts := strings.Join(names, ", ")
+		if hosts == "" {
+			hosts = "-"
+		}
+		if k.err != nil {
+			reason := "unreadable"
+			if os.IsNotExist(k.err) {
+				reason = "missing"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t-\t%s\n", k.path, reason, hosts)
+			continue
+		}
+		keyType := k.info.Type
+		if k.info.Bits > 0 {
+			keyType += fmt.Sprintf(" %d", k.info.Bits)
+		}
+		age := fmt.Sprintf("%dd", int(k.info.Age(now).Hours()/24))
+		if k.old(maxAge, now) {
+			age += " (old)"
+			old = true
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", k.path, keyType, age, hosts)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if old {
+		fmt.Fprintf(statusWriter(w), "\nKeys marked old are over %d days old, replace them with \"sshm keys rotate <key>\"\n", int(maxAge.Hours()/24))
+	}
+	return nil
+}
+
+// rotatedSuffix matches the date rotatedKeyPath appends
+var rotatedSuffix = regexp.MustCompile(`-\d{8}$`)
+
+// rotatedKeyPath returns the path of the key replacing path: the same name
+// with today's date in place of an earlier one
+func rotatedKeyPath(path string, now time.Time) string {
+	return rotatedSuffix.ReplaceAllString(path, "") + "-" + now.Format("20060102")
+}
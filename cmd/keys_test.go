@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sshm/sshm/internal/ssh"
+)
+
+func TestKeysList(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"deploy", "ci"} {
+		kp, err := ssh.GenerateKey(ssh.KeyTypeEd25519, 0, "test", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ssh.WriteKeyPair(kp, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	created := time.Now().Add(-100 * 24 * time.Hour)
+	os.Chtimes(filepath.Join(dir, "deploy.pub"), created, created)
+
+	path := filepath.Join(dir, "hosts.yaml")
+	data := "key_max_age: 90\nhosts:\n" +
+		"  - name: web1\n    host: 10.0.0.1\n    identity: " + filepath.Join(dir, "deploy") + "\n" +
+		"  - name: web2\n    host: 10.0.0.2\n    identity: " + filepath.Join(dir, "deploy") + "\n" +
+		"  - name: ci1\n    host: 10.0.0.3\n    identity: " + filepath.Join(dir, "ci") + "\n" +
+		"  - name: old1\n    host: 10.0.0.4\n    identity: " + filepath.Join(dir, "gone") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		root := newRootCmd()
+		root.SetOut(&out)
+		root.SetErr(&bytes.Buffer{})
+		root.SetArgs(append([]string{"--config", path}, args...))
+		err := root.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("keys", "list")
+	if err != nil {
+		t.Fatalf("keys list failed: %v", err)
+	}
+	for _, want := range []string{"ssh-ed25519  100d (old)  web1, web2", "missing", "old1", "over 90 days old"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "0d (old)  ci1") {
+		t.Errorf("expected the new key not to be old:\n%s", out)
+	}
+
+	if _, err := run("keys", "rotate", filepath.Join(dir, "unused")); !errors.Is(err, errNotFound) {
+		t.Errorf("expected a key no host uses to be not found, got %v", err)
+	}
+}
+
+func TestRotatedKeyPath(t *testing.T) {
+	now := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	if got := rotatedKeyPath("~/.ssh/deploy", now); got != "~/.ssh/deploy-20261016" {
+		t.Errorf("rotatedKeyPath = %q", got)
+	}
+	if got := rotatedKeyPath("~/.ssh/deploy-20250101", now); got != "~/.ssh/deploy-20261016" {
+		t.Errorf("expected the earlier date to be replaced, got %q", got)
+	}
+}
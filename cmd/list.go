@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newListCmd() *cobra.Command {
+	var (
+		opts outputOptions
+		fzf  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List all hosts",
+		Example: `  sshm list
+  sshm list -o json
+  sshm list --fields name,host,tags
+  sshm list --fzf | fzf --delimiter '\t' | cut -f1`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.validate(); err != nil {
+				return err
+			}
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			if fzf {
+				return writeFinderLines(cmd.OutOrStdout(), s.ListHosts())
+			}
+			return writeHosts(cmd.OutOrStdout(), s.ListHosts(), opts)
+		},
+	}
+
+	addOutputFlags(cmd, &opts)
+	cmd.Flags().BoolVar(&fzf, "fzf", false, "one tab separated line per host, name first, for fuzzy finders")
+	return cmd
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sshm/sshm/internal/ssh"
+	"github.com/sshm/sshm/internal/store"
+)
+
+// openTestStore opens the store at path, failing the test on errors
+func openTestStore(t *testing.T, path string) *store.FileStore {
+	t.Helper()
+	s, err := store.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	return s
+}
+
+func TestExitCode(t *testing.T) {
+	_, notFound := findHost(openTestStore(t, filepath.Join(t.TempDir(), "hosts.json")), "missing")
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"explicit code", &exitCodeError{code: 7}, 7},
+		{"generic", errors.New("boom"), exitError},
+		{"host not found", notFound, exitNotFound},
+		{"interrupted", fmt.Errorf("copy: %w", context.Canceled), exitInterrupted},
+		{"auth", errors.New("ssh: handshake failed: ssh: unable to authenticate"), exitAuth},
+		{"network", fmt.Errorf("failed to connect to db1:22: %w", dialErr), exitNetwork},
+		{"timeout", &ssh.TimeoutError{After: time.Second}, exitNetwork},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCommonExitCode(t *testing.T) {
+	timeout := &ssh.TimeoutError{After: time.Second}
+
+	if got := commonExitCode([]error{nil, timeout, timeout}); got != exitNetwork {
+		t.Errorf("expected shared network code, got %d", got)
+	}
+	if got := commonExitCode([]error{timeout, errors.New("unable to authenticate")}); got != exitError {
+		t.Errorf("expected generic code for mixed failures, got %d", got)
+	}
+	if got := commonExitCode([]error{nil, nil}); got != exitOK {
+		t.Errorf("expected success without failures, got %d", got)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/ssh"
+)
+
+func newMountCmd() *cobra.Command {
+	var (
+		readOnly bool
+		options  []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "mount <host> [remote-path] [mountpoint]",
+		Short: "Mount a remote directory with sshfs",
+		Long: `Mount a directory of a host locally with sshfs, using the host's stored
+address, port, identity, jump host and profile timeouts. A stored password is
+passed to sshfs without prompting.
+
+Without a remote path the login directory is mounted. The mountpoint defaults
+to ~/.sshm/mnt/<host> and is created if needed. Unmount with "sshm umount".
+sshfs must be installed (macFUSE and sshfs on macOS).`,
+		Example: `  sshm mount web1
+  sshm mount web1 /var/log ~/mnt/web1-logs --read-only
+  sshm mount db1 /srv -o allow_other`,
+		Args:              cobra.RangeArgs(1, 3),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, args[0])
+			if err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			remote := ""
+			if len(args) > 1 {
+				remote = args[1]
+			}
+			var mountpoint string
+			if len(args) > 2 {
+				mountpoint = args[2]
+			} else if mountpoint, err = defaultMountpoint(host.Name); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(mountpoint, 0700); err != nil {
+				return fmt.Errorf("failed to create mountpoint: %w", err)
+			}
+
+			profile, err := connectProfile(cfg, host)
+			if err != nil {
+				return err
+			}
+			opts := ssh.MountOptions{ReadOnly: readOnly, Extra: options}
+			if err := ssh.Mount(host, profile, remote, mountpoint, opts); err != nil {
+				return err
+			}
+			fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Mounted %s:%s on %s\n", host.Name, remote, mountpoint)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&readOnly, "read-only", false, "mount read-only")
+	cmd.Flags().StringArrayVarP(&options, "option", "o", nil, "extra sshfs -o option (repeatable)")
+
+	return cmd
+}
+
+func newUmountCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "umount <host|mountpoint>",
+		Short: "Unmount a directory mounted with sshm mount",
+		Long: `Unmount a directory mounted with "sshm mount". Given a host name the
+default mountpoint ~/.sshm/mnt/<host> is unmounted and removed.`,
+		Example: `  sshm umount web1
+  sshm umount ~/mnt/web1-logs`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mountpoint, isDefault, err := umountTarget(args[0])
+			if err != nil {
+				return err
+			}
+			if err := ssh.Unmount(mountpoint); err != nil {
+				return err
+			}
+			if isDefault {
+				// Only an empty directory goes, so nothing is lost if this fails
+				os.Remove(mountpoint)
+			}
+			fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Unmounted %s\n", mountpoint)
+			return nil
+		},
+	}
+}
+
+// defaultMountpoint returns ~/.sshm/mnt/<name>
+func defaultMountpoint(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("no mountpoint given and no home directory: %w", err)
+	}
+	return filepath.Join(home, ".sshm", "mnt", name), nil
+}
+
+// umountTarget turns the umount argument into a mountpoint
+// Anything that looks like a path is taken as is; otherwise it is a host
+// whose default mountpoint is used.
+func umountTarget(arg string) (mountpoint string, isDefault bool, err error) {
+	if strings.ContainsRune(arg, os.PathSeparator) || strings.Contains(arg, "/") {
+		return arg, false, nil
+	}
+	if info, statErr := os.Stat(arg); statErr == nil && info.IsDir() {
+		return arg, false, nil
+	}
+
+	s, err := openStore()
+	if err != nil {
+		return "", false, err
+	}
+	host, err := resolveHost(s, arg)
+	if err != nil {
+		return "", false, err
+	}
+	mountpoint, err = defaultMountpoint(host.Name)
+	return mountpoint, err == nil, err
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+func TestUmountTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	openTestStore(t, path).AddHost(models.Host{Name: "web1", Host: "10.0.0.1", Port: 22})
+	configPath = path
+	defer func() { configPath = "" }()
+	t.Setenv("HOME", t.TempDir())
+
+	want, _ := defaultMountpoint("web1")
+	tests := []struct {
+		arg       string
+		want      string
+		isDefault bool
+	}{
+		{"web1", want, true},
+		{"./web1", "./web1", false},
+		{"/mnt/web1", "/mnt/web1", false},
+	}
+	for _, tt := range tests {
+		got, isDefault, err := umountTarget(tt.arg)
+		if err != nil || got != tt.want || isDefault != tt.isDefault {
+			t.Errorf("umountTarget(%q) = %q, %v, %v; want %q, %v", tt.arg, got, isDefault, err, tt.want, tt.isDefault)
+		}
+	}
+
+	if _, _, err := umountTarget("missing"); !errors.Is(err, errNotFound) {
+		t.Errorf("expected an unknown host to be not found, got %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// hostFields lists the fields selectable with --fields, in display order
+var hostFields = []string{
+	"id", "name", "host", "port", "user", "user@host", "identity", "auth_type",
+	"proxy", "group", "tags", "profile", "connection_count", "aliases",
+}
+
+// defaultTableFields are the columns shown by the table format
+var defaultTableFields = []string{"name", "user@host", "port", "group", "tags"}
+
+// outputOptions holds the --output and --fields flags of a command
+type outputOptions struct {
+	format string
+	fields []string
+}
+
+// addOutputFlags registers --output and --fields on cmd
+func addOutputFlags(cmd *cobra.Command, opts *outputOptions) {
+	cmd.Flags().StringVarP(&opts.format, "output", "o", outputTable, "output format: table, json, yaml")
+	cmd.Flags().StringSliceVar(&opts.fields, "fields", nil,
+		"comma separated fields to show ("+strings.Join(hostFields, ", ")+")")
+}
+
+// validate checks the format and field names
+func (o *outputOptions) validate() error {
+	switch o.format {
+	case outputTable, outputJSON, outputYAML:
+	default:
+		return fmt.Errorf("unknown output format %q (use table, json or yaml)", o.format)
+	}
+	for i, f := range o.fields {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if _, ok := hostField(models.Host{}, f); !ok {
+			return fmt.Errorf("unknown field %q (use %s)", f, strings.Join(hostFields, ", "))
+		}
+		o.fields[i] = f
+	}
+	return nil
+}
+
+// hostField returns the value of a selectable field
+func hostField(h models.Host, field string) (interface{}, bool) {
+	switch field {
+	case "id":
+		return h.ID, true
+	case "name":
+		return h.Name, true
+	case "host":
+		return h.Host, true
+	case "port":
+		return h.Port, true
+	case "user":
+		return h.User, true
+	case "user@host":
+		return fmt.Sprintf("%s@%s", h.User, h.Host), true
+	case "identity":
+		return h.Identity, true
+	case "auth_type":
+		return string(h.AuthType), true
+	case "proxy":
+		return h.Proxy, true
+	case "group":
+		return h.Group, true
+	case "tags":
+		tags := h.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		return tags, true
+	case "profile":
+		return h.Profile, true
+	case "connection_count":
+		return h.ConnectionCount, true
+	case "aliases":
+		aliases := h.Aliases
+		if aliases == nil {
+			aliases = map[string]string{}
+		}
+		return aliases, true
+	}
+	return nil, false
+}
+
+// formatField renders a field value for the table format
+func formatField(v interface{}) string {
+	switch val := v.(type) {
+	case []string:
+		return strings.Join(val, ",")
+	case map[string]string:
+		h := models.Host{Aliases: val}
+		parts := make([]string, 0, len(val))
+		for _, name := range h.AliasNames() {
+			parts = append(parts, name+"="+val[name])
+		}
+		return strings.Join(parts, "; ")
+	case int:
+		return strconv.Itoa(val)
+	case string:
+		return val
+	}
+	return fmt.Sprint(v)
+}
+
+// redact drops secrets that should never appear in listings
+func redact(hosts []models.Host) []models.Host {
+	result := make([]models.Host, len(hosts))
+	for i, h := range hosts {
+		h.Password = ""
+		h.Online = nil
+		result[i] = h
+	}
+	return result
+}
+
+// selectFields reduces hosts to the chosen fields
+func selectFields(hosts []models.Host, fields []string) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(hosts))
+	for _, h := range hosts {
+		row := make(map[string]interface{}, len(fields))
+		for _, f := range fields {
+			row[f], _ = hostField(h, f)
+		}
+		result = append(result, row)
+	}
+	return result
+}
+
+// encode writes v as JSON or YAML
+func encode(w io.Writer, format string, v interface{}) error {
+	if format == outputYAML {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeHosts prints hosts in the selected format
+func writeHosts(w io.Writer, hosts []models.Host, opts outputOptions) error {
+	if opts.format != outputTable {
+		if len(opts.fields) > 0 {
+			return encode(w, opts.format, selectFields(hosts, opts.fields))
+		}
+		return encode(w, opts.format, redact(hosts))
+	}
+
+	fields := opts.fields
+	if len(fields) == 0 {
+		fields = defaultTableFields
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	headers := make([]string, len(fields))
+	for i, f := range fields {
+		headers[i] = strings.ToUpper(f)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, h := range hosts {
+		values := make([]string, len(fields))
+		for i, f := range fields {
+			v, _ := hostField(h, f)
+			values[i] = formatField(v)
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeHost prints a single host; the table format lists one field per line
+func writeHost(w io.Writer, host models.Host, opts outputOptions) error {
+	if opts.format != outputTable {
+		if len(opts.fields) > 0 {
+			return encode(w, opts.format, selectFields([]models.Host{host}, opts.fields)[0])
+		}
+		return encode(w, opts.format, redact([]models.Host{host})[0])
+	}
+
+	fields := opts.fields
+	if len(fields) == 0 {
+		fields = hostFields
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, f := range fields {
+		v, _ := hostField(host, f)
+		fmt.Fprintf(tw, "%s:\t%s\n", f, formatField(v))
+	}
+	return tw.Flush()
+}
+
+// diffLine is one entry of a --dry-run preview: '+' for a host that would be
+// added, '-' for one that would be removed and ' ' for one left alone
+type diffLine struct {
+	op   byte
+	host models.Host
+	note string
+}
+
+// writeDiff prints a diff-style preview of changes to the inventory
+func writeDiff(w io.Writer, lines []diffLine) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, l := range lines {
+		addr := l.host.Host
+		if addr != "" {
+			if l.host.User != "" {
+				addr = l.host.User + "@" + addr
+			}
+			addr += ":" + strconv.Itoa(l.host.Port)
+		}
+		if l.note == "" {
+			fmt.Fprintf(tw, "%c %s\t%s\n", l.op, l.host.Name, addr)
+			continue
+		}
+		fmt.Fprintf(tw, "%c %s\t%s\t%s\n", l.op, l.host.Name, addr, l.note)
+	}
+	tw.Flush()
+}
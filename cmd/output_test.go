@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+func TestWriteHosts(t *testing.T) {
+	hosts := []models.Host{
+		{ID: "1", Name: "web1", Host: "10.0.0.4", Port: 22, User: "deploy", Password: "secret", Tags: []string{"web", "prod"}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeHosts(&buf, hosts, outputOptions{format: outputJSON}); err != nil {
+		t.Fatalf("writeHosts json failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("JSON output must not contain passwords: %s", buf.String())
+	}
+
+	buf.Reset()
+	opts := outputOptions{format: outputJSON, fields: []string{"name", "tags"}}
+	if err := opts.validate(); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if err := writeHosts(&buf, hosts, opts); err != nil {
+		t.Fatalf("writeHosts fields failed: %v", err)
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rows); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(rows) != 1 || len(rows[0]) != 2 || rows[0]["name"] != "web1" {
+		t.Errorf("expected only name and tags, got %v", rows)
+	}
+
+	buf.Reset()
+	if err := writeHosts(&buf, hosts, outputOptions{format: outputTable, fields: []string{"name", "user@host"}}); err != nil {
+		t.Fatalf("writeHosts table failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "NAME") || !strings.Contains(lines[1], "deploy@10.0.0.4") {
+		t.Errorf("unexpected table output:\n%s", buf.String())
+	}
+
+	for _, bad := range []outputOptions{{format: "xml"}, {format: outputTable, fields: []string{"nope"}}} {
+		if err := bad.validate(); err == nil {
+			t.Errorf("expected validation error for %+v", bad)
+		}
+	}
+}
+
+func TestFormatPick(t *testing.T) {
+	h := models.Host{Name: "web1", Host: "10.0.0.4", Port: 2222, User: "deploy"}
+	tests := map[string]string{
+		pickName:    "web1",
+		pickAddress: "deploy@10.0.0.4:2222",
+		pickURI:     "ssh://deploy@10.0.0.4:2222",
+		pickCommand: "ssh -p 2222 deploy@10.0.0.4",
+	}
+	for format, want := range tests {
+		if got := formatPick(h, format); got != want {
+			t.Errorf("formatPick(%s) = %q, want %q", format, got, want)
+		}
+	}
+	if got := formatPick(models.Host{Host: "::1", Port: 22}, pickAddress); got != "[::1]:22" {
+		t.Errorf("expected bracketed IPv6 address, got %q", got)
+	}
+}
+
+func TestWriteFinderLines(t *testing.T) {
+	hosts := []models.Host{{Name: "web1", Host: "10.0.0.4", Port: 22, User: "deploy", Group: "prod", Tags: []string{"web", "eu"}}}
+
+	var buf bytes.Buffer
+	if err := writeFinderLines(&buf, hosts); err != nil {
+		t.Fatalf("writeFinderLines failed: %v", err)
+	}
+	if want := "web1\tdeploy@10.0.0.4:22\tprod\tweb,eu\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	var buf bytes.Buffer
+	writeDiff(&buf, []diffLine{
+		{op: '+', host: models.Host{Name: "new1", Host: "10.0.0.2", Port: 2200, User: "bob"}},
+		{op: '-', host: models.Host{Name: "web1", Host: "10.0.0.1", Port: 22}},
+		{op: ' ', host: models.Host{Name: "db1"}, note: "already exists, skipped"},
+	})
+
+	want := "+ new1  bob@10.0.0.2:2200\n" +
+		"- web1  10.0.0.1:22\n" +
+		"  db1     already exists, skipped\n"
+	if buf.String() != want {
+		t.Errorf("unexpected diff:\n%q\nwant:\n%q", buf.String(), want)
+	}
+}
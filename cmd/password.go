@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/secret"
+)
+
+func newPasswordCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "password",
+		Short: "Manage host passwords kept in the system keyring",
+		Long: `Manage the passwords of password hosts kept in the system keyring (macOS
+Keychain, or the Secret Service through secret-tool) instead of the hosts file.
+
+With keyring_passwords: true in the settings, a password host without a saved
+password asks for it on login and offers to keep it in the keyring; it is then
+used for later connections, mounts and "sshm exec --sudo".`,
+		Example: `  sshm password set web1
+  sshm password rm web1`,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(newPasswordSetCmd(), newPasswordRmCmd())
+
+	return cmd
+}
+
+func newPasswordSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <host>",
+		Short: "Save the password of a host in the keyring",
+		Long: `Save the password of a host in the keyring, asked for on the terminal or
+read from the first line of stdin. A password saved in the hosts file takes
+precedence, so remove it first.`,
+		Example: `  sshm password set web1
+  pass show web1 | sshm password set web1`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, args[0])
+			if err != nil {
+				return err
+			}
+			if host.AuthType != models.AuthTypePassword {
+				return fmt.Errorf("%s does not use password auth", host.Name)
+			}
+			if host.Password != "" {
+				return fmt.Errorf("%s has a password in the hosts file, which takes precedence over the keyring", host.Name)
+			}
+			password, err := readSecret(cmd, fmt.Sprintf("Password of %s: ", host.Name))
+			if err != nil {
+				return err
+			}
+			if err := secret.SaveHostPassword(host.ID, password); err != nil {
+				return err
+			}
+			fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Saved the password of %s in the keyring\n", host.Name)
+			return nil
+		},
+	}
+}
+
+func newPasswordRmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "rm <host>",
+		Short:             "Remove the password of a host from the keyring",
+		Example:           `  sshm password rm web1`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, args[0])
+			if err != nil {
+				return err
+			}
+			if err := secret.DeleteHostPassword(host.ID); err != nil {
+				return err
+			}
+			fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Removed the password of %s from the keyring\n", host.Name)
+			return nil
+		},
+	}
+}
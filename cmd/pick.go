@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/tui"
+)
+
+// Formats accepted by pick --format
+const (
+	pickName    = "name"
+	pickAddress = "address"
+	pickURI     = "uri"
+	pickCommand = "command"
+)
+
+func newPickCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "pick",
+		Short: "Choose a host in the TUI and print it",
+		Long: `Open the host list, and print the chosen host to stdout when Enter is pressed.
+
+The list is drawn on stderr, so the result can be captured by the shell or an
+editor integration. Formats:
+
+  name     the host name (default)
+  address  [user@]host:port
+  uri      ssh://[user@]host:port, accepted by ssh and scp
+  command  the full ssh command line
+
+The command exits with status 1 if no host was chosen.`,
+		Example: `  sshm connect "$(sshm pick)"
+  ssh "$(sshm pick --format uri)"`,
+		Args:      cobra.NoArgs,
+		ValidArgs: []string{pickName, pickAddress, pickURI, pickCommand},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch format {
+			case pickName, pickAddress, pickURI, pickCommand:
+			default:
+				return fmt.Errorf("unknown format %q (use name, address, uri or command)", format)
+			}
+
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			if s.Count() == 0 {
+				return fmt.Errorf("no hosts to pick from, add one with sshm add")
+			}
+
+			// Style for the terminal the list is drawn on, not the captured stdout
+			lipgloss.DefaultRenderer().SetOutput(termenv.NewOutput(os.Stderr))
+
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			host, err := tui.Pick(ctx, resolvePaths(), os.Stderr)
+			if err != nil {
+				return err
+			}
+			if host == nil {
+				return &exitCodeError{code: 1}
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), formatPick(*host, format))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", pickName, "output format: name, address, uri, command")
+	return cmd
+}
+
+// formatPick renders the chosen host in the requested format
+func formatPick(h models.Host, format string) string {
+	addr := net.JoinHostPort(h.Host, strconv.Itoa(h.Port))
+	if h.User != "" {
+		addr = h.User + "@" + addr
+	}
+
+	switch format {
+	case pickAddress:
+		return addr
+	case pickURI:
+		return "ssh://" + addr
+	case pickCommand:
+		return h.GenerateSSHCommand()
+	}
+	return h.Name
+}
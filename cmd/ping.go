@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/ssh"
+)
+
+// pingResult is the outcome of checking one host
+type pingResult struct {
+	host    models.Host
+	latency time.Duration
+	err     error
+}
+
+// pingFunc checks a single host
+type pingFunc func(h models.Host) error
+
+func newPingCmd() *cobra.Command {
+	var (
+		all         bool
+		handshake   bool
+		timeout     time.Duration
+		concurrency int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "ping [selector]...",
+		Short: "Check whether hosts are reachable",
+		Long: `Check whether hosts are reachable.
+
+By default only the SSH port is probed over TCP. With --ssh a full SSH
+handshake including authentication is performed, through the jump host if
+one is configured. The command exits non-zero if any host is down.
+
+Hosts are chosen with the same selectors as exec: names, patterns such as
+prod-web-*, tag:, group: and user: terms, AND, OR and NOT.`,
+		Example: `  sshm ping web1
+  sshm ping --all
+  sshm ping tag:prod --ssh --timeout 10s
+  sshm ping 'prod-web-*'`,
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all == (len(args) > 0) {
+				return fmt.Errorf("give one or more hosts, or --all")
+			}
+			if concurrency < 1 {
+				return fmt.Errorf("concurrency must be at least 1")
+			}
+
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			hosts := s.ListHosts()
+			if !all {
+				var err error
+				if hosts, err = selectHosts(s, args); err != nil {
+					return err
+				}
+			}
+			if len(hosts) == 0 {
+				fmt.Fprintln(statusWriter(cmd.OutOrStdout()), "No hosts to ping")
+				return nil
+			}
+
+			check := func(h models.Host) error {
+				return ssh.PingTimeout(h.Host, h.Port, timeout)
+			}
+			if handshake {
+				cfg, err := loadConfig()
+				if err != nil {
+					return err
+				}
+				check = func(h models.Host) error {
+					profile, err := connectProfile(cfg, h)
+					if err != nil {
+						return err
+					}
+					return ssh.Handshake(h, profile, timeout)
+				}
+			}
+
+			results := pingHosts(hosts, check, concurrency)
+			if down := writePingResults(cmd.OutOrStdout(), results); down > 0 {
+				errs := make([]error, len(results))
+				for i, r := range results {
+					errs[i] = r.err
+				}
+				return &exitCodeError{code: commonExitCode(errs)}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&all, "all", "a", false, "ping every host in the inventory")
+	cmd.Flags().BoolVar(&handshake, "ssh", false, "perform a full SSH handshake instead of a TCP probe")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "per-host time limit")
+	cmd.Flags().IntVarP(&concurrency, "concurrency", "c", 20, "number of hosts to check at once")
+
+	return cmd
+}
+
+// pingHosts checks all hosts concurrently and returns results in host order
+func pingHosts(hosts []models.Host, check pingFunc, concurrency int) []pingResult {
+	results := make([]pingResult, len(hosts))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, h := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, h models.Host) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			err := check(h)
+			results[i] = pingResult{host: h, latency: time.Since(start), err: err}
+		}(i, h)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// writePingResults prints a status table and summary, returning how many hosts are down
+func writePingResults(w io.Writer, results []pingResult) int {
+	upStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	downStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tADDRESS\tLATENCY\tSTATUS")
+
+	down := 0
+	for _, r := range results {
+		addr := net.JoinHostPort(r.host.Host, strconv.Itoa(r.host.Port))
+		if r.err != nil {
+			down++
+			fmt.Fprintf(tw, "%s\t%s\t-\t%s %v\n", r.host.Name, addr, downStyle.Render("down"), r.err)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%dms\t%s\n", r.host.Name, addr, r.latency.Milliseconds(), upStyle.Render("up"))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(statusWriter(w), "\n%d up, %d down\n", len(results)-down, down)
+	return down
+}
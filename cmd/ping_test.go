@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+func TestPingHosts(t *testing.T) {
+	hosts := []models.Host{{Name: "up1", Host: "10.0.0.1", Port: 22}, {Name: "down1", Host: "10.0.0.2", Port: 22}}
+	check := func(h models.Host) error {
+		if h.Name == "down1" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	var out bytes.Buffer
+	down := writePingResults(&out, pingHosts(hosts, check, 2))
+	if down != 1 {
+		t.Errorf("expected 1 host down, got %d", down)
+	}
+	if !strings.Contains(out.String(), "connection refused") || !strings.Contains(out.String(), "1 up, 1 down") {
+		t.Errorf("unexpected ping output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/recording"
+)
+
+func newPlayCmd() *cobra.Command {
+	var (
+		speed     float64
+		idleLimit time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "play <file|host>",
+		Short: "Replay a recorded session",
+		Long: `Replay a session recorded as an asciicast v2 file in the terminal. Given a
+host instead of a file, its latest recording is played.
+
+Sessions are recorded for the hosts selected by the recording block of the
+settings; asciinema can play the files too.`,
+		Example: `  sshm play web1
+  sshm play ~/.local/share/sshm/recordings/web1/20240501-093000.cast --speed 2
+  sshm play web1 --idle-limit 1s`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			if _, err := os.Stat(path); err != nil {
+				cfg, cfgErr := loadConfig()
+				if cfgErr != nil {
+					return cfgErr
+				}
+				s, err := openStore()
+				if err != nil {
+					return err
+				}
+				name := path
+				if host, err := resolveHost(s, path); err == nil {
+					name = host.Name
+				}
+				if path, err = recording.Latest(cfg.RecordingDir(), name); err != nil {
+					return err
+				}
+				if path == "" {
+					return fmt.Errorf("no recordings of %s in %s: %w", name, cfg.RecordingDir(), errNotFound)
+				}
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return recording.Play(f, cmd.OutOrStdout(), recording.PlayOptions{Speed: speed, IdleLimit: idleLimit})
+		},
+	}
+
+	cmd.Flags().Float64Var(&speed, "speed", 1, "playback speed multiplier")
+	cmd.Flags().DurationVar(&idleLimit, "idle-limit", 0, "shorten pauses longer than this (e.g. 2s)")
+
+	return cmd
+}
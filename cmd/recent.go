@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/store"
+	"github.com/sshm/sshm/internal/tui"
+)
+
+// recentHost is a host together with its latest connection attempt
+type recentHost struct {
+	Name      string    `json:"name" yaml:"name"`
+	Target    string    `json:"target" yaml:"target"`
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	Success   bool      `json:"success" yaml:"success"`
+	Error     string    `json:"error,omitempty" yaml:"error,omitempty"`
+
+	host models.Host
+}
+
+// recentHosts returns up to limit recently connected hosts that still exist,
+// newest first
+func recentHosts(s *store.FileStore, history *store.HistoryStore, limit int) []recentHost {
+	var recent []recentHost
+	for _, entry := range history.RecentHosts(0) {
+		host, err := s.GetHost(entry.HostID)
+		if err != nil {
+			// Removed since
+			continue
+		}
+		recent = append(recent, recentHost{
+			Name:      host.Name,
+			Target:    fmt.Sprintf("%s@%s", host.User, host.Host),
+			Timestamp: entry.Timestamp,
+			Success:   entry.Success,
+			Error:     entry.Error,
+			host:      host,
+		})
+		if len(recent) == limit {
+			break
+		}
+	}
+	return recent
+}
+
+// lastHost returns the most recently connected host, for "sshm connect -"
+func lastHost(s *store.FileStore) (models.Host, error) {
+	recent := recentHosts(s, store.NewHistoryStore(""), 1)
+	if len(recent) == 0 {
+		return models.Host{}, fmt.Errorf("no previous connection: %w", errNotFound)
+	}
+	return recent[0].host, nil
+}
+
+func newRecentCmd() *cobra.Command {
+	var limit int
+	var opts outputOptions
+
+	cmd := &cobra.Command{
+		Use:   "recent",
+		Short: "List recently connected hosts",
+		Long: `List recently connected hosts, newest first, with the time and outcome of
+the last attempt. "sshm connect -" reconnects to the first one.`,
+		Example: `  sshm recent
+  sshm recent -n 3 -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.validate(); err != nil {
+				return err
+			}
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			recent := recentHosts(s, store.NewHistoryStore(""), limit)
+			if opts.format != outputTable {
+				if recent == nil {
+					recent = []recentHost{}
+				}
+				return encode(cmd.OutOrStdout(), opts.format, recent)
+			}
+			writeRecent(cmd.OutOrStdout(), recent)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "n", 10, "number of hosts to show")
+	cmd.Flags().StringVarP(&opts.format, "output", "o", outputTable, "output format: table, json or yaml")
+	return cmd
+}
+
+// writeRecent prints the recent hosts as a table
+func writeRecent(w io.Writer, recent []recentHost) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tUSER@HOST\tLAST CONNECTED\tSTATUS")
+	for _, r := range recent {
+		status := "ok"
+		if !r.Success {
+			status = "failed"
+			if r.Error != "" {
+				status += ": " + r.Error
+			}
+		}
+		when := fmt.Sprintf("%s (%s)", r.Timestamp.Local().Format("2006-01-02 15:04"), tui.FormatLastUsed(r.Timestamp))
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Name, r.Target, when, status)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/store"
+)
+
+func TestRecentHosts(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, filepath.Join(dir, "hosts.json"))
+	s.AddHost(models.Host{ID: "1", Name: "web1", Host: "10.0.0.1", Port: 22, User: "deploy"})
+	s.AddHost(models.Host{ID: "2", Name: "db1", Host: "10.0.0.2", Port: 22, User: "admin"})
+
+	history := store.NewHistoryStore(filepath.Join(dir, "history.json"))
+	history.AddConnection("1", true, "", 10)
+	history.AddConnection("2", true, "", 10)
+	history.AddConnection("gone", true, "", 10)
+	history.AddConnection("1", false, "connection refused", 10)
+
+	recent := recentHosts(s, history, 10)
+	if len(recent) != 2 {
+		t.Fatalf("expected 2 recent hosts without the removed one, got %+v", recent)
+	}
+	if recent[0].Name != "web1" || recent[0].Success || recent[0].Error != "connection refused" {
+		t.Errorf("expected web1's failed attempt first, got %+v", recent[0])
+	}
+	if recent[1].Name != "db1" || recent[1].Target != "admin@10.0.0.2" {
+		t.Errorf("expected db1 second, got %+v", recent[1])
+	}
+
+	if recent := recentHosts(s, history, 1); len(recent) != 1 || recent[0].Name != "web1" {
+		t.Errorf("expected the limit to keep only web1, got %+v", recent)
+	}
+}
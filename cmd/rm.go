@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/models"
+)
+
+func newRmCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:     "rm <name>...",
+		Aliases: []string{"remove", "delete"},
+		Short:   "Remove one or more hosts",
+		Long:    "Remove one or more hosts by exact name or ID. Connection history is kept.",
+		Example: `  sshm rm web1
+  sshm rm web1 web2 db1
+  sshm rm web1 web2 --dry-run`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+
+			// Resolve every name first so a typo removes nothing
+			var hosts []models.Host
+			seen := make(map[string]bool)
+			for _, name := range args {
+				host, err := findHost(s, name)
+				if err != nil {
+					return err
+				}
+				if !seen[host.ID] {
+					seen[host.ID] = true
+					hosts = append(hosts, host)
+				}
+			}
+
+			if dryRun {
+				lines := make([]diffLine, len(hosts))
+				for i, h := range hosts {
+					lines[i] = diffLine{op: '-', host: h}
+				}
+				writeDiff(cmd.OutOrStdout(), lines)
+				fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Would remove %d hosts\n", len(hosts))
+				return nil
+			}
+
+			s.Batch()
+			for _, host := range hosts {
+				if err := s.DeleteHost(host.ID); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", host.Name, err)
+				}
+			}
+			if err := s.Flush(); err != nil {
+				return err
+			}
+			for _, host := range hosts {
+				fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Removed %s\n", host.Name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the hosts that would be removed (-) without removing them")
+	return cmd
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+func TestRmCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	s := openTestStore(t, path)
+	s.AddHost(models.Host{Name: "web1", Host: "10.0.0.1", Port: 22})
+	s.AddHost(models.Host{Name: "web2", Host: "10.0.0.2", Port: 22})
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		root := newRootCmd()
+		root.SetOut(&out)
+		root.SetArgs(append([]string{"--config", path, "rm"}, args...))
+		err := root.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("web1", "web2", "--dry-run")
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if out != "- web1  10.0.0.1:22\n- web2  10.0.0.2:22\nWould remove 2 hosts\n" {
+		t.Errorf("unexpected dry run output:\n%s", out)
+	}
+	if openTestStore(t, path).Count() != 2 {
+		t.Fatal("expected dry run to leave the store untouched")
+	}
+
+	// An unknown name aborts before anything is removed
+	if _, err := run("web1", "missing"); err == nil {
+		t.Error("expected an unknown host to fail")
+	}
+	if openTestStore(t, path).Count() != 2 {
+		t.Error("expected no host to be removed when one name is unknown")
+	}
+
+	if _, err := run("web1"); err != nil {
+		t.Fatalf("rm failed: %v", err)
+	}
+	if openTestStore(t, path).Count() != 1 {
+		t.Error("expected web1 to be removed")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/tui"
+)
+
+// configPath is the --config flag shared by all subcommands
+var configPath string
+
+// quiet is the --quiet flag shared by all subcommands
+var quiet bool
+
+// newRootCmd builds the sshm command tree
+// Without a subcommand sshm starts the TUI
+func newRootCmd() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "sshm",
+		Short: "SSH Host Manager",
+		Long: `Manage SSH hosts from a terminal UI or non-interactively from scripts.
+
+Exit codes:
+  0    success
+  1    generic failure
+  2    host, tag or group not found
+  3    authentication failure
+  4    network failure (unreachable, refused, timed out)
+  130  interrupted
+
+connect and exec on a single host exit with the remote command's status.`,
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := migrateLegacyConfig(cmd.ErrOrStderr()); err != nil {
+				return err
+			}
+			// The config commands report older layouts themselves
+			if cmd.Parent() == nil || cmd.Parent().Name() != "config" {
+				offerMigration(cmd.ErrOrStderr())
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			return tui.Run(ctx, resolvePaths())
+		},
+	}
+
+	root.Version = currentBuild().String()
+	root.SetVersionTemplate("sshm {{.Version}}\n")
+
+	root.PersistentFlags().StringVar(&configPath, "config", "", "file holding both settings and hosts (default: separate config.yaml and hosts.json, see \"sshm doctor\")")
+	root.PersistentFlags().BoolVar(&quiet, "quiet", false, "only print data and errors, no confirmations, progress or summaries")
+
+	root.AddCommand(
+		newListCmd(),
+		newAddCmd(),
+		newRmCmd(),
+		newEditCmd(),
+		newConnectCmd(),
+		newPlayCmd(),
+		newRecentCmd(),
+		newExecCmd(),
+		newRunCmd(),
+		newSnippetCmd(),
+		newPingCmd(),
+		newCopyIDCmd(),
+		newKeygenCmd(),
+		newKeysCmd(),
+		newTunnelCmd(),
+		newCpCmd(),
+		newMountCmd(),
+		newUmountCmd(),
+		newSearchCmd(),
+		newPickCmd(),
+		newFzfCmd(),
+		newShowCmd(),
+		newImportCmd(),
+		newImportURICmd(),
+		newExportCmd(),
+		newServeCmd(),
+		newVersionCmd(),
+		newDoctorCmd(),
+		newConfigCmd(),
+		newSecretCmd(),
+		newTOTPCmd(),
+		newPasswordCmd(),
+		newDocsCmd(),
+	)
+
+	return root
+}
+
+// resolvePaths returns the --config file, which then holds settings and
+// hosts together, or the default locations
+func resolvePaths() config.Paths {
+	if configPath != "" {
+		return config.SingleFile(configPath)
+	}
+	return config.DefaultPaths()
+}
+
+// resolveConfigPath returns the settings file
+func resolveConfigPath() string {
+	return resolvePaths().Config
+}
+
+// migrateLegacyConfig moves ~/.sshm.json to the default locations on first
+// run; an explicit --config is left alone
+func migrateLegacyConfig(w io.Writer) error {
+	if configPath != "" {
+		return nil
+	}
+	paths := config.DefaultPaths()
+	migrated, err := config.MigrateLegacy(paths)
+	if err != nil {
+		return err
+	}
+	if migrated {
+		fmt.Fprintf(statusWriter(w), "Moved %s to %s and %s\n", config.LegacyPaths().Hosts, paths.Config, paths.Hosts)
+	}
+	return nil
+}
+
+// statusWriter returns w, or a discarding writer under --quiet
+// Use it for confirmations, progress and summaries rather than for data.
+func statusWriter(w io.Writer) io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	return w
+}
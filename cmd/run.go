@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/ssh"
+	"golang.org/x/term"
+)
+
+func newRunCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "run <host> [alias] [args...]",
+		Short: "Run one of a host's command aliases",
+		Long: `Run a named command alias defined on a host. Extra arguments are quoted and
+appended to the alias command; sshm's own flags go before the host. Without an
+alias the host's aliases are listed.
+
+On a terminal the command gets a pseudo terminal, like "ssh -t", so commands
+such as "journalctl -f" or "htop" work and Ctrl+C reaches them. The remote
+exit status is passed through. Aliases are managed with
+"sshm edit <host> --alias name=command" and "--unalias name". Hosts with a
+guarded tag are confirmed first, unless --yes is given.`,
+		Example: `  sshm edit web1 --alias logs="journalctl -f -u app"
+  sshm run web1 logs
+  sshm run web1 logs --since today
+  sshm run web1`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeRunArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, args[0])
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				return writeAliases(cmd.OutOrStdout(), host)
+			}
+
+			command, err := aliasCommand(host, args[1], args[2:])
+			if err != nil {
+				return err
+			}
+			return runOnHost(cmd, host, command, yes)
+		},
+	}
+
+	// Flags after the host belong to the remote command
+	cmd.Flags().SetInterspersed(false)
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "run on guarded hosts without asking")
+
+	return cmd
+}
+
+// runOnHost runs command on the host, with a pseudo terminal when sshm runs
+// on one, and passes the remote exit status through
+// Guarded hosts are confirmed first unless yes is set.
+func runOnHost(cmd *cobra.Command, host models.Host, command string, yes bool) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	profile, err := connectProfile(cfg, host)
+	if err != nil {
+		return err
+	}
+	if err := confirmGuarded(cmd, cfg, host, yes); err != nil {
+		return err
+	}
+
+	if cmd.InOrStdin() == os.Stdin && term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+		session := ssh.NewSession(host, profile)
+		session.SetCommand(command)
+		session.SetContext(ctx)
+		err = session.Run()
+	} else {
+		err = ssh.RunCommand(ctx, host, profile, command, cmd.OutOrStdout(), cmd.ErrOrStderr())
+	}
+
+	if code, remote := ssh.ExitStatus(err); remote {
+		return &exitCodeError{code: code}
+	}
+	return err
+}
+
+// aliasCommand returns the alias command with args quoted and appended
+func aliasCommand(host models.Host, alias string, args []string) (string, error) {
+	command, ok := host.Aliases[alias]
+	if !ok {
+		if len(host.Aliases) == 0 {
+			return "", fmt.Errorf("alias %q %w, %s has no aliases", alias, errNotFound, host.Name)
+		}
+		return "", fmt.Errorf("alias %q %w on %s (have: %s)", alias, errNotFound, host.Name, strings.Join(host.AliasNames(), ", "))
+	}
+	for _, arg := range args {
+		command += " " + ssh.ShellQuote(arg)
+	}
+	return command, nil
+}
+
+// writeAliases lists the aliases of a host
+func writeAliases(w io.Writer, host models.Host) error {
+	if len(host.Aliases) == 0 {
+		fmt.Fprintf(statusWriter(w), "%s has no aliases\n", host.Name)
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range host.AliasNames() {
+		fmt.Fprintf(tw, "%s\t%s\n", name, host.Aliases[name])
+	}
+	return tw.Flush()
+}
+
+// completeRunArgs completes the host, then its alias names
+func completeRunArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return completeHostNames(cmd, args, toComplete)
+	case 1:
+		s, err := openStore()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		host, err := resolveHost(s, args[0])
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var names []string
+		for _, name := range host.AliasNames() {
+			if strings.HasPrefix(name, toComplete) {
+				names = append(names, name)
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveDefault
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+func TestAliasCommand(t *testing.T) {
+	host := models.Host{Name: "web1", Aliases: map[string]string{"logs": "journalctl -f -u app"}}
+
+	got, err := aliasCommand(host, "logs", []string{"--since", "two days ago"})
+	if err != nil || got != "journalctl -f -u app '--since' 'two days ago'" {
+		t.Errorf("aliasCommand = %q, %v", got, err)
+	}
+	if _, err := aliasCommand(host, "restart", nil); !errors.Is(err, errNotFound) {
+		t.Errorf("expected an unknown alias to be not found, got %v", err)
+	}
+}
+
+func TestEditAndListAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	openTestStore(t, path).AddHost(models.Host{Name: "web1", Host: "10.0.0.1", Port: 22})
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		root := newRootCmd()
+		root.SetOut(&out)
+		root.SetArgs(append([]string{"--config", path}, args...))
+		err := root.Execute()
+		return out.String(), err
+	}
+
+	if _, err := run("edit", "web1", "--alias", "logs=journalctl -f -u app", "--alias", "df=df -h"); err != nil {
+		t.Fatalf("edit --alias failed: %v", err)
+	}
+	if _, err := run("edit", "web1", "--unalias", "df"); err != nil {
+		t.Fatalf("edit --unalias failed: %v", err)
+	}
+	if _, err := run("edit", "web1", "--alias", "broken"); err == nil {
+		t.Error("expected an alias without = to be rejected")
+	}
+
+	out, err := run("run", "web1")
+	if err != nil || out != "logs  journalctl -f -u app\n" {
+		t.Errorf("unexpected alias listing %q, %v", out, err)
+	}
+	if _, err := run("run", "web1", "df"); !errors.Is(err, errNotFound) {
+		t.Errorf("expected the removed alias to be not found, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/store"
+)
+
+func newSearchCmd() *cobra.Command {
+	var opts outputOptions
+	var filter store.SearchFilter
+
+	cmd := &cobra.Command{
+		Use:   "search [query]",
+		Short: "Search hosts by name, address, user, group or tag",
+		Long: `Search hosts by name, address, user, group or tag.
+
+The query matches any of those fields as a case-insensitive substring. If
+nothing matches, host names are fuzzy matched instead, so "pdb" finds
+"prod-database". --tag, --group and --user narrow the results further and
+can be used without a query.`,
+		Example: `  sshm search db --tag prod -o json
+  sshm search --group staging --user deploy`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.validate(); err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				filter.Query = args[0]
+			}
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			return writeHosts(cmd.OutOrStdout(), s.FilterHosts(filter), opts)
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&filter.Tags, "tag", "t", nil, "Only hosts with this tag (repeatable, all must match)")
+	cmd.Flags().StringVarP(&filter.Group, "group", "g", "", "Only hosts in this group")
+	cmd.Flags().StringVarP(&filter.User, "user", "u", "", "Only hosts with this user")
+	addOutputFlags(cmd, &opts)
+	return cmd
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/sshm/sshm/internal/keyring"
+	"github.com/sshm/sshm/internal/secret"
+	"github.com/sshm/sshm/internal/store"
+)
+
+func newSecretCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secret",
+		Short: "Encrypt stored passwords and manage their passphrase",
+		Long: `Encrypt the host fields named by encrypted_fields in the settings, and keep
+the passphrase that decrypts them in the system keyring.
+
+Encrypted values are saved as enc:v1:... in the otherwise plain hosts file and
+decrypted when a connection needs them. The passphrase is taken from
+$SSHM_PASSPHRASE, the keyring or, outside the TUI, asked for on the terminal.`,
+		Example: `  sshm secret encrypt
+  sshm secret remember`,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(newSecretEncryptCmd(), newSecretRememberCmd(), newSecretForgetCmd())
+
+	return cmd
+}
+
+func newSecretEncryptCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "encrypt",
+		Short: "Encrypt the stored values of encrypted_fields now",
+		Long: `Encrypt the plain text values of the fields named by encrypted_fields, such
+as passwords saved before the setting was added. Hosts saved later are
+encrypted as they are written.
+
+The first time, the passphrase is asked for twice.`,
+		Example: `  sshm secret encrypt
+  SSHM_PASSPHRASE=... sshm secret encrypt`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			if !s.Encrypts("password") {
+				return fmt.Errorf("nothing to encrypt; add \"encrypted_fields: [password]\" to %s", resolveConfigPath())
+			}
+			if !hasPlainPasswords(s) {
+				fmt.Fprintln(statusWriter(cmd.OutOrStdout()), "Nothing to encrypt")
+				return nil
+			}
+			passphrase, err := secret.StoredPassphrase()
+			if err != nil {
+				if !term.IsTerminal(int(os.Stdin.Fd())) {
+					return err
+				}
+				if encryptedValue(s) != "" {
+					passphrase, err = secret.Passphrase()
+				} else {
+					passphrase, err = readNewPassphrase()
+				}
+				if err != nil {
+					return err
+				}
+			}
+			if err := checkPassphrase(s, passphrase); err != nil {
+				return err
+			}
+			secret.SetPassphrase(passphrase)
+
+			sealed, err := s.Seal()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Encrypted %d passwords\n", sealed)
+			return nil
+		},
+	}
+}
+
+func newSecretRememberCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remember",
+		Short: "Save the passphrase in the system keyring",
+		Long: `Save the passphrase for encrypted values in the system keyring (macOS
+Keychain, or the Secret Service through secret-tool), so neither the TUI nor
+scripts need $SSHM_PASSPHRASE.`,
+		Example: `  sshm secret remember`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			passphrase, err := secret.Passphrase()
+			if err != nil {
+				return err
+			}
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			if err := checkPassphrase(s, passphrase); err != nil {
+				return err
+			}
+			if err := keyring.Set(secret.KeyringAccount, string(passphrase)); err != nil {
+				return err
+			}
+			fmt.Fprintln(statusWriter(cmd.OutOrStdout()), "Saved the passphrase in the keyring")
+			return nil
+		},
+	}
+}
+
+func newSecretForgetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "forget",
+		Short:   "Remove the passphrase from the system keyring",
+		Example: `  sshm secret forget`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := keyring.Delete(secret.KeyringAccount); err != nil {
+				return err
+			}
+			fmt.Fprintln(statusWriter(cmd.OutOrStdout()), "Removed the passphrase from the keyring")
+			return nil
+		},
+	}
+}
+
+// hasPlainPasswords reports whether hosts of the store's own file have
+// passwords that are not encrypted yet
+func hasPlainPasswords(s *store.FileStore) bool {
+	for _, h := range s.ListHosts() {
+		if h.Password != "" && !secret.IsEncrypted(h.Password) && s.IncludedFrom(h.ID) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// encryptedValue returns an encrypted value saved in the store, or ""
+func encryptedValue(s *store.FileStore) string {
+	for _, h := range s.ListHosts() {
+		if secret.IsEncrypted(h.Password) {
+			return h.Password
+		}
+	}
+	return ""
+}
+
+// checkPassphrase makes sure passphrase opens the values already encrypted,
+// so one store never mixes passphrases
+func checkPassphrase(s *store.FileStore, passphrase []byte) error {
+	if value := encryptedValue(s); value != "" {
+		_, err := secret.Decrypt(value, passphrase)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshm/sshm/internal/secret"
+)
+
+func TestSecretEncryptCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.yaml")
+	data := "encrypted_fields: [password]\nhosts:\n  - name: web1\n    host: 10.0.0.1\n    password: hunter2\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write hosts: %v", err)
+	}
+	t.Setenv(secret.PassphraseEnv, "correct horse")
+	secret.SetPassphrase(nil)
+	t.Cleanup(func() { secret.SetPassphrase(nil) })
+
+	var out bytes.Buffer
+	root := newRootCmd()
+	root.SetOut(&out)
+	root.SetArgs([]string{"--config", path, "secret", "encrypt"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("secret encrypt failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Encrypted 1 passwords") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+	saved, _ := os.ReadFile(path)
+	if strings.Contains(string(saved), "hunter2") || !strings.Contains(string(saved), secret.Prefix) {
+		t.Errorf("expected the password encrypted in:\n%s", saved)
+	}
+
+	// Another passphrase must not encrypt alongside the first one
+	saved = append(saved, "  - name: web2\n    host: 10.0.0.2\n    password: swordfish\n"...)
+	if err := os.WriteFile(path, saved, 0600); err != nil {
+		t.Fatalf("failed to write hosts: %v", err)
+	}
+	secret.SetPassphrase([]byte("other"))
+	root = newRootCmd()
+	root.SetOut(&out)
+	root.SetArgs([]string{"--config", path, "secret", "encrypt"})
+	if err := root.Execute(); err == nil {
+		t.Error("expected a wrong passphrase to be rejected")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/server"
+)
+
+func newServeCmd() *cobra.Command {
+	var (
+		listen string
+		token  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the inventory over a local HTTP JSON API",
+		Long: `Serve the inventory over an HTTP JSON API for editors, launchers and
+dashboards until interrupted:
+
+  GET    /api/hosts                ?q=, ?tag=, ?group=, ?user= or ?selector=
+  POST   /api/hosts                add a host (same fields as "sshm show -o json")
+  GET    /api/hosts/{name}         one host
+  DELETE /api/hosts/{name}         remove a host
+  GET    /api/hosts/{name}/status  reachability and last connection
+  GET    /api/status               the same for all hosts, or ?selector=
+
+Passwords are never returned. Every request must send "Authorization: Bearer
+<token>" with the token of --token (or $SSHM_API_TOKEN); without one a random
+token is made and printed at startup, and listening on anything but a
+loopback address requires one. Requests from web pages are refused: those
+with an Origin header, those for other Host names than loopback ones while
+listening on loopback, and writes not sent as application/json.`,
+		Example: `  sshm serve
+  sshm serve --listen 127.0.0.1:7422 --token "$(openssl rand -hex 16)"
+  curl -s -H "Authorization: Bearer $SSHM_API_TOKEN" localhost:7422/api/hosts?tag=prod`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if token == "" {
+				token = os.Getenv("SSHM_API_TOKEN")
+			}
+			loopback := isLoopback(listen)
+			if token == "" && !loopback {
+				return fmt.Errorf("refusing to serve on %s without --token; the API can modify the inventory", listen)
+			}
+			generated := token == ""
+			if generated {
+				var err error
+				if token, err = newToken(); err != nil {
+					return err
+				}
+			}
+
+			ln, err := net.Listen("tcp", listen)
+			if err != nil {
+				return err
+			}
+			paths := resolvePaths()
+			srv := &http.Server{
+				Handler:           server.New(paths.Hosts, paths.Config, server.Options{Token: token, AnyHost: !loopback}).Handler(),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			done := make(chan error, 1)
+			go func() { done <- srv.Serve(ln) }()
+			fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Serving on http://%s, press Ctrl+C to stop\n", ln.Addr())
+			if generated {
+				// Printed even with --quiet, nothing can be done without it
+				fmt.Fprintf(cmd.OutOrStdout(), "Token: %s\n", token)
+			}
+
+			select {
+			case err := <-done:
+				return err
+			case <-ctx.Done():
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					return err
+				}
+				fmt.Fprintln(statusWriter(cmd.OutOrStdout()), "Stopped")
+				return nil
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&listen, "listen", "127.0.0.1:7422", "address to listen on")
+	cmd.Flags().StringVar(&token, "token", "", "require this bearer token (default $SSHM_API_TOKEN)")
+
+	return cmd
+}
+
+// newToken makes a random bearer token for the API
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to make a token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isLoopback reports whether addr only listens on the local machine
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:7422": true,
+		"[::1]:7422":     true,
+		"localhost:7422": true,
+		"0.0.0.0:7422":   false,
+		":7422":          false,
+		"10.0.0.5:7422":  false,
+	} {
+		if got := isLoopback(addr); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestServeRefusesPublicAddressWithoutToken(t *testing.T) {
+	t.Setenv("SSHM_API_TOKEN", "")
+	root := newRootCmd()
+	root.SetArgs([]string{"serve", "--listen", "0.0.0.0:0"})
+	err := root.Execute()
+	if err == nil || !strings.Contains(err.Error(), "without --token") {
+		t.Errorf("expected serve to refuse, got %v", err)
+	}
+}
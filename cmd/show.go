@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/clipboard"
+	"github.com/sshm/sshm/internal/models"
+)
+
+// outputSSHConfig is the extra --output format of show: the host as an
+// OpenSSH config block
+const outputSSHConfig = "ssh-config"
+
+func newShowCmd() *cobra.Command {
+	var opts outputOptions
+	var copyOut bool
+
+	cmd := &cobra.Command{
+		Use:   "show <name>",
+		Short: "Show the details of a host",
+		Long: `Show the details of a host, resolved like "sshm connect". The password is
+never printed.
+
+-o ssh-config prints the host as an OpenSSH config block (Host, HostName,
+User, Port, IdentityFile, ProxyJump) to share with someone or paste into
+~/.ssh/config. --copy puts the output on the clipboard instead of printing it.`,
+		Example: `  sshm show web1
+  sshm show web1 -o json
+  sshm show web1 --fields user@host,port
+  sshm show web1 -o ssh-config --copy`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.format != outputSSHConfig {
+				if err := opts.validate(); err != nil {
+					return err
+				}
+			}
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, args[0])
+			if err != nil {
+				return err
+			}
+
+			var out bytes.Buffer
+			if opts.format == outputSSHConfig {
+				err = writeSSHConfigBlock(&out, host)
+			} else {
+				err = writeHost(&out, host, opts)
+			}
+			if err != nil {
+				return err
+			}
+			if !copyOut {
+				_, err = io.Copy(cmd.OutOrStdout(), &out)
+				return err
+			}
+			if err := clipboard.CopyToClipboard(out.String()); err != nil {
+				return fmt.Errorf("failed to copy to clipboard: %w", err)
+			}
+			fmt.Fprintf(statusWriter(cmd.ErrOrStderr()), "Copied %s to the clipboard\n", host.Name)
+			return nil
+		},
+	}
+
+	addOutputFlags(cmd, &opts)
+	cmd.Flags().Lookup("output").Usage = "output format: table, json, yaml, ssh-config"
+	cmd.Flags().BoolVar(&copyOut, "copy", false, "copy the output to the clipboard instead of printing it")
+	return cmd
+}
+
+// writeSSHConfigBlock writes host as an OpenSSH config block, with the
+// options the configs matching it add
+func writeSSHConfigBlock(w io.Writer, host models.Host) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	_, err = fmt.Fprintln(w, strings.Join(host.SSHConfigLines(cfg.ApplyConfigs(host, models.Profile{})), "\n"))
+	return err
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShowSSHConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.yaml")
+	data := `configs:
+  - name: eu
+    groups: [eu]
+    identity_file: ~/.ssh/eu
+hosts:
+  - name: web1
+    host: 10.0.0.1
+    port: 2222
+    user: deploy
+    proxy: bastion
+    group: eu
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write hosts: %v", err)
+	}
+
+	var out bytes.Buffer
+	root := newRootCmd()
+	root.SetOut(&out)
+	root.SetArgs([]string{"--config", path, "show", "web1", "-o", "ssh-config"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("show failed: %v", err)
+	}
+	want := `Host web1
+    HostName 10.0.0.1
+    Port 2222
+    User deploy
+    IdentityFile ~/.ssh/eu
+    ProxyJump bastion
+    # Group: eu
+`
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/models"
+)
+
+func newSnippetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snippet",
+		Short: "Run named command snippets on hosts",
+		Long: `Run named commands defined under snippets in the settings. A snippet without
+tags applies to every host; one with tags only to hosts carrying one of them.
+
+Commands may hold {{param}} or {{param=default}} placeholders, given as
+param=value arguments, asked for on the terminal when missing, and filled in
+verbatim. In the TUI, s picks a snippet for the selected host.`,
+		Example: `  sshm snippet list web1
+  sshm snippet run restart-app web1
+  sshm snippet run tail-log web1 lines=200`,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(newSnippetListCmd(), newSnippetRunCmd())
+
+	return cmd
+}
+
+func newSnippetListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list [host]",
+		Short: "List the snippets, or those that apply to a host",
+		Example: `  sshm snippet list
+  sshm snippet list web1`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			snippets := cfg.Snippets
+			if len(args) == 1 {
+				s, err := openStore()
+				if err != nil {
+					return err
+				}
+				host, err := resolveHost(s, args[0])
+				if err != nil {
+					return err
+				}
+				snippets = cfg.SnippetsFor(host)
+			}
+			return writeSnippets(cmd.OutOrStdout(), snippets)
+		},
+	}
+}
+
+func newSnippetRunCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "run <snippet> <host> [param=value...]",
+		Short: "Run a snippet on a host",
+		Long: `Run a snippet on a host like "sshm run" runs an alias: with a pseudo terminal
+when sshm runs on one, passing the remote exit status through. Hosts with a
+guarded tag are confirmed first, unless --yes is given.`,
+		Example: `  sshm snippet run restart-app web1
+  sshm snippet run tail-log web1 lines=200 unit=nginx`,
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeSnippetArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			snippet, err := findSnippet(cfg, args[0])
+			if err != nil {
+				return err
+			}
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, args[1])
+			if err != nil {
+				return err
+			}
+			if !snippet.AppliesTo(host) {
+				return fmt.Errorf("snippet %s is for hosts tagged %s", snippet.Name, strings.Join(snippet.Tags, ", "))
+			}
+
+			values := make(map[string]string)
+			for _, arg := range args[2:] {
+				name, value, ok := strings.Cut(arg, "=")
+				if !ok || name == "" {
+					return fmt.Errorf("invalid parameter %q (use name=value)", arg)
+				}
+				values[name] = value
+			}
+			if err := askSnippetParams(cmd, snippet, values); err != nil {
+				return err
+			}
+			command, err := snippet.Expand(values)
+			if err != nil {
+				return err
+			}
+			return runOnHost(cmd, host, command, yes)
+		},
+	}
+
+	// Flags after the host belong to the parameters
+	cmd.Flags().SetInterspersed(false)
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "run on guarded hosts without asking")
+
+	return cmd
+}
+
+// findSnippet looks up a snippet by name, listing the others when missing
+func findSnippet(cfg *config.Config, name string) (models.Snippet, error) {
+	if snippet, ok := cfg.FindSnippet(name); ok {
+		return snippet, nil
+	}
+	if len(cfg.Snippets) == 0 {
+		return models.Snippet{}, fmt.Errorf("snippet %q %w, the settings define none", name, errNotFound)
+	}
+	names := make([]string, len(cfg.Snippets))
+	for i, s := range cfg.Snippets {
+		names[i] = s.Name
+	}
+	return models.Snippet{}, fmt.Errorf("snippet %q %w (have: %s)", name, errNotFound, strings.Join(names, ", "))
+}
+
+// askSnippetParams asks on the terminal for the parameters without a value
+// or default; off a terminal they are left for Expand to report
+func askSnippetParams(cmd *cobra.Command, snippet models.Snippet, values map[string]string) error {
+	if cmd.InOrStdin() != os.Stdin || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	reader := bufio.NewReader(os.Stdin)
+	for _, p := range snippet.Params() {
+		if _, ok := values[p.Name]; ok || p.HasDefault {
+			continue
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s: ", p.Name)
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		values[p.Name] = strings.TrimRight(line, "\r\n")
+	}
+	return nil
+}
+
+// writeSnippets lists snippets with their tags and description or command
+func writeSnippets(w io.Writer, snippets []models.Snippet) error {
+	if len(snippets) == 0 {
+		fmt.Fprintln(statusWriter(w), "No snippets")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTAGS\tDESCRIPTION")
+	for _, s := range snippets {
+		tags := strings.Join(s.Tags, ",")
+		if tags == "" {
+			tags = "-"
+		}
+		description := s.Description
+		if description == "" {
+			description = s.Command
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, tags, description)
+	}
+	return tw.Flush()
+}
+
+// completeSnippetArgs completes the snippet name, then the host
+func completeSnippetArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		cfg, err := loadConfig()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var names []string
+		for _, s := range cfg.Snippets {
+			if strings.HasPrefix(s.Name, toComplete) {
+				names = append(names, s.Name+"\t"+s.Description)
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		return completeHostNames(cmd, nil, toComplete)
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
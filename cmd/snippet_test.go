@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnippetListAndRunErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.yaml")
+	data := `snippets:
+  - name: uptime
+    command: uptime
+  - name: restart-app
+    description: Restart the app
+    command: sudo systemctl restart {{unit=app}}
+    tags: [web]
+  - name: tail-log
+    command: tail -n {{lines}} /var/log/syslog
+hosts:
+  - name: web1
+    host: 10.0.0.1
+    tags: [web]
+  - name: db1
+    host: 10.0.0.2
+    tags: [db]
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write hosts: %v", err)
+	}
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		root := newRootCmd()
+		root.SetOut(&out)
+		root.SetErr(&bytes.Buffer{})
+		root.SetIn(strings.NewReader(""))
+		root.SetArgs(append([]string{"--config", path}, args...))
+		err := root.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("snippet", "list", "db1")
+	if err != nil {
+		t.Fatalf("snippet list failed: %v", err)
+	}
+	if !strings.Contains(out, "uptime") || strings.Contains(out, "restart-app") {
+		t.Errorf("expected only the untagged snippets for db1, got:\n%s", out)
+	}
+	if out, _ := run("snippet", "list"); !strings.Contains(out, "Restart the app") {
+		t.Errorf("expected descriptions in the listing, got:\n%s", out)
+	}
+
+	if _, err := run("snippet", "run", "vacuum", "db1"); !errors.Is(err, errNotFound) {
+		t.Errorf("expected an unknown snippet to be not found, got %v", err)
+	}
+	if _, err := run("snippet", "run", "restart-app", "db1"); err == nil {
+		t.Error("expected a snippet to be refused on hosts without its tags")
+	}
+	if _, err := run("snippet", "run", "tail-log", "db1"); err == nil || !strings.Contains(err.Error(), `"lines"`) {
+		t.Errorf("expected a missing parameter to be reported, got %v", err)
+	}
+	if _, err := run("snippet", "run", "tail-log", "db1", "lines"); err == nil {
+		t.Error("expected a parameter without = to be rejected")
+	}
+}
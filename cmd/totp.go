@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/sshm/sshm/internal/clipboard"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/totp"
+)
+
+func newTOTPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "totp",
+		Short: "Answer 2FA verification code prompts from a TOTP secret",
+		Long: `Keep a TOTP secret per host in the system keyring (macOS Keychain, or the
+Secret Service through secret-tool) for servers that ask for a verification
+code during login.
+
+With totp: auto on the host, sshm answers the prompt with the current code;
+with totp: show it prints the code and copies it to the clipboard so you can
+paste it yourself.`,
+		Example: `  sshm totp set web1
+  sshm totp code web1 --copy`,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(newTOTPSetCmd(), newTOTPCodeCmd(), newTOTPRmCmd())
+
+	return cmd
+}
+
+func newTOTPSetCmd() *cobra.Command {
+	var show bool
+
+	cmd := &cobra.Command{
+		Use:   "set <host>",
+		Short: "Save the TOTP secret of a host in the keyring",
+		Long: `Save the TOTP secret of a host in the keyring and answer its verification
+code prompts from then on. The secret is the base32 text or otpauth:// URI of
+the authenticator setup, asked for on the terminal or read from stdin.`,
+		Example: `  sshm totp set web1
+  sshm totp set web1 --show
+  echo "otpauth://totp/web1?secret=JBSWY3DPEHPK3PXP" | sshm totp set web1`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, args[0])
+			if err != nil {
+				return err
+			}
+			value, err := readSecret(cmd, "TOTP secret or otpauth:// URI: ")
+			if err != nil {
+				return err
+			}
+			if _, err := totp.Parse(value); err != nil {
+				return err
+			}
+			if err := totp.Save(host.ID, value); err != nil {
+				return err
+			}
+
+			host.TOTP = models.TOTPAuto
+			if show {
+				host.TOTP = models.TOTPShow
+			}
+			if err := s.UpdateHost(host); err != nil {
+				return fmt.Errorf("failed to update host: %w", err)
+			}
+			fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Saved the TOTP secret of %s\n", host.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&show, "show", false, "show and copy codes instead of answering prompts")
+	return cmd
+}
+
+func newTOTPCodeCmd() *cobra.Command {
+	var copyOut bool
+
+	cmd := &cobra.Command{
+		Use:   "code <host>",
+		Short: "Print the current verification code of a host",
+		Example: `  sshm totp code web1
+  sshm totp code web1 --copy`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, args[0])
+			if err != nil {
+				return err
+			}
+			key, err := totp.Load(host.ID)
+			if err != nil {
+				return fmt.Errorf("%s: %w", host.Name, err)
+			}
+			now := time.Now()
+			code := key.Code(now)
+			if !copyOut {
+				fmt.Fprintln(cmd.OutOrStdout(), code)
+				return nil
+			}
+			if err := clipboard.CopyToClipboard(code); err != nil {
+				return fmt.Errorf("failed to copy to clipboard: %w", err)
+			}
+			fmt.Fprintf(statusWriter(cmd.ErrOrStderr()), "Copied the code of %s to the clipboard (valid %s)\n", host.Name, key.Remaining(now))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&copyOut, "copy", false, "copy the code to the clipboard instead of printing it")
+	return cmd
+}
+
+func newTOTPRmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "rm <host>",
+		Short:             "Remove the TOTP secret of a host from the keyring",
+		Example:           `  sshm totp rm web1`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, args[0])
+			if err != nil {
+				return err
+			}
+			if err := totp.Delete(host.ID); err != nil {
+				return err
+			}
+			if host.TOTP != "" {
+				host.TOTP = ""
+				if err := s.UpdateHost(host); err != nil {
+					return fmt.Errorf("failed to update host: %w", err)
+				}
+			}
+			fmt.Fprintf(statusWriter(cmd.OutOrStdout()), "Removed the TOTP secret of %s\n", host.Name)
+			return nil
+		},
+	}
+}
+
+// readSecret asks for a secret on the terminal without echoing it, or reads
+// the first line of stdin; spaces are kept since passwords may have them
+func readSecret(cmd *cobra.Command, prompt string) (string, error) {
+	var value string
+	if f, ok := cmd.InOrStdin().(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(cmd.ErrOrStderr(), prompt)
+		data, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(cmd.ErrOrStderr())
+		if err != nil {
+			return "", err
+		}
+		value = string(data)
+	} else {
+		line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("nothing to read on stdin")
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+	if strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("empty input")
+	}
+	return value, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/sshm/sshm/internal/ssh"
+)
+
+func newTunnelCmd() *cobra.Command {
+	var local, remote, dynamic []string
+
+	cmd := &cobra.Command{
+		Use:   "tunnel <host>",
+		Short: "Forward ports through a host until interrupted",
+		Long: `Forward ports through a host until interrupted, using ssh's syntax:
+
+  -L [bind_address:]port:host:hostport  local port to host:hostport as seen from the server
+  -R [bind_address:]port:host:hostport  server port to host:hostport as seen from here
+  -D [bind_address:]port                local SOCKS5 proxy through the server
+
+Local forwards bind to localhost unless an address is given. Each flag can
+be repeated. Press Ctrl+C to close the tunnel.`,
+		Example: `  sshm tunnel db1 -L 5432:localhost:5432
+  sshm tunnel bastion -D 1080 -L 8080:intranet:80
+  sshm tunnel web1 -R 9000:localhost:3000`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeHostNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var forwards []ssh.Forward
+			for _, group := range []struct {
+				kind  ssh.ForwardKind
+				specs []string
+			}{{ssh.ForwardLocal, local}, {ssh.ForwardRemote, remote}, {ssh.ForwardDynamic, dynamic}} {
+				for _, spec := range group.specs {
+					f, err := ssh.ParseForward(group.kind, spec)
+					if err != nil {
+						return err
+					}
+					forwards = append(forwards, f)
+				}
+			}
+			if len(forwards) == 0 {
+				return fmt.Errorf("no forwards given, use -L, -R or -D")
+			}
+
+			s, err := openStore()
+			if err != nil {
+				return err
+			}
+			host, err := resolveHost(s, args[0])
+			if err != nil {
+				return err
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			profile, err := connectProfile(cfg, host)
+			if err != nil {
+				return err
+			}
+			tunnel, err := ssh.OpenTunnel(host, profile)
+			if err != nil {
+				return err
+			}
+			defer tunnel.Close()
+
+			out := statusWriter(cmd.OutOrStdout())
+			started := 0
+			for _, f := range forwards {
+				active, err := tunnel.Start(f)
+				if err != nil {
+					fmt.Fprintf(out, "✗ %s: %v\n", f, err)
+					continue
+				}
+				fmt.Fprintf(out, "✓ %s\n", active)
+				started++
+			}
+			if started == 0 {
+				return fmt.Errorf("no forward could be started")
+			}
+
+			fmt.Fprintf(out, "Tunnel to %s open, press Ctrl+C to close\n", host.Name)
+
+			ctx := cmd.Context()
+			lost := make(chan error, 1)
+			go func() { lost <- tunnel.Wait() }()
+
+			select {
+			case <-ctx.Done():
+				fmt.Fprintln(out, "Tunnel closed")
+				return nil
+			case err := <-lost:
+				return fmt.Errorf("connection to %s lost: %v", host.Name, err)
+			}
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&local, "local", "L", nil, "local forward [bind_address:]port:host:hostport")
+	cmd.Flags().StringArrayVarP(&remote, "remote", "R", nil, "remote forward [bind_address:]port:host:hostport")
+	cmd.Flags().StringArrayVarP(&dynamic, "dynamic", "D", nil, "SOCKS5 proxy on [bind_address:]port")
+
+	return cmd
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Build metadata, injected at build time:
+//
+//	go build -ldflags "-X main.version=1.3.0 -X main.commit=$(git rev-parse --short HEAD) -X main.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+//
+// Builds without ldflags fall back to the module and VCS information Go
+// embeds in the binary.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// pseudoVersion matches the timestamp and commit of a Go pseudo-version
+var pseudoVersion = regexp.MustCompile(`\d{14}-[0-9a-f]{12}`)
+
+// buildInfo describes the running binary
+type buildInfo struct {
+	Version   string `json:"version" yaml:"version"`
+	Commit    string `json:"commit" yaml:"commit"`
+	Date      string `json:"date" yaml:"date"`
+	GoVersion string `json:"go_version" yaml:"go_version"`
+	Platform  string `json:"platform" yaml:"platform"`
+}
+
+// currentBuild returns the build metadata, filling gaps from debug.BuildInfo
+func currentBuild() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		// Untagged builds get pseudo-versions, which only repeat the commit
+		if v := bi.Main.Version; info.Version == "" && v != "(devel)" && !pseudoVersion.MatchString(v) {
+			info.Version = v
+		}
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.Date == "":
+				info.Date = s.Value
+			}
+		}
+	}
+
+	info.Version = strings.TrimPrefix(info.Version, "v")
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if len(info.Commit) > 12 {
+		info.Commit = info.Commit[:12]
+	}
+	return info
+}
+
+// String returns a one line summary such as
+// "1.3.0 (commit 1a2b3c4, built 2026-04-01T10:00:00Z, go1.25.3 linux/amd64)"
+func (b buildInfo) String() string {
+	s := b.Version + " ("
+	if b.Commit != "" {
+		s += "commit " + b.Commit + ", "
+	}
+	if b.Date != "" {
+		s += "built " + b.Date + ", "
+	}
+	return s + b.GoVersion + " " + b.Platform + ")"
+}
+
+func newVersionCmd() *cobra.Command {
+	var short bool
+	var opts outputOptions
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version and build information",
+		Example: `  sshm version
+  sshm version --short
+  sshm version -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.validate(); err != nil {
+				return err
+			}
+			info := currentBuild()
+			out := cmd.OutOrStdout()
+			if short {
+				fmt.Fprintln(out, info.Version)
+				return nil
+			}
+			if opts.format != outputTable {
+				return encode(out, opts.format, info)
+			}
+			fmt.Fprintf(out, "sshm %s\n", info)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&short, "short", false, "print only the version number")
+	cmd.Flags().StringVarP(&opts.format, "output", "o", outputTable, "output format: table, json or yaml")
+	return cmd
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBuildInfoString(t *testing.T) {
+	info := buildInfo{Version: "1.3.0", Commit: "abc1234", Date: "2026-04-01", GoVersion: "go1.25.3", Platform: "linux/amd64"}
+	if got, want := info.String(), "1.3.0 (commit abc1234, built 2026-04-01, go1.25.3 linux/amd64)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	info.Commit, info.Date = "", ""
+	if got, want := info.String(), "1.3.0 (go1.25.3 linux/amd64)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestPseudoVersion(t *testing.T) {
+	for v, want := range map[string]bool{
+		"v1.3.0":                             false,
+		"v1.3.0-rc.1":                        false,
+		"v0.0.0-20261016114040-55fa680b4488": true,
+		"v1.2.1-0.20261016114040-55fa680b4488+dirty": true,
+	} {
+		if got := pseudoVersion.MatchString(v); got != want {
+			t.Errorf("pseudoVersion.MatchString(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestWriteDoctorReport(t *testing.T) {
+	var buf bytes.Buffer
+	failed := writeDoctorReport(&buf, []doctorCheck{
+		{name: "sshm", detail: "1.3.0", ok: true},
+		{name: "identities", detail: "missing key file for web1"},
+	})
+	if failed != 1 {
+		t.Errorf("expected 1 failed check, got %d", failed)
+	}
+	if !strings.Contains(buf.String(), "✗ identities  missing key file for web1") {
+		t.Errorf("unexpected report:\n%s", buf.String())
+	}
+}
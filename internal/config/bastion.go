@@ -0,0 +1,91 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+// BastionPolicy refuses direct connections to some hosts: they have to jump
+// through one of the bastions of the defaults block
+type BastionPolicy struct {
+	// Guarded covers hosts carrying a guarded tag
+	Guarded bool `json:"guarded,omitempty" yaml:"guarded,omitempty"`
+	// Tags cover hosts carrying any of them
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// ErrBastionRequired is returned for connections the bastion policy refuses
+var ErrBastionRequired = errors.New("direct connection refused by bastion_policy")
+
+// bastionTag returns the tag that puts host under the bastion policy, or ""
+func (c *Config) bastionTag(host models.Host) string {
+	p := c.BastionPolicy
+	if p.Guarded {
+		if tag := c.GuardedTag(host); tag != "" {
+			return tag
+		}
+	}
+	for _, tag := range host.Tags {
+		for _, covered := range p.Tags {
+			if strings.EqualFold(tag, covered) {
+				return tag
+			}
+		}
+	}
+	return ""
+}
+
+// Bastions returns the jump hosts of the defaults block: the default proxy
+// and those of the bastions
+func (c *Config) Bastions() []string {
+	var bastions []string
+	if c.Defaults.Proxy != "" && c.Defaults.Proxy != models.ProxyNone {
+		bastions = append(bastions, c.Defaults.Proxy)
+	}
+	for _, b := range c.Defaults.Bastions {
+		if b.Proxy != "" {
+			bastions = append(bastions, b.Proxy)
+		}
+	}
+	return bastions
+}
+
+// CheckBastion returns an error wrapping ErrBastionRequired when the policy
+// covers host and it would not connect through one of the Bastions
+// Jump hosts are compared by name, ignoring user and port; a proxy command
+// doesn't count. The bastions themselves may be connected to directly.
+func (c *Config) CheckBastion(host models.Host) error {
+	tag := c.bastionTag(host)
+	if tag == "" {
+		return nil
+	}
+	bastions := c.Bastions()
+	for _, b := range bastions {
+		name := proxyHostName(b)
+		if strings.EqualFold(name, host.Name) || strings.EqualFold(name, host.Host) {
+			return nil
+		}
+		if host.Proxy != "" && strings.EqualFold(name, proxyHostName(host.Proxy)) {
+			return nil
+		}
+	}
+	if len(bastions) == 0 {
+		return fmt.Errorf("%w: %s is tagged %s, but the defaults name no proxy or bastions", ErrBastionRequired, host.Name, tag)
+	}
+	return fmt.Errorf("%w: %s is tagged %s, set its proxy to %s", ErrBastionRequired, host.Name, tag, strings.Join(bastions, " or "))
+}
+
+// proxyHostName returns the host of a [user@]host[:port] jump host
+func proxyHostName(proxy string) string {
+	if i := strings.LastIndex(proxy, "@"); i >= 0 {
+		proxy = proxy[i+1:]
+	}
+	if host, _, err := net.SplitHostPort(proxy); err == nil {
+		return host
+	}
+	return proxy
+}
@@ -1,15 +1,13 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"gopkg.in/yaml.v3"
-
 	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/theme"
 )
 
 // Config holds the entire application configuration
@@ -19,10 +17,96 @@ type Config struct {
 	Configs  []models.SSHConfig  `json:"configs" yaml:"configs"`
 	Profiles []models.Profile   `json:"profiles" yaml:"profiles"`
 	Theme    string             `json:"theme" yaml:"theme"`
+	// Language of the TUI (en, de); empty follows $LC_ALL, $LC_MESSAGES or
+	// $LANG
+	Language string `json:"language,omitempty" yaml:"language,omitempty"`
+	// GuardedTags lists tags (e.g. "production") that require an extra
+	// confirmation before connecting to a host carrying them
+	GuardedTags []string `json:"guarded_tags,omitempty" yaml:"guarded_tags,omitempty"`
+	// BastionPolicy refuses direct connections to hosts with some tags, see
+	// BastionPolicy
+	BastionPolicy BastionPolicy `json:"bastion_policy,omitempty" yaml:"bastion_policy,omitempty"`
+	// Columns selects and orders the host list table columns
+	// (name, user@host, port, group, tags, last_used, latency)
+	Columns []string `json:"columns,omitempty" yaml:"columns,omitempty"`
+	// Defaults apply to hosts that leave user, port, identity or proxy empty
+	Defaults models.Defaults `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+	// Include lists more hosts files (paths relative to this file, ~/ and
+	// glob patterns allowed) whose hosts are added read-only
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+	// IncludeRefresh is how many seconds apart the TUI looks for changes to
+	// included files; 60 by default, -1 turns it off
+	IncludeRefresh int `json:"include_refresh,omitempty" yaml:"include_refresh,omitempty"`
+	// Keybindings rebinds TUI actions (connect, add, quit, ...) to keys
+	Keybindings map[string][]string `json:"keybindings,omitempty" yaml:"keybindings,omitempty"`
+	// Themes defines custom themes, or tweaks the dark and light presets,
+	// by name; theme selects one
+	Themes map[string]theme.Custom `json:"themes,omitempty" yaml:"themes,omitempty"`
+	// EncryptedFields lists host fields (password) saved encrypted, see
+	// package secret
+	EncryptedFields []string `json:"encrypted_fields,omitempty" yaml:"encrypted_fields,omitempty"`
+	// Recording saves the interactive sessions of some hosts, see Recording
+	Recording Recording `json:"recording,omitempty" yaml:"recording,omitempty"`
+	// Vault is the server issuing credentials to hosts with vault-cert or
+	// vault-otp auth
+	Vault Vault `json:"vault,omitempty" yaml:"vault,omitempty"`
+	// KeyringPasswords keeps the passwords of password hosts in the system
+	// keyring instead of the hosts file, offering to save them after login
+	KeyringPasswords bool `json:"keyring_passwords,omitempty" yaml:"keyring_passwords,omitempty"`
+	// Snippets are named commands run with "sshm snippet run" or from the
+	// TUI, on every host or on hosts with given tags
+	Snippets []models.Snippet `json:"snippets,omitempty" yaml:"snippets,omitempty"`
+	// Presentation starts the TUI with hosts and users masked, for demos and
+	// screen sharing; P toggles it
+	Presentation bool `json:"presentation,omitempty" yaml:"presentation,omitempty"`
+	// KeyMaxAge is how many days old identity files may get before doctor
+	// and "sshm keys" remind to rotate them; 0 never reminds
+	KeyMaxAge int `json:"key_max_age,omitempty" yaml:"key_max_age,omitempty"`
+	// IdleLock locks the TUI after a while without input, see IdleLock
+	IdleLock IdleLock `json:"idle_lock,omitempty" yaml:"idle_lock,omitempty"`
+	// HealthCheck sets the timings of the TUI's background probes
+	HealthCheck HealthCheck `json:"health_check,omitempty" yaml:"health_check,omitempty"`
+	// Tmux opens connections in new tmux windows or panes, see Tmux
+	Tmux Tmux `json:"tmux,omitempty" yaml:"tmux,omitempty"`
+	// WSL uses the Windows keys and agent when running in WSL, see WSL
+	WSL WSL `json:"wsl,omitempty" yaml:"wsl,omitempty"`
+	// Notify announces the end of long jobs on the desktop, see Notify
+	Notify Notify `json:"notify,omitempty" yaml:"notify,omitempty"`
+}
+
+// GuardedTag returns the first tag of the host that is configured as guarded,
+// or an empty string if the host carries no guarded tag
+func (c *Config) GuardedTag(host models.Host) string {
+	for _, tag := range host.Tags {
+		for _, guarded := range c.GuardedTags {
+			if strings.EqualFold(tag, guarded) {
+				return tag
+			}
+		}
+	}
+	return ""
 }
 
 // GetProfile returns the profile for a host, falling back to default if not found
+// The default profile takes keep-alive and timeout from the defaults block.
+// The options of the configs matching the host's group or tags are merged
+// in, later configs overriding earlier ones.
 func (c *Config) GetProfile(host models.Host) models.Profile {
+	return c.ApplyConfigs(host, c.baseProfile(host))
+}
+
+// ApplyConfigs merges the options of the configs matching the host's group
+// or tags into profile, in file order
+func (c *Config) ApplyConfigs(host models.Host, profile models.Profile) models.Profile {
+	for _, sc := range c.Configs {
+		if sc.Matches(host) {
+			profile = sc.ApplyProfile(profile)
+		}
+	}
+	return profile
+}
+
+func (c *Config) baseProfile(host models.Host) models.Profile {
 	// If host specifies a profile, look it up
 	if host.Profile != "" {
 		for _, p := range c.Profiles {
@@ -32,7 +116,7 @@ func (c *Config) GetProfile(host models.Host) models.Profile {
 		}
 	}
 	// Fall back to default profile
-	return models.DefaultProfile()
+	return c.Defaults.ApplyProfile(models.DefaultProfile())
 }
 
 // AddProfile adds a new profile to the configuration
@@ -57,17 +141,18 @@ func removeProfile(profiles []models.Profile, name string) []models.Profile {
 	return result
 }
 
-// GetDefaultConfigPath returns the default configuration file path
+// GetDefaultConfigPath returns the default settings file path, see DefaultPaths
 func GetDefaultConfigPath() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return ".sshm.json"
-	}
-	return filepath.Join(home, ".sshm.json")
+	return DefaultPaths().Config
+}
+
+// GetDefaultHostsPath returns the default host inventory path, see DefaultPaths
+func GetDefaultHostsPath() string {
+	return DefaultPaths().Hosts
 }
 
 // LoadConfig loads configuration from the specified path
-// Supports both JSON and YAML formats based on file extension
+// The format, JSON or YAML, is detected from the extension or the content
 // If path is empty, uses default path
 func LoadConfig(path string) (*Config, error) {
 	if path == "" {
@@ -82,82 +167,41 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
+	format := DetectFormat(path, data)
+	data, _ = UpgradeData(path, data)
 	var cfg Config
-
-	// Detect format from file extension
-	if isYAML(path) {
-		if err := yaml.Unmarshal(data, &cfg); err != nil {
-			// Try legacy array format
-			if legacyCfg := tryParseLegacyYAML(data); legacyCfg != nil {
-				return legacyCfg, nil
-			}
-			return nil, fmt.Errorf("failed to parse YAML config: %w", err)
+	if err := Unmarshal(format, data, &cfg); err != nil {
+		// Try legacy array format
+		var hosts []models.Host
+		if Unmarshal(format, data, &hosts) == nil && len(hosts) > 0 {
+			cfg = Config{Hosts: hosts}
+		} else {
+			return nil, fmt.Errorf("failed to parse %s config: %w", strings.ToUpper(format), err)
 		}
-	} else {
-		// Try JSON first
-		jsonErr := json.Unmarshal(data, &cfg)
-		if jsonErr != nil {
-			// Try legacy array format
-			if legacyCfg := tryParseLegacyJSON(data); legacyCfg != nil {
-				return legacyCfg, nil
-			}
-			// Try YAML as fallback
-			yamlErr := yaml.Unmarshal(data, &cfg)
-			if yamlErr != nil {
-				return nil, fmt.Errorf("failed to parse config (JSON: %v, YAML: %v)", jsonErr, yamlErr)
-			}
+	}
+	for i, host := range cfg.Hosts {
+		if host.ID == "" {
+			cfg.Hosts[i].ID = HostID(host.Name)
 		}
 	}
 
 	return &cfg, nil
 }
 
-// tryParseLegacyJSON tries to parse a legacy JSON array format
-func tryParseLegacyJSON(data []byte) *Config {
-	var hosts []models.Host
-	if err := json.Unmarshal(data, &hosts); err == nil && len(hosts) > 0 {
-		return &Config{Hosts: hosts}
-	}
-	return nil
-}
-
-// tryParseLegacyYAML tries to parse a legacy YAML array format
-func tryParseLegacyYAML(data []byte) *Config {
-	var hosts []models.Host
-	if err := yaml.Unmarshal(data, &hosts); err == nil && len(hosts) > 0 {
-		return &Config{Hosts: hosts}
-	}
-	return nil
-}
-
-// isYAML returns true if the file path has a .yaml or .yml extension
-func isYAML(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	return ext == ".yaml" || ext == ".yml"
-}
-
 // SaveConfig saves configuration to the specified path
-// Supports both JSON and YAML formats based on file extension
+// An existing file keeps its format; new files are YAML when the extension
+// says so and JSON otherwise
 // If path is empty, uses default path
 func SaveConfig(cfg *Config, path string) error {
 	if path == "" {
 		path = GetDefaultConfigPath()
 	}
 
-	var data []byte
-	var err error
-
-	// Detect format from file extension
-	if isYAML(path) {
-		data, err = yaml.Marshal(cfg)
-		if err != nil {
-			return fmt.Errorf("failed to marshal YAML config: %w", err)
-		}
-	} else {
-		data, err = json.MarshalIndent(cfg, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal JSON config: %w", err)
-		}
+	existing, _ := os.ReadFile(path)
+	format := DetectFormat(path, existing)
+	data, err := Marshal(format, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s config: %w", strings.ToUpper(format), err)
 	}
 
 	if err := os.WriteFile(path, data, 0600); err != nil {
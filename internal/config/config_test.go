@@ -1,8 +1,14 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/sshm/sshm/internal/models"
 )
 
 func getTestFilePath(filename string) string {
@@ -89,3 +95,621 @@ func TestSaveConfigJSON(t *testing.T) {
 		t.Errorf("Host count mismatch: %d vs %d", len(cfg2.Hosts), len(cfg.Hosts))
 	}
 }
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		path string
+		data string
+		want string
+	}{
+		{"hosts.yaml", `{"hosts": []}`, FormatYAML},
+		{"hosts.YML", "", FormatYAML},
+		{"hosts.json", "hosts: []", FormatJSON},
+		{"config", `{"hosts": []}`, FormatJSON},
+		{"config", "  [\n]", FormatJSON},
+		{"config", "# sshm\nhosts: []", FormatYAML},
+		{"config", "", FormatJSON},
+	}
+
+	for _, tt := range tests {
+		if got := DetectFormat(tt.path, []byte(tt.data)); got != tt.want {
+			t.Errorf("DetectFormat(%q, %q) = %s, want %s", tt.path, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigYAMLWithoutExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	data := "# my hosts\ntheme: light\nhosts:\n  - name: web1\n    host: 10.0.0.1\n    port: 2222\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Theme != "light" || len(cfg.Hosts) != 1 || cfg.Hosts[0].Port != 2222 {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestSetKeyKeepsYAMLComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "# my hosts\ntheme: light # for the office\nhosts:\n  - name: web1\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	if err := SetKey(path, "theme", "dark"); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	for _, want := range []string{"# my hosts", "theme: dark # for the office", "name: web1"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestSetKeyKeepsJSONSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"theme": "light", "hosts": []}`), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	if err := SetKey(path, "guarded_tags", []string{"prod"}); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Theme != "light" || len(cfg.GuardedTags) != 1 {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestSetKeyThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("theme: dark\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "config.yaml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("no symlinks: %v", err)
+	}
+
+	if err := SetKey(link, "theme", "light"); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected the link to stay a link, got %v (%v)", info.Mode(), err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("expected the mode to be kept, got %v", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(target); !strings.Contains(string(data), "theme: light") {
+		t.Errorf("expected the linked file to be updated, got %q", data)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(dir, "dotfiles", "*.tmp")); len(tmp) > 0 {
+		t.Errorf("expected no temporary file to be left behind, got %v", tmp)
+	}
+}
+
+func TestSetKeyRefusesDamagedFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"hosts.json": `{"hosts": [{"name": "web1"`,
+		"hosts.yaml": "hosts: [web1\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if err := SetKey(path, "hosts", []string{}); err == nil {
+			t.Errorf("expected SetKey to refuse the damaged %s", name)
+		}
+		if data, _ := os.ReadFile(path); string(data) != content {
+			t.Errorf("%s was changed to %q", name, data)
+		}
+	}
+
+	// Legacy files holding just a list of hosts are replaced
+	path := filepath.Join(dir, "legacy.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "web1"}]`), 0600); err != nil {
+		t.Fatalf("failed to write legacy.json: %v", err)
+	}
+	if err := SetKey(path, "theme", "dark"); err != nil {
+		t.Errorf("SetKey on a legacy file failed: %v", err)
+	}
+}
+
+func TestGuardedTag(t *testing.T) {
+	cfg := &Config{GuardedTags: []string{"production"}}
+
+	if tag := cfg.GuardedTag(models.Host{Tags: []string{"web", "Production"}}); tag != "Production" {
+		t.Errorf("expected guarded tag Production, got %q", tag)
+	}
+	if tag := cfg.GuardedTag(models.Host{Tags: []string{"staging"}}); tag != "" {
+		t.Errorf("expected no guarded tag, got %q", tag)
+	}
+	if tag := (&Config{}).GuardedTag(models.Host{Tags: []string{"production"}}); tag != "" {
+		t.Errorf("expected no guarded tag without config, got %q", tag)
+	}
+}
+
+func TestCheckBastion(t *testing.T) {
+	cfg := &Config{
+		GuardedTags:   []string{"production"},
+		BastionPolicy: BastionPolicy{Guarded: true, Tags: []string{"pci"}},
+		Defaults: models.Defaults{Proxy: "jump", Bastions: []models.Bastion{
+			{Proxy: "ops@bastion-eu:2222", Groups: []string{"eu"}},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		host    models.Host
+		refused bool
+	}{
+		{"untagged", models.Host{Name: "dev1", Host: "10.0.0.1"}, false},
+		{"guarded direct", models.Host{Name: "web1", Host: "10.0.0.2", Tags: []string{"Production"}}, true},
+		{"tagged direct", models.Host{Name: "card1", Host: "10.0.0.3", Tags: []string{"pci"}}, true},
+		{"default proxy", models.Host{Name: "web2", Host: "10.0.0.4", Proxy: "jump", Tags: []string{"production"}}, false},
+		{"bastion with user", models.Host{Name: "web3", Host: "10.0.0.5", Proxy: "admin@bastion-eu", Tags: []string{"production"}}, false},
+		{"other proxy", models.Host{Name: "web4", Host: "10.0.0.6", Proxy: "laptop", Tags: []string{"production"}}, true},
+		{"bastion itself", models.Host{Name: "jump", Host: "203.0.113.1", Tags: []string{"production"}}, false},
+	}
+	for _, tt := range tests {
+		err := cfg.CheckBastion(tt.host)
+		if refused := errors.Is(err, ErrBastionRequired); refused != tt.refused {
+			t.Errorf("%s: CheckBastion = %v, want refused %v", tt.name, err, tt.refused)
+		}
+	}
+
+	if err := (&Config{GuardedTags: []string{"production"}}).CheckBastion(models.Host{Tags: []string{"production"}}); err != nil {
+		t.Errorf("expected no policy to allow direct connections, got %v", err)
+	}
+
+	problems := Validate("x.yaml", []byte("bastion_policy:\n  guarded: true\n"))
+	want := []string{
+		`x.yaml:2:3: bastion_policy needs a proxy or bastions in defaults to connect through`,
+		`x.yaml:2:12: warning: bastion_policy covers guarded hosts, but guarded_tags is empty`,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for i, p := range problems {
+		if p.String() != want[i] {
+			t.Errorf("problem %d = %q, want %q", i, p.String(), want[i])
+		}
+	}
+}
+
+func TestParseSSHConfigSkipsPatterns(t *testing.T) {
+	hosts, err := NewSSHConfigParser().ParseConfigString("Host *\n    User root\n\nHost web\n    HostName 10.0.0.1\n")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0].Name != "web" {
+		t.Errorf("expected only web, got %+v", hosts)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	data := "name,host,port,user,tags\nweb1,10.0.0.4,2222,deploy,web;prod\n,,,,\ndb1,db.example.com,,admin,\n"
+	hosts, err := ParseImport(ImportCSV, []byte(data))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(hosts) != 2 {
+		t.Fatalf("expected 2 hosts, got %d", len(hosts))
+	}
+	if hosts[0].Port != 2222 || hosts[0].User != "deploy" || len(hosts[0].Tags) != 2 {
+		t.Errorf("unexpected first host: %+v", hosts[0])
+	}
+	if hosts[1].Port != 22 || hosts[1].ID == "" {
+		t.Errorf("expected default port and ID, got %+v", hosts[1])
+	}
+
+	if _, err := ParseCSV(strings.NewReader("name,host,port\nweb,1.2.3.4,abc\n")); err == nil {
+		t.Error("expected error for invalid port")
+	}
+	if _, err := ParseCSV(strings.NewReader("name,user\nweb,root\n")); err == nil {
+		t.Error("expected error for missing host column")
+	}
+
+	// FormatCSV output must round-trip through ParseCSV
+	var buf bytes.Buffer
+	if err := FormatCSV(&buf, hosts); err != nil {
+		t.Fatalf("format failed: %v", err)
+	}
+	again, err := ParseCSV(&buf)
+	if err != nil || len(again) != 2 || again[0].Name != "web1" || strings.Join(again[0].Tags, ",") != "web,prod" {
+		t.Errorf("round trip failed: %+v (%v)", again, err)
+	}
+}
+
+func TestParseAnsibleInventory(t *testing.T) {
+	ini := `# inventory
+bastion ansible_host=203.0.113.1
+
+[web]
+web1 ansible_host=10.0.0.4 ansible_user=deploy ansible_port=2222
+web2
+
+[db]
+db1 ansible_host=10.0.1.5
+web1
+
+[web:vars]
+http_port=80
+`
+	yml := `all:
+  hosts:
+    bastion:
+      ansible_host: 203.0.113.1
+  children:
+    web:
+      hosts:
+        web1:
+          ansible_host: 10.0.0.4
+          ansible_port: 2222
+`
+
+	hosts, err := ParseImport(ImportAnsible, []byte(ini))
+	if err != nil {
+		t.Fatalf("INI parse failed: %v", err)
+	}
+	if len(hosts) != 4 {
+		t.Fatalf("expected 4 hosts, got %d: %+v", len(hosts), hosts)
+	}
+	web1 := hosts[1]
+	if web1.Name != "web1" || web1.Host != "10.0.0.4" || web1.Port != 2222 || web1.User != "deploy" || web1.Group != "web" {
+		t.Errorf("unexpected web1: %+v", web1)
+	}
+	if strings.Join(web1.Tags, ",") != "db,ansible" {
+		t.Errorf("expected extra groups as tags, got %v", web1.Tags)
+	}
+	if hosts[2].Host != "web2" {
+		t.Errorf("expected host name as address, got %q", hosts[2].Host)
+	}
+
+	hosts, err = ParseImport(ImportAnsible, []byte(yml))
+	if err != nil {
+		t.Fatalf("YAML parse failed: %v", err)
+	}
+	if len(hosts) != 2 || hosts[1].Name != "web1" || hosts[1].Port != 2222 || hosts[1].Group != "web" {
+		t.Errorf("unexpected YAML hosts: %+v", hosts)
+	}
+}
+
+func TestParsePuTTYSessions(t *testing.T) {
+	reg := "Windows Registry Editor Version 5.00\r\n\r\n" +
+		"[HKEY_CURRENT_USER\\Software\\SimonTatham\\PuTTY\\Sessions\\Default%20Settings]\r\n" +
+		"\"HostName\"=\"\"\r\n\r\n" +
+		"[HKEY_CURRENT_USER\\Software\\SimonTatham\\PuTTY\\Sessions\\my%20server]\r\n" +
+		"\"HostName\"=\"admin@10.0.0.9\"\r\n" +
+		"\"PortNumber\"=dword:000008ae\r\n" +
+		"\"Protocol\"=\"ssh\"\r\n\r\n" +
+		"[HKEY_CURRENT_USER\\Software\\SimonTatham\\PuTTY\\Sessions\\serial]\r\n" +
+		"\"HostName\"=\"COM1\"\r\n" +
+		"\"Protocol\"=\"serial\"\r\n"
+
+	// regedit writes UTF-16LE with a byte order mark
+	encoded := []byte{0xFF, 0xFE}
+	for _, r := range reg {
+		encoded = append(encoded, byte(r), 0)
+	}
+
+	for _, data := range [][]byte{[]byte(reg), encoded} {
+		hosts, err := ParseImport(ImportPuTTY, data)
+		if err != nil {
+			t.Fatalf("parse failed: %v", err)
+		}
+		if len(hosts) != 1 {
+			t.Fatalf("expected 1 SSH session, got %+v", hosts)
+		}
+		h := hosts[0]
+		if h.Name != "my server" || h.Host != "10.0.0.9" || h.User != "admin" || h.Port != 2222 {
+			t.Errorf("unexpected session: %+v", h)
+		}
+	}
+}
+
+func TestNewHosts(t *testing.T) {
+	existing := []models.Host{{Name: "web1"}}
+	added, skipped := NewHosts(existing, []models.Host{{Name: "web1"}, {Name: "web2"}, {Name: "web2"}})
+	if len(added) != 1 || added[0].Name != "web2" {
+		t.Errorf("expected only web2 added, got %+v", added)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("expected 2 skipped, got %v", skipped)
+	}
+}
+
+func TestDefaultPathsHonourXDG(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg/config")
+	t.Setenv("XDG_DATA_HOME", "/xdg/data")
+
+	paths := DefaultPaths()
+	if paths.Config != filepath.Join("/xdg/config", "sshm", "config.yaml") {
+		t.Errorf("unexpected config path %s", paths.Config)
+	}
+	if paths.Hosts != filepath.Join("/xdg/data", "sshm", "hosts.json") {
+		t.Errorf("unexpected hosts path %s", paths.Hosts)
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	legacy := `{"theme": "light", "profiles": [{"name": "ops"}], "hosts": [{"id": "1", "name": "web1"}]}`
+	if err := os.WriteFile(filepath.Join(home, ".sshm.json"), []byte(legacy), 0600); err != nil {
+		t.Fatalf("failed to write legacy config: %v", err)
+	}
+
+	paths := Paths{
+		Config:  filepath.Join(home, "config", "sshm", "config.yaml"),
+		Hosts:   filepath.Join(home, "data", "sshm", "hosts.json"),
+		History: filepath.Join(home, "data", "sshm", "history.json"),
+	}
+	migrated, err := MigrateLegacy(paths)
+	if err != nil || !migrated {
+		t.Fatalf("expected migration, got %v, %v", migrated, err)
+	}
+
+	settings, err := LoadConfig(paths.Config)
+	if err != nil {
+		t.Fatalf("failed to load migrated settings: %v", err)
+	}
+	if settings.Theme != "light" || len(settings.Profiles) != 1 || len(settings.Hosts) != 0 {
+		t.Errorf("unexpected settings: %+v", settings)
+	}
+	hosts, err := LoadConfig(paths.Hosts)
+	if err != nil {
+		t.Fatalf("failed to load migrated hosts: %v", err)
+	}
+	if len(hosts.Hosts) != 1 || hosts.Theme != "" {
+		t.Errorf("unexpected hosts file: %+v", hosts)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".sshm.json.migrated")); err != nil {
+		t.Errorf("expected the legacy file to be renamed: %v", err)
+	}
+
+	// Once the new files exist nothing happens again
+	if migrated, err := MigrateLegacy(paths); err != nil || migrated {
+		t.Errorf("expected no second migration, got %v, %v", migrated, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	data := `theme: dark
+colour: red
+encrypted_fields: [password, notes]
+defaults:
+  port: abc
+  bastions:
+    - proxy: bastion-eu
+      cidrs: [10.1.0.0/33]
+hosts:
+  - name: web1
+    host: 10.0.0.1
+    port: 70000
+  - name: WEB1
+    host: 10.0.0.2
+    identity: /nonexistent/key
+  - name: db
+`
+	want := []string{
+		`x.yaml:2:1: unknown field "colour"`,
+		`x.yaml:3:30: unknown encrypted field "notes" (use password)`,
+		`x.yaml:5:9: port must be a whole number, got "abc"`,
+		`x.yaml:8:15: invalid CIDR "10.1.0.0/33" (use e.g. 10.1.0.0/16)`,
+		`x.yaml:12:11: port 70000 must be 1-65535`,
+		`x.yaml:13:11: duplicate host name "WEB1", first defined on line 10`,
+		`x.yaml:15:15: warning: identity file /nonexistent/key does not exist`,
+		`x.yaml:16:5: host "db" has no address`,
+	}
+
+	problems := Validate("x.yaml", []byte(data))
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for i, p := range problems {
+		if p.String() != want[i] {
+			t.Errorf("problem %d = %q, want %q", i, p.String(), want[i])
+		}
+	}
+}
+
+func TestGetProfileMergesConfigs(t *testing.T) {
+	cfg := &Config{
+		Profiles: []models.Profile{{Name: "slow", Timeout: 90}},
+		Configs: []models.SSHConfig{
+			{Name: "web", Groups: []string{"web"}, IdentityFile: "~/.ssh/web", ForwardAgent: true},
+			{Name: "prod", Tags: []string{"production"}, ProxyCommand: "corkscrew proxy 8080 %h %p", IdentityFile: "~/.ssh/prod"},
+		},
+	}
+
+	p := cfg.GetProfile(models.Host{Group: "web", Tags: []string{"production"}, Profile: "slow"})
+	if p.Timeout != 90 || !p.ForwardAgent || p.IdentityFile != "~/.ssh/prod" || p.ProxyCommand == "" {
+		t.Errorf("expected the named profile with both configs merged, the later one winning, got %+v", p)
+	}
+	if p := cfg.GetProfile(models.Host{Group: "db"}); p != models.DefaultProfile() {
+		t.Errorf("expected the default profile for an unmatched host, got %+v", p)
+	}
+}
+
+func TestUpgradeData(t *testing.T) {
+	current := []byte(`{"hosts": [{"name": "a", "host": "10.0.0.1"}]}`)
+	if out, changes := UpgradeData("x.json", current); len(changes) != 0 || string(out) != string(current) {
+		t.Errorf("expected the current layout to be left alone, got %v", changes)
+	}
+
+	out, changes := UpgradeData("x.json", []byte(`[{"name": "a", "HostName": "10.0.0.1", "username": "root", "host": "x"}]`))
+	if len(changes) != 2 || changes[0].Message != "a bare list of hosts is now the hosts key" {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+	// host is already set, so HostName is left for validation to report
+	want := "{\n  \"hosts\": [\n    {\n      \"name\": \"a\",\n      \"HostName\": \"10.0.0.1\",\n      \"user\": \"root\",\n      \"host\": \"x\"\n    }\n  ]\n}"
+	if string(out) != want {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	out, changes = UpgradeData("x.yaml", []byte("hosts:\n  web:\n    address: 10.0.0.2 # primary\n"))
+	if len(changes) != 2 || string(out) != "hosts:\n  - name: web\n    host: 10.0.0.2 # primary\n" {
+		t.Errorf("unexpected upgrade %v:\n%s", changes, out)
+	}
+}
+
+func TestLoadConfigUpgradesLegacyLayouts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.yaml")
+	if err := os.WriteFile(path, []byte("- name: web\n  hostname: 10.0.0.2\n  login: deploy\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.Hosts) != 1 || cfg.Hosts[0].Host != "10.0.0.2" || cfg.Hosts[0].User != "deploy" || cfg.Hosts[0].ID != HostID("web") {
+		t.Errorf("unexpected hosts %+v", cfg.Hosts)
+	}
+
+	changes, err := MigrateFile(path, false)
+	if err != nil || len(changes) != 4 {
+		t.Fatalf("expected 4 changes, got %v, %v", changes, err)
+	}
+	if changes, _ := CheckUpgrade(path); len(changes) != 0 {
+		t.Errorf("expected the migrated file to be current, got %v", changes)
+	}
+	again, err := LoadConfig(path)
+	if err != nil || again.Hosts[0].ID != cfg.Hosts[0].ID {
+		t.Errorf("expected the saved ID to match the derived one, got %+v, %v", again, err)
+	}
+}
+
+func TestValidateThemes(t *testing.T) {
+	data := `theme: ocean
+themes:
+  light:
+    extends: sepia
+    colors:
+      primary: orange
+  forest:
+    colors:
+      primary: "#228b22"
+      error: "300"
+    styles:
+      title:
+        background: "#12"
+`
+	want := []string{
+		`x.yaml:1:8: unknown theme "ocean"`,
+		`x.yaml:4:14: unknown preset "sepia" (use dark or light)`,
+		`x.yaml:6:16: invalid color "orange" (use 0-255 or #rrggbb)`,
+		`x.yaml:10:14: invalid color "300" (use 0-255 or #rrggbb)`,
+		`x.yaml:13:21: invalid color "#12" (use 0-255 or #rrggbb)`,
+	}
+
+	problems := Validate("x.yaml", []byte(data))
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for i, p := range problems {
+		if p.String() != want[i] {
+			t.Errorf("problem %d = %q, want %q", i, p.String(), want[i])
+		}
+	}
+}
+
+func TestValidateJSONSyntax(t *testing.T) {
+	problems := Validate("x.json", []byte("{\n  \"hosts\": [\n    {\"name\": \"a\",}\n  ]\n}\n"))
+	if len(problems) != 1 || problems[0].Line != 3 || problems[0].Column != 19 {
+		t.Errorf("expected a located syntax error, got %v", problems)
+	}
+	if problems := Validate("x.json", []byte(`{"hosts": [{"name": "a", "host": "10.0.0.1"}]}`)); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
+
+func TestLoadIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.yaml", "include: [team/*.yaml]\n")
+	write("team/a.yaml", "include: [../main.yaml, b.json]\nhosts:\n  - name: a1\n")
+	write("team/b.json", `{"hosts": [{"name": "b1"}]}`)
+
+	files, err := LoadIncludes(filepath.Join(dir, "main.yaml"), []string{"team/*.yaml"})
+	if err != nil {
+		t.Fatalf("LoadIncludes failed: %v", err)
+	}
+	if len(files) != 2 || files[0].Hosts[0].Name != "a1" || files[1].Hosts[0].Name != "b1" {
+		t.Errorf("unexpected included files: %+v", files)
+	}
+
+	// A missing plain path is an error, the readable files still come back
+	files, err = LoadIncludes(filepath.Join(dir, "main.yaml"), []string{"missing.yaml", "team/b.json"})
+	if err == nil || len(files) != 1 {
+		t.Errorf("expected an error and one file, got %v and %+v", err, files)
+	}
+}
+
+func TestValidateSnippets(t *testing.T) {
+	data := `snippets:
+  - name: restart-app
+    command: systemctl restart app
+  - name: Restart-App
+    command: systemctl restart app
+  - name: uptime
+  - command: df -h
+`
+	want := []string{
+		`x.yaml:4:11: duplicate snippet "Restart-App", first defined on line 2`,
+		`x.yaml:6:5: snippet has no command`,
+		`x.yaml:7:5: snippet has no name`,
+	}
+
+	problems := Validate("x.yaml", []byte(data))
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for i, p := range problems {
+		if p.String() != want[i] {
+			t.Errorf("problem %d = %q, want %q", i, p.String(), want[i])
+		}
+	}
+
+	cfg := &Config{Snippets: []models.Snippet{
+		{Name: "uptime", Command: "uptime"},
+		{Name: "vacuum", Command: "vacuumdb --all", Tags: []string{"db"}},
+	}}
+	if got := cfg.SnippetsFor(models.Host{Tags: []string{"web"}}); len(got) != 1 || got[0].Name != "uptime" {
+		t.Errorf("SnippetsFor = %v", got)
+	}
+	if s, ok := cfg.FindSnippet("VACUUM"); !ok || s.Name != "vacuum" {
+		t.Errorf("FindSnippet = %v, %v", s, ok)
+	}
+}
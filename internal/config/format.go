@@ -0,0 +1,231 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File formats of the config and host store
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// DetectFormat returns the format of a config file
+// A .json, .yaml or .yml extension decides; otherwise content starting with
+// "{" or "[" is JSON and anything else YAML. New files without content or a
+// known extension are JSON.
+func DetectFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] == '{' || trimmed[0] == '[' {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// Unmarshal decodes data in the given format into v
+func Unmarshal(format string, data []byte, v interface{}) error {
+	if format == FormatYAML {
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil
+		}
+		return yaml.Unmarshal(data, v)
+	}
+	return jsonPosition(data, json.Unmarshal(data, v))
+}
+
+// PositionError is a parse error located in the file
+type PositionError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// jsonPosition adds the line and column to JSON syntax and type errors,
+// which only carry a byte offset
+func jsonPosition(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return &PositionError{Line: line, Column: column, Err: err}
+}
+
+// Marshal encodes v in the given format, indented for hand editing
+func Marshal(format string, v interface{}) ([]byte, error) {
+	if format == FormatYAML {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// SetYAMLKey replaces the value of a top-level key in a YAML document,
+// keeping comments, key order and every other setting as written
+// The key is appended when missing; an empty document or a legacy list of
+// hosts is replaced by a mapping holding just the key, anything else is
+// refused rather than lost.
+func SetYAMLKey(doc []byte, key string, value interface{}) ([]byte, error) {
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if len(bytes.TrimSpace(doc)) > 0 {
+		if err := yaml.Unmarshal(doc, &root); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+	}
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		if top := root.Content[0]; top.Kind != yaml.MappingNode && top.Kind != yaml.SequenceNode && top.Tag != "!!null" {
+			return nil, fmt.Errorf("failed to parse YAML: the document is not a mapping")
+		}
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	mapping := root.Content[0]
+	replaced := false
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			// Keep comments attached to the old value
+			valueNode.HeadComment = mapping.Content[i+1].HeadComment
+			valueNode.LineComment = mapping.Content[i+1].LineComment
+			mapping.Content[i+1] = &valueNode
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		mapping.Content = append(mapping.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &valueNode)
+	}
+
+	return Marshal(FormatYAML, &root)
+}
+
+// SetKey replaces one top-level key of the file at path, keeping the file's
+// format and every other setting; YAML files also keep their comments
+// The file is written to a temporary file and renamed so a crash never
+// leaves it truncated. A file that can't be read or parsed is left alone
+// with an error, so a damaged file is never replaced by a near-empty one.
+func SetKey(path, key string, value interface{}) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var data []byte
+	if DetectFormat(path, existing) == FormatYAML {
+		if data, err = SetYAMLKey(existing, key, value); err != nil {
+			return fmt.Errorf("failed to update %s in %s: %w", key, path, err)
+		}
+	} else {
+		valueData, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", key, err)
+		}
+
+		doc := make(map[string]json.RawMessage)
+		if len(bytes.TrimSpace(existing)) > 0 {
+			if err := json.Unmarshal(existing, &doc); err != nil {
+				// Legacy array files are not objects and are simply replaced
+				var legacy []json.RawMessage
+				if json.Unmarshal(existing, &legacy) != nil {
+					return fmt.Errorf("failed to parse %s: %w", path, err)
+				}
+			}
+		}
+		if doc == nil {
+			doc = make(map[string]json.RawMessage)
+		}
+		doc[key] = valueData
+
+		if data, err = json.MarshalIndent(doc, "", "  "); err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", key, err)
+		}
+	}
+
+	return WriteFileAtomic(path, data)
+}
+
+// WriteFileAtomic replaces the file at path, or the one it links to, with
+// data through a temporary file in the same directory, keeping its mode
+// New files get mode 0600 and their directory is created if needed.
+func WriteFileAtomic(path string, data []byte) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
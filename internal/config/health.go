@@ -0,0 +1,48 @@
+package config
+
+import "time"
+
+// Default health check timings
+const (
+	defaultHealthInterval = 60 * time.Second
+	// Without a ttl, results outlive a few missed rounds
+	defaultHealthTTLRounds = 3
+)
+
+// HealthCheck sets how often the TUI probes the hosts in the background to
+// show which are up
+type HealthCheck struct {
+	// Interval is how many seconds apart rounds of probes start; 60 by
+	// default
+	Interval int `json:"interval,omitempty" yaml:"interval,omitempty"`
+	// Jitter is up to how many seconds rounds start earlier or later; a
+	// tenth of the interval by default
+	Jitter int `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+	// TTL is how many seconds a result is shown before the host is unknown
+	// again; three intervals by default
+	TTL int `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
+
+// Period returns the time between rounds of probes
+func (h HealthCheck) Period() time.Duration {
+	if h.Interval <= 0 {
+		return defaultHealthInterval
+	}
+	return time.Duration(h.Interval) * time.Second
+}
+
+// Spread returns up to how much earlier or later rounds start
+func (h HealthCheck) Spread() time.Duration {
+	if h.Jitter <= 0 {
+		return h.Period() / 10
+	}
+	return time.Duration(h.Jitter) * time.Second
+}
+
+// Lifetime returns how long a result stays valid
+func (h HealthCheck) Lifetime() time.Duration {
+	if h.TTL <= 0 {
+		return defaultHealthTTLRounds * h.Period()
+	}
+	return time.Duration(h.TTL) * time.Second
+}
@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+// IdleLock hides the TUI after a while without input, for shared screens
+// where the inventory itself is sensitive
+type IdleLock struct {
+	// After is how many seconds without a key press lock the TUI; 0 never
+	// locks
+	After int `json:"after,omitempty" yaml:"after,omitempty"`
+	// Passphrase unlocks with the store passphrase instead of any key
+	Passphrase bool `json:"passphrase,omitempty" yaml:"passphrase,omitempty"`
+}
+
+// Timeout returns how long the TUI waits for input before locking, 0 when
+// it never locks
+func (l IdleLock) Timeout() time.Duration {
+	if l.After <= 0 {
+		return 0
+	}
+	return time.Duration(l.After) * time.Second
+}
@@ -0,0 +1,460 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+	"github.com/sshm/sshm/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Import formats understood by ParseImport
+const (
+	ImportSSHConfig = "ssh-config"
+	ImportAnsible   = "ansible"
+	ImportCSV       = "csv"
+	ImportPuTTY     = "putty"
+)
+
+// ImportFormats lists the supported import formats
+var ImportFormats = []string{ImportSSHConfig, ImportAnsible, ImportCSV, ImportPuTTY}
+
+// csvColumns are the columns written by FormatCSV and recognised by ParseCSV
+var csvColumns = []string{"name", "host", "port", "user", "identity", "proxy", "group", "tags"}
+
+// ParseImport parses hosts from data in the given import format
+func ParseImport(format string, data []byte) ([]models.Host, error) {
+	switch format {
+	case ImportSSHConfig:
+		return NewSSHConfigParser().ParseConfigString(string(data))
+	case ImportAnsible:
+		return ParseAnsibleInventory(data)
+	case ImportCSV:
+		return ParseCSV(bytes.NewReader(data))
+	case ImportPuTTY:
+		return ParsePuTTYSessions(data)
+	}
+	return nil, fmt.Errorf("unknown import format %q (use %s)", format, strings.Join(ImportFormats, ", "))
+}
+
+// NewHosts returns the hosts whose names are not already taken by existing
+// ones, along with the names that were skipped
+func NewHosts(existing, hosts []models.Host) (added []models.Host, skipped []string) {
+	taken := make(map[string]bool)
+	for _, h := range existing {
+		taken[h.Name] = true
+	}
+	for _, h := range hosts {
+		if taken[h.Name] {
+			skipped = append(skipped, h.Name)
+			continue
+		}
+		taken[h.Name] = true
+		added = append(added, h)
+	}
+	return added, skipped
+}
+
+// newImportedHost creates a host with a fresh ID and the default port
+func newImportedHost(name string) models.Host {
+	return models.Host{
+		ID:   uuid.New().String(),
+		Name: name,
+		Port: 22,
+	}
+}
+
+// ParseCSV parses hosts from CSV with a header row
+// Recognised columns are name, host, port, user, identity, proxy, group and
+// tags (separated by ';' or spaces); unknown columns are ignored
+func ParseCSV(r io.Reader) ([]models.Host, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []models.Host{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	index := make(map[string]int)
+	for i, col := range header {
+		index[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+	if _, ok := index["name"]; !ok {
+		return nil, fmt.Errorf("CSV header must contain a name column")
+	}
+	if _, ok := index["host"]; !ok {
+		return nil, fmt.Errorf("CSV header must contain a host column")
+	}
+
+	var hosts []models.Host
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		field := func(name string) string {
+			if i, ok := index[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		if field("name") == "" && field("host") == "" {
+			continue
+		}
+		if field("name") == "" || field("host") == "" {
+			return nil, fmt.Errorf("line %d: name and host are required", line)
+		}
+
+		host := newImportedHost(field("name"))
+		host.Host = field("host")
+		host.User = field("user")
+		host.Identity = expandHome(field("identity"))
+		host.Proxy = field("proxy")
+		host.Group = field("group")
+		if p := field("port"); p != "" {
+			port, err := strconv.Atoi(p)
+			if err != nil || port < 1 || port > 65535 {
+				return nil, fmt.Errorf("line %d: invalid port %q", line, p)
+			}
+			host.Port = port
+		}
+		host.Tags = strings.FieldsFunc(field("tags"), func(r rune) bool {
+			return r == ';' || r == ' ' || r == ','
+		})
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
+
+// FormatCSV writes hosts as CSV in the layout read by ParseCSV
+func FormatCSV(w io.Writer, hosts []models.Host) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, h := range hosts {
+		record := []string{
+			h.Name, h.Host, strconv.Itoa(h.Port), h.User, h.Identity, h.Proxy, h.Group,
+			strings.Join(h.Tags, ";"),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ansibleHost collects the variables of one inventory host
+type ansibleHost struct {
+	name   string
+	vars   map[string]string
+	groups []string
+}
+
+// ansibleInventory accumulates hosts in the order they are first seen
+type ansibleInventory struct {
+	hosts map[string]*ansibleHost
+	order []string
+}
+
+func (inv *ansibleInventory) add(name, group string, vars map[string]string) {
+	h, ok := inv.hosts[name]
+	if !ok {
+		h = &ansibleHost{name: name, vars: make(map[string]string)}
+		inv.hosts[name] = h
+		inv.order = append(inv.order, name)
+	}
+	for k, v := range vars {
+		h.vars[k] = v
+	}
+	if group != "" && group != "all" && group != "ungrouped" {
+		for _, g := range h.groups {
+			if g == group {
+				return
+			}
+		}
+		h.groups = append(h.groups, group)
+	}
+}
+
+// ParseAnsibleInventory parses an Ansible inventory in INI or YAML form
+// The first group of a host becomes its group, further groups become tags;
+// ansible_host, ansible_port, ansible_user and ansible_ssh_private_key_file
+// are mapped onto the host
+func ParseAnsibleInventory(data []byte) ([]models.Host, error) {
+	inv := &ansibleInventory{hosts: make(map[string]*ansibleHost)}
+
+	var err error
+	if looksLikeYAMLInventory(data) {
+		err = parseAnsibleYAML(data, inv)
+	} else {
+		err = parseAnsibleINI(data, inv)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]models.Host, 0, len(inv.order))
+	for _, name := range inv.order {
+		hosts = append(hosts, inv.hosts[name].toModel())
+	}
+	return hosts, nil
+}
+
+// looksLikeYAMLInventory reports whether the first meaningful line is YAML
+func looksLikeYAMLInventory(data []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		return line == "---" || strings.HasSuffix(line, ":")
+	}
+	return false
+}
+
+func parseAnsibleINI(data []byte, inv *ansibleInventory) error {
+	group := ""
+	skip := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("line %d: invalid section %q", lineNo, line)
+			}
+			group = strings.TrimSpace(line[1 : len(line)-1])
+			// [group:vars] and [group:children] do not list hosts
+			skip = strings.Contains(group, ":")
+			continue
+		}
+		if skip {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		vars := make(map[string]string)
+		for _, f := range fields[1:] {
+			if k, v, ok := strings.Cut(f, "="); ok {
+				vars[k] = strings.Trim(v, `"'`)
+			}
+		}
+		inv.add(fields[0], group, vars)
+	}
+	return scanner.Err()
+}
+
+// ansibleYAMLGroup mirrors a group of a YAML inventory
+type ansibleYAMLGroup struct {
+	Hosts    map[string]map[string]interface{} `yaml:"hosts"`
+	Children map[string]*ansibleYAMLGroup      `yaml:"children"`
+}
+
+func parseAnsibleYAML(data []byte, inv *ansibleInventory) error {
+	var groups map[string]*ansibleYAMLGroup
+	if err := yaml.Unmarshal(data, &groups); err != nil {
+		return fmt.Errorf("failed to parse YAML inventory: %w", err)
+	}
+
+	var walk func(name string, g *ansibleYAMLGroup)
+	walk = func(name string, g *ansibleYAMLGroup) {
+		if g == nil {
+			return
+		}
+		hostNames := make([]string, 0, len(g.Hosts))
+		for h := range g.Hosts {
+			hostNames = append(hostNames, h)
+		}
+		sort.Strings(hostNames)
+		for _, h := range hostNames {
+			vars := make(map[string]string)
+			for k, v := range g.Hosts[h] {
+				vars[k] = fmt.Sprint(v)
+			}
+			inv.add(h, name, vars)
+		}
+
+		children := make([]string, 0, len(g.Children))
+		for c := range g.Children {
+			children = append(children, c)
+		}
+		sort.Strings(children)
+		for _, c := range children {
+			walk(c, g.Children[c])
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		walk(name, groups[name])
+	}
+	return nil
+}
+
+func (h *ansibleHost) toModel() models.Host {
+	host := newImportedHost(h.name)
+	host.Host = h.name
+	if v := h.vars["ansible_host"]; v != "" {
+		host.Host = v
+	}
+	if v := h.vars["ansible_port"]; v != "" {
+		if port, err := strconv.Atoi(v); err == nil {
+			host.Port = port
+		}
+	}
+	host.User = h.vars["ansible_user"]
+	host.Identity = expandHome(h.vars["ansible_ssh_private_key_file"])
+	if len(h.groups) > 0 {
+		host.Group = h.groups[0]
+		host.Tags = append(host.Tags, h.groups[1:]...)
+	}
+	host.Tags = append(host.Tags, "ansible")
+	return host
+}
+
+// ParsePuTTYSessions parses saved PuTTY sessions from a registry export
+// (reg export HKCU\Software\SimonTatham\PuTTY\Sessions sessions.reg)
+// Only SSH sessions with a host name are imported
+func ParsePuTTYSessions(data []byte) ([]models.Host, error) {
+	text := decodeRegFile(data)
+
+	var hosts []models.Host
+	var current *models.Host
+	protocol := ""
+
+	flush := func() {
+		if current != nil && current.Host != "" && (protocol == "" || protocol == "ssh") {
+			hosts = append(hosts, *current)
+		}
+		current = nil
+		protocol = ""
+	}
+
+	const sessionsKey = `\software\simontatham\putty\sessions\`
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			flush()
+			key := line[1 : len(line)-1]
+			i := strings.Index(strings.ToLower(key), sessionsKey)
+			if i < 0 {
+				continue
+			}
+			name := key[i+len(sessionsKey):]
+			if decoded, err := url.PathUnescape(name); err == nil {
+				name = decoded
+			}
+			if name == "" || name == "Default Settings" {
+				continue
+			}
+			h := newImportedHost(name)
+			h.Tags = []string{"putty"}
+			current = &h
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.Trim(key, `"`)
+
+		switch key {
+		case "HostName":
+			host := regString(value)
+			// PuTTY allows user@host in the host name field
+			if user, addr, found := strings.Cut(host, "@"); found {
+				current.User = user
+				host = addr
+			}
+			current.Host = host
+		case "UserName":
+			if u := regString(value); u != "" {
+				current.User = u
+			}
+		case "PortNumber":
+			if port, ok := regDword(value); ok && port > 0 {
+				current.Port = port
+			}
+		case "PublicKeyFile":
+			// PuTTY keys (.ppk) cannot be used directly; keep the path for reference
+			current.Identity = regString(value)
+		case "Protocol":
+			protocol = regString(value)
+		}
+	}
+	flush()
+
+	return hosts, nil
+}
+
+// decodeRegFile converts a .reg export, which regedit writes as UTF-16LE
+// with a byte order mark, to a string
+func decodeRegFile(data []byte) string {
+	if len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE {
+		data = data[2:]
+		u16 := make([]uint16, 0, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			u16 = append(u16, uint16(data[i])|uint16(data[i+1])<<8)
+		}
+		return strings.ReplaceAll(string(utf16.Decode(u16)), "\r", "")
+	}
+	return strings.ReplaceAll(string(data), "\r", "")
+}
+
+// regString decodes a quoted registry string value
+func regString(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return ""
+	}
+	value = value[1 : len(value)-1]
+	return strings.NewReplacer(`\\`, `\`, `\"`, `"`).Replace(value)
+}
+
+// regDword decodes a dword:0000xxxx registry value
+func regDword(value string) (int, bool) {
+	hex, ok := strings.CutPrefix(strings.TrimSpace(value), "dword:")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(hex, 16, 64)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+// defaultIncludeRefresh is how often included files are checked for changes
+const defaultIncludeRefresh = time.Minute
+
+// IncludeRefreshInterval returns how often the TUI re-reads included files
+// that changed, 0 when it doesn't
+func (c *Config) IncludeRefreshInterval() time.Duration {
+	switch {
+	case c.IncludeRefresh < 0:
+		return 0
+	case c.IncludeRefresh == 0:
+		return defaultIncludeRefresh
+	}
+	return time.Duration(c.IncludeRefresh) * time.Second
+}
+
+// IncludedFile is a hosts file named by an include directive
+type IncludedFile struct {
+	Path  string
+	Hosts []models.Host
+}
+
+// ReadHosts reads the hosts and include list of a hosts file, which may be
+// a settings object or a legacy bare list of hosts; a missing file has none
+// Older layouts are upgraded in memory, see UpgradeData.
+func ReadHosts(path string) (hosts []models.Host, include []string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	format := DetectFormat(path, data)
+	data, _ = UpgradeData(path, data)
+
+	var cfg models.Config
+	if err := Unmarshal(format, data, &cfg); err == nil {
+		return cfg.Hosts, cfg.Include, nil
+	}
+	if err := Unmarshal(format, data, &hosts); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return hosts, nil, nil
+}
+
+// LoadIncludes reads the files named by the include list of the file at
+// from, following their own include lists
+// Entries are relative to the including file, may start with ~/ and may be
+// glob patterns; a pattern matching nothing is fine, a missing plain path is
+// an error. Files already seen, including from itself, are skipped, so
+// include cycles end. Every readable file is returned even when others fail.
+func LoadIncludes(from string, include []string) ([]IncludedFile, error) {
+	seen := map[string]bool{cleanPath(from): true}
+	var files []IncludedFile
+	var errs []error
+
+	var walk func(from string, include []string)
+	walk = func(from string, include []string) {
+		for _, entry := range include {
+			paths, err := expandInclude(from, entry)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, path := range paths {
+				if seen[cleanPath(path)] {
+					continue
+				}
+				seen[cleanPath(path)] = true
+
+				hosts, nested, err := ReadHosts(path)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				files = append(files, IncludedFile{Path: path, Hosts: hosts})
+				walk(path, nested)
+			}
+		}
+	}
+	walk(from, include)
+
+	return files, errors.Join(errs...)
+}
+
+// expandInclude resolves one include entry to the files it names
+func expandInclude(from, entry string) ([]string, error) {
+	path := expandHome(entry)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+	if !hasGlob(path) {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("include %s in %s: %w", entry, from, err)
+		}
+		return []string{path}, nil
+	}
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("include %s in %s: %w", entry, from, err)
+	}
+	return matches, nil
+}
+
+func hasGlob(path string) bool {
+	for _, r := range path {
+		switch r {
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+func cleanPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
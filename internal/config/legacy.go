@@ -0,0 +1,271 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+// Upgrade is one change made to bring a file to the current layout
+type Upgrade struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// hostFieldAliases maps older and OpenSSH-style host field names, with case,
+// "_" and "-" ignored, to the current names
+var hostFieldAliases = map[string]string{
+	"address":      "host",
+	"hostname":     "host",
+	"ip":           "host",
+	"username":     "user",
+	"login":        "user",
+	"identityfile": "identity",
+	"key":          "identity",
+	"keyfile":      "identity",
+	"proxyjump":    "proxy",
+	"jump":         "proxy",
+	"jumphost":     "proxy",
+	"bastion":      "proxy",
+	"auth":         "auth_type",
+}
+
+func init() {
+	// Current names in another case or spelling, such as "Port" or "authType"
+	for name := range yamlFields(reflect.TypeOf(models.Host{})) {
+		hostFieldAliases[normalizeField(name)] = name
+	}
+}
+
+func normalizeField(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// HostID returns the ID of a host that has none, derived from its name so it
+// stays the same across loads until the file is saved with it
+func HostID(name string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte("sshm:"+name)).String()
+}
+
+// UpgradeData returns a settings or hosts file rewritten in the current
+// layout, in its own format, and the changes made:
+//   - a bare list of hosts becomes the hosts key
+//   - hosts keyed by name become a list
+//   - older field names such as hostname, username or identity_file are
+//     renamed
+//
+// Hosts without an ID need no upgrade since their ID is derived from the
+// name, see HostID. Files in the current layout and files that don't parse
+// are returned as they are.
+func UpgradeData(path string, data []byte) ([]byte, []Upgrade) {
+	out, changes, err := upgradeData(path, data, false)
+	if err != nil {
+		return data, nil
+	}
+	return out, changes
+}
+
+// CheckUpgrade reports the changes UpgradeData would make to the file at
+// path; a missing file needs none
+func CheckUpgrade(path string) ([]Upgrade, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	_, changes := UpgradeData(path, data)
+	return changes, nil
+}
+
+// MigrateFile rewrites the file at path in the current layout, see
+// UpgradeData, and saves the IDs of hosts without one
+// The old file is kept as path.bak. With dryRun the changes are only
+// reported. A file in the current layout is left alone.
+func MigrateFile(path string, dryRun bool) ([]Upgrade, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	out, changes, err := upgradeData(path, data, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(changes) == 0 || dryRun {
+		return changes, nil
+	}
+	if err := os.WriteFile(path+".bak", data, 0600); err != nil {
+		return nil, err
+	}
+	return changes, WriteFileAtomic(path, out)
+}
+
+// upgradeData upgrades data, also adding missing host IDs with assignIDs
+func upgradeData(path string, data []byte, assignIDs bool) ([]byte, []Upgrade, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return data, nil, nil
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, err
+	}
+	changes := upgradeDocument(&root, assignIDs)
+	if len(changes) == 0 {
+		return data, nil, nil
+	}
+
+	var out []byte
+	var err error
+	if DetectFormat(path, data) == FormatYAML {
+		out, err = Marshal(FormatYAML, &root)
+	} else {
+		out, err = json.MarshalIndent(jsonNode{&root}, "", "  ")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, changes, nil
+}
+
+// upgradeDocument rewrites a parsed file in place, see UpgradeData
+func upgradeDocument(root *yaml.Node, assignIDs bool) []Upgrade {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil
+	}
+	var changes []Upgrade
+	change := func(node *yaml.Node, format string, args ...interface{}) {
+		changes = append(changes, Upgrade{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+	}
+
+	doc := root.Content[0]
+	if doc.Kind == yaml.SequenceNode {
+		change(doc, "a bare list of hosts is now the hosts key")
+		doc = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "hosts"}, doc,
+		}}
+		root.Content[0] = doc
+	}
+
+	hosts := mappingValue(doc, "hosts")
+	if hosts == nil {
+		return changes
+	}
+	if hosts.Kind == yaml.MappingNode {
+		change(hosts, "hosts keyed by name are now a list")
+		list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: hosts.Line, Column: hosts.Column}
+		for i := 0; i+1 < len(hosts.Content); i += 2 {
+			key, host := hosts.Content[i], hosts.Content[i+1]
+			if host.Kind != yaml.MappingNode {
+				continue
+			}
+			if mappingValue(host, "name") == nil {
+				host.Content = append([]*yaml.Node{
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: "name"},
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.Value},
+				}, host.Content...)
+			}
+			list.Content = append(list.Content, host)
+		}
+		*hosts = *list
+	}
+	if hosts.Kind != yaml.SequenceNode {
+		return changes
+	}
+
+	for _, host := range hosts.Content {
+		if host.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(host.Content); i += 2 {
+			key := host.Content[i]
+			name, ok := hostFieldAliases[normalizeField(key.Value)]
+			if !ok || name == key.Value || mappingValue(host, name) != nil {
+				continue
+			}
+			change(key, "%q is now %q", key.Value, name)
+			key.Value = name
+		}
+		if assignIDs && mappingValue(host, "id") == nil {
+			name := mappingValue(host, "name")
+			if name == nil || name.Value == "" {
+				continue
+			}
+			id := HostID(name.Value)
+			change(host, "host %q is saved with id %s", name.Value, id)
+			host.Content = append([]*yaml.Node{
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: "id"},
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: id},
+			}, host.Content...)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Line != changes[j].Line {
+			return changes[i].Line < changes[j].Line
+		}
+		return changes[i].Column < changes[j].Column
+	})
+	return changes
+}
+
+// jsonNode encodes a YAML node as JSON, keeping the order of keys
+type jsonNode struct {
+	*yaml.Node
+}
+
+func (n jsonNode) MarshalJSON() ([]byte, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return []byte("null"), nil
+		}
+		return json.Marshal(jsonNode{n.Content[0]})
+	case yaml.AliasNode:
+		return json.Marshal(jsonNode{n.Alias})
+	case yaml.MappingNode:
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return nil, err
+			}
+			value, err := json.Marshal(jsonNode{n.Content[i+1]})
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+	case yaml.SequenceNode:
+		items := make([]jsonNode, len(n.Content))
+		for i, item := range n.Content {
+			items[i] = jsonNode{item}
+		}
+		return json.Marshal(items)
+	}
+	var v interface{}
+	if err := n.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// Notify shows a desktop notification when jobs that ran for a while finish:
+// commands and snippets run from the TUI, "sshm exec" and "sshm cp"
+type Notify struct {
+	// After is how many seconds a job runs before its end is notified; 0
+	// never notifies
+	After int `json:"after,omitempty" yaml:"after,omitempty"`
+}
+
+// Due reports whether a job that ran for elapsed is long enough to notify
+func (n Notify) Due(elapsed time.Duration) bool {
+	return n.After > 0 && elapsed >= time.Duration(n.After)*time.Second
+}
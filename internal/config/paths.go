@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Paths are the files sshm keeps its settings and hosts in
+// With --config both name the same file, which then holds everything.
+type Paths struct {
+	// Config holds settings: theme, profiles, guarded tags, columns, ...
+	Config string
+	// Hosts holds the host inventory
+	Hosts string
+	// History holds the connection history
+	History string
+}
+
+// SingleFile returns paths keeping settings and hosts together in path
+// The connection history stays at its default location.
+func SingleFile(path string) Paths {
+	return Paths{Config: path, Hosts: path, History: DefaultPaths().History}
+}
+
+// DefaultPaths returns the platform locations of sshm's files:
+//
+//	Linux    $XDG_CONFIG_HOME/sshm/config.yaml, $XDG_DATA_HOME/sshm/hosts.json
+//	macOS    ~/Library/Application Support/sshm/{config.yaml,hosts.json}
+//	Windows  %AppData%\sshm\config.yaml, %LocalAppData%\sshm\hosts.json
+//
+// $XDG_CONFIG_HOME and $XDG_DATA_HOME are honoured on every platform.
+func DefaultPaths() Paths {
+	data := dataDir()
+	return Paths{
+		Config:  filepath.Join(configDir(), "sshm", "config.yaml"),
+		Hosts:   filepath.Join(data, "sshm", "hosts.json"),
+		History: filepath.Join(data, "sshm", "history.json"),
+	}
+}
+
+// LegacyPaths returns the files used before the XDG locations: everything in
+// ~/.sshm.json and the history in ~/.sshm_history.json
+func LegacyPaths() Paths {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return SingleFile(".sshm.json")
+	}
+	return Paths{
+		Config:  filepath.Join(home, ".sshm.json"),
+		Hosts:   filepath.Join(home, ".sshm.json"),
+		History: filepath.Join(home, ".sshm_history.json"),
+	}
+}
+
+func configDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return dir
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		return dir
+	}
+	return "."
+}
+
+func dataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return dir
+	}
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return dir
+		}
+	case "darwin", "ios":
+		// Settings and data share Application Support
+		return configDir()
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(home, ".local", "share")
+}
+
+// MigrateLegacy moves ~/.sshm.json and ~/.sshm_history.json to paths
+// It only acts when neither new file exists yet: hosts go to paths.Hosts,
+// every other setting to paths.Config, and the old file is renamed to
+// ~/.sshm.json.migrated so it is not picked up again. The returned bool
+// reports whether anything was migrated.
+func MigrateLegacy(paths Paths) (bool, error) {
+	legacy := LegacyPaths()
+	if exists(paths.Config) || exists(paths.Hosts) {
+		return false, nil
+	}
+
+	migrated := false
+	if data, err := os.ReadFile(legacy.Hosts); err == nil {
+		if err := splitLegacy(data, paths); err != nil {
+			return false, fmt.Errorf("failed to migrate %s: %w", legacy.Hosts, err)
+		}
+		if err := os.Rename(legacy.Hosts, legacy.Hosts+".migrated"); err != nil {
+			return true, fmt.Errorf("migrated %s but could not rename it: %w", legacy.Hosts, err)
+		}
+		migrated = true
+	} else if !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read %s: %w", legacy.Hosts, err)
+	}
+
+	if exists(legacy.History) && !exists(paths.History) {
+		if err := os.MkdirAll(filepath.Dir(paths.History), 0700); err != nil {
+			return migrated, err
+		}
+		if err := os.Rename(legacy.History, paths.History); err != nil {
+			return migrated, fmt.Errorf("failed to migrate %s: %w", legacy.History, err)
+		}
+		migrated = true
+	}
+	return migrated, nil
+}
+
+// splitLegacy writes the hosts of a legacy file to paths.Hosts and the rest
+// of its settings to paths.Config
+func splitLegacy(data []byte, paths Paths) error {
+	format := DetectFormat(LegacyPaths().Hosts, data)
+
+	var doc map[string]interface{}
+	if err := Unmarshal(format, data, &doc); err != nil {
+		// Legacy array files hold nothing but hosts
+		var hosts []interface{}
+		if err := Unmarshal(format, data, &hosts); err != nil {
+			return err
+		}
+		doc = map[string]interface{}{"hosts": hosts}
+	}
+
+	hosts, ok := doc["hosts"]
+	if !ok || hosts == nil {
+		hosts = []interface{}{}
+	}
+	delete(doc, "hosts")
+
+	if err := SetKey(paths.Hosts, "hosts", hosts); err != nil {
+		return err
+	}
+	if len(doc) == 0 {
+		return nil
+	}
+	settings, err := Marshal(FormatYAML, doc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(paths.Config), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(paths.Config, settings, 0600)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/recording"
+)
+
+// Recording selects the hosts whose interactive sessions are saved as
+// asciicast v2 files
+type Recording struct {
+	// Dir holds the recordings, one directory per host; RecordingsDir by
+	// default
+	Dir string `json:"dir,omitempty" yaml:"dir,omitempty"`
+	// Hosts are name patterns such as "prod-*"
+	Hosts []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	// Tags record hosts carrying any of them
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// Guarded records hosts carrying a guarded tag
+	Guarded bool `json:"guarded,omitempty" yaml:"guarded,omitempty"`
+	// Input records keystrokes too
+	Input bool `json:"input,omitempty" yaml:"input,omitempty"`
+	// RedactWindow is how many seconds after the start of a session
+	// password prompts are redacted; 30 by default, -1 turns it off
+	RedactWindow int `json:"redact_window,omitempty" yaml:"redact_window,omitempty"`
+}
+
+// RecordingsDir returns the default directory of session recordings
+func RecordingsDir() string {
+	return filepath.Join(dataDir(), "sshm", "recordings")
+}
+
+// RecordingDir returns the directory recordings are saved in
+func (c *Config) RecordingDir() string {
+	if c.Recording.Dir != "" {
+		return expandHome(c.Recording.Dir)
+	}
+	return RecordingsDir()
+}
+
+// Records reports whether sessions to host are recorded
+func (c *Config) Records(host models.Host) bool {
+	r := c.Recording
+	if r.Guarded && c.GuardedTag(host) != "" {
+		return true
+	}
+	for _, pattern := range r.Hosts {
+		if models.MatchPattern(pattern, host.Name) {
+			return true
+		}
+	}
+	for _, tag := range host.Tags {
+		for _, recorded := range r.Tags {
+			if strings.EqualFold(tag, recorded) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RecordingPath returns the file a session to host started at t is
+// recorded in, or "" when the host is not recorded
+func (c *Config) RecordingPath(host models.Host, t time.Time) string {
+	if !c.Records(host) {
+		return ""
+	}
+	return recording.Path(c.RecordingDir(), host.Name, t)
+}
+
+// RecordingOptions returns the recorder options of the settings
+func (c *Config) RecordingOptions() recording.Options {
+	return recording.Options{
+		Input:        c.Recording.Input,
+		RedactWindow: time.Duration(c.Recording.RedactWindow) * time.Second,
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+// SnippetsFor returns the snippets that can run on host, in settings order
+func (c *Config) SnippetsFor(host models.Host) []models.Snippet {
+	var snippets []models.Snippet
+	for _, s := range c.Snippets {
+		if s.AppliesTo(host) {
+			snippets = append(snippets, s)
+		}
+	}
+	return snippets
+}
+
+// FindSnippet returns the snippet called name, ignoring case
+func (c *Config) FindSnippet(name string) (models.Snippet, bool) {
+	for _, s := range c.Snippets {
+		if strings.EqualFold(s.Name, name) {
+			return s, true
+		}
+	}
+	return models.Snippet{}, false
+}
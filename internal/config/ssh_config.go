@@ -58,7 +58,7 @@ func (p *SSHConfigParser) ParseConfigString(content string) ([]models.Host, erro
 		// Match Host directive
 		if strings.HasPrefix(line, "Host ") {
 			// Save previous host if exists
-			if currentHost != nil && currentHost.name != "" {
+			if currentHost != nil && currentHost.name != "" && !currentHost.isPattern() {
 				host := currentHost.toModel()
 				if host.Name != "" {
 					hosts = append(hosts, host)
@@ -99,7 +99,7 @@ func (p *SSHConfigParser) ParseConfigString(content string) ([]models.Host, erro
 	}
 
 	// Don't forget the last host
-	if currentHost != nil && currentHost.name != "" {
+	if currentHost != nil && currentHost.name != "" && !currentHost.isPattern() {
 		host := currentHost.toModel()
 		if host.Name != "" {
 			hosts = append(hosts, host)
@@ -123,6 +123,12 @@ type parsedSSHHost struct {
 	isImported          bool // flag to indicate this was imported
 }
 
+// isPattern reports whether the Host line is a wildcard pattern such as
+// "Host *" that applies settings to other hosts rather than naming one
+func (h *parsedSSHHost) isPattern() bool {
+	return strings.ContainsAny(h.name, "*?!")
+}
+
 func (h *parsedSSHHost) toModel() models.Host {
 	host := h.name
 	
@@ -182,19 +188,7 @@ func ImportFromSSHConfig(storePath string) ([]models.Host, error) {
 	if storePath != "" {
 		existingCfg, err := LoadConfig(storePath)
 		if err == nil {
-			// Filter out hosts that already exist
-			existingHosts := make(map[string]bool)
-			for _, h := range existingCfg.Hosts {
-				existingHosts[h.Name] = true
-			}
-			
-			var newHosts []models.Host
-			for _, h := range hosts {
-				if !existingHosts[h.Name] {
-					newHosts = append(newHosts, h)
-				}
-			}
-			hosts = newHosts
+			hosts, _ = NewHosts(existingCfg.Hosts, hosts)
 		}
 	}
 
@@ -0,0 +1,29 @@
+package config
+
+// Where connections from the TUI open inside tmux
+const (
+	TmuxWindow = "window"
+	TmuxPane   = "pane"
+)
+
+// TmuxLayouts are the layouts tmux can arrange the panes of a window in
+var TmuxLayouts = []string{"tiled", "even-horizontal", "even-vertical", "main-horizontal", "main-vertical"}
+
+// Tmux opens connections from the TUI in tmux instead of in place, when
+// sshm runs inside a tmux session
+type Tmux struct {
+	// Open is where connecting opens a host: "window" for a new window or
+	// "pane" for a split of the current one; empty connects in place
+	Open string `json:"open,omitempty" yaml:"open,omitempty"`
+	// Layout arranges the panes of connecting to all listed hosts at once,
+	// one of TmuxLayouts; tiled by default
+	Layout string `json:"layout,omitempty" yaml:"layout,omitempty"`
+}
+
+// PaneLayout returns the layout of the split panes
+func (t Tmux) PaneLayout() string {
+	if t.Layout == "" {
+		return TmuxLayouts[0]
+	}
+	return t.Layout
+}
@@ -0,0 +1,493 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sshm/sshm/internal/i18n"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/theme"
+)
+
+// Problem is one issue found while validating a config or hosts file
+type Problem struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+	// Warning marks problems that don't stop sshm from working, such as an
+	// identity file that is missing on this machine
+	Warning bool
+}
+
+// String formats the problem as "path:line:column: message"
+func (p Problem) String() string {
+	var b strings.Builder
+	b.WriteString(p.Path)
+	if p.Line > 0 {
+		fmt.Fprintf(&b, ":%d:%d", p.Line, p.Column)
+	}
+	b.WriteString(": ")
+	if p.Warning {
+		b.WriteString("warning: ")
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+// ValidateFile reads and validates the file at path, see Validate
+// A missing file has no problems.
+func ValidateFile(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return Validate(path, data), nil
+}
+
+// Validate checks a config or hosts file more strictly than loading does:
+// it reports unknown fields, values of the wrong type, duplicate host names,
+// invalid ports and auth types, and identity files that don't exist, each
+// with its line and column
+// JSON files are checked the same way since JSON is valid YAML.
+func Validate(path string, data []byte) []Problem {
+	v := &validator{path: path, names: make(map[string]*yaml.Node)}
+
+	if DetectFormat(path, data) == FormatJSON {
+		// Report JSON syntax errors the way the loader sees them
+		var doc interface{}
+		if err := Unmarshal(FormatJSON, data, &doc); err != nil {
+			var posErr *PositionError
+			if errors.As(err, &posErr) {
+				return []Problem{{Path: path, Line: posErr.Line, Column: posErr.Column, Message: posErr.Err.Error()}}
+			}
+			return []Problem{{Path: path, Message: err.Error()}}
+		}
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return []Problem{{Path: path, Message: err.Error()}}
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+
+	// Older layouts load fine; point them out and check them as upgraded
+	for _, u := range upgradeDocument(&root, false) {
+		v.problems = append(v.problems, Problem{Path: path, Line: u.Line, Column: u.Column, Message: u.Message + ` (run "sshm config migrate")`, Warning: true})
+	}
+
+	doc := root.Content[0]
+	v.check(doc, reflect.TypeOf(Config{}), "")
+	if hosts := mappingValue(doc, "hosts"); hosts != nil && hosts.Kind == yaml.SequenceNode {
+		v.hosts(hosts)
+	}
+	if defaults := mappingValue(doc, "defaults"); defaults != nil {
+		if identity := mappingValue(defaults, "identity"); identity != nil {
+			v.identity(identity)
+		}
+		if policy := mappingValue(defaults, "host_key_policy"); policy != nil {
+			v.hostKeyPolicy(policy)
+		}
+		if bastions := mappingValue(defaults, "bastions"); bastions != nil && bastions.Kind == yaml.SequenceNode {
+			v.bastions(bastions)
+		}
+	}
+	if configs := mappingValue(doc, "configs"); configs != nil && configs.Kind == yaml.SequenceNode {
+		for _, sc := range configs.Content {
+			if identity := mappingValue(sc, "identity_file"); identity != nil {
+				v.identity(identity)
+			}
+			if patterns := mappingValue(sc, "hosts"); patterns != nil && patterns.Kind == yaml.SequenceNode {
+				for _, p := range patterns.Content {
+					if err := models.ValidatePattern(p.Value); err != nil {
+						v.fail(p, "%v", err)
+					}
+				}
+			}
+		}
+	}
+	if rec := mappingValue(doc, "recording"); rec != nil {
+		if patterns := mappingValue(rec, "hosts"); patterns != nil && patterns.Kind == yaml.SequenceNode {
+			for _, p := range patterns.Content {
+				if err := models.ValidatePattern(p.Value); err != nil {
+					v.fail(p, "%v", err)
+				}
+			}
+		}
+	}
+	if policy := mappingValue(doc, "bastion_policy"); policy != nil && policy.Kind == yaml.MappingNode {
+		defaults := mappingValue(doc, "defaults")
+		var proxy, bastions *yaml.Node
+		if defaults != nil {
+			proxy, bastions = mappingValue(defaults, "proxy"), mappingValue(defaults, "bastions")
+		}
+		if (proxy == nil || proxy.Value == "" || proxy.Value == models.ProxyNone) && (bastions == nil || len(bastions.Content) == 0) {
+			v.fail(policy, "bastion_policy needs a proxy or bastions in defaults to connect through")
+		}
+		if guarded := mappingValue(policy, "guarded"); guarded != nil && guarded.Value == "true" {
+			if tags := mappingValue(doc, "guarded_tags"); tags == nil || len(tags.Content) == 0 {
+				v.warn(guarded, "bastion_policy covers guarded hosts, but guarded_tags is empty")
+			}
+		}
+	}
+	if age := mappingValue(doc, "key_max_age"); age != nil {
+		if n, err := strconv.Atoi(age.Value); err == nil && n < 0 {
+			v.fail(age, "key_max_age must be a number of days, or 0 to never remind")
+		}
+	}
+	if refresh := mappingValue(doc, "include_refresh"); refresh != nil {
+		if n, err := strconv.Atoi(refresh.Value); err == nil && n < -1 {
+			v.fail(refresh, "include_refresh must be a number of seconds, 0 for the default or -1 to turn it off")
+		}
+	}
+	if lock := mappingValue(doc, "idle_lock"); lock != nil {
+		if after := mappingValue(lock, "after"); after != nil {
+			if n, err := strconv.Atoi(after.Value); err == nil && n < 0 {
+				v.fail(after, "idle_lock after must be a number of seconds, or 0 to never lock")
+			}
+		}
+	}
+	if notify := mappingValue(doc, "notify"); notify != nil {
+		if after := mappingValue(notify, "after"); after != nil {
+			if n, err := strconv.Atoi(after.Value); err == nil && n < 0 {
+				v.fail(after, "notify after must be a number of seconds, or 0 to never notify")
+			}
+		}
+	}
+	if health := mappingValue(doc, "health_check"); health != nil {
+		for _, key := range []string{"interval", "jitter", "ttl"} {
+			if value := mappingValue(health, key); value != nil {
+				if n, err := strconv.Atoi(value.Value); err == nil && n < 0 {
+					v.fail(value, "health_check %s must be a number of seconds, or 0 for the default", key)
+				}
+			}
+		}
+		var hc HealthCheck
+		if err := health.Decode(&hc); err == nil && hc.Lifetime() < hc.Period() {
+			v.warn(health, "health_check ttl is shorter than the interval, hosts show as unknown between rounds")
+		}
+	}
+	if language := mappingValue(doc, "language"); language != nil && language.Value != "" && !i18n.Known(language.Value) {
+		v.fail(language, "unknown language %q (use %s)", language.Value, strings.Join(i18n.Languages(), ", "))
+	}
+	if tmux := mappingValue(doc, "tmux"); tmux != nil {
+		if open := mappingValue(tmux, "open"); open != nil && open.Value != "" && open.Value != TmuxWindow && open.Value != TmuxPane {
+			v.fail(open, "unknown tmux open %q (use %s or %s)", open.Value, TmuxWindow, TmuxPane)
+		}
+		if layout := mappingValue(tmux, "layout"); layout != nil && layout.Value != "" && !slices.Contains(TmuxLayouts, layout.Value) {
+			v.fail(layout, "unknown tmux layout %q (use %s)", layout.Value, strings.Join(TmuxLayouts, ", "))
+		}
+	}
+	if snippets := mappingValue(doc, "snippets"); snippets != nil && snippets.Kind == yaml.SequenceNode {
+		v.snippets(snippets)
+	}
+	v.themes(mappingValue(doc, "themes"), mappingValue(doc, "theme"))
+	if fields := mappingValue(doc, "encrypted_fields"); fields != nil && fields.Kind == yaml.SequenceNode {
+		for _, f := range fields.Content {
+			if !models.IsEncryptableField(f.Value) {
+				v.fail(f, "unknown encrypted field %q (use %s)", f.Value, strings.Join(models.EncryptableFields, ", "))
+			}
+		}
+	}
+
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		if v.problems[i].Line != v.problems[j].Line {
+			return v.problems[i].Line < v.problems[j].Line
+		}
+		return v.problems[i].Column < v.problems[j].Column
+	})
+	return v.problems
+}
+
+// validator collects the problems of one file
+type validator struct {
+	path     string
+	problems []Problem
+	names    map[string]*yaml.Node // host names seen so far
+}
+
+func (v *validator) fail(node *yaml.Node, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Path: v.path, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warn(node *yaml.Node, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Path: v.path, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// check compares node against the Go type it is loaded into, reporting
+// unknown fields and values of the wrong kind
+func (v *validator) check(node *yaml.Node, t reflect.Type, field string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.fail(node, "%s must be a mapping of settings", describe(field))
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			ft, ok := fields[key.Value]
+			if !ok {
+				v.fail(key, "unknown field %q%s", key.Value, within(field))
+				continue
+			}
+			v.check(value, ft, key.Value)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.fail(node, "%s must be a list", describe(field))
+			return
+		}
+		for _, item := range node.Content {
+			v.check(item, t.Elem(), field)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.fail(node, "%s must be a mapping", describe(field))
+			return
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			v.check(node.Content[i], t.Elem(), field)
+		}
+	case reflect.Int, reflect.Int64:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			v.fail(node, "%s must be a whole number, got %q", describe(field), node.Value)
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			v.fail(node, "%s must be true or false, got %q", describe(field), node.Value)
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			v.fail(node, "%s must be text", describe(field))
+		}
+	}
+}
+
+// hosts checks each host entry beyond the field types checked by check
+func (v *validator) hosts(list *yaml.Node) {
+	for _, node := range list.Content {
+		if node.Kind != yaml.MappingNode {
+			continue
+		}
+
+		name := mappingValue(node, "name")
+		switch {
+		case name == nil || strings.TrimSpace(name.Value) == "":
+			v.fail(node, "host has no name")
+		case v.names[strings.ToLower(name.Value)] != nil:
+			v.fail(name, "duplicate host name %q, first defined on line %d", name.Value, v.names[strings.ToLower(name.Value)].Line)
+		default:
+			v.names[strings.ToLower(name.Value)] = name
+		}
+		if addr := mappingValue(node, "host"); addr == nil || strings.TrimSpace(addr.Value) == "" {
+			v.fail(node, "host %s has no address", label(name))
+		}
+		if port := mappingValue(node, "port"); port != nil && port.Tag == "!!int" {
+			if n, err := strconv.Atoi(port.Value); err != nil || n < 0 || n > 65535 {
+				v.fail(port, "port %s must be 1-65535", port.Value)
+			}
+		}
+		if auth := mappingValue(node, "auth_type"); auth != nil {
+			switch models.AuthType(auth.Value) {
+			case "", models.AuthTypePassword, models.AuthTypeKey, models.AuthTypeAgent, models.AuthTypeVaultCert, models.AuthTypeVaultOTP:
+			default:
+				v.fail(auth, "unknown auth_type %q (use password, key, agent, vault-cert or vault-otp)", auth.Value)
+			}
+		}
+		if policy := mappingValue(node, "host_key_policy"); policy != nil {
+			v.hostKeyPolicy(policy)
+		}
+		if mode := mappingValue(node, "totp"); mode != nil && !models.ValidTOTPMode(models.TOTPMode(mode.Value)) {
+			v.fail(mode, "unknown totp %q (use auto or show)", mode.Value)
+		}
+		if aliases := mappingValue(node, "aliases"); aliases != nil && aliases.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(aliases.Content); i += 2 {
+				if err := models.ValidateAliasName(aliases.Content[i].Value); err != nil {
+					v.fail(aliases.Content[i], "%v", err)
+				}
+			}
+		}
+		if identity := mappingValue(node, "identity"); identity != nil {
+			v.identity(identity)
+		}
+	}
+}
+
+// themes checks the colors and presets of custom themes and that the
+// selected theme exists
+func (v *validator) themes(themes, selected *yaml.Node) {
+	if selected != nil && selected.Kind == yaml.ScalarNode && selected.Value != "" {
+		if _, ok := theme.Presets[selected.Value]; !ok && mappingValue(themes, selected.Value) == nil {
+			v.fail(selected, "unknown theme %q", selected.Value)
+		}
+	}
+	if themes == nil || themes.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 1; i < len(themes.Content); i += 2 {
+		def := themes.Content[i]
+		if extends := mappingValue(def, "extends"); extends != nil && extends.Value != "" {
+			if _, ok := theme.Presets[extends.Value]; !ok {
+				v.fail(extends, "unknown preset %q (use dark or light)", extends.Value)
+			}
+		}
+		if colors := mappingValue(def, "colors"); colors != nil && colors.Kind == yaml.MappingNode {
+			for j := 1; j < len(colors.Content); j += 2 {
+				v.color(colors.Content[j])
+			}
+		}
+		if styles := mappingValue(def, "styles"); styles != nil && styles.Kind == yaml.MappingNode {
+			for j := 1; j < len(styles.Content); j += 2 {
+				for _, key := range []string{"foreground", "background"} {
+					if color := mappingValue(styles.Content[j], key); color != nil {
+						v.color(color)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) color(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Value != "" && !theme.ValidColor(node.Value) {
+		v.fail(node, "invalid color %q (use 0-255 or #rrggbb)", node.Value)
+	}
+}
+
+// bastions checks that each bastion names a proxy and the hosts it is for
+func (v *validator) bastions(list *yaml.Node) {
+	for _, b := range list.Content {
+		if b.Kind != yaml.MappingNode {
+			continue
+		}
+		if proxy := mappingValue(b, "proxy"); proxy == nil || proxy.Value == "" {
+			v.fail(b, "bastion has no proxy")
+		}
+		groups, cidrs := mappingValue(b, "groups"), mappingValue(b, "cidrs")
+		if (groups == nil || len(groups.Content) == 0) && (cidrs == nil || len(cidrs.Content) == 0) {
+			v.fail(b, "bastion needs groups or cidrs to apply to")
+		}
+		if cidrs != nil && cidrs.Kind == yaml.SequenceNode {
+			for _, c := range cidrs.Content {
+				if _, _, err := net.ParseCIDR(c.Value); err != nil {
+					v.fail(c, "invalid CIDR %q (use e.g. 10.1.0.0/16)", c.Value)
+				}
+			}
+		}
+	}
+}
+
+// snippets checks that snippets have unique single-word names and a command
+func (v *validator) snippets(list *yaml.Node) {
+	seen := make(map[string]*yaml.Node)
+	for _, node := range list.Content {
+		if node.Kind != yaml.MappingNode {
+			continue
+		}
+		name := mappingValue(node, "name")
+		switch {
+		case name == nil:
+			v.fail(node, "snippet has no name")
+		case models.ValidateAliasName(name.Value) != nil:
+			v.fail(name, "invalid snippet name %q (use a single word without =)", name.Value)
+		case seen[strings.ToLower(name.Value)] != nil:
+			v.fail(name, "duplicate snippet %q, first defined on line %d", name.Value, seen[strings.ToLower(name.Value)].Line)
+		default:
+			seen[strings.ToLower(name.Value)] = name
+		}
+		if command := mappingValue(node, "command"); command == nil || strings.TrimSpace(command.Value) == "" {
+			v.fail(node, "snippet has no command")
+		}
+	}
+}
+
+func (v *validator) hostKeyPolicy(node *yaml.Node) {
+	if !models.ValidHostKeyPolicy(models.HostKeyPolicy(node.Value)) {
+		v.fail(node, "unknown host_key_policy %q (use strict, tofu or insecure)", node.Value)
+	}
+}
+
+// identity warns about identity files missing on this machine
+func (v *validator) identity(node *yaml.Node) {
+	if node.Value == "" {
+		return
+	}
+	if _, err := os.Stat(expandHome(node.Value)); err != nil {
+		v.warn(node, "identity file %s does not exist", node.Value)
+	}
+}
+
+// yamlFields maps the YAML keys of a struct type to their field types
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func describe(field string) string {
+	if field == "" {
+		return "the file"
+	}
+	return field
+}
+
+func within(field string) string {
+	if field == "" {
+		return ""
+	}
+	return " in " + field
+}
+
+func label(name *yaml.Node) string {
+	if name == nil || name.Value == "" {
+		return "entry"
+	}
+	return strconv.Quote(name.Value)
+}
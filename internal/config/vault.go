@@ -0,0 +1,28 @@
+package config
+
+import "github.com/sshm/sshm/internal/vault"
+
+// Vault locates the SSH secrets engine of a HashiCorp Vault server
+// The token comes from $VAULT_TOKEN or the ~/.vault-token written by
+// "vault login", never from the settings.
+type Vault struct {
+	// Address is the server URL, $VAULT_ADDR by default
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	// Mount is where the SSH secrets engine is mounted, "ssh" by default
+	Mount string `json:"mount,omitempty" yaml:"mount,omitempty"`
+	// Namespace is the Vault Enterprise namespace, $VAULT_NAMESPACE by
+	// default
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Role is used by hosts without a vault_role
+	Role string `json:"role,omitempty" yaml:"role,omitempty"`
+}
+
+// VaultClient returns a client for the Vault of the settings
+func (c *Config) VaultClient() *vault.Client {
+	return &vault.Client{
+		Address:   c.Vault.Address,
+		Mount:     c.Vault.Mount,
+		Namespace: c.Vault.Namespace,
+		Role:      c.Vault.Role,
+	}
+}
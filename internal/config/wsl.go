@@ -0,0 +1,33 @@
+package config
+
+// defaultAgentRelay bridges the Windows agent's named pipe to the standard
+// input and output of a process WSL can start
+const defaultAgentRelay = "npiperelay.exe"
+
+// WSL makes sshm running in the Windows Subsystem for Linux use the keys
+// and agent of the Windows side
+// Identity paths such as C:\Users\me\.ssh\id_ed25519 are translated to
+// their /mnt/c place in WSL, and the other way around on Windows, either way.
+type WSL struct {
+	// WindowsKeys also tries the default keys in the .ssh folder of the
+	// Windows user and lists them in "sshm keys"
+	WindowsKeys bool `json:"windows_keys,omitempty" yaml:"windows_keys,omitempty"`
+	// WindowsAgent uses the Windows OpenSSH agent when SSH_AUTH_SOCK is
+	// unset, through Relay
+	WindowsAgent bool `json:"windows_agent,omitempty" yaml:"windows_agent,omitempty"`
+	// Relay is the Windows program run to reach the agent's named pipe,
+	// called like npiperelay; npiperelay.exe on the PATH by default
+	Relay string `json:"relay,omitempty" yaml:"relay,omitempty"`
+}
+
+// AgentRelay returns the relay program to reach the Windows agent through,
+// or "" when the Windows agent isn't used
+func (w WSL) AgentRelay() string {
+	switch {
+	case !w.WindowsAgent:
+		return ""
+	case w.Relay == "":
+		return defaultAgentRelay
+	}
+	return w.Relay
+}
@@ -0,0 +1,197 @@
+// Package editor opens hosts in the user's $EDITOR as YAML or JSON and reads
+// back the edited result
+package editor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/sshm/sshm/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported formats
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// Session is one editing round trip through a temporary file
+// A single host is edited as an object, an inventory as a list.
+type Session struct {
+	path     string
+	format   string
+	single   bool
+	original []byte
+}
+
+// NewSession writes hosts to a temporary file ready for editing
+func NewSession(hosts []models.Host, format string, single bool) (*Session, error) {
+	if format != FormatYAML && format != FormatJSON {
+		return nil, fmt.Errorf("unknown format: %s (use yaml or json)", format)
+	}
+	if single && len(hosts) != 1 {
+		return nil, fmt.Errorf("expected one host, got %d", len(hosts))
+	}
+
+	data, err := Marshal(hosts, format, single)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp("", "sshm-*."+format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	return &Session{path: f.Name(), format: format, single: single, original: data}, nil
+}
+
+// Path returns the temporary file being edited
+func (s *Session) Path() string {
+	return s.path
+}
+
+// Cmd returns the editor command for the session's file
+func (s *Session) Cmd() *exec.Cmd {
+	return Command(s.path)
+}
+
+// Result reads the edited file back and validates every host
+// changed is false when the file was saved without modifications.
+func (s *Session) Result() (hosts []models.Host, changed bool, err error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read edited file: %w", err)
+	}
+	if bytes.Equal(data, s.original) {
+		return nil, false, nil
+	}
+
+	hosts, err = Unmarshal(data, s.format, s.single)
+	if err != nil {
+		return nil, true, err
+	}
+	if err := Validate(hosts); err != nil {
+		return nil, true, err
+	}
+	return hosts, true, nil
+}
+
+// Close removes the temporary file
+func (s *Session) Close() error {
+	return os.Remove(s.path)
+}
+
+// Command builds the command that opens path in $VISUAL or $EDITOR,
+// falling back to vi (notepad on Windows)
+// The variables may include arguments, such as "code --wait".
+func Command(path string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			args = []string{"notepad"}
+		}
+	}
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// Marshal renders hosts in format for editing
+// Runtime state such as the online status is left out.
+func Marshal(hosts []models.Host, format string, single bool) ([]byte, error) {
+	cleaned := make([]models.Host, len(hosts))
+	for i, h := range hosts {
+		h.Online = nil
+		cleaned[i] = h
+	}
+
+	var v interface{} = cleaned
+	if single {
+		v = cleaned[0]
+	}
+	if format == FormatJSON {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+	return yaml.Marshal(v)
+}
+
+// Unmarshal parses edited hosts, filling in the default port
+func Unmarshal(data []byte, format string, single bool) ([]models.Host, error) {
+	var hosts []models.Host
+	var err error
+	if single {
+		var h models.Host
+		err = unmarshal(data, format, &h)
+		hosts = []models.Host{h}
+	} else {
+		err = unmarshal(data, format, &hosts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", strings.ToUpper(format), err)
+	}
+
+	for i := range hosts {
+		if hosts[i].Port == 0 {
+			hosts[i].Port = 22
+		}
+	}
+	return hosts, nil
+}
+
+func unmarshal(data []byte, format string, v interface{}) error {
+	var err error
+	if format == FormatJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(v)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(v)
+	}
+	if errors.Is(err, io.EOF) {
+		// An emptied file is an empty inventory
+		return nil
+	}
+	return err
+}
+
+// Validate checks each host and rejects duplicate names
+func Validate(hosts []models.Host) error {
+	names := make(map[string]bool, len(hosts))
+	for i := range hosts {
+		if err := hosts[i].Validate(); err != nil {
+			return err
+		}
+		if names[hosts[i].Name] {
+			return fmt.Errorf("duplicate host name %q", hosts[i].Name)
+		}
+		names[hosts[i].Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package editor
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	online := true
+	hosts := []models.Host{
+		{ID: "1", Name: "web1", Host: "10.0.0.1", Port: 22, Tags: []string{"web"}, Online: &online},
+		{ID: "2", Name: "db1", Host: "10.0.0.2", Port: 5432},
+	}
+
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		data, err := Marshal(hosts, format, false)
+		if err != nil {
+			t.Fatalf("%s: Marshal failed: %v", format, err)
+		}
+		if strings.Contains(string(data), "online") {
+			t.Errorf("%s: expected online status to be left out:\n%s", format, data)
+		}
+		got, err := Unmarshal(data, format, false)
+		if err != nil {
+			t.Fatalf("%s: Unmarshal failed: %v", format, err)
+		}
+		if len(got) != 2 || got[0].Name != "web1" || got[1].Port != 5432 || got[0].Tags[0] != "web" {
+			t.Errorf("%s: round trip mismatch: %+v", format, got)
+		}
+	}
+}
+
+func TestUnmarshalRejectsUnknownFields(t *testing.T) {
+	if _, err := Unmarshal([]byte("name: web1\nhost: 10.0.0.1\nprot: 22\n"), FormatYAML, true); err == nil {
+		t.Error("expected a misspelled field to be rejected")
+	}
+	hosts, err := Unmarshal([]byte("name: web1\nhost: 10.0.0.1\n"), FormatYAML, true)
+	if err != nil || hosts[0].Port != 22 {
+		t.Errorf("expected the default port to be filled in, got %+v, %v", hosts, err)
+	}
+}
+
+func TestValidateRejectsDuplicateNames(t *testing.T) {
+	hosts := []models.Host{
+		{Name: "web1", Host: "10.0.0.1", Port: 22},
+		{Name: "web1", Host: "10.0.0.2", Port: 22},
+	}
+	if err := Validate(hosts); err == nil {
+		t.Error("expected duplicate names to be rejected")
+	}
+}
+
+func TestSessionResult(t *testing.T) {
+	host := models.Host{ID: "1", Name: "web1", Host: "10.0.0.1", Port: 22}
+	session, err := NewSession([]models.Host{host}, FormatYAML, true)
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	defer session.Close()
+
+	if _, changed, err := session.Result(); changed || err != nil {
+		t.Fatalf("expected an untouched file to report no changes, got %v, %v", changed, err)
+	}
+
+	os.WriteFile(session.Path(), []byte("name: web2\nhost: 10.0.0.1\nport: 2222\n"), 0600)
+	hosts, changed, err := session.Result()
+	if !changed || err != nil {
+		t.Fatalf("expected a valid change, got %v, %v", changed, err)
+	}
+	if hosts[0].Name != "web2" || hosts[0].Port != 2222 {
+		t.Errorf("unexpected result %+v", hosts[0])
+	}
+
+	os.WriteFile(session.Path(), []byte("name: web2\nport: 2222\n"), 0600)
+	if _, changed, err := session.Result(); !changed || err == nil {
+		t.Error("expected a host without an address to fail validation")
+	}
+}
@@ -0,0 +1,239 @@
+// Package health probes hosts in the background and caches the results, so
+// views can show reachability without waiting on the network
+package health
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+// Probe checks whether a host is reachable
+type Probe func(host models.Host) error
+
+// Result is the outcome of probing a host
+type Result struct {
+	HostID  string
+	Online  bool
+	Err     error
+	Latency time.Duration // round trip of the probe, set when online
+	Checked time.Time
+}
+
+// Timings set how often hosts are probed and how long results are kept
+type Timings struct {
+	Interval time.Duration // between rounds of probes
+	Jitter   time.Duration // rounds start up to this much earlier or later
+	TTL      time.Duration // results older than this are unknown again
+}
+
+// maxConcurrent bounds the probes running at once
+const maxConcurrent = 16
+
+// Scheduler probes a set of hosts in rounds, caches the results and
+// publishes each one on Updates
+// Rounds run every Interval give or take Jitter, so many instances don't
+// probe the same hosts in lockstep. A round probes every host; one started
+// by SetHosts only those without a fresh result.
+type Scheduler struct {
+	probe   Probe
+	updates chan Result
+	wake    chan struct{}
+	stop    chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	timings Timings
+	hosts   []models.Host
+	results map[string]Result // by host ID
+	all     bool              // the next round probes every host
+	started bool
+}
+
+// NewScheduler creates a scheduler probing with probe; Start runs it
+func NewScheduler(probe Probe, timings Timings) *Scheduler {
+	return &Scheduler{
+		probe:   probe,
+		updates: make(chan Result, maxConcurrent),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		timings: timings,
+		results: make(map[string]Result),
+		all:     true,
+	}
+}
+
+// Start runs the rounds of probes until Stop, starting with one right away
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	go s.run()
+}
+
+// Stop ends the rounds; probes under way finish without publishing
+func (s *Scheduler) Stop() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// Updates returns the channel results are published on
+// It is never closed; subscribers stop reading once Stop was called.
+func (s *Scheduler) Updates() <-chan Result {
+	return s.updates
+}
+
+// Done returns a channel closed by Stop
+func (s *Scheduler) Done() <-chan struct{} {
+	return s.stop
+}
+
+// SetTimings changes the timings from the next round on
+func (s *Scheduler) SetTimings(timings Timings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timings = timings
+}
+
+// SetHosts replaces the hosts to probe, forgetting the results of those
+// gone, and probes new ones right away
+func (s *Scheduler) SetHosts(hosts []models.Host) {
+	s.mu.Lock()
+	s.hosts = hosts
+	known := make(map[string]bool, len(hosts))
+	fresh := true
+	for _, h := range hosts {
+		known[h.ID] = true
+		if _, ok := s.results[h.ID]; !ok {
+			fresh = false
+		}
+	}
+	for id := range s.results {
+		if !known[id] {
+			delete(s.results, id)
+		}
+	}
+	s.mu.Unlock()
+	if !fresh {
+		s.poke()
+	}
+}
+
+// CheckNow starts a round probing every host without waiting for the next
+func (s *Scheduler) CheckNow() {
+	s.mu.Lock()
+	s.all = true
+	s.mu.Unlock()
+	s.poke()
+}
+
+// poke wakes the scheduler for a round, unless one is already pending
+func (s *Scheduler) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Result returns the cached result for a host, if it is still fresh
+func (s *Scheduler) Result(hostID string) (Result, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.results[hostID]
+	if !ok || (s.timings.TTL > 0 && time.Since(r.Checked) > s.timings.TTL) {
+		return Result{}, false
+	}
+	return r, true
+}
+
+// Check probes a host now, caching and publishing the result
+func (s *Scheduler) Check(host models.Host) Result {
+	start := time.Now()
+	err := s.probe(host)
+	r := Result{HostID: host.ID, Online: err == nil, Err: err, Checked: time.Now()}
+	if err == nil {
+		r.Latency = time.Since(start)
+	}
+	s.mu.Lock()
+	s.results[host.ID] = r
+	s.mu.Unlock()
+	select {
+	case s.updates <- r:
+	case <-s.stop:
+	}
+	return r
+}
+
+// run probes rounds of hosts until Stop
+func (s *Scheduler) run() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-timer.C:
+			s.mu.Lock()
+			s.all = true
+			s.mu.Unlock()
+		case <-s.wake:
+		}
+
+		s.round()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		s.mu.Lock()
+		timer.Reset(next(s.timings))
+		s.mu.Unlock()
+	}
+}
+
+// round probes the hosts due, a few at a time
+func (s *Scheduler) round() {
+	s.mu.Lock()
+	var due []models.Host
+	for _, h := range s.hosts {
+		if _, ok := s.results[h.ID]; s.all || !ok {
+			due = append(due, h)
+		}
+	}
+	s.all = false
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrent)
+	for _, h := range due {
+		select {
+		case <-s.stop:
+			wg.Wait()
+			return
+		case slots <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(host models.Host) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			s.Check(host)
+		}(h)
+	}
+	wg.Wait()
+}
+
+// next returns the delay until the next round: the interval moved by a
+// random amount within the jitter
+func next(t Timings) time.Duration {
+	d := t.Interval
+	if t.Jitter > 0 {
+		d += time.Duration(rand.Int64N(int64(2*t.Jitter)+1)) - t.Jitter
+	}
+	return max(d, time.Second)
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+func TestScheduler(t *testing.T) {
+	var mu sync.Mutex
+	probed := make(map[string]int)
+	probe := func(h models.Host) error {
+		mu.Lock()
+		probed[h.ID]++
+		mu.Unlock()
+		if h.Host == "down" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	s := NewScheduler(probe, Timings{Interval: time.Hour, TTL: time.Hour})
+	s.SetHosts([]models.Host{{ID: "a", Host: "up"}, {ID: "b", Host: "down"}})
+	s.Start()
+	defer s.Stop()
+
+	wait := func(n int) map[string]Result {
+		got := make(map[string]Result)
+		for range n {
+			select {
+			case r := <-s.Updates():
+				got[r.HostID] = r
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for results, got %v", got)
+			}
+		}
+		return got
+	}
+	first := wait(2)
+	if !first["a"].Online || first["b"].Online || first["b"].Err == nil {
+		t.Errorf("unexpected first round %+v", first)
+	}
+	if r, ok := s.Result("b"); !ok || r.Online {
+		t.Errorf("Result(b) = %+v, %v", r, ok)
+	}
+
+	// New hosts are probed right away, the others keep their results
+	s.SetHosts([]models.Host{{ID: "a", Host: "up"}, {ID: "c", Host: "up"}})
+	if got := wait(1); !got["c"].Online {
+		t.Errorf("expected c probed, got %+v", got)
+	}
+	if _, ok := s.Result("b"); ok {
+		t.Error("expected the result of a removed host dropped")
+	}
+	mu.Lock()
+	if probed["a"] != 1 {
+		t.Errorf("expected a probed once, got %d", probed["a"])
+	}
+	mu.Unlock()
+
+	s.CheckNow()
+	if got := wait(2); len(got) != 2 {
+		t.Errorf("expected every host probed, got %+v", got)
+	}
+
+	// Results expire
+	s.SetTimings(Timings{Interval: time.Hour, TTL: time.Nanosecond})
+	time.Sleep(time.Millisecond)
+	if _, ok := s.Result("a"); ok {
+		t.Error("expected the result of a expired")
+	}
+}
+
+func TestNextJitter(t *testing.T) {
+	timings := Timings{Interval: time.Minute, Jitter: 10 * time.Second}
+	for range 100 {
+		if d := next(timings); d < 50*time.Second || d > 70*time.Second {
+			t.Fatalf("next() = %v, outside the jitter", d)
+		}
+	}
+	if d := next(Timings{}); d != time.Second {
+		t.Errorf("next() without interval = %v, want 1s", d)
+	}
+}
@@ -0,0 +1,138 @@
+// Package i18n translates the messages of the TUI
+// Each language is a catalog in locales/<language>.yaml mapping message IDs
+// to fmt format strings. English is the source: messages missing from
+// another catalog are shown in English.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fallback is the language of the source catalog
+const Fallback = "en"
+
+//go:embed locales/*.yaml
+var files embed.FS
+
+// catalogs holds the messages by language, loaded once
+var catalogs = sync.OnceValue(func() map[string]map[string]string {
+	entries, err := files.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	catalogs := make(map[string]map[string]string, len(entries))
+	for _, e := range entries {
+		data, err := files.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := yaml.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("locales/%s: %v", e.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(e.Name(), ".yaml")] = messages
+	}
+	return catalogs
+})
+
+// active is the language in use, replaced by Init
+var active struct {
+	sync.RWMutex
+	language string
+}
+
+// Languages returns the languages there are catalogs for
+func Languages() []string {
+	var languages []string
+	for language := range catalogs() {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Known reports whether there is a catalog for language
+func Known(language string) bool {
+	_, ok := catalogs()[language]
+	return ok
+}
+
+// Init selects the language of the messages: the configured one, or else
+// the one of the locale in $LC_ALL, $LC_MESSAGES or $LANG
+// An unknown configured language is an error and leaves English active;
+// locales without a catalog silently fall back to English.
+func Init(language string) error {
+	var err error
+	switch {
+	case language == "":
+		language = FromEnv()
+	case !Known(language):
+		err = fmt.Errorf("unknown language %q (use %s)", language, strings.Join(Languages(), ", "))
+		language = Fallback
+	}
+	if !Known(language) {
+		language = Fallback
+	}
+	active.Lock()
+	active.language = language
+	active.Unlock()
+	return err
+}
+
+// Language returns the language in use
+func Language() string {
+	active.RLock()
+	defer active.RUnlock()
+	if active.language == "" {
+		return Fallback
+	}
+	return active.language
+}
+
+// FromEnv returns the language of the locale set in the environment, in
+// the order gettext looks at it, or "" for none
+func FromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return localeLanguage(value)
+		}
+	}
+	return ""
+}
+
+// localeLanguage returns the language of a locale name such as
+// de_DE.UTF-8, or "" for the C and POSIX locales
+func localeLanguage(locale string) string {
+	if locale == "C" || locale == "POSIX" || strings.HasPrefix(locale, "C.") {
+		return ""
+	}
+	language, _, _ := strings.Cut(locale, "_")
+	language, _, _ = strings.Cut(language, ".")
+	language, _, _ = strings.Cut(language, "@")
+	return strings.ToLower(language)
+}
+
+// T returns the message with the ID in the active language, formatted with
+// args when there are any
+// A message missing from the catalog is taken from English, and an unknown
+// ID is returned as it is, so a mistake shows instead of an empty label.
+func T(id string, args ...any) string {
+	format, ok := catalogs()[Language()][id]
+	if !ok {
+		if format, ok = catalogs()[Fallback][id]; !ok {
+			format = id
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,82 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// verbPattern matches the fmt verbs of a message
+var verbPattern = regexp.MustCompile(`%[-+# 0]*\d*(?:\.\d+)?[a-zA-Z%]`)
+
+func TestCatalogs(t *testing.T) {
+	if !slices.Contains(Languages(), Fallback) || len(Languages()) < 2 {
+		t.Fatalf("Languages() = %v, want %s and at least one more", Languages(), Fallback)
+	}
+	source := catalogs()[Fallback]
+	for _, language := range Languages() {
+		for id, format := range catalogs()[language] {
+			want, ok := source[id]
+			if !ok {
+				t.Errorf("%s: %s is not in the %s catalog", language, id, Fallback)
+				continue
+			}
+			if got, want := verbPattern.FindAllString(format, -1), verbPattern.FindAllString(want, -1); !slices.Equal(got, want) {
+				t.Errorf("%s: %s has the verbs %v, want %v", language, id, got, want)
+			}
+		}
+		for id := range source {
+			if _, ok := catalogs()[language][id]; !ok {
+				t.Errorf("%s: %s is missing", language, id)
+			}
+		}
+	}
+}
+
+func TestLocaleLanguage(t *testing.T) {
+	tests := map[string]string{
+		"de_DE.UTF-8": "de",
+		"en_US":       "en",
+		"fr":          "fr",
+		"de_AT@euro":  "de",
+		"pt.UTF-8":    "pt",
+		"C":           "",
+		"C.UTF-8":     "",
+		"POSIX":       "",
+		"EN_GB.utf8":  "en",
+	}
+	for locale, want := range tests {
+		if got := localeLanguage(locale); got != want {
+			t.Errorf("localeLanguage(%q) = %q, want %q", locale, got, want)
+		}
+	}
+}
+
+func TestInit(t *testing.T) {
+	defer Init(Fallback)
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "de_DE.UTF-8")
+	t.Setenv("LANG", "en_US.UTF-8")
+
+	if err := Init(""); err != nil || Language() != "de" {
+		t.Errorf("Init(\"\") = %v with LC_MESSAGES=de_DE, language %q, want de", err, Language())
+	}
+	if got := T("list.hosts", 3); got != "3 Hosts" {
+		t.Errorf("T(list.hosts) = %q, want %q", got, "3 Hosts")
+	}
+
+	t.Setenv("LC_ALL", "fr_FR.UTF-8")
+	if err := Init(""); err != nil || Language() != Fallback {
+		t.Errorf("Init(\"\") = %v with LC_ALL=fr_FR, language %q, want %s", err, Language(), Fallback)
+	}
+
+	if err := Init("xx"); err == nil || Language() != Fallback {
+		t.Errorf("Init(xx) = %v, language %q, want an error and %s", err, Language(), Fallback)
+	}
+	if got := T("list.hosts", 3); got != "3 hosts" {
+		t.Errorf("T(list.hosts) = %q, want %q", got, "3 hosts")
+	}
+	if got := T("no.such.message"); got != "no.such.message" {
+		t.Errorf("T of an unknown ID = %q, want the ID", got)
+	}
+}
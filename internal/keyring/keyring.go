@@ -0,0 +1,130 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// Service is the keyring service sshm stores its secrets under
+const Service = "sshm"
+
+// ErrNotFound is returned when the keyring holds no secret for an account
+var ErrNotFound = errors.New("not found in the keyring")
+
+// ErrUnsupported is returned when no keyring is available on this system
+var ErrUnsupported = errors.New("no keyring available (needs macOS or secret-tool from libsecret)")
+
+// goos picks the keyring tool; tests replace it
+var goos = runtime.GOOS
+
+// run executes a keyring tool with input on its stdin and returns its output
+// Tests replace it.
+var run = func(input, name string, args ...string) (string, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", ErrUnsupported
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return stdout.String(), nil
+}
+
+// Get returns the secret stored for account
+func Get(account string) (string, error) {
+	switch goos {
+	case "darwin":
+		out, err := run("", "security", "find-generic-password", "-s", Service, "-a", account, "-w")
+		if err != nil {
+			// security exits non-zero for missing items and has no quiet mode
+			if errors.Is(err, ErrUnsupported) {
+				return "", err
+			}
+			return "", ErrNotFound
+		}
+		return fromSecurity(strings.TrimSuffix(out, "\n")), nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		out, err := run("", "secret-tool", "lookup", "service", Service, "account", account)
+		if err != nil {
+			if errors.Is(err, ErrUnsupported) {
+				return "", err
+			}
+			return "", ErrNotFound
+		}
+		if out == "" {
+			return "", ErrNotFound
+		}
+		return strings.TrimSuffix(out, "\n"), nil
+	}
+	return "", ErrUnsupported
+}
+
+// Set stores secret for account, replacing any previous one
+// The secret is passed on stdin, never on a command line.
+func Set(account, secret string) error {
+	switch goos {
+	case "darwin":
+		// security -i reads commands from stdin, keeping the secret out of ps
+		if strings.ContainsAny(secret, "\r\n") {
+			return errors.New("security: secrets with line breaks can't be stored")
+		}
+		command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+			securityQuote(Service), securityQuote(account), securityQuote(secret))
+		_, err := run(command, "security", "-i")
+		return err
+	case "linux", "freebsd", "openbsd", "netbsd":
+		_, err := run(secret, "secret-tool", "store", "--label=sshm "+account, "service", Service, "account", account)
+		return err
+	}
+	return ErrUnsupported
+}
+
+// Delete removes the secret stored for account; a missing secret is fine
+func Delete(account string) error {
+	switch goos {
+	case "darwin":
+		if _, err := run("", "security", "delete-generic-password", "-s", Service, "-a", account); err != nil && errors.Is(err, ErrUnsupported) {
+			return err
+		}
+		return nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		_, err := run("", "secret-tool", "clear", "service", Service, "account", account)
+		return err
+	}
+	return ErrUnsupported
+}
+
+// securityQuote quotes s for the commands "security -i" reads, which only
+// knows double quotes with backslash escapes
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// fromSecurity undoes the hex encoding "security find-generic-password -w"
+// prints passwords in when they aren't printable ASCII
+func fromSecurity(out string) string {
+	b, err := hex.DecodeString(out)
+	if err != nil || len(b) == 0 || !utf8.Valid(b) {
+		return out
+	}
+	for _, c := range b {
+		if c < ' ' || c > '~' {
+			return string(b)
+		}
+	}
+	// Printable ASCII would have been printed as is
+	return out
+}
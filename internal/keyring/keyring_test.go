@@ -0,0 +1,127 @@
+package keyring
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	originalRun, originalOS := run, goos
+	t.Cleanup(func() { run, goos = originalRun, originalOS })
+	goos = "linux"
+	stored := map[string]string{}
+	var calls []string
+	run = func(input, name string, args ...string) (string, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		account := args[len(args)-1]
+		switch args[0] {
+		case "store":
+			stored[account] = input
+		case "lookup":
+			return stored[account], nil
+		case "clear":
+			delete(stored, account)
+		}
+		return "", nil
+	}
+
+	if _, err := Get("store-passphrase"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := Set("store-passphrase", "s3cret"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if strings.Contains(strings.Join(calls, "\n"), "s3cret") {
+		t.Errorf("secret passed on the command line: %v", calls)
+	}
+	if got, err := Get("store-passphrase"); err != nil || got != "s3cret" {
+		t.Errorf("expected s3cret, got %q (%v)", got, err)
+	}
+	if err := Delete("store-passphrase"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := Get("store-passphrase"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after Delete, got %v", err)
+	}
+}
+
+// securityTokens splits a command line the way "security -i" does:
+// double quotes group, and a backslash takes the next character literally
+func securityTokens(line string) []string {
+	var tokens []string
+	var token strings.Builder
+	inToken, quoted := false, false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			token.WriteByte(line[i])
+			inToken = true
+		case c == '"':
+			quoted = !quoted
+			inToken = true
+		case (c == ' ' || c == '\n') && !quoted:
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteByte(c)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+	return tokens
+}
+
+func TestKeyringDarwin(t *testing.T) {
+	originalRun, originalOS := run, goos
+	t.Cleanup(func() { run, goos = originalRun, originalOS })
+	goos = "darwin"
+	stored := map[string]string{}
+	run = func(input, name string, args ...string) (string, error) {
+		if args[0] == "-i" {
+			args = securityTokens(input)
+		}
+		flags := map[string]string{}
+		for i := 1; i+1 < len(args); i++ {
+			if strings.HasPrefix(args[i], "-") {
+				flags[args[i]] = args[i+1]
+			}
+		}
+		switch args[0] {
+		case "add-generic-password":
+			stored[flags["-a"]] = flags["-w"]
+		case "find-generic-password":
+			secret, ok := stored[flags["-a"]]
+			if !ok {
+				return "", errors.New("security: The specified item could not be found in the keychain.")
+			}
+			// Like security, anything but printable ASCII comes out as hex
+			for _, c := range []byte(secret) {
+				if c < ' ' || c > '~' {
+					return hex.EncodeToString([]byte(secret)) + "\n", nil
+				}
+			}
+			return secret + "\n", nil
+		}
+		return "", nil
+	}
+
+	for _, secret := range []string{"s3cret", "pässwörd €", `say "hi" \ bye`, "tab\there", "deadbeef"} {
+		if err := Set("store-passphrase", secret); err != nil {
+			t.Fatalf("Set(%q) failed: %v", secret, err)
+		}
+		if got, err := Get("store-passphrase"); err != nil || got != secret {
+			t.Errorf("stored %q, got %q (%v)", secret, got, err)
+		}
+	}
+	if err := Set("store-passphrase", "two\nlines"); err == nil {
+		t.Error("expected a secret with a line break to be refused")
+	}
+}
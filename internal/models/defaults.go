@@ -0,0 +1,133 @@
+package models
+
+import (
+	"net"
+	"strings"
+)
+
+// ProxyNone opts a host out of the default proxy
+const ProxyNone = "none"
+
+// Defaults are settings applied to hosts that leave them empty, so large
+// inventories don't repeat the same user, key or jump host on every entry
+type Defaults struct {
+	User     string `json:"user,omitempty" yaml:"user,omitempty"`
+	Port     int    `json:"port,omitempty" yaml:"port,omitempty"`
+	Identity string `json:"identity,omitempty" yaml:"identity,omitempty"`
+	Proxy    string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// HostKeyPolicy applies to hosts without one; tofu when empty
+	HostKeyPolicy HostKeyPolicy `json:"host_key_policy,omitempty" yaml:"host_key_policy,omitempty"`
+	// KeepAliveInterval and ConnectTimeout, in seconds, apply to hosts
+	// without a profile of their own
+	KeepAliveInterval int `json:"keepalive_interval,omitempty" yaml:"keepalive_interval,omitempty"`
+	ConnectTimeout    int `json:"connect_timeout,omitempty" yaml:"connect_timeout,omitempty"`
+	// Bastions pick the jump host of hosts without a proxy by group or
+	// address, before falling back to Proxy
+	Bastions []Bastion `json:"bastions,omitempty" yaml:"bastions,omitempty"`
+}
+
+// Bastion is the jump host for the hosts in one of Groups or with an
+// address in one of CIDRs
+// Only addresses written as IPs match a CIDR; host names are not resolved.
+type Bastion struct {
+	Proxy  string   `json:"proxy" yaml:"proxy"`
+	Groups []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+	CIDRs  []string `json:"cidrs,omitempty" yaml:"cidrs,omitempty"`
+}
+
+// Matches reports whether the bastion applies to the host
+func (b Bastion) Matches(h Host) bool {
+	for _, group := range b.Groups {
+		if h.Group != "" && strings.EqualFold(group, h.Group) {
+			return true
+		}
+	}
+	ip := net.ParseIP(h.Host)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range b.CIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyFor returns the default proxy of h: the first matching bastion's,
+// else Proxy
+func (d Defaults) proxyFor(h Host) string {
+	for _, b := range d.Bastions {
+		if b.Matches(h) {
+			return b.Proxy
+		}
+	}
+	return d.Proxy
+}
+
+// Apply fills the empty fields of h from the defaults
+// The proxy comes from the first bastion matching the host, else the default
+// proxy. A host with proxy "none" connects directly even when a default
+// proxy or bastion applies.
+func (d Defaults) Apply(h Host) Host {
+	if h.User == "" {
+		h.User = d.User
+	}
+	if h.Port == 0 {
+		h.Port = d.Port
+	}
+	if h.Identity == "" {
+		h.Identity = d.Identity
+	}
+	if h.HostKeyPolicy == "" {
+		h.HostKeyPolicy = d.HostKeyPolicy
+	}
+	switch h.Proxy {
+	case "":
+		h.Proxy = d.proxyFor(h)
+	case ProxyNone:
+		h.Proxy = ""
+	}
+	// Don't jump through a host to reach itself
+	if h.Proxy == h.Name {
+		h.Proxy = ""
+	}
+	return h
+}
+
+// Strip is the reverse of Apply: fields equal to their default are emptied
+// so saving a host doesn't copy the defaults into it
+func (d Defaults) Strip(h Host) Host {
+	if d.User != "" && h.User == d.User {
+		h.User = ""
+	}
+	if d.Port != 0 && h.Port == d.Port {
+		h.Port = 0
+	}
+	if d.Identity != "" && h.Identity == d.Identity {
+		h.Identity = ""
+	}
+	if d.HostKeyPolicy != "" && h.HostKeyPolicy == d.HostKeyPolicy {
+		h.HostKeyPolicy = ""
+	}
+	if proxy := d.proxyFor(h); proxy != "" && h.Name != proxy {
+		switch h.Proxy {
+		case proxy:
+			h.Proxy = ""
+		case "":
+			h.Proxy = ProxyNone
+		}
+	}
+	return h
+}
+
+// ApplyProfile overrides the keep-alive and timeout of p with the defaults
+func (d Defaults) ApplyProfile(p Profile) Profile {
+	if d.KeepAliveInterval > 0 {
+		p.KeepAliveInterval = d.KeepAliveInterval
+	}
+	if d.ConnectTimeout > 0 {
+		p.Timeout = d.ConnectTimeout
+	}
+	return p
+}
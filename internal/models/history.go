@@ -9,6 +9,18 @@ type ConnectionHistory struct {
 	Success    bool      `json:"success" yaml:"success"`
 	Error      string    `json:"error,omitempty" yaml:"error,omitempty"`
 	Duration   int64     `json:"duration_ms,omitempty" yaml:"duration_ms,omitempty"` // connection time in milliseconds
+	Warnings   []string  `json:"warnings,omitempty" yaml:"warnings,omitempty"`       // weak keys and algorithms seen while connecting
+	// HostKeyChanges are the changed host keys met while connecting, with
+	// whether they were accepted
+	HostKeyChanges []HostKeyChange `json:"host_key_changes,omitempty" yaml:"host_key_changes,omitempty"`
+}
+
+// HostKeyChange is a server key other than the one in known_hosts
+type HostKeyChange struct {
+	Host           string `json:"host" yaml:"host"` // as written in known_hosts
+	OldFingerprint string `json:"old_fingerprint" yaml:"old_fingerprint"`
+	NewFingerprint string `json:"new_fingerprint" yaml:"new_fingerprint"`
+	Approved       bool   `json:"approved" yaml:"approved"` // the host name was typed to accept the new key
 }
 
 // HistoryStats contains aggregated connection statistics for a host
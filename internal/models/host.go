@@ -2,6 +2,8 @@ package models
 
 import (
 	"fmt"
+	"path"
+	"sort"
 	"strings"
 )
 
@@ -12,33 +14,166 @@ const (
 	AuthTypePassword AuthType = "password"
 	AuthTypeKey      AuthType = "key"
 	AuthTypeAgent    AuthType = "agent"
+	// AuthTypeVaultCert signs a fresh key with Vault's SSH secrets engine
+	// for each connection
+	AuthTypeVaultCert AuthType = "vault-cert"
+	// AuthTypeVaultOTP logs in with a one-time password issued by Vault
+	AuthTypeVaultOTP AuthType = "vault-otp"
 )
 
+// HostKeyPolicy decides how the host key presented by a server is checked
+// against ~/.ssh/known_hosts
+type HostKeyPolicy string
+
+const (
+	// HostKeyStrict rejects hosts missing from known_hosts
+	HostKeyStrict HostKeyPolicy = "strict"
+	// HostKeyTOFU trusts a host on first use and records its key, the
+	// default
+	HostKeyTOFU HostKeyPolicy = "tofu"
+	// HostKeyInsecure accepts any key, for throwaway lab machines
+	HostKeyInsecure HostKeyPolicy = "insecure"
+)
+
+// ValidHostKeyPolicy reports whether p is empty or a known policy
+func ValidHostKeyPolicy(p HostKeyPolicy) bool {
+	switch p {
+	case "", HostKeyStrict, HostKeyTOFU, HostKeyInsecure:
+		return true
+	}
+	return false
+}
+
+// TOTPMode decides what sshm does with the TOTP secret kept in the keyring
+// for a host when the server asks for a verification code
+type TOTPMode string
+
+const (
+	// TOTPAuto answers the prompt with the current code
+	TOTPAuto TOTPMode = "auto"
+	// TOTPShow prints the code and copies it to the clipboard for the user
+	// to paste
+	TOTPShow TOTPMode = "show"
+)
+
+// ValidTOTPMode reports whether m is empty or a known mode
+func ValidTOTPMode(m TOTPMode) bool {
+	switch m {
+	case "", TOTPAuto, TOTPShow:
+		return true
+	}
+	return false
+}
+
 // Host represents an SSH host entry
 type Host struct {
-	ID              string    `json:"id" yaml:"id"`
-	Name            string    `json:"name" yaml:"name"`
-	Host            string    `json:"host" yaml:"host"`
-	Port            int       `json:"port" yaml:"port"`
-	User            string    `json:"user" yaml:"user"`
-	Password        string    `json:"password,omitempty" yaml:"password,omitempty"`
-	Identity        string    `json:"identity,omitempty" yaml:"identity,omitempty"`
-	AuthType        AuthType  `json:"auth_type,omitempty" yaml:"auth_type,omitempty"`
-	Proxy           string    `json:"proxy,omitempty" yaml:"proxy,omitempty"`
-	Group           string    `json:"group,omitempty" yaml:"group,omitempty"`
-	Tags            []string  `json:"tags,omitempty" yaml:"tags,omitempty"`
-	ConnectionCount int       `json:"connection_count,omitempty" yaml:"connection_count,omitempty"`
-	Profile         string    `json:"profile,omitempty" yaml:"profile,omitempty"` // Profile name to use for this host
-	Online          *bool     `json:"online,omitempty" yaml:"online,omitempty"`   // Online status (nil = unknown, true = online, false = offline)
+	ID              string            `json:"id" yaml:"id"`
+	Name            string            `json:"name" yaml:"name"`
+	Host            string            `json:"host" yaml:"host"`
+	Port            int               `json:"port,omitempty" yaml:"port,omitempty"` // 0 takes the default port
+	User            string            `json:"user,omitempty" yaml:"user,omitempty"` // empty takes the default user
+	Password        string            `json:"password,omitempty" yaml:"password,omitempty"`
+	Identity        string            `json:"identity,omitempty" yaml:"identity,omitempty"`
+	AuthType        AuthType          `json:"auth_type,omitempty" yaml:"auth_type,omitempty"`
+	HostKeyPolicy   HostKeyPolicy     `json:"host_key_policy,omitempty" yaml:"host_key_policy,omitempty"` // empty takes the default policy
+	VaultRole       string            `json:"vault_role,omitempty" yaml:"vault_role,omitempty"`           // Vault SSH role for vault auth, empty takes vault.role
+	TOTP            TOTPMode          `json:"totp,omitempty" yaml:"totp,omitempty"`                       // answer verification code prompts from the keyring secret
+	Proxy           string            `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	Group           string            `json:"group,omitempty" yaml:"group,omitempty"`
+	Tags            []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+	ConnectionCount int               `json:"connection_count,omitempty" yaml:"connection_count,omitempty"`
+	Profile         string            `json:"profile,omitempty" yaml:"profile,omitempty"` // Profile name to use for this host
+	Online          *bool             `json:"online,omitempty" yaml:"online,omitempty"`   // Online status (nil = unknown, true = online, false = offline)
+	Aliases         map[string]string `json:"aliases,omitempty" yaml:"aliases,omitempty"` // Named commands run with "sshm run <host> <alias>"
 }
 
 // SSHConfig represents SSH configuration settings
+// The settings apply to hosts whose name matches one of Hosts, in one of
+// Groups or carrying one of Tags; without any of those, to hosts whose name
+// matches Name, like an OpenSSH Host line.
 type SSHConfig struct {
-	Name               string `json:"name" yaml:"name"`
-	IdentityFile       string `json:"identity_file,omitempty" yaml:"identity_file,omitempty"`
-	ProxyCommand       string `json:"proxy_command,omitempty" yaml:"proxy_command,omitempty"`
-	ForwardAgent       bool   `json:"forward_agent,omitempty" yaml:"forward_agent,omitempty"`
-	ServerAliveInterval int   `json:"server_alive_interval,omitempty" yaml:"server_alive_interval,omitempty"`
+	Name                string   `json:"name" yaml:"name"`
+	Hosts               []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	Groups              []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+	Tags                []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	IdentityFile        string   `json:"identity_file,omitempty" yaml:"identity_file,omitempty"`
+	ProxyCommand        string   `json:"proxy_command,omitempty" yaml:"proxy_command,omitempty"`
+	ForwardAgent        bool     `json:"forward_agent,omitempty" yaml:"forward_agent,omitempty"`
+	ServerAliveInterval int      `json:"server_alive_interval,omitempty" yaml:"server_alive_interval,omitempty"`
+}
+
+// Matches reports whether the settings apply to the host
+// Host patterns use * and ? and a leading ! excludes matching hosts even
+// when their group or tags match. Names are compared without regard to case.
+func (c SSHConfig) Matches(h Host) bool {
+	patterns := c.Hosts
+	if len(c.Hosts) == 0 && len(c.Groups) == 0 && len(c.Tags) == 0 {
+		patterns = []string{c.Name}
+	}
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if MatchPattern(strings.TrimPrefix(pattern, "!"), h.Name) {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	if matched {
+		return true
+	}
+
+	for _, group := range c.Groups {
+		if h.Group != "" && strings.EqualFold(h.Group, group) {
+			return true
+		}
+	}
+	for _, tag := range c.Tags {
+		for _, hostTag := range h.Tags {
+			if strings.EqualFold(hostTag, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchPattern reports whether name matches a host pattern with * and ?,
+// ignoring case; malformed patterns match nothing
+func MatchPattern(pattern, name string) bool {
+	if pattern == "" {
+		return false
+	}
+	ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return err == nil && ok
+}
+
+// ValidatePattern checks a host pattern, which may start with ! to exclude
+func ValidatePattern(pattern string) error {
+	if _, err := path.Match(strings.TrimPrefix(pattern, "!"), ""); err != nil || strings.TrimPrefix(pattern, "!") == "" {
+		return fmt.Errorf("invalid host pattern %q", pattern)
+	}
+	return nil
+}
+
+// ApplyProfile sets the options of c on p; options c leaves empty keep
+// the profile's value
+func (c SSHConfig) ApplyProfile(p Profile) Profile {
+	if c.IdentityFile != "" {
+		p.IdentityFile = c.IdentityFile
+	}
+	if c.ProxyCommand != "" {
+		p.ProxyCommand = c.ProxyCommand
+	}
+	if c.ForwardAgent {
+		p.ForwardAgent = true
+	}
+	if c.ServerAliveInterval > 0 {
+		p.KeepAliveInterval = c.ServerAliveInterval
+		p.ServerAliveEnabled = true
+	}
+	return p
 }
 
 // Config holds the entire application configuration
@@ -46,6 +181,22 @@ type Config struct {
 	Hosts     []Host     `json:"hosts" yaml:"hosts"`
 	Configs   []SSHConfig `json:"configs" yaml:"configs"`
 	Profiles  []Profile  `json:"profiles" yaml:"profiles"`
+	Defaults  Defaults   `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+	Include   []string   `json:"include,omitempty" yaml:"include,omitempty"`
+	EncryptedFields []string `json:"encrypted_fields,omitempty" yaml:"encrypted_fields,omitempty"`
+}
+
+// EncryptableFields lists the host fields encrypted_fields may name
+var EncryptableFields = []string{"password"}
+
+// IsEncryptableField reports whether name is one of EncryptableFields
+func IsEncryptableField(name string) bool {
+	for _, f := range EncryptableFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
 }
 
 // GenerateSSHCommand generates an SSH command string from the host
@@ -72,3 +223,102 @@ func (h *Host) GenerateSSHCommand() string {
 
 	return strings.Join(args, " ")
 }
+
+// SSHConfigLines renders the host as an OpenSSH config Host block, adding
+// the identity file, proxy command and other options from the configs
+// matching it (see Config.ApplyConfigs) where the host sets none
+func (h *Host) SSHConfigLines(options Profile) []string {
+	lines := []string{fmt.Sprintf("Host %s", h.Name)}
+
+	if h.Host != "" {
+		lines = append(lines, fmt.Sprintf("    HostName %s", h.Host))
+	}
+
+	if h.Port != 0 && h.Port != 22 {
+		lines = append(lines, fmt.Sprintf("    Port %d", h.Port))
+	}
+
+	if h.User != "" {
+		lines = append(lines, fmt.Sprintf("    User %s", h.User))
+	}
+
+	if h.Identity != "" {
+		lines = append(lines, fmt.Sprintf("    IdentityFile %s", h.Identity))
+	} else if options.IdentityFile != "" {
+		lines = append(lines, fmt.Sprintf("    IdentityFile %s", options.IdentityFile))
+	}
+
+	if h.Proxy != "" {
+		lines = append(lines, fmt.Sprintf("    ProxyJump %s", h.Proxy))
+	} else if options.ProxyCommand != "" {
+		lines = append(lines, fmt.Sprintf("    ProxyCommand %s", options.ProxyCommand))
+	}
+
+	if options.ForwardAgent {
+		lines = append(lines, "    ForwardAgent yes")
+	}
+
+	if options.KeepAliveInterval > 0 {
+		lines = append(lines, fmt.Sprintf("    ServerAliveInterval %d", options.KeepAliveInterval))
+	}
+
+	// Add group and tags as comments
+	if h.Group != "" {
+		lines = append(lines, fmt.Sprintf("    # Group: %s", h.Group))
+	}
+	if len(h.Tags) > 0 {
+		lines = append(lines, fmt.Sprintf("    # Tags: %s", strings.Join(h.Tags, ", ")))
+	}
+
+	return lines
+}
+
+// Validate checks that the host has the fields needed to connect
+func (h *Host) Validate() error {
+	switch {
+	case strings.TrimSpace(h.Name) == "":
+		return fmt.Errorf("name is required")
+	case strings.TrimSpace(h.Host) == "":
+		return fmt.Errorf("%s: host is required", h.Name)
+	case h.Port < 1 || h.Port > 65535:
+		return fmt.Errorf("%s: port must be 1-65535", h.Name)
+	}
+	switch h.AuthType {
+	case "", AuthTypePassword, AuthTypeKey, AuthTypeAgent, AuthTypeVaultCert, AuthTypeVaultOTP:
+	default:
+		return fmt.Errorf("%s: unknown auth_type %q (use password, key, agent, vault-cert or vault-otp)", h.Name, h.AuthType)
+	}
+	if !ValidHostKeyPolicy(h.HostKeyPolicy) {
+		return fmt.Errorf("%s: unknown host_key_policy %q (use strict, tofu or insecure)", h.Name, h.HostKeyPolicy)
+	}
+	if !ValidTOTPMode(h.TOTP) {
+		return fmt.Errorf("%s: unknown totp %q (use auto or show)", h.Name, h.TOTP)
+	}
+	for name, command := range h.Aliases {
+		if err := ValidateAliasName(name); err != nil {
+			return fmt.Errorf("%s: %w", h.Name, err)
+		}
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("%s: alias %q has no command", h.Name, name)
+		}
+	}
+	return nil
+}
+
+// ValidateAliasName checks that an alias name is a single word
+func ValidateAliasName(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t\n=") {
+		return fmt.Errorf("invalid alias name %q (use a single word without =)", name)
+	}
+	return nil
+}
+
+// AliasNames returns the names of the host's aliases in sorted order
+func (h *Host) AliasNames() []string {
+	names := make([]string, 0, len(h.Aliases))
+	for name := range h.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
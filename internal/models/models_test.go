@@ -1,6 +1,8 @@
 package models
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -138,3 +140,205 @@ func TestDefaultProfile(t *testing.T) {
 		t.Errorf("Expected ServerAliveEnabled to be true by default")
 	}
 }
+
+func TestHostValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    Host
+		wantErr bool
+	}{
+		{"valid", Host{Name: "web1", Host: "10.0.0.1", Port: 22}, false},
+		{"missing name", Host{Host: "10.0.0.1", Port: 22}, true},
+		{"missing host", Host{Name: "web1", Port: 22}, true},
+		{"bad port", Host{Name: "web1", Host: "10.0.0.1", Port: 70000}, true},
+		{"bad auth type", Host{Name: "web1", Host: "10.0.0.1", Port: 22, AuthType: "kerberos"}, true},
+		{"alias", Host{Name: "web1", Host: "10.0.0.1", Port: 22, Aliases: map[string]string{"logs": "journalctl -f"}}, false},
+		{"alias with space", Host{Name: "web1", Host: "10.0.0.1", Port: 22, Aliases: map[string]string{"tail logs": "tail"}}, true},
+		{"empty alias command", Host{Name: "web1", Host: "10.0.0.1", Port: 22, Aliases: map[string]string{"logs": " "}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.host.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAliasNames(t *testing.T) {
+	h := Host{Aliases: map[string]string{"restart": "systemctl restart app", "logs": "journalctl -f", "df": "df -h"}}
+	names := h.AliasNames()
+	if len(names) != 3 || names[0] != "df" || names[1] != "logs" || names[2] != "restart" {
+		t.Errorf("expected sorted alias names, got %v", names)
+	}
+}
+
+func TestDefaultsApplyAndStrip(t *testing.T) {
+	d := Defaults{User: "deploy", Port: 2222, Identity: "~/.ssh/deploy", Proxy: "bastion"}
+
+	tests := []struct {
+		name    string
+		stored  Host
+		applied Host
+	}{
+		{"empty", Host{Name: "web1"}, Host{Name: "web1", User: "deploy", Port: 2222, Identity: "~/.ssh/deploy", Proxy: "bastion"}},
+		{"own values", Host{Name: "db1", User: "postgres", Port: 22, Proxy: "jump2"}, Host{Name: "db1", User: "postgres", Port: 22, Identity: "~/.ssh/deploy", Proxy: "jump2"}},
+		{"no proxy", Host{Name: "lan", Proxy: ProxyNone}, Host{Name: "lan", User: "deploy", Port: 2222, Identity: "~/.ssh/deploy"}},
+		{"proxy itself", Host{Name: "bastion"}, Host{Name: "bastion", User: "deploy", Port: 2222, Identity: "~/.ssh/deploy"}},
+	}
+	for _, tt := range tests {
+		applied := d.Apply(tt.stored)
+		if !reflect.DeepEqual(applied, tt.applied) {
+			t.Errorf("%s: Apply() = %+v, want %+v", tt.name, applied, tt.applied)
+		}
+		if stripped := d.Strip(applied); !reflect.DeepEqual(stripped, tt.stored) {
+			t.Errorf("%s: Strip() = %+v, want %+v", tt.name, stripped, tt.stored)
+		}
+	}
+}
+
+func TestDefaultsBastions(t *testing.T) {
+	d := Defaults{Proxy: "bastion", Bastions: []Bastion{
+		{Proxy: "bastion-eu", CIDRs: []string{"10.1.0.0/16"}},
+		{Proxy: "bastion-us", Groups: []string{"US"}},
+	}}
+
+	tests := []struct {
+		name    string
+		stored  Host
+		applied Host
+	}{
+		{"cidr", Host{Name: "web1", Host: "10.1.2.3"}, Host{Name: "web1", Host: "10.1.2.3", Proxy: "bastion-eu"}},
+		{"group", Host{Name: "web2", Host: "db.example.com", Group: "us"}, Host{Name: "web2", Host: "db.example.com", Group: "us", Proxy: "bastion-us"}},
+		{"first match", Host{Name: "web3", Host: "10.1.0.9", Group: "us"}, Host{Name: "web3", Host: "10.1.0.9", Group: "us", Proxy: "bastion-eu"}},
+		{"fallback", Host{Name: "web4", Host: "10.2.0.1"}, Host{Name: "web4", Host: "10.2.0.1", Proxy: "bastion"}},
+		{"explicit", Host{Name: "web5", Host: "10.1.0.5", Proxy: "jump2"}, Host{Name: "web5", Host: "10.1.0.5", Proxy: "jump2"}},
+		{"no proxy", Host{Name: "web6", Host: "10.1.0.6", Proxy: ProxyNone}, Host{Name: "web6", Host: "10.1.0.6"}},
+		{"bastion itself", Host{Name: "bastion-eu", Host: "10.1.0.1"}, Host{Name: "bastion-eu", Host: "10.1.0.1"}},
+	}
+	for _, tt := range tests {
+		applied := d.Apply(tt.stored)
+		if !reflect.DeepEqual(applied, tt.applied) {
+			t.Errorf("%s: Apply() = %+v, want %+v", tt.name, applied, tt.applied)
+		}
+		if stripped := d.Strip(applied); !reflect.DeepEqual(stripped, tt.stored) {
+			t.Errorf("%s: Strip() = %+v, want %+v", tt.name, stripped, tt.stored)
+		}
+	}
+}
+
+func TestDefaultsApplyProfile(t *testing.T) {
+	p := Defaults{ConnectTimeout: 5}.ApplyProfile(DefaultProfile())
+	if p.Timeout != 5 || p.KeepAliveInterval != DefaultProfile().KeepAliveInterval {
+		t.Errorf("unexpected profile %+v", p)
+	}
+}
+
+func TestSSHConfigMatchesAndApply(t *testing.T) {
+	sc := SSHConfig{Name: "web", Groups: []string{"Web"}, Tags: []string{"prod"}, ForwardAgent: true, ServerAliveInterval: 30}
+	if !sc.Matches(Host{Group: "web"}) || !sc.Matches(Host{Tags: []string{"db", "PROD"}}) {
+		t.Error("expected the config to match by group and by tag")
+	}
+	if sc.Matches(Host{Group: "db", Tags: []string{"staging"}}) || (SSHConfig{}).Matches(Host{}) {
+		t.Error("expected no match")
+	}
+
+	p := sc.ApplyProfile(Profile{KeepAliveInterval: 15, IdentityFile: "/keys/a"})
+	if !p.ForwardAgent || p.KeepAliveInterval != 30 || !p.ServerAliveEnabled || p.IdentityFile != "/keys/a" {
+		t.Errorf("unexpected profile %+v", p)
+	}
+}
+
+func TestSSHConfigHostPatterns(t *testing.T) {
+	sc := SSHConfig{Name: "web", Hosts: []string{"web-*", "!web-legacy"}, Tags: []string{"prod"}}
+	if !sc.Matches(Host{Name: "WEB-1"}) {
+		t.Error("expected web-* to match WEB-1")
+	}
+	if sc.Matches(Host{Name: "web-legacy", Tags: []string{"prod"}}) {
+		t.Error("expected !web-legacy to exclude the host despite its tag")
+	}
+	if sc.Matches(Host{Name: "web"}) {
+		t.Error("expected the name to be ignored when host patterns are given")
+	}
+
+	// Without selectors the name is the pattern, like an OpenSSH Host line
+	if !(SSHConfig{Name: "db?"}).Matches(Host{Name: "db1"}) || !(SSHConfig{Name: "*"}).Matches(Host{Name: "any"}) {
+		t.Error("expected the name to match as a pattern")
+	}
+
+	if ValidatePattern("web-[") == nil || ValidatePattern("!") == nil || ValidatePattern("!web-*") != nil {
+		t.Error("unexpected pattern validation")
+	}
+}
+
+func TestSnippetExpand(t *testing.T) {
+	s := Snippet{Name: "tail", Command: "journalctl -u {{unit}} -n {{lines=100}} | grep {{ unit }}"}
+
+	params := s.Params()
+	if len(params) != 2 || params[0].Name != "unit" || params[0].HasDefault || params[1].Default != "100" {
+		t.Fatalf("unexpected params %+v", params)
+	}
+
+	got, err := s.Expand(map[string]string{"unit": "nginx"})
+	if err != nil || got != "journalctl -u nginx -n 100 | grep nginx" {
+		t.Errorf("Expand = %q, %v", got, err)
+	}
+	if _, err := s.Expand(nil); err == nil {
+		t.Error("expected a missing parameter to be an error")
+	}
+	if _, err := s.Expand(map[string]string{"unit": "nginx", "user": "root"}); err == nil {
+		t.Error("expected an unknown parameter to be an error")
+	}
+}
+
+func TestSnippetAppliesTo(t *testing.T) {
+	host := Host{Name: "web-1", Tags: []string{"web", "prod"}}
+	if !(Snippet{Name: "uptime"}).AppliesTo(host) {
+		t.Error("expected a snippet without tags to apply to every host")
+	}
+	if !(Snippet{Name: "restart", Tags: []string{"db", "Web"}}).AppliesTo(host) {
+		t.Error("expected a snippet to apply to hosts with one of its tags")
+	}
+	if (Snippet{Name: "vacuum", Tags: []string{"db"}}).AppliesTo(host) {
+		t.Error("expected a snippet not to apply to hosts without its tags")
+	}
+}
+
+func TestHostURI(t *testing.T) {
+	host := Host{
+		ID: "abc", Name: "web 1", Host: "10.0.0.4", Port: 2222, User: "deploy", Password: "s3cret",
+		Identity: "~/.ssh/deploy", Group: "prod", Tags: []string{"web", "eu&us"}, HostKeyPolicy: HostKeyTOFU,
+	}
+	uri := host.URI()
+	if uri != "sshm://deploy@10.0.0.4:2222?name=web+1&group=prod&tag=web&tag=eu%26us" {
+		t.Errorf("URI() = %q", uri)
+	}
+	if strings.Contains(uri, "s3cret") {
+		t.Errorf("the password leaked into %q", uri)
+	}
+
+	got, err := ParseHostURI(uri)
+	want := host
+	want.ID, want.Password, want.Identity, want.HostKeyPolicy = "", "", "", ""
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHostURI = %+v, %v, want %+v", got, err, want)
+	}
+
+	// A link must not switch off host key checking or point at local files
+	got, err = ParseHostURI("sshm://10.0.0.4?policy=insecure&identity=%2Ftmp%2Fkey")
+	if err != nil || got.HostKeyPolicy != "" || got.Identity != "" {
+		t.Errorf("expected policy and identity to be ignored, got %+v, %v", got, err)
+	}
+
+	got, err = ParseHostURI("sshm://[fe80::1]")
+	if err != nil || got.Host != "fe80::1" || got.Name != "fe80::1" || got.Port != 0 {
+		t.Errorf("ParseHostURI of an IPv6 address = %+v, %v", got, err)
+	}
+	if v6 := (&Host{Name: "v6", Host: "fe80::1"}).URI(); v6 != "sshm://[fe80::1]?name=v6" {
+		t.Errorf("URI() of an IPv6 address = %q", v6)
+	}
+
+	for _, bad := range []string{"ssh://web1", "sshm://?name=x", "sshm://web1:http"} {
+		if _, err := ParseHostURI(bad); err == nil {
+			t.Errorf("expected ParseHostURI(%q) to fail", bad)
+		}
+	}
+}
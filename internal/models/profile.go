@@ -7,6 +7,13 @@ type Profile struct {
 	KeepAliveInterval  int    `json:"keepalive_interval" yaml:"keepalive_interval"` // Keep-alive interval in seconds
 	KeepAliveCountMax  int    `json:"keepalive_count_max" yaml:"keepalive_count_max"` // Max keep-alive count before disconnect
 	ServerAliveEnabled bool   `json:"server_alive_enabled" yaml:"server_alive_enabled"` // Enable server alive messages
+	// IdentityFile is used by hosts without an identity of their own
+	IdentityFile string `json:"identity_file,omitempty" yaml:"identity_file,omitempty"`
+	// ProxyCommand connects hosts without a jump host through the command's
+	// stdin and stdout; %h, %p and %r expand to the host, port and user
+	ProxyCommand string `json:"proxy_command,omitempty" yaml:"proxy_command,omitempty"`
+	// ForwardAgent forwards the local SSH agent to sessions
+	ForwardAgent bool `json:"forward_agent,omitempty" yaml:"forward_agent,omitempty"`
 }
 
 // DefaultProfile returns the default profile settings
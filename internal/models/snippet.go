@@ -0,0 +1,92 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Snippet is a named command that can be run on hosts
+// The command may hold {{param}} or {{param=default}} placeholders, filled
+// in verbatim when the snippet runs.
+type Snippet struct {
+	Name        string `json:"name" yaml:"name"`
+	Command     string `json:"command" yaml:"command"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Tags limit the snippet to hosts carrying one of them; without tags it
+	// applies to every host
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// SnippetParam is a placeholder of a snippet command
+type SnippetParam struct {
+	Name       string
+	Default    string
+	HasDefault bool
+}
+
+// snippetPlaceholder matches {{name}} and {{name=default}}
+var snippetPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*(=[^}]*)?\}\}`)
+
+// Params returns the placeholders of the command in order of appearance,
+// each once; the first default given for a name wins
+func (s Snippet) Params() []SnippetParam {
+	var params []SnippetParam
+	seen := make(map[string]int)
+	for _, m := range snippetPlaceholder.FindAllStringSubmatch(s.Command, -1) {
+		name, def := m[1], m[2]
+		if i, ok := seen[name]; ok {
+			if !params[i].HasDefault && def != "" {
+				params[i].Default, params[i].HasDefault = def[1:], true
+			}
+			continue
+		}
+		seen[name] = len(params)
+		p := SnippetParam{Name: name}
+		if def != "" {
+			p.Default, p.HasDefault = def[1:], true
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// Expand fills in the placeholders from values, falling back to their
+// defaults, and reports the first parameter left without a value
+func (s Snippet) Expand(values map[string]string) (string, error) {
+	params := s.Params()
+	resolved := make(map[string]string, len(params))
+	for _, p := range params {
+		value, ok := values[p.Name]
+		if !ok {
+			if !p.HasDefault {
+				return "", fmt.Errorf("snippet %s needs a value for %q", s.Name, p.Name)
+			}
+			value = p.Default
+		}
+		resolved[p.Name] = value
+	}
+	for name := range values {
+		if _, ok := resolved[name]; !ok {
+			return "", fmt.Errorf("snippet %s has no parameter %q", s.Name, name)
+		}
+	}
+	return snippetPlaceholder.ReplaceAllStringFunc(s.Command, func(m string) string {
+		return resolved[snippetPlaceholder.FindStringSubmatch(m)[1]]
+	}), nil
+}
+
+// AppliesTo reports whether the snippet can run on the host
+func (s Snippet) AppliesTo(h Host) bool {
+	if len(s.Tags) == 0 {
+		return true
+	}
+	for _, tag := range s.Tags {
+		for _, hostTag := range h.Tags {
+			if strings.EqualFold(tag, hostTag) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// URIScheme is the scheme of the links hosts are shared with
+const URIScheme = "sshm"
+
+// URI returns a link to share the host with someone else running sshm:
+// sshm://user@host:port?name=web1&group=prod&tag=web&tag=eu
+// Only what connecting needs goes in. The password, aliases, ID and history
+// stay behind, and so do the identity file, a path on this machine, and the
+// host key policy, which a received link must not be able to weaken.
+func (h *Host) URI() string {
+	u := url.URL{Scheme: URIScheme, Host: h.Host}
+	if strings.Contains(h.Host, ":") {
+		u.Host = "[" + h.Host + "]"
+	}
+	if h.Port != 0 {
+		u.Host = net.JoinHostPort(h.Host, strconv.Itoa(h.Port))
+	}
+	if h.User != "" {
+		u.User = url.User(h.User)
+	}
+	// Set in a fixed order, so the same host always gives the same link
+	var query []string
+	add := func(key, value string) {
+		if value != "" {
+			query = append(query, key+"="+url.QueryEscape(value))
+		}
+	}
+	add("name", h.Name)
+	add("proxy", h.Proxy)
+	add("group", h.Group)
+	for _, tag := range h.Tags {
+		add("tag", tag)
+	}
+	add("auth", string(h.AuthType))
+	u.RawQuery = strings.Join(query, "&")
+	return u.String()
+}
+
+// ParseHostURI reads a host from a link made by URI
+// The name defaults to the address; the host still has to be validated.
+// Parameters URI doesn't write, such as identity or policy in a crafted
+// link, are ignored; see IgnoredURIParams.
+func ParseHostURI(s string) (Host, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return Host{}, fmt.Errorf("invalid host link: %w", err)
+	}
+	if u.Scheme != URIScheme {
+		return Host{}, fmt.Errorf("invalid host link %q: the scheme is not %s://", s, URIScheme)
+	}
+	if u.Hostname() == "" {
+		return Host{}, fmt.Errorf("invalid host link %q: no address", s)
+	}
+
+	q := u.Query()
+	h := Host{
+		Name:     q.Get("name"),
+		Host:     u.Hostname(),
+		User:     u.User.Username(),
+		Proxy:    q.Get("proxy"),
+		Group:    q.Get("group"),
+		Tags:     q["tag"],
+		AuthType: AuthType(q.Get("auth")),
+	}
+	if port := u.Port(); port != "" {
+		if h.Port, err = strconv.Atoi(port); err != nil {
+			return Host{}, fmt.Errorf("invalid host link %q: bad port %q", s, port)
+		}
+	}
+	if h.Name == "" {
+		h.Name = h.Host
+	}
+	return h, nil
+}
+
+// IgnoredURIParams returns the parameters of a host link that ParseHostURI
+// ignores, sorted, so importers can say what was left out
+func IgnoredURIParams(s string) []string {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+	var ignored []string
+	for key := range u.Query() {
+		switch key {
+		case "name", "proxy", "group", "tag", "auth":
+		default:
+			ignored = append(ignored, key)
+		}
+	}
+	sort.Strings(ignored)
+	return ignored
+}
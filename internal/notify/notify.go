@@ -0,0 +1,84 @@
+// Package notify shows desktop notifications, so jobs finishing while the
+// terminal is out of sight don't go unnoticed
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnsupported is returned when there is no way to notify on this system
+var ErrUnsupported = errors.New("no desktop notifications available (needs notify-send, macOS or Windows)")
+
+// Title is the title notifications are sent under
+const Title = "sshm"
+
+// windowsAppID is the application the toasts are shown for; Windows only
+// shows those of registered applications, and PowerShell is one
+const windowsAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// run executes a notification tool
+// Tests replace it.
+var run = func(name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return ErrUnsupported
+	}
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %s", name, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Send shows a notification with the body under Title
+// Linux without notify-send, such as WSL, falls back to Windows toasts
+// when powershell.exe can be run.
+func Send(body string) error {
+	name, args := command(runtime.GOOS, Title, body)
+	if name == "" {
+		return ErrUnsupported
+	}
+	err := run(name, args...)
+	if errors.Is(err, ErrUnsupported) && runtime.GOOS == "linux" {
+		name, args = command("windows", Title, body)
+		err = run(name, args...)
+	}
+	return err
+}
+
+// command returns the program and arguments showing a notification on goos,
+// or "" when there is none
+func command(goos, title, body string) (string, []string) {
+	switch goos {
+	case "darwin":
+		// Passed as arguments, the texts need no AppleScript quoting
+		return "osascript", []string{
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, body,
+		}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return "notify-send", []string{"--app-name=" + title, title, body}
+	case "windows":
+		script := `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode(` + psQuote(title) + `)) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode(` + psQuote(body) + `)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier(` + psQuote(windowsAppID) + `).Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+		return "powershell.exe", []string{"-NoProfile", "-NonInteractive", "-Command", script}
+	}
+	return "", nil
+}
+
+// psQuote returns s as a PowerShell string literal, taken as it is
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"errors"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	body := `web1: it's "done"`
+
+	name, args := command("darwin", Title, body)
+	if name != "osascript" || !slices.Equal(args[len(args)-2:], []string{Title, body}) {
+		t.Errorf("darwin: %s %q, want the texts as osascript arguments", name, args)
+	}
+
+	name, args = command("linux", Title, body)
+	if name != "notify-send" || args[len(args)-1] != body {
+		t.Errorf("linux: %s %q, want notify-send with the body", name, args)
+	}
+
+	name, args = command("windows", Title, body)
+	script := args[len(args)-1]
+	if name != "powershell.exe" || !strings.Contains(script, `'web1: it''s "done"'`) {
+		t.Errorf("windows: %s %q, want the body as a quoted PowerShell string", name, script)
+	}
+
+	if name, _ := command("plan9", Title, body); name != "" {
+		t.Errorf("plan9: %s, want no command", name)
+	}
+}
+
+func TestSendFallsBackToWindows(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("exercises the fallback of Linux")
+	}
+	original := run
+	t.Cleanup(func() { run = original })
+	var tried []string
+	run = func(name string, args ...string) error {
+		tried = append(tried, name)
+		if name == "notify-send" {
+			return ErrUnsupported
+		}
+		return nil
+	}
+
+	if err := Send("done"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if !slices.Equal(tried, []string{"notify-send", "powershell.exe"}) {
+		t.Errorf("tried %v, want notify-send then powershell.exe", tried)
+	}
+
+	run = func(name string, args ...string) error { return ErrUnsupported }
+	if err := Send("done"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported without any tool, got %v", err)
+	}
+}
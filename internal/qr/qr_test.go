@@ -0,0 +1,172 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTables(t *testing.T) {
+	// Data codewords at level L from ISO 18004, table 7
+	for version, want := range map[int]int{1: 19, 2: 34, 7: 156, 10: 274, 14: 461, 25: 1276, 32: 1955, 40: 2956} {
+		if got := dataCodewords(version); got != want {
+			t.Errorf("dataCodewords(%d) = %d, want %d", version, got, want)
+		}
+	}
+	for version, want := range map[int][]int{1: nil, 2: {6, 18}, 7: {6, 22, 38}, 32: {6, 34, 60, 86, 112, 138}, 40: {6, 30, 58, 86, 114, 142, 170}} {
+		if got := alignmentPositions(version); !slices.Equal(got, want) {
+			t.Errorf("alignmentPositions(%d) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+// decode reads data back from a code, checking the format information and
+// the error correction codewords on the way
+func decode(t *testing.T, c *Code) []byte {
+	t.Helper()
+	version := (c.Size - 17) / 4
+
+	var format int
+	for i := 14; i >= 9; i-- {
+		format = format<<1 | bit(c.modules[8][14-i])
+	}
+	format = format<<1 | bit(c.modules[8][7])
+	format = format<<1 | bit(c.modules[8][8])
+	format = format<<1 | bit(c.modules[7][8])
+	for i := 5; i >= 0; i-- {
+		format = format<<1 | bit(c.modules[i][8])
+	}
+	format ^= 0x5412
+	mask := format >> 10 & 7
+	if format>>13 != formatL {
+		t.Fatalf("format %015b is not level L", format)
+	}
+	check := newCode(version)
+	check.drawFormat(mask)
+	for i := range c.Size {
+		if check.function[8][i] && check.modules[8][i] != c.modules[8][i] || check.function[i][8] && check.modules[i][8] != c.modules[i][8] {
+			t.Fatalf("format information differs from that of mask %d", mask)
+		}
+	}
+
+	c.applyMask(mask)
+	defer c.applyMask(mask)
+	raw := make([]byte, rawModules(version)/8)
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range c.Size {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				if x := right - j; !c.function[y][x] && i < len(raw)*8 {
+					raw[i>>3] |= byte(bit(c.modules[y][x])) << (7 - i&7)
+					i++
+				}
+			}
+		}
+	}
+
+	blocks, eccLen := eccBlocks[version], eccPerBlock[version]
+	short := blocks - len(raw)%blocks
+	shortLen := len(raw) / blocks
+	split := make([][]byte, blocks)
+	k := 0
+	for i := range shortLen + 1 {
+		for j := range split {
+			// Short blocks have no codeword where the long ones have their last
+			// data codeword
+			if i != shortLen-eccLen || j >= short {
+				split[j] = append(split[j], raw[k])
+				k++
+			}
+		}
+	}
+	var data []byte
+	for _, block := range split {
+		n := len(block) - eccLen
+		if !bytes.Equal(rsRemainder(block[:n], rsDivisor(eccLen)), block[n:]) {
+			t.Fatalf("error correction codewords don't match the data")
+		}
+		data = append(data, block[:n]...)
+	}
+
+	if data[0]>>4 != 0b0100 {
+		t.Fatalf("mode %04b is not byte mode", data[0]>>4)
+	}
+	var bits bitBuffer
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	read := func(from, n int) int {
+		v := 0
+		for _, b := range bits[from : from+n] {
+			v = v<<1 | bit(b)
+		}
+		return v
+	}
+	n := read(4, countBits(version))
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(read(4+countBits(version)+8*i, 8))
+	}
+	return out
+}
+
+func bit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestEncode(t *testing.T) {
+	for _, tt := range []struct {
+		data    string
+		version int
+	}{
+		{"sshm", 1},
+		{"sshm://deploy@web1.example.com:2222?name=web1&group=prod&tag=web", 4},
+		{"sshm://" + strings.Repeat("x", 300), 11},
+		{strings.Repeat("q", 2900), 40},
+	} {
+		c, err := Encode([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("Encode(%d bytes) failed: %v", len(tt.data), err)
+		}
+		if got := (c.Size - 17) / 4; got != tt.version {
+			t.Errorf("Encode(%d bytes) gave version %d, want %d", len(tt.data), got, tt.version)
+		}
+		if got := decode(t, c); string(got) != tt.data {
+			t.Errorf("decoded %q, want %q", got, tt.data)
+		}
+	}
+
+	if _, err := Encode(make([]byte, 3000)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("expected ErrTooLong for 3000 bytes, got %v", err)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	c, err := Encode([]byte("sshm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := c.Blocks(2)
+	if len(lines) != (c.Size+4+1)/2 {
+		t.Fatalf("got %d lines, want %d", len(lines), (c.Size+4+1)/2)
+	}
+	// The quiet zone is light, the top left finder dark
+	if lines[0] != strings.Repeat("█", c.Size+4) {
+		t.Errorf("first line %q is not all light", lines[0])
+	}
+	if got := []rune(lines[1])[2]; got != ' ' {
+		t.Errorf("top left corner of the finder is %q, want dark", got)
+	}
+}
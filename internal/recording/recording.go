@@ -0,0 +1,325 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ext is the file extension of recordings
+const Ext = ".cast"
+
+// DefaultRedactWindow is how long after the start of a session password
+// prompts are redacted
+const DefaultRedactWindow = 30 * time.Second
+
+// Options control what a Recorder writes
+type Options struct {
+	// Input records keystrokes as well as output
+	Input bool
+	// RedactWindow is how long after the start password prompts are
+	// redacted; 0 takes DefaultRedactWindow and a negative value disables
+	// redaction
+	RedactWindow time.Duration
+}
+
+// Header is the first line of an asciicast v2 file
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// promptPattern matches the end of output asking for a secret
+var promptPattern = regexp.MustCompile(`(?i)(password|passphrase|passcode|verification code)[^\r\n]*[:?]\s*$`)
+
+// Recorder writes a terminal session as an asciicast v2 file: a header line
+// followed by one JSON array per event, [seconds, "o"|"i"|"r", data]
+// Within the redact window, whatever follows a password prompt is written as
+// asterisks until the next line, in both directions.
+type Recorder struct {
+	mu        sync.Mutex
+	w         io.Writer
+	closer    io.Closer
+	start     time.Time
+	opts      Options
+	tail      string // end of the current output line, to spot prompts
+	redacting bool
+	err       error
+}
+
+// Create starts a recording in a new file at path, creating its directory
+func Create(path string, width, height int, title string, opts Options) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start recording: %w", err)
+	}
+	r, err := NewRecorder(f, width, height, title, opts)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// NewRecorder writes the header to w and returns a recorder for the events
+func NewRecorder(w io.Writer, width, height int, title string, opts Options) (*Recorder, error) {
+	if opts.RedactWindow == 0 {
+		opts.RedactWindow = DefaultRedactWindow
+	}
+	r := &Recorder{w: w, start: time.Now(), opts: opts}
+	header := Header{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Output returns w with everything written to it also recorded as output
+func (r *Recorder) Output(w io.Writer) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		r.record("o", p)
+		return w.Write(p)
+	})
+}
+
+// Input returns rd with everything read from it also recorded as input,
+// when Options.Input is set
+func (r *Recorder) Input(rd io.Reader) io.Reader {
+	if !r.opts.Input {
+		return rd
+	}
+	return readerFunc(func(p []byte) (int, error) {
+		n, err := rd.Read(p)
+		if n > 0 {
+			r.record("i", p[:n])
+		}
+		return n, err
+	})
+}
+
+// Resize records a change of the terminal size
+func (r *Recorder) Resize(width, height int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.write("r", fmt.Sprintf("%dx%d", width, height))
+}
+
+// Close finishes the recording, returning the first write error if any
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closer != nil {
+		if err := r.closer.Close(); r.err == nil {
+			r.err = err
+		}
+		r.closer = nil
+	}
+	return r.err
+}
+
+func (r *Recorder) record(kind string, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := string(p)
+	if r.redacting {
+		data = r.redact(data)
+	}
+	r.write(kind, data)
+
+	if kind == "o" && r.opts.RedactWindow > 0 && time.Since(r.start) < r.opts.RedactWindow {
+		r.tail += string(p)
+		if i := strings.LastIndexAny(r.tail, "\r\n"); i >= 0 {
+			r.tail = r.tail[i+1:]
+		}
+		if len(r.tail) > 256 {
+			r.tail = r.tail[len(r.tail)-256:]
+		}
+		if promptPattern.MatchString(r.tail) {
+			r.redacting = true
+		}
+	}
+}
+
+// redact masks data up to the end of the line, which ends redaction
+func (r *Recorder) redact(data string) string {
+	end := strings.IndexAny(data, "\r\n")
+	if end < 0 {
+		return strings.Repeat("*", len(data))
+	}
+	r.redacting = false
+	r.tail = ""
+	return strings.Repeat("*", end) + data[end:]
+}
+
+func (r *Recorder) write(kind, data string) {
+	if r.err != nil {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+	event, err := json.Marshal([]interface{}{roundTime(elapsed), kind, data})
+	if err != nil {
+		r.err = err
+		return
+	}
+	if _, err := fmt.Fprintf(r.w, "%s\n", event); err != nil {
+		r.err = err
+	}
+}
+
+// roundTime keeps timestamps to microseconds like asciinema
+func roundTime(seconds float64) float64 {
+	return float64(int64(seconds*1e6)) / 1e6
+}
+
+// Event is one recorded event
+type Event struct {
+	Time float64
+	Kind string
+	Data string
+}
+
+// Read parses an asciicast v2 recording
+func Read(rd io.Reader) (Header, []Event, error) {
+	var header Header
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return header, nil, err
+		}
+		return header, nil, fmt.Errorf("empty recording")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return header, nil, fmt.Errorf("invalid recording header: %w", err)
+	}
+	if header.Version != 2 {
+		return header, nil, fmt.Errorf("unsupported asciicast version %d (only 2 is supported)", header.Version)
+	}
+
+	var events []Event
+	line := 1
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var raw []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &raw); err != nil || len(raw) != 3 {
+			return header, nil, fmt.Errorf("invalid event on line %d", line)
+		}
+		t, ok1 := raw[0].(float64)
+		kind, ok2 := raw[1].(string)
+		data, ok3 := raw[2].(string)
+		if !ok1 || !ok2 || !ok3 {
+			return header, nil, fmt.Errorf("invalid event on line %d", line)
+		}
+		events = append(events, Event{Time: t, Kind: kind, Data: data})
+	}
+	return header, events, scanner.Err()
+}
+
+// PlayOptions control playback speed
+type PlayOptions struct {
+	// Speed multiplies the playback speed; 0 means 1
+	Speed float64
+	// IdleLimit caps pauses between events; 0 keeps them as recorded
+	IdleLimit time.Duration
+}
+
+// Play writes the output events of a recording to w with their original
+// timing, adjusted by opts
+func Play(rd io.Reader, w io.Writer, opts PlayOptions) error {
+	_, events, err := Read(rd)
+	if err != nil {
+		return err
+	}
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+	last := 0.0
+	for _, e := range events {
+		if e.Kind != "o" {
+			continue
+		}
+		pause := time.Duration((e.Time - last) / speed * float64(time.Second))
+		if opts.IdleLimit > 0 && pause > opts.IdleLimit {
+			pause = opts.IdleLimit
+		}
+		if pause > 0 {
+			time.Sleep(pause)
+		}
+		last = e.Time
+		if _, err := io.WriteString(w, e.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Path returns where a recording of host started at t is saved in dir
+func Path(dir, host string, t time.Time) string {
+	return filepath.Join(dir, safeName(host), t.Format("20060102-150405")+Ext)
+}
+
+// Latest returns the newest recording of host in dir, or "" if there is
+// none
+func Latest(dir, host string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, safeName(host), "*"+Ext))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+	// Names sort by start time
+	sort.Strings(matches)
+	return matches[len(matches)-1], nil
+}
+
+// safeName makes a host name usable as a directory name
+func safeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
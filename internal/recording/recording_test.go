@@ -0,0 +1,154 @@
+package recording
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordAndRead(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewRecorder(&buf, 80, 24, "web1", Options{Input: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var screen bytes.Buffer
+	out := r.Output(&screen)
+	io.WriteString(out, "Last login: today\r\n")
+	io.WriteString(out, "[sudo] password for alice: ")
+	in, _ := io.ReadAll(r.Input(strings.NewReader("hunter2\r")))
+	io.WriteString(out, "\r\n$ ")
+	io.ReadAll(r.Input(strings.NewReader("ls\r")))
+	r.Resize(100, 30)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(in) != "hunter2\r" {
+		t.Errorf("input passed through as %q", in)
+	}
+	if !strings.HasSuffix(screen.String(), "$ ") {
+		t.Errorf("output passed through as %q", screen.String())
+	}
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("password was recorded:\n%s", buf.String())
+	}
+
+	header, events, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != 2 || header.Width != 80 || header.Height != 24 || header.Title != "web1" {
+		t.Errorf("unexpected header %+v", header)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Kind+":"+e.Data)
+	}
+	want := []string{
+		"o:Last login: today\r\n",
+		"o:[sudo] password for alice: ",
+		"i:*******\r",
+		"o:\r\n$ ",
+		"i:ls\r",
+		"r:100x30",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestRecordWithoutInput(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewRecorder(&buf, 80, 24, "", Options{RedactWindow: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(r.Output(io.Discard), "Password: ")
+	io.ReadAll(r.Input(strings.NewReader("secret\r")))
+	io.WriteString(r.Output(io.Discard), "ok\r\n")
+
+	_, events, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].Data != "ok\r\n" {
+		t.Errorf("expected only output events, got %+v", events)
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty", "", "empty recording"},
+		{"version", `{"version": 1, "width": 80, "height": 24}`, "unsupported asciicast version 1"},
+		{"event", "{\"version\": 2, \"width\": 80, \"height\": 24}\n[0.5, \"o\"]\n", "invalid event on line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Read(strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Read() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlay(t *testing.T) {
+	data := `{"version": 2, "width": 80, "height": 24}
+[0.1, "o", "hello "]
+[0.2, "i", "x"]
+[60.0, "o", "world"]
+`
+	var out bytes.Buffer
+	start := time.Now()
+	if err := Play(strings.NewReader(data), &out, PlayOptions{Speed: 10, IdleLimit: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "hello world" {
+		t.Errorf("played %q", out.String())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("idle limit not applied, took %s", elapsed)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	dir := t.TempDir()
+
+	if path, err := Latest(dir, "web1"); err != nil || path != "" {
+		t.Fatalf("Latest() = %q, %v without recordings", path, err)
+	}
+
+	older := Path(dir, "web1", time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC))
+	newer := Path(dir, "web1", time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC))
+	for _, path := range []string{newer, older} {
+		r, err := Create(path, 80, 24, "web1", Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+	}
+	if _, err := Create(newer, 80, 24, "web1", Options{}); err == nil {
+		t.Error("Create() overwrote an existing recording")
+	}
+	if info, err := os.Stat(newer); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("recording mode = %v, %v", info.Mode().Perm(), err)
+	}
+
+	path, err := Latest(dir, "web1")
+	if err != nil || path != newer {
+		t.Errorf("Latest() = %q, %v, want %q", path, err, newer)
+	}
+	if got := Path(dir, "a/b", time.Time{}); filepath.Dir(got) != filepath.Join(dir, "a_b") {
+		t.Errorf("Path() = %q, host name not made safe", got)
+	}
+}
@@ -0,0 +1,216 @@
+package secret
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/term"
+
+	"github.com/sshm/sshm/internal/keyring"
+)
+
+// Prefix marks an encrypted value in an otherwise plain text file
+const Prefix = "enc:v1:"
+
+// PassphraseEnv names the environment variable holding the passphrase
+const PassphraseEnv = "SSHM_PASSPHRASE"
+
+// KeyringAccount is the keyring account the passphrase is remembered under
+const KeyringAccount = "store-passphrase"
+
+// ErrLocked is returned when a passphrase is needed but none is available
+// without asking
+var ErrLocked = errors.New(`no passphrase for encrypted values (set $` + PassphraseEnv + ` or run "sshm secret remember")`)
+
+// ErrWrongPassphrase is returned when a value doesn't decrypt
+var ErrWrongPassphrase = errors.New("wrong passphrase for encrypted value")
+
+const (
+	saltSize = 16
+	keySize  = chacha20poly1305.KeySize
+)
+
+// IsEncrypted reports whether value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Encrypt seals plaintext with a key derived from passphrase and returns it
+// as Prefix followed by base64 of salt, nonce and ciphertext
+func Encrypt(plaintext string, passphrase []byte) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	aead, err := chacha20poly1305.NewX(deriveKey(passphrase, salt))
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	out := append(salt, nonce...)
+	out = aead.Seal(out, nonce, []byte(plaintext), nil)
+	return Prefix + base64.RawStdEncoding.EncodeToString(out), nil
+}
+
+// Decrypt opens a value produced by Encrypt
+func Decrypt(value string, passphrase []byte) (string, error) {
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("value is not encrypted")
+	}
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil || len(data) < saltSize+chacha20poly1305.NonceSizeX {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	salt, rest := data[:saltSize], data[saltSize:]
+	aead, err := chacha20poly1305.NewX(deriveKey(passphrase, salt))
+	if err != nil {
+		return "", err
+	}
+	nonce, sealed := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+	return string(plaintext), nil
+}
+
+// keys caches derived keys, since Argon2 is slow on purpose
+var keys sync.Map
+
+// deriveKey stretches passphrase into a key with Argon2id
+func deriveKey(passphrase, salt []byte) []byte {
+	id := sha256.Sum256(append(append([]byte{}, salt...), passphrase...))
+	if key, ok := keys.Load(id); ok {
+		return key.([]byte)
+	}
+	key := argon2.IDKey(passphrase, salt, 1, 64*1024, 4, keySize)
+	keys.Store(id, key)
+	return key
+}
+
+var (
+	mu     sync.Mutex
+	cached []byte
+
+	// prompting lets one goroutine at a time ask on the terminal, the others
+	// wait for its answer
+	prompting sync.Mutex
+)
+
+// SetPassphrase makes passphrase the one used for the rest of the process;
+// nil forgets it
+func SetPassphrase(passphrase []byte) {
+	mu.Lock()
+	defer mu.Unlock()
+	cached = passphrase
+}
+
+// Forget drops the passphrase in use and the keys derived from it, so the
+// next encrypted value needs it from $SSHM_PASSPHRASE, the keyring or the
+// terminal again
+func Forget() {
+	SetPassphrase(nil)
+	keys.Range(func(id, key interface{}) bool {
+		clear(key.([]byte))
+		keys.Delete(id)
+		return true
+	})
+}
+
+// StoredPassphrase returns the passphrase without asking for it: the one
+// already in use, $SSHM_PASSPHRASE or the one remembered in the keyring
+func StoredPassphrase() ([]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+	if p := os.Getenv(PassphraseEnv); p != "" {
+		cached = []byte(p)
+		return cached, nil
+	}
+	if p, err := keyring.Get(KeyringAccount); err == nil && p != "" {
+		cached = []byte(p)
+		return cached, nil
+	}
+	return nil, ErrLocked
+}
+
+// Passphrase returns the stored passphrase, see StoredPassphrase, or asks
+// for it on the terminal
+// Concurrent callers share a single prompt.
+func Passphrase() ([]byte, error) {
+	if p, err := StoredPassphrase(); err == nil {
+		return p, nil
+	}
+	prompting.Lock()
+	defer prompting.Unlock()
+	// Answered while waiting for the terminal
+	if p, err := StoredPassphrase(); err == nil {
+		return p, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, ErrLocked
+	}
+	fmt.Fprint(os.Stderr, "sshm passphrase: ")
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return nil, ErrLocked
+	}
+	SetPassphrase(p)
+	return p, nil
+}
+
+// Reveal returns value, decrypted when it is encrypted
+// A wrong passphrase is forgotten so the next attempt asks again.
+func Reveal(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	passphrase, err := Passphrase()
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := Decrypt(value, passphrase)
+	if errors.Is(err, ErrWrongPassphrase) {
+		SetPassphrase(nil)
+	}
+	return plaintext, err
+}
+
+// passwordAccount is the keyring account holding the password of a host
+func passwordAccount(hostID string) string {
+	return "password:" + hostID
+}
+
+// HostPassword returns the password saved in the keyring for a host
+func HostPassword(hostID string) (string, error) {
+	return keyring.Get(passwordAccount(hostID))
+}
+
+// SaveHostPassword saves the password of a host in the keyring
+func SaveHostPassword(hostID, password string) error {
+	return keyring.Set(passwordAccount(hostID), password)
+}
+
+// DeleteHostPassword removes the password of a host from the keyring
+func DeleteHostPassword(hostID string) error {
+	return keyring.Delete(passwordAccount(hostID))
+}
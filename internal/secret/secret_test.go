@@ -0,0 +1,45 @@
+package secret
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	sealed, err := Encrypt("hunter2", []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !IsEncrypted(sealed) || strings.Contains(sealed, "hunter2") {
+		t.Fatalf("unexpected encrypted value %q", sealed)
+	}
+	if again, _ := Encrypt("hunter2", []byte("correct horse")); again == sealed {
+		t.Error("expected a fresh salt and nonce for each value")
+	}
+
+	plain, err := Decrypt(sealed, []byte("correct horse"))
+	if err != nil || plain != "hunter2" {
+		t.Errorf("expected hunter2, got %q (%v)", plain, err)
+	}
+	if _, err := Decrypt(sealed, []byte("wrong")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+	if _, err := Decrypt(Prefix+"AAAA", []byte("correct horse")); err == nil {
+		t.Error("expected an error for a malformed value")
+	}
+}
+
+func TestReveal(t *testing.T) {
+	t.Setenv(PassphraseEnv, "correct horse")
+	SetPassphrase(nil)
+	t.Cleanup(func() { SetPassphrase(nil) })
+
+	if plain, err := Reveal("not encrypted"); err != nil || plain != "not encrypted" {
+		t.Errorf("expected plain values unchanged, got %q (%v)", plain, err)
+	}
+	sealed, _ := Encrypt("hunter2", []byte("correct horse"))
+	if plain, err := Reveal(sealed); err != nil || plain != "hunter2" {
+		t.Errorf("expected hunter2, got %q (%v)", plain, err)
+	}
+}
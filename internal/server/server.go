@@ -0,0 +1,366 @@
+// Package server exposes the host inventory over a local HTTP JSON API so
+// editors, launchers and dashboards can integrate with sshm
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/secret"
+	"github.com/sshm/sshm/internal/ssh"
+	"github.com/sshm/sshm/internal/store"
+)
+
+// Options configure the API server
+type Options struct {
+	// Token, when set, must be sent as "Authorization: Bearer <token>"
+	Token string
+	// AnyHost accepts every Host header; otherwise only loopback names and
+	// addresses are, so pages rebinding their DNS name get nothing
+	AnyHost bool
+	// HistoryPath is the connection history file ("" for the default)
+	HistoryPath string
+	// ProbeTimeout limits each reachability check (default 3s)
+	ProbeTimeout time.Duration
+	// ProbeConcurrency limits how many hosts are checked at once (default 20)
+	ProbeConcurrency int
+}
+
+// Server serves the API for the store at a path
+// The store is reopened for every request so changes made by other sshm
+// processes are picked up; writes are serialised.
+type Server struct {
+	storePath  string
+	configPath string
+	opts       Options
+	mu         sync.Mutex
+
+	// probe checks whether a host's SSH port answers
+	probe func(h models.Host, timeout time.Duration) error
+}
+
+// New creates a server for the store keeping hosts in storePath and its
+// settings (encrypted_fields, defaults, include) in configPath
+func New(storePath, configPath string, opts Options) *Server {
+	if opts.ProbeTimeout <= 0 {
+		opts.ProbeTimeout = 3 * time.Second
+	}
+	if opts.ProbeConcurrency <= 0 {
+		opts.ProbeConcurrency = 20
+	}
+	return &Server{
+		storePath:  storePath,
+		configPath: configPath,
+		opts:       opts,
+		probe: func(h models.Host, timeout time.Duration) error {
+			return ssh.PingTimeout(h.Host, h.Port, timeout)
+		},
+	}
+}
+
+// Handler returns the HTTP handler serving the API:
+//
+//	GET    /api/hosts                  list, filtered by q, tag, group, user or selector
+//	POST   /api/hosts                  add a host
+//	GET    /api/hosts/{name}           one host by exact name or ID
+//	DELETE /api/hosts/{name}           remove a host
+//	GET    /api/hosts/{name}/status    reachability and last connection of a host
+//	GET    /api/status                 the same for every host, or those of selector
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/hosts", s.listHosts)
+	mux.HandleFunc("POST /api/hosts", s.addHost)
+	mux.HandleFunc("GET /api/hosts/{name}", s.getHost)
+	mux.HandleFunc("DELETE /api/hosts/{name}", s.deleteHost)
+	mux.HandleFunc("GET /api/hosts/{name}/status", s.hostStatus)
+	mux.HandleFunc("GET /api/status", s.status)
+	return s.guard(s.authenticate(mux))
+}
+
+// guard rejects what browsers send on behalf of web pages: requests with an
+// Origin, Host names other than loopback ones (DNS rebinding) and writes
+// that aren't JSON, which pages can post without a preflight
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, errors.New("cross-origin requests are not allowed"))
+			return
+		}
+		if !s.opts.AnyHost && !loopbackHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not a loopback name or address", r.Host))
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodDelete {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, errors.New("the body must be sent as application/json"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopbackHost reports whether the Host header value names the local machine
+func loopbackHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authenticate rejects requests without the configured bearer token
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.opts.Token == "" {
+		return next
+	}
+	want := []byte("Bearer " + s.opts.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// open reads the store, failing when its file can't be read or parsed
+// Nobody is at the terminal to ask for the passphrase of encrypted fields.
+func (s *Server) open() (*store.FileStore, error) {
+	st, err := store.NewFileStoreWithConfig(s.storePath, s.configPath)
+	if err != nil {
+		return nil, err
+	}
+	st.SetPassphraseFunc(secret.StoredPassphrase)
+	return st, nil
+}
+
+func (s *Server) listHosts(w http.ResponseWriter, r *http.Request) {
+	hosts, code, err := s.selectHosts(r)
+	if err != nil {
+		writeError(w, code, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, redact(hosts))
+}
+
+// selectHosts applies the selector or search parameters of r, returning the
+// HTTP status to report on failure
+func (s *Server) selectHosts(r *http.Request) ([]models.Host, int, error) {
+	q := r.URL.Query()
+	st, err := s.open()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if expr := q.Get("selector"); expr != "" {
+		sel, err := store.ParseSelector(expr)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		hosts, err := sel.Select(st.ListHosts())
+		if err != nil {
+			return nil, statusFor(err), err
+		}
+		return hosts, http.StatusOK, nil
+	}
+	return st.FilterHosts(store.SearchFilter{
+		Query: q.Get("q"),
+		Tags:  q["tag"],
+		Group: q.Get("group"),
+		User:  q.Get("user"),
+	}), http.StatusOK, nil
+}
+
+func (s *Server) addHost(w http.ResponseWriter, r *http.Request) {
+	var host models.Host
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&host); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid host: %w", err))
+		return
+	}
+	if host.Port == 0 {
+		host.Port = 22
+	}
+	host.Online = nil
+	if err := host.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, err := s.open()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if _, err := st.GetHostByName(host.Name); err == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("host %q already exists", host.Name))
+		return
+	}
+	if err := st.AddHost(host); err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	added, _ := st.GetHostByName(host.Name)
+	writeJSON(w, http.StatusCreated, redact([]models.Host{added})[0])
+}
+
+func (s *Server) getHost(w http.ResponseWriter, r *http.Request) {
+	st, err := s.open()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	host, err := st.GetHostByName(r.PathValue("name"))
+	if err != nil {
+		writeError(w, statusFor(err), fmt.Errorf("host %q not found", r.PathValue("name")))
+		return
+	}
+	writeJSON(w, http.StatusOK, redact([]models.Host{host})[0])
+}
+
+func (s *Server) deleteHost(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, err := s.open()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	host, err := st.GetHostByName(r.PathValue("name"))
+	if err == nil {
+		err = st.DeleteHost(host.ID)
+	}
+	if err != nil {
+		writeError(w, statusFor(err), fmt.Errorf("host %q not found", r.PathValue("name")))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// hostState is the status of one host
+type hostState struct {
+	Name          string     `json:"name"`
+	Address       string     `json:"address"`
+	Reachable     bool       `json:"reachable"`
+	LatencyMs     int64      `json:"latency_ms,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	LastConnected *time.Time `json:"last_connected,omitempty"`
+	LastSuccess   *bool      `json:"last_success,omitempty"`
+	Connections   int        `json:"connections"`
+}
+
+func (s *Server) hostStatus(w http.ResponseWriter, r *http.Request) {
+	st, err := s.open()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	host, err := st.GetHostByName(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("host %q not found", r.PathValue("name")))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.states([]models.Host{host})[0])
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	hosts, code, err := s.selectHosts(r)
+	if err != nil {
+		writeError(w, code, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.states(hosts))
+}
+
+// states probes the hosts concurrently and adds their connection history
+func (s *Server) states(hosts []models.Host) []hostState {
+	history := store.NewHistoryStore(s.opts.HistoryPath)
+	states := make([]hostState, len(hosts))
+	sem := make(chan struct{}, s.opts.ProbeConcurrency)
+	var wg sync.WaitGroup
+
+	for i, h := range hosts {
+		state := hostState{Name: h.Name, Address: net.JoinHostPort(h.Host, strconv.Itoa(h.Port))}
+		entries := history.GetHistoryForHost(h.ID)
+		state.Connections = len(entries)
+		for _, e := range entries {
+			if state.LastConnected == nil || e.Timestamp.After(*state.LastConnected) {
+				ts, ok := e.Timestamp, e.Success
+				state.LastConnected, state.LastSuccess = &ts, &ok
+			}
+		}
+		states[i] = state
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, h models.Host) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			start := time.Now()
+			if err := s.probe(h, s.opts.ProbeTimeout); err != nil {
+				states[i].Error = err.Error()
+				return
+			}
+			states[i].Reachable = true
+			states[i].LatencyMs = time.Since(start).Milliseconds()
+		}(i, h)
+	}
+	wg.Wait()
+	return states
+}
+
+// redact drops passwords and transient fields from hosts sent to clients
+func redact(hosts []models.Host) []models.Host {
+	result := make([]models.Host, len(hosts))
+	for i, h := range hosts {
+		h.Password = ""
+		h.Online = nil
+		result[i] = h
+	}
+	return result
+}
+
+// statusFor maps store errors to HTTP status codes
+func statusFor(err error) int {
+	var ambiguous *store.AmbiguousError
+	switch {
+	case errors.Is(err, store.ErrHostNotFound), errors.Is(err, store.ErrNoMatch):
+		return http.StatusNotFound
+	case errors.Is(err, store.ErrHostExists):
+		return http.StatusConflict
+	case errors.As(err, &ambiguous):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
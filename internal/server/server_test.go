@@ -0,0 +1,231 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/secret"
+	"github.com/sshm/sshm/internal/store"
+)
+
+// openTestStore opens the store at path, failing the test on errors
+func openTestStore(t *testing.T, path string) *store.FileStore {
+	t.Helper()
+	s, err := store.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	return s
+}
+
+func newTestServer(t *testing.T, token string) (*httptest.Server, string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts.json")
+	s := openTestStore(t, path)
+	s.AddHost(models.Host{Name: "web1", Host: "10.0.0.1", Port: 22, Password: "secret", Tags: []string{"web", "prod"}})
+	s.AddHost(models.Host{Name: "db1", Host: "10.0.0.2", Port: 22, Group: "eu", Tags: []string{"db"}})
+
+	srv := New(path, path, Options{Token: token, HistoryPath: filepath.Join(dir, "history.json")})
+	srv.probe = func(h models.Host, timeout time.Duration) error {
+		if h.Name == "db1" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts, path
+}
+
+func do(t *testing.T, method, url, body string, v interface{}) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		json.NewDecoder(resp.Body).Decode(v)
+	}
+	return resp.StatusCode
+}
+
+func TestHostsEndpoints(t *testing.T) {
+	ts, path := newTestServer(t, "")
+
+	var hosts []models.Host
+	if code := do(t, "GET", ts.URL+"/api/hosts?tag=prod", "", &hosts); code != 200 || len(hosts) != 1 || hosts[0].Name != "web1" {
+		t.Fatalf("GET /api/hosts?tag=prod = %d %v", code, hosts)
+	}
+	if hosts[0].Password != "" {
+		t.Error("expected the password to be redacted")
+	}
+	if code := do(t, "GET", ts.URL+"/api/hosts?selector=tag:db+AND+group:eu", "", &hosts); code != 200 || len(hosts) != 1 || hosts[0].Name != "db1" {
+		t.Errorf("selector query = %d %v", code, hosts)
+	}
+	if code := do(t, "GET", ts.URL+"/api/hosts?selector=(tag:db", "", nil); code != http.StatusBadRequest {
+		t.Errorf("expected a bad selector to be a 400, got %d", code)
+	}
+
+	var added models.Host
+	if code := do(t, "POST", ts.URL+"/api/hosts", `{"name":"cache1","host":"10.0.0.3","user":"redis"}`, &added); code != http.StatusCreated || added.ID == "" || added.Port != 22 {
+		t.Errorf("POST /api/hosts = %d %+v", code, added)
+	}
+	if code := do(t, "POST", ts.URL+"/api/hosts", `{"name":"cache1","host":"10.0.0.4"}`, nil); code != http.StatusConflict {
+		t.Errorf("expected a duplicate name to be a 409, got %d", code)
+	}
+	if code := do(t, "POST", ts.URL+"/api/hosts", `{"name":"x","hostname":"10.0.0.4"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected unknown fields to be a 400, got %d", code)
+	}
+
+	if code := do(t, "DELETE", ts.URL+"/api/hosts/web1", "", nil); code != http.StatusNoContent {
+		t.Errorf("DELETE = %d", code)
+	}
+	if code := do(t, "GET", ts.URL+"/api/hosts/web1", "", nil); code != http.StatusNotFound {
+		t.Errorf("expected a deleted host to be a 404, got %d", code)
+	}
+	if openTestStore(t, path).Count() != 2 {
+		t.Error("expected the changes to be saved")
+	}
+}
+
+func TestDamagedStore(t *testing.T) {
+	ts, path := newTestServer(t, "")
+	damaged := `{"hosts": [{"name": "web1"`
+	if err := os.WriteFile(path, []byte(damaged), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := do(t, "GET", ts.URL+"/api/hosts", "", nil); code != http.StatusInternalServerError {
+		t.Errorf("expected listing a damaged store to be a 500, got %d", code)
+	}
+	if code := do(t, "POST", ts.URL+"/api/hosts", `{"name":"cache1","host":"10.0.0.3"}`, nil); code != http.StatusInternalServerError {
+		t.Errorf("expected adding to a damaged store to be a 500, got %d", code)
+	}
+	if data, _ := os.ReadFile(path); string(data) != damaged {
+		t.Errorf("damaged file was changed to %q", data)
+	}
+}
+
+func TestSeparateConfig(t *testing.T) {
+	dir := t.TempDir()
+	hostsPath, configPath := filepath.Join(dir, "hosts.json"), filepath.Join(dir, "config.yaml")
+	settings := "encrypted_fields: [password]\ndefaults:\n  user: deploy\n"
+	if err := os.WriteFile(configPath, []byte(settings), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(secret.PassphraseEnv, "correct horse")
+	defer secret.Forget()
+
+	ts := httptest.NewServer(New(hostsPath, configPath, Options{HistoryPath: filepath.Join(dir, "history.json")}).Handler())
+	defer ts.Close()
+
+	var added models.Host
+	if code := do(t, "POST", ts.URL+"/api/hosts", `{"name":"web1","host":"10.0.0.1","password":"hunter2"}`, &added); code != http.StatusCreated {
+		t.Fatalf("POST /api/hosts = %d", code)
+	}
+	if added.User != "deploy" {
+		t.Errorf("expected the default user, got %q", added.User)
+	}
+	data, err := os.ReadFile(hostsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") || !strings.Contains(string(data), secret.Prefix) {
+		t.Errorf("expected the password to be saved encrypted, got %s", data)
+	}
+	if strings.Contains(string(data), "deploy") {
+		t.Errorf("expected the default user to be left out of the hosts file, got %s", data)
+	}
+}
+
+func TestStatusEndpoint(t *testing.T) {
+	ts, _ := newTestServer(t, "")
+
+	var states []hostState
+	if code := do(t, "GET", ts.URL+"/api/status", "", &states); code != 200 || len(states) != 2 {
+		t.Fatalf("GET /api/status = %d %v", code, states)
+	}
+	for _, s := range states {
+		if want := s.Name == "web1"; s.Reachable != want {
+			t.Errorf("%s: reachable = %v, want %v", s.Name, s.Reachable, want)
+		}
+	}
+
+	var state hostState
+	if code := do(t, "GET", ts.URL+"/api/hosts/db1/status", "", &state); code != 200 || state.Error != "connection refused" {
+		t.Errorf("GET /api/hosts/db1/status = %d %+v", code, state)
+	}
+}
+
+func TestToken(t *testing.T) {
+	ts, _ := newTestServer(t, "s3cret")
+
+	if code := do(t, "GET", ts.URL+"/api/hosts", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("expected a request without token to be a 401, got %d", code)
+	}
+	req, _ := http.NewRequest("GET", ts.URL+"/api/hosts", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("expected the token to be accepted, got %d", resp.StatusCode)
+	}
+}
+
+func TestBrowserRequests(t *testing.T) {
+	ts, path := newTestServer(t, "")
+	send := func(method, body string, header http.Header, host string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+"/api/hosts", strings.NewReader(body))
+		req.Header = header
+		if host != "" {
+			req.Host = host
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	body := `{"name":"cache1","host":"10.0.0.3"}`
+
+	// A form or fetch posting text/plain needs no preflight
+	if code := send("POST", body, http.Header{"Content-Type": {"text/plain"}}, ""); code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected a text/plain POST to be a 415, got %d", code)
+	}
+	if code := send("GET", "", http.Header{"Origin": {"https://example.com"}}, ""); code != http.StatusForbidden {
+		t.Errorf("expected a request with an Origin to be a 403, got %d", code)
+	}
+	if code := send("GET", "", http.Header{}, "rebound.example.com:7422"); code != http.StatusForbidden {
+		t.Errorf("expected a foreign Host to be a 403, got %d", code)
+	}
+	for _, host := range []string{"localhost:7422", "127.0.0.1", "[::1]:7422"} {
+		if code := send("GET", "", http.Header{}, host); code != http.StatusOK {
+			t.Errorf("expected Host %s to be accepted, got %d", host, code)
+		}
+	}
+	if code := send("POST", body, http.Header{"Content-Type": {"application/json; charset=utf-8"}}, ""); code != http.StatusCreated {
+		t.Errorf("expected a JSON POST to be accepted, got %d", code)
+	}
+	if openTestStore(t, path).Count() != 3 {
+		t.Error("expected only the JSON POST to add a host")
+	}
+}
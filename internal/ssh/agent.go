@@ -0,0 +1,66 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// AgentStatus describes the state of the local SSH agent
+type AgentStatus struct {
+	Available bool  // Agent socket is reachable
+	Keys      int   // Number of identities held by the agent
+	Err       error // Reason the agent is unavailable, if any
+}
+
+// String returns a short human readable summary of the agent status
+func (s AgentStatus) String() string {
+	if !s.Available {
+		return "agent: unavailable"
+	}
+	if s.Keys == 1 {
+		return "agent: 1 key"
+	}
+	return fmt.Sprintf("agent: %d keys", s.Keys)
+}
+
+// CheckAgent reports whether an SSH agent is reachable via SSH_AUTH_SOCK
+// and how many keys it currently holds
+func CheckAgent() AgentStatus {
+	var keys []*agent.Key
+	err := withAgent(func(a agent.ExtendedAgent) error {
+		var err error
+		keys, err = a.List()
+		return err
+	})
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, errNoAgent):
+		return AgentStatus{Err: err}
+	case errors.As(err, &opErr):
+		return AgentStatus{Err: fmt.Errorf("cannot reach agent: %w", err)}
+	case err != nil:
+		return AgentStatus{Err: fmt.Errorf("failed to list agent keys: %w", err)}
+	}
+
+	return AgentStatus{Available: true, Keys: len(keys)}
+}
+
+// IdentityExists returns whether the given identity file exists on disk
+// An empty path is treated as existing since default keys will be used
+func IdentityExists(path string) bool {
+	if path == "" {
+		return true
+	}
+
+	expandedPath, err := expandPath(path)
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(expandedPath)
+	return err == nil
+}
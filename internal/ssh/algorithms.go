@@ -0,0 +1,221 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// minRSABits is the smallest RSA key size not considered weak
+const minRSABits = 2048
+
+// Algorithms OpenSSH has removed or disables by default
+var (
+	deprecatedKex = map[string]bool{
+		"diffie-hellman-group1-sha1":         true,
+		"diffie-hellman-group14-sha1":        true,
+		"diffie-hellman-group-exchange-sha1": true,
+	}
+	deprecatedCiphers = map[string]bool{
+		"3des-cbc":                    true,
+		"aes128-cbc":                  true,
+		"aes192-cbc":                  true,
+		"aes256-cbc":                  true,
+		"rijndael-cbc@lysator.liu.se": true,
+		"blowfish-cbc":                true,
+		"cast128-cbc":                 true,
+		"arcfour":                     true,
+		"arcfour128":                  true,
+		"arcfour256":                  true,
+		"none":                        true,
+	}
+	deprecatedHostKeys = map[string]bool{
+		ssh.KeyAlgoDSA: true,
+		// ssh-rsa signs with SHA-1; servers with RSA keys offer rsa-sha2-* too
+		ssh.KeyAlgoRSA:     true,
+		ssh.CertAlgoDSAv01: true,
+		ssh.CertAlgoRSAv01: true,
+	}
+)
+
+// Weakness returns why the key is too weak to keep using, or "" when it
+// isn't
+func (k KeyInfo) Weakness() string {
+	return keyWeakness(k.Type, k.Bits)
+}
+
+// keyWeakness flags DSA keys and RSA keys under minRSABits
+func keyWeakness(keyType string, bits int) string {
+	switch {
+	case keyType == ssh.KeyAlgoDSA:
+		return "DSA key, deprecated and refused by current OpenSSH"
+	case keyType == ssh.KeyAlgoRSA && bits > 0 && bits < minRSABits:
+		return fmt.Sprintf("%d-bit RSA key, use at least %d bits", bits, minRSABits)
+	}
+	return ""
+}
+
+// ServerAlgorithms are the algorithms a server offers in its key exchange
+type ServerAlgorithms struct {
+	Kex      []string
+	HostKeys []string
+	Ciphers  []string // client to server and server to client, without repeats
+}
+
+// Warnings returns a warning for each kind of algorithm the server only
+// offers deprecated ones of
+func (a ServerAlgorithms) Warnings() []string {
+	var warnings []string
+	check := func(kind string, offered []string, deprecated map[string]bool) {
+		var names []string
+		for _, name := range offered {
+			if kexExtension(name) {
+				continue
+			}
+			if !deprecated[name] {
+				return
+			}
+			names = append(names, name)
+		}
+		if len(names) > 0 {
+			warnings = append(warnings, fmt.Sprintf("server offers only deprecated %s (%s)", kind, strings.Join(names, ", ")))
+		}
+	}
+	check("key exchanges", a.Kex, deprecatedKex)
+	check("host key algorithms", a.HostKeys, deprecatedHostKeys)
+	check("ciphers", a.Ciphers, deprecatedCiphers)
+	return warnings
+}
+
+// kexExtension reports whether name is an extension signalled in the key
+// exchange list rather than a method, like ext-info-s
+func kexExtension(name string) bool {
+	return strings.HasPrefix(name, "ext-info-") || strings.HasPrefix(name, "kex-strict-")
+}
+
+// maxKexInitSize bounds what algorithmConn keeps while looking for the
+// server's key exchange init, version and banner lines included
+const maxKexInitSize = 64 * 1024
+
+// msgKexInit is the SSH_MSG_KEXINIT message number
+const msgKexInit = 20
+
+// errIncomplete is returned by parseServerKexInit until it has enough data
+var errIncomplete = errors.New("incomplete")
+
+// algorithmConn is a net.Conn noting the algorithms in the server's first,
+// unencrypted key exchange init as the handshake reads it
+type algorithmConn struct {
+	net.Conn
+
+	mu         sync.Mutex
+	data       []byte
+	done       bool
+	algorithms *ServerAlgorithms
+}
+
+// newAlgorithmConn wraps conn to note the algorithms the server offers
+func newAlgorithmConn(conn net.Conn) *algorithmConn {
+	return &algorithmConn{Conn: conn}
+}
+
+// Read reads from the connection, keeping the data until the key exchange
+// init is complete
+func (c *algorithmConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done || n == 0 {
+		return n, err
+	}
+	c.data = append(c.data, p[:n]...)
+	algorithms, parseErr := parseServerKexInit(c.data)
+	switch {
+	case parseErr == nil:
+		c.algorithms = &algorithms
+	case errors.Is(parseErr, errIncomplete) && len(c.data) < maxKexInitSize:
+		return n, err
+	}
+	c.done = true
+	c.data = nil
+	return n, err
+}
+
+// Algorithms returns the algorithms the server offered, if they were seen
+func (c *algorithmConn) Algorithms() (ServerAlgorithms, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.algorithms == nil {
+		return ServerAlgorithms{}, false
+	}
+	return *c.algorithms, true
+}
+
+// parseServerKexInit reads the algorithms from the start of what a server
+// sends: optional banner lines, the version line and the key exchange init
+// packet (RFC 4253 sections 4.2, 6 and 7.1)
+func parseServerKexInit(data []byte) (ServerAlgorithms, error) {
+	for {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			return ServerAlgorithms{}, errIncomplete
+		}
+		line := data[:end]
+		data = data[end+1:]
+		if bytes.HasPrefix(line, []byte("SSH-")) {
+			break
+		}
+	}
+
+	if len(data) < 5 {
+		return ServerAlgorithms{}, errIncomplete
+	}
+	length := binary.BigEndian.Uint32(data)
+	if length < 2 || length > maxKexInitSize {
+		return ServerAlgorithms{}, fmt.Errorf("invalid packet length %d", length)
+	}
+	if uint32(len(data)-4) < length {
+		return ServerAlgorithms{}, errIncomplete
+	}
+	padding := uint32(data[4])
+	if padding >= length {
+		return ServerAlgorithms{}, fmt.Errorf("invalid padding length %d", padding)
+	}
+	payload := data[5 : 4+length-padding]
+	if len(payload) < 17 || payload[0] != msgKexInit {
+		return ServerAlgorithms{}, errors.New("first packet is not a key exchange init")
+	}
+
+	// Message number and cookie, then the name-lists
+	rest := payload[17:]
+	var lists [4][]string
+	for i := range lists {
+		if len(rest) < 4 {
+			return ServerAlgorithms{}, errors.New("truncated key exchange init")
+		}
+		n := binary.BigEndian.Uint32(rest)
+		if uint32(len(rest)-4) < n {
+			return ServerAlgorithms{}, errors.New("truncated key exchange init")
+		}
+		if n > 0 {
+			lists[i] = strings.Split(string(rest[4:4+n]), ",")
+		}
+		rest = rest[4+n:]
+	}
+
+	algorithms := ServerAlgorithms{Kex: lists[0], HostKeys: lists[1]}
+	seen := make(map[string]bool)
+	for _, name := range append(lists[2], lists[3]...) {
+		if !seen[name] {
+			seen[name] = true
+			algorithms.Ciphers = append(algorithms.Ciphers, name)
+		}
+	}
+	return algorithms, nil
+}
@@ -1,11 +1,12 @@
 package ssh
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
-	"os/signal"
 	"os/user"
 	"path/filepath"
 	"strconv"
@@ -14,11 +15,15 @@ import (
 	"time"
 
 	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/secret"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/term"
 )
 
+// errPasswordRequired is returned when password auth is selected without a password
+var errPasswordRequired = errors.New("password auth selected but no password set")
+
 // AuthMethod represents the authentication method for SSH
 type AuthMethod int
 
@@ -27,12 +32,23 @@ const (
 	AuthMethodPassword
 	AuthMethodKeyFile
 	AuthMethodSSHAgent
+	AuthMethodVaultCert
+	AuthMethodVaultOTP
 )
 
 // Connector handles SSH connections
 type Connector struct {
-	client *ssh.Client
-	config *ssh.ClientConfig
+	client       *ssh.Client
+	config       *ssh.ClientConfig
+	proxy        *ssh.Client   // jump host connection carrying client, if any
+	forwardAgent bool          // sessions request agent forwarding
+	done         chan struct{} // closed by Close to stop keep-alives
+
+	noPrompt      bool   // never ask for passwords on the terminal
+	typedPassword string // password asked for on the terminal, if any
+
+	warnings       []string               // weak keys and algorithms seen while connecting
+	hostKeyChanges []models.HostKeyChange // changed host keys met while connecting
 }
 
 // NewConnector creates a new SSH connector
@@ -41,21 +57,68 @@ func NewConnector() *Connector {
 }
 
 // Connect establishes an SSH connection to the host
+// The profile's identity file stands in for a missing host identity, its
+// proxy command is used for hosts without a jump host, and its keep-alive
+// and agent forwarding settings apply to the connection.
 func (c *Connector) Connect(host models.Host, profile models.Profile) error {
+	c.warnings = nil
+	c.hostKeyChanges = nil
+	if host.Identity == "" {
+		host.Identity = profile.IdentityFile
+	}
 	config, err := c.buildClientConfig(host, profile)
 	if err != nil {
 		return fmt.Errorf("failed to build client config: %w", err)
 	}
 
-	// Handle ProxyJump connection
-	if host.Proxy != "" {
-		return c.connectViaProxy(host, profile, config)
+	switch {
+	case host.Proxy != "":
+		// Handle ProxyJump connection
+		err = c.connectViaProxy(host, profile, config)
+	case profile.ProxyCommand != "":
+		err = c.connectViaCommand(host, profile, config)
+	default:
+		addr := net.JoinHostPort(host.Host, strconv.Itoa(host.Port))
+		var client *ssh.Client
+		client, err = c.dial(addr, config, "")
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", addr, err)
+		}
+		c.client = client
+		c.config = config
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.typedPassword != "" {
+		offerToSavePassword(host, c.typedPassword)
+		c.typedPassword = ""
 	}
 
-	addr := fmt.Sprintf("%s:%d", host.Host, host.Port)
-	client, err := ssh.Dial("tcp", addr, config)
+	if profile.ForwardAgent {
+		c.setupAgentForwarding()
+	}
+	if profile.ServerAliveEnabled && profile.KeepAliveInterval > 0 {
+		c.done = make(chan struct{})
+		go c.keepAlive(time.Duration(profile.KeepAliveInterval)*time.Second, profile.KeepAliveCountMax)
+	}
+	return nil
+}
+
+// connectViaCommand connects to the host over the stdin and stdout of the
+// profile's proxy command
+func (c *Connector) connectViaCommand(host models.Host, profile models.Profile, config *ssh.ClientConfig) error {
+	conn, err := dialProxyCommand(expandProxyCommand(profile.ProxyCommand, host.Host, host.Port, host.User))
 	if err != nil {
-		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+		return err
+	}
+
+	addr := net.JoinHostPort(host.Host, strconv.Itoa(host.Port))
+	client, err := c.handshake(conn, addr, config, "")
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to %s via proxy command: %w", addr, err)
 	}
 
 	c.client = client
@@ -63,6 +126,62 @@ func (c *Connector) Connect(host models.Host, profile models.Profile) error {
 	return nil
 }
 
+// setupAgentForwarding serves agent requests from the host with the local
+// agent; without a local agent there is nothing to forward
+func (c *Connector) setupAgentForwarding() {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return
+	}
+	if err := agent.ForwardToRemote(c.client, socket); err == nil {
+		c.forwardAgent = true
+	}
+}
+
+// newSession opens a session, requesting agent forwarding when enabled
+func (c *Connector) newSession() (*ssh.Session, error) {
+	session, err := c.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	if c.forwardAgent {
+		if err := agent.RequestAgentForwarding(session); err != nil {
+			session.Close()
+			return nil, fmt.Errorf("agent forwarding request failed: %w", err)
+		}
+	}
+	return session, nil
+}
+
+// keepAlive sends a keep-alive request every interval and closes the
+// connection after countMax unanswered requests in a row
+func (c *Connector) keepAlive(interval time.Duration, countMax int) {
+	if countMax <= 0 {
+		countMax = 3
+	}
+	client, done := c.client, c.done
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				missed++
+				if missed >= countMax {
+					client.Close()
+					return
+				}
+				continue
+			}
+			missed = 0
+		}
+	}
+}
+
 // connectViaProxy connects to the target host via a jump proxy
 func (c *Connector) connectViaProxy(host models.Host, profile models.Profile, config *ssh.ClientConfig) error {
 	// Parse proxy host (supports user@host:port format)
@@ -72,35 +191,40 @@ func (c *Connector) connectViaProxy(host models.Host, profile models.Profile, co
 	}
 
 	// Connect to proxy first
-	proxyAddr := fmt.Sprintf("%s:%d", proxyHost, proxyPort)
+	proxyAddr := net.JoinHostPort(proxyHost, strconv.Itoa(proxyPort))
 	proxyConfig := *config
 	proxyConfig.User = proxyUser
+	proxyConfig.HostKeyCallback = hostKeyCallback(host.HostKeyPolicy, c.approveHostKey(proxyHost))
 
-	proxyClient, err := ssh.Dial("tcp", proxyAddr, &proxyConfig)
+	proxyClient, err := c.dial(proxyAddr, &proxyConfig, "jump host "+proxyHost)
 	if err != nil {
 		return fmt.Errorf("failed to connect to proxy %s: %w", proxyAddr, err)
 	}
-	defer proxyClient.Close()
 
 	// Create a channel to the target host through the proxy
-	targetAddr := fmt.Sprintf("%s:%d", host.Host, host.Port)
+	targetAddr := net.JoinHostPort(host.Host, strconv.Itoa(host.Port))
 	client, err := proxyClient.Dial("tcp", targetAddr)
 	if err != nil {
+		proxyClient.Close()
 		return fmt.Errorf("failed to connect to target %s via proxy: %w", targetAddr, err)
 	}
 
 	// Establish the SSH connection through the proxy
-	conn, chans, reqs, err := ssh.NewClientConn(client, targetAddr, config)
+	targetClient, err := c.handshake(client, targetAddr, config, "")
 	if err != nil {
+		proxyClient.Close()
 		return fmt.Errorf("failed to establish SSH connection via proxy: %w", err)
 	}
 
-	c.client = ssh.NewClient(conn, chans, reqs)
+	// The proxy connection carries the tunnel and must stay open until Close
+	c.proxy = proxyClient
+	c.client = targetClient
 	c.config = config
 	return nil
 }
 
 // parseProxyHost parses a proxy host string in format [user@]host[:port]
+// IPv6 addresses with a port are bracketed: [2001:db8::1]:2222
 func parseProxyHost(proxy string) (host, user string, port int, err error) {
 	port = 22 // default port
 
@@ -111,14 +235,14 @@ func parseProxyHost(proxy string) (host, user string, port int, err error) {
 	}
 
 	// Extract port if present
-	if idx := strings.LastIndex(proxy, ":"); idx != -1 {
-		port, err = strconv.Atoi(proxy[idx+1:])
+	if h, p, splitErr := net.SplitHostPort(proxy); splitErr == nil {
+		port, err = strconv.Atoi(p)
 		if err != nil {
 			return "", "", 0, fmt.Errorf("invalid port: %w", err)
 		}
-		host = proxy[:idx]
+		host = h
 	} else {
-		host = proxy
+		host = strings.TrimSuffix(strings.TrimPrefix(proxy, "["), "]")
 	}
 
 	return host, user, port, nil
@@ -131,8 +255,8 @@ func (c *Connector) ConnectWithAuth(host models.Host, profile models.Profile, au
 		return fmt.Errorf("failed to build client config: %w", err)
 	}
 
-	addr := fmt.Sprintf("%s:%d", host.Host, host.Port)
-	client, err := ssh.Dial("tcp", addr, config)
+	addr := net.JoinHostPort(host.Host, strconv.Itoa(host.Port))
+	client, err := c.dial(addr, config, "")
 	if err != nil {
 		return fmt.Errorf("failed to connect to %s: %w", addr, err)
 	}
@@ -142,6 +266,57 @@ func (c *Connector) ConnectWithAuth(host models.Host, profile models.Profile, au
 	return nil
 }
 
+// dial connects to addr over TCP like ssh.Dial, noting weak algorithms
+// offered by the server under the given name ("" for the host itself)
+func (c *Connector) dial(addr string, config *ssh.ClientConfig, name string) (*ssh.Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return c.handshake(conn, addr, config, name)
+}
+
+// handshake establishes an SSH connection over conn and notes the
+// deprecated algorithms the server offers, even when the handshake fails
+// for lack of a common one
+// The key types known_hosts holds for addr are asked for first, so a host
+// with several keys isn't taken for one whose key changed.
+func (c *Connector) handshake(conn net.Conn, addr string, config *ssh.ClientConfig, name string) (*ssh.Client, error) {
+	if config.HostKeyAlgorithms == nil {
+		if algorithms := hostKeyAlgorithms(addr); algorithms != nil {
+			withAlgorithms := *config
+			withAlgorithms.HostKeyAlgorithms = algorithms
+			config = &withAlgorithms
+		}
+	}
+	recorder := newAlgorithmConn(conn)
+	sshConn, chans, reqs, err := ssh.NewClientConn(recorder, addr, config)
+	if algorithms, ok := recorder.Algorithms(); ok {
+		for _, warning := range algorithms.Warnings() {
+			if name != "" {
+				warning = name + ": " + warning
+			}
+			c.warnings = append(c.warnings, warning)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// HostKeyChanges returns the changed host keys met by the last Connect,
+// with whether they were approved
+func (c *Connector) HostKeyChanges() []models.HostKeyChange {
+	return c.hostKeyChanges
+}
+
+// Warnings returns the weak client keys and deprecated server algorithms
+// seen by the last Connect, also after it failed
+func (c *Connector) Warnings() []string {
+	return c.warnings
+}
+
 // buildClientConfig builds SSH client configuration based on host's AuthType
 func (c *Connector) buildClientConfig(host models.Host, profile models.Profile) (*ssh.ClientConfig, error) {
 	// Use the host's AuthType if specified
@@ -149,11 +324,11 @@ func (c *Connector) buildClientConfig(host models.Host, profile models.Profile)
 
 	switch authType {
 	case string(models.AuthTypePassword):
-		if host.Password != "" {
+		if host.Password != "" || KeyringPasswords {
 			return c.buildClientConfigWithAuth(host, profile, AuthMethodPassword)
 		}
 		// Fall through to try other methods if no password
-		return nil, fmt.Errorf("password auth selected but no password set")
+		return nil, errPasswordRequired
 
 	case string(models.AuthTypeKey):
 		if host.Identity != "" {
@@ -165,6 +340,12 @@ func (c *Connector) buildClientConfig(host models.Host, profile models.Profile)
 	case string(models.AuthTypeAgent):
 		return c.buildClientConfigWithAuth(host, profile, AuthMethodSSHAgent)
 
+	case string(models.AuthTypeVaultCert):
+		return c.buildClientConfigWithAuth(host, profile, AuthMethodVaultCert)
+
+	case string(models.AuthTypeVaultOTP):
+		return c.buildClientConfigWithAuth(host, profile, AuthMethodVaultOTP)
+
 	default:
 		// Legacy behavior: try all methods
 		methods := []AuthMethod{AuthMethodPassword, AuthMethodSSHAgent, AuthMethodKeyFile}
@@ -184,13 +365,17 @@ func (c *Connector) buildClientConfigWithAuth(host models.Host, profile models.P
 	config := &ssh.ClientConfig{
 		User:            host.User,
 		Auth:            []ssh.AuthMethod{},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKeyCallback(host.HostKeyPolicy, c.approveHostKey(host.Name)),
 		Timeout:         time.Duration(profile.Timeout) * time.Second,
 	}
 
 	switch auth {
 	case AuthMethodPassword:
-		if err := c.addPasswordAuth(config, host.Password); err != nil {
+		if host.Password == "" && KeyringPasswords && host.AuthType == models.AuthTypePassword {
+			if err := c.addKeyringPasswordAuth(config, host); err != nil {
+				return nil, err
+			}
+		} else if err := c.addPasswordAuth(config, host.Password); err != nil {
 			return nil, err
 		}
 
@@ -206,6 +391,16 @@ func (c *Connector) buildClientConfigWithAuth(host models.Host, profile models.P
 			}
 		}
 
+	case AuthMethodVaultCert:
+		if err := c.addVaultCertAuth(config, host); err != nil {
+			return nil, err
+		}
+
+	case AuthMethodVaultOTP:
+		if err := c.addVaultOTPAuth(config, host); err != nil {
+			return nil, err
+		}
+
 	case AuthMethodKeyFile, AuthMethodNone:
 		if host.Identity != "" {
 			if err := c.addKeyFileAuth(config, host.Identity); err != nil {
@@ -222,30 +417,21 @@ func (c *Connector) buildClientConfigWithAuth(host models.Host, profile models.P
 	if len(config.Auth) == 0 {
 		return nil, fmt.Errorf("no authentication method available")
 	}
+	// Servers asking for a second factor after the first one get the code
+	if host.TOTP != "" {
+		config.Auth = append(config.Auth, ssh.KeyboardInteractive(totpChallenge(host)))
+	}
 
 	return config, nil
 }
 
 // addSSHAgentAuth adds SSH agent authentication
 // Returns nil if agent is not available (graceful fallback)
+// The agent connection is shared by the connectors of the process.
 func (c *Connector) addSSHAgentAuth(config *ssh.ClientConfig) error {
-	socket := os.Getenv("SSH_AUTH_SOCK")
-	if socket == "" {
-		// Agent not available - return nil to allow fallback to other auth methods
-		return nil
-	}
-
-	conn, err := net.Dial("unix", socket)
-	if err != nil {
-		// Agent socket not accessible - return nil to allow fallback
-		return nil
-	}
-	defer conn.Close()
-
-	sshAgent := agent.NewClient(conn)
-	signers, err := sshAgent.Signers()
+	signers, err := agentSigners()
 	if err != nil || len(signers) == 0 {
-		// No keys available from agent - return nil to allow fallback
+		// Agent not available or without keys - return nil to allow fallback
 		return nil
 	}
 
@@ -253,11 +439,16 @@ func (c *Connector) addSSHAgentAuth(config *ssh.ClientConfig) error {
 	return nil
 }
 
-// addPasswordAuth adds password authentication
+// addPasswordAuth adds password authentication, decrypting an encrypted
+// password first
 func (c *Connector) addPasswordAuth(config *ssh.ClientConfig, password string) error {
 	if password == "" {
 		return fmt.Errorf("password is empty")
 	}
+	password, err := secret.Reveal(password)
+	if err != nil {
+		return err
+	}
 	config.Auth = append(config.Auth, ssh.Password(password))
 	return nil
 }
@@ -269,15 +460,17 @@ func (c *Connector) addKeyFileAuth(config *ssh.ClientConfig, keyPath string) err
 		return fmt.Errorf("failed to expand identity path: %w", err)
 	}
 
-	key, err := os.ReadFile(expandedPath)
-	if err != nil {
+	signer, err := loadSigner(expandedPath)
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
 		return fmt.Errorf("failed to read identity file: %w", err)
 	}
-
-	signer, err := ssh.ParsePrivateKey(key)
 	if err != nil {
 		return fmt.Errorf("failed to parse private key: %w", err)
 	}
+	if weakness := keyWeakness(signer.PublicKey().Type(), keyBits(signer.PublicKey())); weakness != "" {
+		c.warnings = append(c.warnings, fmt.Sprintf("%s: %s", keyPath, weakness))
+	}
 
 	config.Auth = append(config.Auth, ssh.PublicKeys(signer))
 	return nil
@@ -292,18 +485,13 @@ func (c *Connector) addDefaultKeysAuth(config *ssh.ClientConfig) error {
 		"~/.ssh/id_dsa",
 	}
 
-	for _, keyPath := range defaultKeys {
+	for _, keyPath := range append(defaultKeys, WindowsIdentities()...) {
 		expandedPath, err := expandPath(keyPath)
 		if err != nil {
 			continue
 		}
 
-		key, err := os.ReadFile(expandedPath)
-		if err != nil {
-			continue
-		}
-
-		signer, err := ssh.ParsePrivateKey(key)
+		signer, err := loadSigner(expandedPath)
 		if err != nil {
 			continue
 		}
@@ -318,8 +506,10 @@ func (c *Connector) addDefaultKeysAuth(config *ssh.ClientConfig) error {
 	return nil
 }
 
-// expandPath expands ~ to home directory
+// expandPath expands ~ to home directory, translating paths written for
+// the other side of WSL first
 func expandPath(path string) (string, error) {
+	path = translatePath(path)
 	if len(path) > 1 && path[:2] == "~/" {
 		usr, err := user.Current()
 		if err != nil {
@@ -352,6 +542,10 @@ func LaunchSSH(host models.Host) error {
 	if host.Proxy != "" {
 		args = append(args, "-J", host.Proxy)
 	}
+
+	if checking := strictHostKeyChecking(host.HostKeyPolicy); checking != "" {
+		args = append(args, "-o", "StrictHostKeyChecking="+checking)
+	}
 	
 	// Add user@host
 	args = append(args, fmt.Sprintf("%s@%s", host.User, host.Host))
@@ -384,61 +578,23 @@ func (c *Connector) GetClient() *ssh.Client {
 
 // Close closes the SSH connection
 func (c *Connector) Close() error {
+	if c.done != nil {
+		close(c.done)
+		c.done = nil
+	}
+	var err error
 	if c.client != nil {
-		return c.client.Close()
+		err = c.client.Close()
 	}
-	return nil
+	if c.proxy != nil {
+		c.proxy.Close()
+	}
+	return err
 }
 
 // ConnectAndInteract connects to host and starts an interactive session
 func ConnectAndInteract(host models.Host, profile models.Profile) error {
-	connector := NewConnector()
-	defer connector.Close()
-
-	if err := connector.Connect(host, profile); err != nil {
-		return err
-	}
-
-	session, err := connector.client.NewSession()
-	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
-	}
-	defer session.Close()
-
-	// Set up terminal
-	session.Stdout = os.Stdout
-	session.Stderr = os.Stderr
-	session.Stdin = os.Stdin
-
-	modes := ssh.TerminalModes{
-		ssh.ECHO:          1,
-		ssh.TTY_OP_ISPEED: 14400,
-		ssh.TTY_OP_OSPEED: 14400,
-	}
-
-	// Get terminal dimensions
-	width, height := getTerminalSize()
-	err = session.RequestPty("xterm", height, width, modes)
-	if err != nil {
-		return fmt.Errorf("request for pseudo terminal failed: %w", err)
-	}
-
-	// Start a goroutine to handle terminal resize
-	resizeChan := make(chan os.Signal, 1)
-	signal.Notify(resizeChan, syscall.SIGWINCH)
-	go func() {
-		for range resizeChan {
-			width, height := getTerminalSize()
-			session.WindowChange(height, width)
-		}
-	}()
-
-	err = session.Shell()
-	if err != nil {
-		return fmt.Errorf("failed to start shell: %w", err)
-	}
-
-	return session.Wait()
+	return NewSession(host, profile).Run()
 }
 
 // getTerminalSize returns the terminal width and height
@@ -453,7 +609,7 @@ func getTerminalSize() (width, height int) {
 // getTerminalSizeImpl gets terminal size using multiple methods
 func getTerminalSizeImpl() (int, int, error) {
 	// Try to use golang.org/x/term for proper terminal size detection
-	width, height, err := term.GetSize(syscall.Stdout)
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
 	if err == nil && width > 0 && height > 0 {
 		return width, height, nil
 	}
@@ -479,7 +635,7 @@ func CheckConnection(host models.Host) error {
 	defer connector.Close()
 
 	// Just test TCP connectivity first
-	addr := fmt.Sprintf("%s:%d", host.Host, host.Port)
+	addr := net.JoinHostPort(host.Host, strconv.Itoa(host.Port))
 	conn, err := net.Dial("tcp", addr)
 	if err != nil {
 		return fmt.Errorf("cannot reach %s: %w", addr, err)
@@ -491,11 +647,55 @@ func CheckConnection(host models.Host) error {
 
 // Ping checks if the host is reachable (TCP only)
 func Ping(host string, port int) error {
-	addr := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("tcp", addr, 5e9) // 5 second timeout
+	return PingTimeout(host, port, 5*time.Second)
+}
+
+// PingTimeout checks if the host is reachable within the given time (TCP only)
+func PingTimeout(host string, port int, timeout time.Duration) error {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", addr, timeout)
 	if err != nil {
 		return err
 	}
 	conn.Close()
 	return nil
 }
+
+// Handshake connects and authenticates to the host, then disconnects
+// Unlike Ping it goes through the jump host and proves the credentials work
+func Handshake(host models.Host, profile models.Profile, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() {
+		connector := NewConnector()
+		connector.noPrompt = true
+		defer connector.Close()
+		errc <- connector.Connect(host, profile)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return &TimeoutError{After: timeout}
+	}
+}
+
+// TimeoutError reports an operation that gave up after a time limit
+// It implements net.Error so it is classified as a network failure.
+type TimeoutError struct {
+	After time.Duration
+}
+
+func (e *TimeoutError) Error() string   { return fmt.Sprintf("timed out after %s", e.After) }
+func (e *TimeoutError) Timeout() bool   { return true }
+func (e *TimeoutError) Temporary() bool { return true }
+
+// IsNetworkError reports whether err means the host could not be reached,
+// as opposed to rejecting the login or the command failing
+func IsNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -1,9 +1,27 @@
 package ssh
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sshm/sshm/internal/models"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 func TestParseProxyHost(t *testing.T) {
@@ -19,6 +37,9 @@ func TestParseProxyHost(t *testing.T) {
 		{"user@example.com:2222", "example.com", "user", 2222},
 		{"192.168.1.1:22", "192.168.1.1", "", 22},
 		{"jump.server.com", "jump.server.com", "", 22},
+		{"2001:db8::1", "2001:db8::1", "", 22},
+		{"admin@[2001:db8::1]", "2001:db8::1", "admin", 22},
+		{"admin@[2001:db8::1]:2222", "2001:db8::1", "admin", 2222},
 	}
 
 	for _, tt := range tests {
@@ -66,6 +87,28 @@ func TestExpandPath(t *testing.T) {
 	}
 }
 
+func TestWSLPaths(t *testing.T) {
+	tests := []struct {
+		windows, wsl string
+	}{
+		{`C:\Users\me\.ssh\id_ed25519`, "/mnt/c/Users/me/.ssh/id_ed25519"},
+		{"D:/keys/deploy", "/mnt/d/keys/deploy"},
+	}
+	for _, tt := range tests {
+		if got := wslPath(tt.windows); got != tt.wsl {
+			t.Errorf("wslPath(%q) = %q, want %q", tt.windows, got, tt.wsl)
+		}
+	}
+	if got := windowsPath("/mnt/c/Users/me/.ssh/id_rsa"); got != `C:\Users\me\.ssh\id_rsa` {
+		t.Errorf("windowsPath = %q", got)
+	}
+	for _, path := range []string{"~/.ssh/id_rsa", "/home/me/.ssh/id_rsa", "/mnt/data/key", "c:key"} {
+		if wslPath(path) != path || windowsPath(path) != path {
+			t.Errorf("expected %q to be left alone", path)
+		}
+	}
+}
+
 func TestHostGenerateSSHCommand(t *testing.T) {
 	tests := []struct {
 		host     models.Host
@@ -140,3 +183,780 @@ func TestPing(t *testing.T) {
 		t.Error("Ping() should have failed for invalid port")
 	}
 }
+
+func TestCheckAgentWithoutSocket(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	status := CheckAgent()
+	if status.Available {
+		t.Error("CheckAgent() should report unavailable without SSH_AUTH_SOCK")
+	}
+	if status.String() != "agent: unavailable" {
+		t.Errorf("AgentStatus.String() = %q, want %q", status.String(), "agent: unavailable")
+	}
+}
+
+func TestAgentStatusString(t *testing.T) {
+	tests := []struct {
+		status   AgentStatus
+		expected string
+	}{
+		{AgentStatus{Available: true, Keys: 0}, "agent: 0 keys"},
+		{AgentStatus{Available: true, Keys: 1}, "agent: 1 key"},
+		{AgentStatus{Available: true, Keys: 3}, "agent: 3 keys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := tt.status.String(); got != tt.expected {
+				t.Errorf("AgentStatus.String() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIdentityExists(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "id_test")
+	if err := os.WriteFile(keyPath, []byte("key"), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	if !IdentityExists("") {
+		t.Error("IdentityExists(\"\") should be true (default keys)")
+	}
+	if !IdentityExists(keyPath) {
+		t.Errorf("IdentityExists(%q) should be true", keyPath)
+	}
+	if IdentityExists(keyPath + ".missing") {
+		t.Error("IdentityExists() should be false for a missing file")
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	if _, ok := ExitStatus(nil); ok {
+		t.Error("ExitStatus(nil) should not report a remote exit")
+	}
+	if _, ok := ExitStatus(errors.New("dial failed")); ok {
+		t.Error("ExitStatus() should not report a remote exit for connection errors")
+	}
+	if _, ok := ExitStatus(fmt.Errorf("session: %w", &gossh.ExitError{})); !ok {
+		t.Error("ExitStatus() should unwrap a remote exit error")
+	}
+}
+
+func TestReadPublicKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	identity := filepath.Join(dir, "deploy")
+	line := strings.TrimSpace(string(gossh.MarshalAuthorizedKey(sshPub))) + " deploy@laptop"
+	if err := os.WriteFile(identity+".pub", []byte(line+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The host's identity is preferred when no key is given
+	key, path, err := ReadPublicKey("", models.Host{Identity: identity})
+	if err != nil {
+		t.Fatalf("ReadPublicKey failed: %v", err)
+	}
+	if string(key) != line || path != identity+".pub" {
+		t.Errorf("got %q from %s, want %q", key, path, line)
+	}
+
+	invalid := filepath.Join(dir, "invalid.pub")
+	os.WriteFile(invalid, []byte("not a key"), 0644)
+	if _, _, err := ReadPublicKey(invalid, models.Host{}); err == nil {
+		t.Error("expected error for invalid public key")
+	}
+	if _, _, err := ReadPublicKey(filepath.Join(dir, "missing.pub"), models.Host{}); err == nil {
+		t.Error("expected error for missing public key")
+	}
+}
+
+func TestIsAuthError(t *testing.T) {
+	if !IsAuthError(fmt.Errorf("failed to connect: %w", errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey]"))) {
+		t.Error("expected rejected credentials to be an auth error")
+	}
+	if !IsAuthError(fmt.Errorf("failed to build client config: %w", errPasswordRequired)) {
+		t.Error("expected missing password to be an auth error")
+	}
+	if IsAuthError(errors.New("dial tcp 10.0.0.1:22: connect: connection refused")) {
+		t.Error("expected network failure not to be an auth error")
+	}
+	if IsAuthError(nil) {
+		t.Error("expected nil not to be an auth error")
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	for _, tt := range []struct {
+		keyType string
+		bits    int
+		algo    string
+	}{
+		{KeyTypeEd25519, 0, gossh.KeyAlgoED25519},
+		{KeyTypeRSA, 2048, gossh.KeyAlgoRSA},
+		{KeyTypeECDSA, 384, gossh.KeyAlgoECDSA384},
+	} {
+		kp, err := GenerateKey(tt.keyType, tt.bits, "test@example", nil)
+		if err != nil {
+			t.Fatalf("GenerateKey(%s) failed: %v", tt.keyType, err)
+		}
+		signer, err := gossh.ParsePrivateKey(kp.Private)
+		if err != nil {
+			t.Fatalf("private %s key does not parse: %v", tt.keyType, err)
+		}
+		if signer.PublicKey().Type() != tt.algo {
+			t.Errorf("expected %s key, got %s", tt.algo, signer.PublicKey().Type())
+		}
+		pub, comment, _, _, err := gossh.ParseAuthorizedKey(kp.Public)
+		if err != nil || comment != "test@example" || string(pub.Marshal()) != string(signer.PublicKey().Marshal()) {
+			t.Errorf("public %s key does not match private key (comment %q, %v)", tt.keyType, comment, err)
+		}
+	}
+
+	if _, err := GenerateKey(KeyTypeRSA, 1024, "", nil); err == nil {
+		t.Error("expected short RSA keys to be rejected")
+	}
+	if _, err := GenerateKey("dsa", 0, "", nil); err == nil {
+		t.Error("expected unknown key type to be rejected")
+	}
+
+	// Encrypted keys need the passphrase
+	kp, err := GenerateKey(KeyTypeEd25519, 0, "", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gossh.ParsePrivateKey(kp.Private); err == nil {
+		t.Error("expected encrypted key to require a passphrase")
+	}
+	if _, err := gossh.ParsePrivateKeyWithPassphrase(kp.Private, []byte("secret")); err != nil {
+		t.Errorf("encrypted key does not parse with passphrase: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys", "id_test")
+	if err := WriteKeyPair(kp, path); err != nil {
+		t.Fatalf("WriteKeyPair failed: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected private key with mode 0600, got %v (%v)", info, err)
+	}
+	if err := WriteKeyPair(kp, path); err == nil {
+		t.Error("expected existing key not to be overwritten")
+	}
+}
+
+func TestInspectKey(t *testing.T) {
+	dir := t.TempDir()
+	kp, err := GenerateKey(KeyTypeECDSA, 384, "test@example", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "id_ecdsa")
+	if err := WriteKeyPair(kp, path); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().Add(-400 * 24 * time.Hour)
+	os.Chtimes(path+".pub", created, created)
+
+	info, err := InspectKey(path)
+	if err != nil {
+		t.Fatalf("InspectKey failed: %v", err)
+	}
+	fingerprint, _ := Fingerprint(kp.Public)
+	if info.Type != gossh.KeyAlgoECDSA384 || info.Bits != 384 || info.Fingerprint != fingerprint {
+		t.Errorf("unexpected key info %+v", info)
+	}
+	if age := info.Age(time.Now()); age < 399*24*time.Hour {
+		t.Errorf("expected the age of the .pub file, got %v", age)
+	}
+
+	// Without the .pub file the encrypted private key still tells its type
+	os.Remove(path + ".pub")
+	if info, err := InspectKey(path); err != nil || info.Type != gossh.KeyAlgoECDSA384 || info.Age(time.Now()) > time.Hour {
+		t.Errorf("expected the private key to be inspected, got %+v (%v)", info, err)
+	}
+	if _, err := InspectKey(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected a missing key to fail")
+	}
+	if GenerateKeyType(gossh.KeyAlgoECDSA384) != KeyTypeECDSA || GenerateKeyType("ssh-dss") != KeyTypeEd25519 {
+		t.Error("unexpected key types to generate")
+	}
+}
+
+func TestSignerCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "id_test")
+	write := func(keyType string, bits int) {
+		t.Helper()
+		kp, err := GenerateKey(keyType, bits, "", nil)
+		if err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		if err := os.WriteFile(path, kp.Private, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(KeyTypeEd25519, 0)
+	first, err := loadSigner(path)
+	if err != nil {
+		t.Fatalf("loadSigner failed: %v", err)
+	}
+	if again, _ := loadSigner(path); again != first {
+		t.Error("expected the parsed key to be reused")
+	}
+
+	// A replaced key is parsed again
+	write(KeyTypeECDSA, 256)
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+	replaced, err := loadSigner(path)
+	if err != nil {
+		t.Fatalf("loadSigner failed: %v", err)
+	}
+	if replaced.PublicKey().Type() != gossh.KeyAlgoECDSA256 {
+		t.Errorf("expected the new ECDSA key, got %s", replaced.PublicKey().Type())
+	}
+
+	os.Remove(path)
+	if _, err := loadSigner(path); !os.IsNotExist(err) {
+		t.Errorf("expected a removed key to fail, got %v", err)
+	}
+}
+
+func TestSharedAgent(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("no unix sockets: %v", err)
+	}
+	defer listener.Close()
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	keyring := agent.NewKeyring()
+	keyring.Add(agent.AddedKey{PrivateKey: private})
+	accepted := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+	defer func() {
+		if client, err := agentClient(); err == nil {
+			dropAgent(client)
+		}
+	}()
+
+	if status := CheckAgent(); !status.Available || status.Keys != 1 {
+		t.Fatalf("unexpected agent status %+v", status)
+	}
+	if signers, err := agentSigners(); err != nil || len(signers) != 1 {
+		t.Fatalf("agentSigners() = %d signers, %v", len(signers), err)
+	}
+	first := <-accepted
+	if len(accepted) != 0 {
+		t.Error("expected a single agent connection")
+	}
+
+	// An agent that went away is dialed again
+	first.Close()
+	if status := CheckAgent(); !status.Available {
+		t.Errorf("expected the agent to be reached again, got %+v", status)
+	}
+}
+
+func TestAlgorithmWarnings(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := gossh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A server offering only what OpenSSH dropped shares nothing with the
+	// client, but the warnings still explain why
+	serverConfig := &gossh.ServerConfig{NoClientAuth: true}
+	serverConfig.KeyExchanges = []string{"diffie-hellman-group1-sha1"}
+	serverConfig.Ciphers = []string{"aes128-cbc"}
+	serverConfig.AddHostKey(hostKey)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			gossh.NewServerConn(conn, serverConfig)
+			conn.Close()
+		}
+	}()
+
+	c := NewConnector()
+	config := &gossh.ClientConfig{User: "test", HostKeyCallback: gossh.InsecureIgnoreHostKey(), Timeout: 5 * time.Second}
+	if _, err := c.dial(listener.Addr().String(), config, "jump host old"); err == nil {
+		t.Fatal("expected the handshake to fail without a common key exchange")
+	}
+	warnings := strings.Join(c.Warnings(), "\n")
+	if !strings.Contains(warnings, "jump host old: server offers only deprecated key exchanges (diffie-hellman-group1-sha1)") ||
+		!strings.Contains(warnings, "ciphers (aes128-cbc)") {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+	if strings.Contains(warnings, "host key") {
+		t.Errorf("expected ssh-ed25519 not to be flagged, got %q", warnings)
+	}
+
+	if _, err := parseServerKexInit([]byte("banner\r\nSSH-2.0-test\r\n\x00\x00")); !errors.Is(err, errIncomplete) {
+		t.Errorf("expected a partial packet to be incomplete, got %v", err)
+	}
+	if _, err := parseServerKexInit([]byte("SSH-2.0-test\r\n\x00\x00\x00\x0c\x0a\x15xxxxxxxxxxxxx")); err == nil || errors.Is(err, errIncomplete) {
+		t.Errorf("expected another first packet to be rejected, got %v", err)
+	}
+
+	if keyWeakness(gossh.KeyAlgoDSA, 1024) == "" || keyWeakness(gossh.KeyAlgoRSA, 1024) == "" {
+		t.Error("expected DSA and 1024-bit RSA keys to be weak")
+	}
+	if w := keyWeakness(gossh.KeyAlgoRSA, 3072) + (KeyInfo{Type: gossh.KeyAlgoED25519}).Weakness(); w != "" {
+		t.Errorf("expected RSA 3072 and ed25519 keys to be fine, got %q", w)
+	}
+}
+
+func TestParseForward(t *testing.T) {
+	tests := []struct {
+		kind    ForwardKind
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{ForwardLocal, "8080:localhost:80", "-L localhost:8080 -> localhost:80", false},
+		{ForwardLocal, "0.0.0.0:8080:db:5432", "-L 0.0.0.0:8080 -> db:5432", false},
+		{ForwardLocal, "[::1]:8080:[fe80::1]:80", "-L [::1]:8080 -> [fe80::1]:80", false},
+		{ForwardRemote, "9000:localhost:3000", "-R :9000 -> localhost:3000", false},
+		{ForwardDynamic, "1080", "-D :1080 (SOCKS5)", false},
+		{ForwardDynamic, "127.0.0.1:1080", "-D 127.0.0.1:1080 (SOCKS5)", false},
+		{ForwardLocal, "8080", "", true},
+		{ForwardLocal, "8080:localhost:http", "", true},
+		{ForwardLocal, "99999:localhost:80", "", true},
+		{ForwardDynamic, "a:b:c", "", true},
+		{ForwardLocal, "[::1:8080:x:80", "", true},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseForward(tt.kind, tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseForward(%s, %q) expected error", tt.kind, tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseForward(%s, %q) unexpected error: %v", tt.kind, tt.spec, err)
+			continue
+		}
+		if f.String() != tt.want {
+			t.Errorf("ParseForward(%s, %q) = %q, want %q", tt.kind, tt.spec, f.String(), tt.want)
+		}
+	}
+}
+
+func TestSocks5Connect(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	var dialed string
+	dial := func(network, addr string) (net.Conn, error) {
+		dialed = addr
+		a, _ := net.Pipe()
+		return a, nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		conn, err := socks5Connect(server, dial)
+		if conn != nil {
+			conn.Close()
+		}
+		done <- err
+	}()
+
+	// Greeting with one method (no auth), then CONNECT example.com:443
+	client.Write([]byte{5, 1, 0})
+	reply := make([]byte, 2)
+	io.ReadFull(client, reply)
+	if reply[0] != 5 || reply[1] != 0 {
+		t.Fatalf("unexpected greeting reply %v", reply)
+	}
+	req := []byte{5, 1, 0, 3, byte(len("example.com"))}
+	req = append(req, "example.com"...)
+	req = append(req, 0x01, 0xBB)
+	client.Write(req)
+	resp := make([]byte, 10)
+	io.ReadFull(client, resp)
+
+	if err := <-done; err != nil {
+		t.Fatalf("socks5Connect failed: %v", err)
+	}
+	if resp[1] != 0 {
+		t.Errorf("expected success reply, got %v", resp)
+	}
+	if dialed != "example.com:443" {
+		t.Errorf("expected to dial example.com:443, got %q", dialed)
+	}
+}
+
+func TestCopyTreeRecursiveAndResume(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "site")
+	os.MkdirAll(filepath.Join(src, "css"), 0755)
+	os.WriteFile(filepath.Join(src, "index.html"), []byte("<html></html>"), 0644)
+	os.WriteFile(filepath.Join(src, "css", "app.css"), []byte("body { margin: 0 }"), 0644)
+
+	dst := t.TempDir()
+	if err := copyTree(context.Background(), localFS{}, src, localFS{}, dst, TransferOptions{}); err == nil {
+		t.Fatal("expected copying a directory without Recursive to fail")
+	}
+	if err := copyTree(context.Background(), localFS{}, src, localFS{}, dst, TransferOptions{Recursive: true}); err != nil {
+		t.Fatalf("copyTree failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "site", "css", "app.css"))
+	if err != nil || string(data) != "body { margin: 0 }" {
+		t.Fatalf("unexpected copy %q, %v", data, err)
+	}
+
+	// A partial destination is continued from its current size
+	partial := filepath.Join(dst, "partial.html")
+	os.WriteFile(partial, []byte("<html>"), 0644)
+	var reported int64
+	opts := TransferOptions{Resume: true, Progress: func(name string, done, total int64) {
+		if reported == 0 {
+			reported = done
+		}
+	}}
+	if err := copyTree(context.Background(), localFS{}, filepath.Join(src, "index.html"), localFS{}, partial, opts); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	data, _ = os.ReadFile(partial)
+	if string(data) != "<html></html>" {
+		t.Errorf("expected resumed file to be complete, got %q", data)
+	}
+	if reported != int64(len("<html>")) {
+		t.Errorf("expected progress to start at the resumed offset, got %d", reported)
+	}
+}
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSudoWriter(t *testing.T) {
+	var out bytes.Buffer
+	stdin := &closeRecorder{}
+	asked := 0
+	sw := &sudoWriter{w: &out, stdin: stdin, password: func() (string, error) {
+		asked++
+		return "s3cret", nil
+	}}
+
+	// The prompt may arrive split across writes
+	half := len(sudoPrompt) / 2
+	sw.Write([]byte("warning: x\n" + sudoPrompt[:half]))
+	sw.Write([]byte(sudoPrompt[half:]))
+	sw.answering.Wait()
+	sw.Write([]byte(sudoStarted + "\ndone\n"))
+	sw.Flush()
+
+	if out.String() != "warning: x\ndone\n" {
+		t.Errorf("expected the prompt to be removed, got %q", out.String())
+	}
+	if stdin.String() != "s3cret\n" || !stdin.closed || asked != 1 {
+		t.Errorf("expected the password to be sent once and stdin closed, got %q closed=%v asked=%d", stdin.String(), stdin.closed, asked)
+	}
+
+	// A second prompt means the password was rejected
+	sw.Write([]byte("Sorry, try again.\n" + sudoPrompt))
+	if !errors.Is(sw.Err(), ErrSudoPassword) || asked != 1 {
+		t.Errorf("expected ErrSudoPassword without asking again, got %v", sw.Err())
+	}
+	if !IsAuthError(sw.Err()) {
+		t.Error("expected a rejected sudo password to count as an auth error")
+	}
+
+	// Without a prompt stdin is closed as soon as the command starts
+	out.Reset()
+	stdin = &closeRecorder{}
+	sw = &sudoWriter{w: &out, stdin: stdin, password: func() (string, error) {
+		t.Error("expected no password to be asked for")
+		return "", nil
+	}}
+	sw.Write([]byte(sudoStarted[:5]))
+	if stdin.closed {
+		t.Error("expected stdin to stay open before the command starts")
+	}
+	sw.Write([]byte(sudoStarted[5:] + "\nhello\n"))
+	sw.Flush()
+	if !stdin.closed || out.String() != "hello\n" {
+		t.Errorf("expected stdin closed and the notice removed, got closed=%v %q", stdin.closed, out.String())
+	}
+}
+
+func TestSudoCommand(t *testing.T) {
+	got := SudoCommand("echo 'hi' && id")
+	want := `sudo -S -p '` + sudoPrompt + `' sh -c 'echo '\''` + sudoStarted + `'\'' >&2
+echo '\''hi'\'' && id'`
+	if got != want {
+		t.Errorf("SudoCommand = %s, want %s", got, want)
+	}
+}
+
+func TestSSHFSArgs(t *testing.T) {
+	profile := models.Profile{Timeout: 10, KeepAliveInterval: 15, KeepAliveCountMax: 3, ServerAliveEnabled: true}
+	host := models.Host{User: "deploy", Host: "10.0.0.4", Port: 2222, Identity: "/keys/deploy", Proxy: "bastion"}
+
+	got := strings.Join(SSHFSArgs(host, profile, "/var/log", "/mnt/web1", MountOptions{ReadOnly: true, Extra: []string{"allow_other"}}), " ")
+	want := "deploy@10.0.0.4:/var/log /mnt/web1 -p 2222 -o reconnect -o IdentityFile=/keys/deploy -o ProxyJump=bastion " +
+		"-o ConnectTimeout=10 -o ServerAliveInterval=15 -o ServerAliveCountMax=3 -o ro -o allow_other"
+	if got != want {
+		t.Errorf("SSHFSArgs =\n%s\nwant\n%s", got, want)
+	}
+
+	// A stored password is read from stdin and the key is not offered
+	host = models.Host{User: "root", Host: "db", Port: 22, Identity: "/keys/x", AuthType: models.AuthTypePassword, Password: "pw"}
+	got = strings.Join(SSHFSArgs(host, models.Profile{}, "", "/mnt/db", MountOptions{}), " ")
+	if got != "root@db: /mnt/db -o reconnect -o password_stdin" {
+		t.Errorf("unexpected password args: %s", got)
+	}
+
+	// Group and tag options arrive through the profile
+	host = models.Host{User: "deploy", Host: "10.0.0.5", Port: 22}
+	profile = models.Profile{IdentityFile: "/keys/team", ProxyCommand: "nc -x proxy:1080 %h %p"}
+	got = strings.Join(SSHFSArgs(host, profile, "", "/mnt/web2", MountOptions{}), " ")
+	if got != "deploy@10.0.0.5: /mnt/web2 -o reconnect -o IdentityFile=/keys/team -o ProxyCommand=nc -x proxy:1080 %h %p" {
+		t.Errorf("unexpected profile args: %s", got)
+	}
+}
+
+func TestProxyCommand(t *testing.T) {
+	if got := expandProxyCommand("connect %r@%h:%p 100%%", "10.0.0.1", 2222, "deploy"); got != "connect deploy@10.0.0.1:2222 100%" {
+		t.Errorf("expandProxyCommand = %q", got)
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("needs cat")
+	}
+
+	conn, err := dialProxyCommand("cat")
+	if err != nil {
+		t.Fatalf("dialProxyCommand failed: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("SSH-2.0-test\n")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	buf := make([]byte, 13)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "SSH-2.0-test\n" {
+		t.Errorf("expected the command to echo, got %q, %v", buf, err)
+	}
+}
+
+func TestHostKeyPolicy(t *testing.T) {
+	original := KnownHostsFile
+	KnownHostsFile = filepath.Join(t.TempDir(), "known_hosts")
+	t.Cleanup(func() { KnownHostsFile = original })
+
+	newKey := func() gossh.PublicKey {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		key, err := gossh.NewPublicKey(pub)
+		if err != nil {
+			t.Fatalf("failed to convert key: %v", err)
+		}
+		return key
+	}
+	key, other := newKey(), newKey()
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 2222}
+
+	if err := hostKeyCallback(models.HostKeyStrict, nil)("10.0.0.1:2222", addr, key); !errors.Is(err, ErrHostKeyUnknown) {
+		t.Errorf("strict: expected ErrHostKeyUnknown, got %v", err)
+	}
+	if err := hostKeyCallback("", nil)("10.0.0.1:2222", addr, key); err != nil {
+		t.Fatalf("tofu: expected the first key to be trusted, got %v", err)
+	}
+	data, _ := os.ReadFile(KnownHostsFile)
+	if !strings.HasPrefix(string(data), "[10.0.0.1]:2222 ssh-ed25519 ") {
+		t.Errorf("unexpected known_hosts:\n%s", data)
+	}
+	if err := hostKeyCallback(models.HostKeyStrict, nil)("10.0.0.1:2222", addr, key); err != nil {
+		t.Errorf("strict: expected the recorded key to pass, got %v", err)
+	}
+	if err := hostKeyCallback(models.HostKeyTOFU, nil)("10.0.0.1:2222", addr, other); !errors.Is(err, ErrHostKeyChanged) {
+		t.Errorf("tofu: expected ErrHostKeyChanged, got %v", err)
+	}
+	if err := hostKeyCallback(models.HostKeyInsecure, nil)("10.0.0.1:2222", addr, other); err != nil {
+		t.Errorf("insecure: expected any key to pass, got %v", err)
+	}
+
+	// Without a terminal a change can't be approved, but is noted
+	c := &Connector{noPrompt: true}
+	if err := hostKeyCallback(models.HostKeyTOFU, c.approveHostKey("web1"))("10.0.0.1:2222", addr, other); !errors.Is(err, ErrHostKeyChanged) {
+		t.Errorf("expected the change to be refused, got %v", err)
+	}
+	changes := c.HostKeyChanges()
+	if len(changes) != 1 || changes[0].Host != "[10.0.0.1]:2222" || changes[0].Approved ||
+		changes[0].OldFingerprint != gossh.FingerprintSHA256(key) || changes[0].NewFingerprint != gossh.FingerprintSHA256(other) {
+		t.Errorf("unexpected host key changes %+v", changes)
+	}
+
+	// An approved key replaces the recorded one
+	approve := func(name string, old, offered gossh.PublicKey) bool { return true }
+	if err := hostKeyCallback(models.HostKeyStrict, approve)("10.0.0.1:2222", addr, other); err != nil {
+		t.Fatalf("expected the approved key to be accepted, got %v", err)
+	}
+	if err := hostKeyCallback(models.HostKeyStrict, nil)("10.0.0.1:2222", addr, other); err != nil {
+		t.Errorf("expected the new key to be recorded, got %v", err)
+	}
+	if err := hostKeyCallback(models.HostKeyStrict, nil)("10.0.0.1:2222", addr, key); !errors.Is(err, ErrHostKeyChanged) {
+		t.Errorf("expected the old key to be gone, got %v", err)
+	}
+
+	// A key of another type is a new key, not a changed one
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ecKey, err := gossh.NewPublicKey(&ecPriv.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	if err := hostKeyCallback(models.HostKeyStrict, nil)("10.0.0.1:2222", addr, ecKey); !errors.Is(err, ErrHostKeyUnknown) {
+		t.Errorf("strict: expected a key of another type to be unknown, got %v", err)
+	}
+	if err := hostKeyCallback(models.HostKeyTOFU, nil)("10.0.0.1:2222", addr, ecKey); err != nil {
+		t.Errorf("tofu: expected a key of another type to be trusted, got %v", err)
+	}
+	if err := hostKeyCallback(models.HostKeyStrict, nil)("10.0.0.1:2222", addr, other); err != nil {
+		t.Errorf("expected the ed25519 key to stay, got %v", err)
+	}
+
+	// Recorded key types are asked for first
+	algorithms := hostKeyAlgorithms("10.0.0.1:2222")
+	if len(algorithms) < 2 || algorithms[0] != gossh.KeyAlgoED25519 || algorithms[1] != gossh.KeyAlgoECDSA256 {
+		t.Errorf("unexpected host key algorithms %v", algorithms)
+	}
+	if algorithms := hostKeyAlgorithms("10.0.0.9:22"); algorithms != nil {
+		t.Errorf("expected the defaults for an unknown host, got %v", algorithms)
+	}
+}
+
+func TestReplaceHostKey(t *testing.T) {
+	original := KnownHostsFile
+	KnownHostsFile = filepath.Join(t.TempDir(), "known_hosts")
+	t.Cleanup(func() { KnownHostsFile = original })
+
+	newKey := func() gossh.PublicKey {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		key, err := gossh.NewPublicKey(pub)
+		if err != nil {
+			t.Fatalf("failed to convert key: %v", err)
+		}
+		return key
+	}
+	old, offered, shared := newKey(), newKey(), newKey()
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ecKey, err := gossh.NewPublicKey(&ecPriv.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	content := knownhosts.Line([]string{"[10.0.0.1]:2222", "web1.example.com"}, old) + "\n" +
+		knownhosts.Line([]string{knownhosts.HashHostname("[10.0.0.1]:2222")}, old) + "\n" +
+		knownhosts.Line([]string{"[10.0.0.1]:2222"}, ecKey) + "\n" +
+		knownhosts.Line([]string{"10.0.0.2"}, shared) + "\n"
+	if err := os.WriteFile(KnownHostsFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 2222}
+	approve := func(name string, old, offered gossh.PublicKey) bool { return true }
+	if err := hostKeyCallback(models.HostKeyStrict, approve)("10.0.0.1:2222", addr, offered); err != nil {
+		t.Fatalf("expected the approved key to be accepted, got %v", err)
+	}
+
+	data, _ := os.ReadFile(KnownHostsFile)
+	want := knownhosts.Line([]string{"web1.example.com"}, old) + "\n" +
+		knownhosts.Line([]string{"[10.0.0.1]:2222"}, ecKey) + "\n" +
+		knownhosts.Line([]string{"10.0.0.2"}, shared) + "\n" +
+		knownhosts.Line([]string{"[10.0.0.1]:2222"}, offered) + "\n"
+	if string(data) != want {
+		t.Errorf("unexpected known_hosts:\n%s\nwant:\n%s", data, want)
+	}
+	if info, err := os.Stat(KnownHostsFile); runtime.GOOS != "windows" && (err != nil || info.Mode().Perm() != 0644) {
+		t.Errorf("expected the file mode to be kept, got %v (%v)", info.Mode(), err)
+	}
+}
+
+func TestTOTPChallenge(t *testing.T) {
+	for _, q := range []string{"Verification code: ", "Enter your OTP:", "Authenticator code: "} {
+		if !codePrompt.MatchString(q) {
+			t.Errorf("%q not recognized as a code prompt", q)
+		}
+	}
+	if codePrompt.MatchString("Password: ") {
+		t.Error("password prompt taken for a code prompt")
+	}
+
+	host := models.Host{Name: "web1", Password: "s3cret", TOTP: models.TOTPAuto}
+	answers, err := totpChallenge(host)("deploy", "", []string{"Password: "}, []bool{false})
+	if err != nil || len(answers) != 1 || answers[0] != "s3cret" {
+		t.Errorf("expected the saved password, got %q (%v)", answers, err)
+	}
+	config, err := (&Connector{}).buildClientConfigWithAuth(host, models.Profile{}, AuthMethodPassword)
+	if err != nil || len(config.Auth) != 2 {
+		t.Errorf("expected password and keyboard-interactive auth, got %v (%v)", config, err)
+	}
+}
+
+func TestKeyringPasswords(t *testing.T) {
+	host := models.Host{ID: "no-such-host-" + t.Name(), Name: "web1", AuthType: models.AuthTypePassword}
+	c := &Connector{noPrompt: true}
+	if _, err := c.buildClientConfig(host, models.Profile{}); !errors.Is(err, errPasswordRequired) {
+		t.Errorf("without keyring_passwords: expected errPasswordRequired, got %v", err)
+	}
+
+	KeyringPasswords = true
+	t.Cleanup(func() { KeyringPasswords = false })
+	if _, err := c.buildClientConfig(host, models.Profile{}); !errors.Is(err, errPasswordRequired) {
+		t.Errorf("nothing in the keyring and no prompt: expected errPasswordRequired, got %v", err)
+	}
+	if got := KeyringPassword(host); got != "" {
+		t.Errorf("KeyringPassword() = %q for a host without one", got)
+	}
+	host.Password = "s3cret"
+	config, err := c.buildClientConfig(host, models.Profile{})
+	if err != nil || len(config.Auth) != 1 {
+		t.Errorf("a password in the hosts file should be used as before, got %v (%v)", config, err)
+	}
+}
@@ -0,0 +1,111 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sshm/sshm/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// installKeyScript appends the public key read from stdin to
+// ~/.ssh/authorized_keys unless it is already present, creating the
+// directory and file with safe permissions and keeping a missing final
+// newline from gluing the key onto the last entry
+const installKeyScript = `umask 077; mkdir -p ~/.ssh && touch ~/.ssh/authorized_keys && read -r key && ` +
+	`if grep -qxF "$key" ~/.ssh/authorized_keys; then echo present; ` +
+	`else { [ -z "$(tail -c1 ~/.ssh/authorized_keys)" ] || echo; echo "$key"; } >> ~/.ssh/authorized_keys && echo added; fi`
+
+// defaultPublicKeys are tried in order when no public key is given
+var defaultPublicKeys = []string{
+	"~/.ssh/id_ed25519.pub",
+	"~/.ssh/id_ecdsa.pub",
+	"~/.ssh/id_rsa.pub",
+}
+
+// ReadPublicKey loads and validates a public key in authorized_keys format
+// With an empty path the host's identity (plus .pub) and then the default
+// keys in ~/.ssh are tried; the path actually used is returned
+func ReadPublicKey(path string, host models.Host) ([]byte, string, error) {
+	candidates := []string{path}
+	if path == "" {
+		candidates = nil
+		if host.Identity != "" {
+			candidates = append(candidates, host.Identity+".pub")
+		}
+		candidates = append(candidates, defaultPublicKeys...)
+	}
+
+	for _, candidate := range candidates {
+		expanded, err := expandPath(candidate)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(expanded)
+		if err != nil {
+			if path != "" {
+				return nil, "", fmt.Errorf("failed to read public key: %w", err)
+			}
+			continue
+		}
+		key, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s is not a valid public key: %w", expanded, err)
+		}
+		line := bytes.TrimSpace(ssh.MarshalAuthorizedKey(key))
+		if comment != "" {
+			line = append(line, ' ')
+			line = append(line, comment...)
+		}
+		return line, expanded, nil
+	}
+
+	return nil, "", fmt.Errorf("no public key found, pass one with -i")
+}
+
+// CopyID installs the public key in the host's authorized_keys
+// It reports whether the key was added (false if it was already present)
+func CopyID(host models.Host, profile models.Profile, publicKey []byte) (bool, error) {
+	connector := NewConnector()
+	defer connector.Close()
+
+	if err := connector.Connect(host, profile); err != nil {
+		return false, err
+	}
+
+	session, err := connector.client.NewSession()
+	if err != nil {
+		return false, fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = bytes.NewReader(append(bytes.TrimSpace(publicKey), '\n'))
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run(installKeyScript); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return false, fmt.Errorf("failed to install key: %s", msg)
+		}
+		return false, fmt.Errorf("failed to install key: %w", err)
+	}
+
+	return strings.TrimSpace(stdout.String()) == "added", nil
+}
+
+// IsAuthError reports whether err means the server rejected, or there were
+// no, credentials, as opposed to a network problem
+func IsAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "unable to authenticate") ||
+		strings.Contains(msg, "no authentication method available") ||
+		strings.Contains(msg, "no supported methods remain") ||
+		errors.Is(err, errPasswordRequired) ||
+		errors.Is(err, ErrSudoPassword)
+}
@@ -0,0 +1,81 @@
+package ssh
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/sshm/sshm/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// RunCommand runs a non-interactive command on the host and copies its
+// output to stdout and stderr
+// Cancelling ctx aborts the connection attempt or closes the running
+// session; the returned error is then ctx.Err(). Output of a running
+// session is written before it returns, never after.
+func RunCommand(ctx context.Context, host models.Host, profile models.Profile, command string, stdout, stderr io.Writer) error {
+	return runCommand(ctx, host, profile, command, stdout, stderr, nil)
+}
+
+// runCommand is RunCommand with a hook to adjust the session before it starts
+func runCommand(ctx context.Context, host models.Host, profile models.Profile, command string, stdout, stderr io.Writer, prepare func(*ssh.Session) error) error {
+	var (
+		mu        sync.Mutex
+		connected *Connector
+	)
+	errc := make(chan error, 1)
+
+	go func() {
+		connector := NewConnector()
+		defer connector.Close()
+
+		if err := connector.Connect(host, profile); err != nil {
+			errc <- err
+			return
+		}
+
+		mu.Lock()
+		if ctx.Err() != nil {
+			mu.Unlock()
+			errc <- ctx.Err()
+			return
+		}
+		connected = connector
+		mu.Unlock()
+
+		session, err := connector.newSession()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer session.Close()
+
+		session.Stdout = stdout
+		session.Stderr = stderr
+		if prepare != nil {
+			if err := prepare(session); err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- session.Run(command)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		mu.Lock()
+		running := connected
+		if running != nil {
+			running.Close()
+		}
+		mu.Unlock()
+		if running != nil {
+			// The session's copiers may still be writing what arrived last
+			<-errc
+		}
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,303 @@
+package ssh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+// ForwardKind is the direction of a port forward
+type ForwardKind string
+
+const (
+	ForwardLocal   ForwardKind = "L" // local port to a destination reached from the host
+	ForwardRemote  ForwardKind = "R" // port on the host to a destination reached locally
+	ForwardDynamic ForwardKind = "D" // local SOCKS5 proxy through the host
+)
+
+// Forward describes one port forward
+type Forward struct {
+	Kind     ForwardKind
+	BindAddr string
+	BindPort int
+	DestHost string // unused for dynamic forwards
+	DestPort int
+}
+
+// ParseForward parses a forward spec in ssh's syntax:
+// [bind_address:]port:host:hostport for -L and -R, [bind_address:]port for -D
+// IPv6 addresses must be enclosed in square brackets
+func ParseForward(kind ForwardKind, spec string) (Forward, error) {
+	parts, err := splitForwardSpec(spec)
+	if err != nil {
+		return Forward{}, err
+	}
+
+	f := Forward{Kind: kind}
+	if kind == ForwardLocal {
+		f.BindAddr = "localhost"
+	}
+
+	var bindPort string
+	switch {
+	case kind == ForwardDynamic && len(parts) == 1:
+		bindPort = parts[0]
+	case kind == ForwardDynamic && len(parts) == 2:
+		f.BindAddr, bindPort = parts[0], parts[1]
+	case kind != ForwardDynamic && len(parts) == 3:
+		bindPort, f.DestHost = parts[0], parts[1]
+		f.DestPort, err = parseForwardPort(parts[2])
+	case kind != ForwardDynamic && len(parts) == 4:
+		f.BindAddr, bindPort, f.DestHost = parts[0], parts[1], parts[2]
+		f.DestPort, err = parseForwardPort(parts[3])
+	default:
+		if kind == ForwardDynamic {
+			return Forward{}, fmt.Errorf("invalid forward %q, expected [bind_address:]port", spec)
+		}
+		return Forward{}, fmt.Errorf("invalid forward %q, expected [bind_address:]port:host:hostport", spec)
+	}
+	if err != nil {
+		return Forward{}, err
+	}
+	if f.BindPort, err = parseForwardPort(bindPort); err != nil {
+		return Forward{}, err
+	}
+	if kind != ForwardDynamic && f.DestHost == "" {
+		return Forward{}, fmt.Errorf("invalid forward %q, missing destination host", spec)
+	}
+	return f, nil
+}
+
+// splitForwardSpec splits on colons outside square brackets
+func splitForwardSpec(spec string) ([]string, error) {
+	var parts []string
+	var current strings.Builder
+	inBrackets := false
+	for _, r := range spec {
+		switch {
+		case r == '[' && !inBrackets:
+			inBrackets = true
+		case r == ']' && inBrackets:
+			inBrackets = false
+		case r == ':' && !inBrackets:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if inBrackets {
+		return nil, fmt.Errorf("invalid forward %q, unclosed bracket", spec)
+	}
+	return append(parts, current.String()), nil
+}
+
+func parseForwardPort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+// bindAddress returns the listen address of the forward
+func (f Forward) bindAddress() string {
+	return net.JoinHostPort(f.BindAddr, strconv.Itoa(f.BindPort))
+}
+
+// destAddress returns the address connections are forwarded to
+func (f Forward) destAddress() string {
+	return net.JoinHostPort(f.DestHost, strconv.Itoa(f.DestPort))
+}
+
+// String renders the forward as "-L localhost:8080 -> localhost:80"
+func (f Forward) String() string {
+	if f.Kind == ForwardDynamic {
+		return fmt.Sprintf("-D %s (SOCKS5)", f.bindAddress())
+	}
+	return fmt.Sprintf("-%s %s -> %s", f.Kind, f.bindAddress(), f.destAddress())
+}
+
+// Tunnel is an SSH connection carrying port forwards
+type Tunnel struct {
+	connector *Connector
+	mu        sync.Mutex
+	listeners []net.Listener
+}
+
+// OpenTunnel connects to the host for forwarding
+func OpenTunnel(host models.Host, profile models.Profile) (*Tunnel, error) {
+	connector := NewConnector()
+	if err := connector.Connect(host, profile); err != nil {
+		connector.Close()
+		return nil, err
+	}
+	return &Tunnel{connector: connector}, nil
+}
+
+// Start begins serving the forward in the background
+// For forwards with port 0 the returned forward carries the assigned port
+func (t *Tunnel) Start(f Forward) (Forward, error) {
+	client := t.connector.client
+
+	var listener net.Listener
+	var err error
+	if f.Kind == ForwardRemote {
+		listener, err = client.Listen("tcp", f.bindAddress())
+	} else {
+		listener, err = net.Listen("tcp", f.bindAddress())
+	}
+	if err != nil {
+		return f, fmt.Errorf("cannot listen on %s: %w", f.bindAddress(), err)
+	}
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+		f.BindPort = addr.Port
+	}
+
+	t.mu.Lock()
+	t.listeners = append(t.listeners, listener)
+	t.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go t.serve(f, conn)
+		}
+	}()
+	return f, nil
+}
+
+// serve forwards a single accepted connection
+func (t *Tunnel) serve(f Forward, conn net.Conn) {
+	defer conn.Close()
+
+	var remote net.Conn
+	var err error
+	switch f.Kind {
+	case ForwardLocal:
+		remote, err = t.connector.client.Dial("tcp", f.destAddress())
+	case ForwardRemote:
+		remote, err = net.Dial("tcp", f.destAddress())
+	case ForwardDynamic:
+		remote, err = socks5Connect(conn, t.connector.client.Dial)
+	}
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+
+	pipe(conn, remote)
+}
+
+// Wait blocks until the SSH connection ends
+func (t *Tunnel) Wait() error {
+	return t.connector.client.Wait()
+}
+
+// Close stops all forwards and disconnects
+func (t *Tunnel) Close() error {
+	t.mu.Lock()
+	for _, l := range t.listeners {
+		l.Close()
+	}
+	t.listeners = nil
+	t.mu.Unlock()
+	return t.connector.Close()
+}
+
+// pipe copies data both ways until either side is done
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	copyHalf := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go copyHalf(a, b)
+	go copyHalf(b, a)
+	<-done
+}
+
+// socks5Connect performs a SOCKS5 handshake (no authentication, CONNECT
+// only) on conn and dials the requested destination
+func socks5Connect(conn net.Conn, dial func(network, addr string) (net.Conn, error)) (net.Conn, error) {
+	// Greeting: version, number of methods, methods
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	if header[0] != 5 {
+		return nil, errors.New("not a SOCKS5 client")
+	}
+	if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{5, 0}); err != nil {
+		return nil, err
+	}
+
+	// Request: version, command, reserved, address type
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return nil, err
+	}
+	if req[1] != 1 {
+		conn.Write([]byte{5, 7, 0, 1, 0, 0, 0, 0, 0, 0}) // command not supported
+		return nil, errors.New("unsupported SOCKS command")
+	}
+
+	var host string
+	switch req[3] {
+	case 1: // IPv4
+		addr := make([]byte, 4)
+		if _, err := io.ReadFull(conn, addr); err != nil {
+			return nil, err
+		}
+		host = net.IP(addr).String()
+	case 3: // domain name
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return nil, err
+		}
+		name := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return nil, err
+		}
+		host = string(name)
+	case 4: // IPv6
+		addr := make([]byte, 16)
+		if _, err := io.ReadFull(conn, addr); err != nil {
+			return nil, err
+		}
+		host = net.IP(addr).String()
+	default:
+		conn.Write([]byte{5, 8, 0, 1, 0, 0, 0, 0, 0, 0}) // address type not supported
+		return nil, errors.New("unsupported SOCKS address type")
+	}
+
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(conn, portBytes); err != nil {
+		return nil, err
+	}
+	port := binary.BigEndian.Uint16(portBytes)
+
+	remote, err := dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0}) // connection refused
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		remote.Close()
+		return nil, err
+	}
+	return remote, nil
+}
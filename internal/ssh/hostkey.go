@@ -0,0 +1,267 @@
+package ssh
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/models"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/term"
+)
+
+// KnownHostsFile is the known_hosts file host keys are checked against and
+// recorded in, shared with OpenSSH
+var KnownHostsFile = "~/.ssh/known_hosts"
+
+// ErrHostKeyChanged is returned when a host presents a key other than the
+// one recorded for it
+var ErrHostKeyChanged = errors.New("host key changed")
+
+// ErrHostKeyUnknown is returned by the strict policy for hosts missing from
+// known_hosts
+var ErrHostKeyUnknown = errors.New("host key not in known_hosts")
+
+// approveFunc is asked whether to accept the key a host presents in place of
+// the one recorded for it under name in known_hosts
+type approveFunc func(name string, old, key ssh.PublicKey) bool
+
+// knownHostsCheck returns the path of the known_hosts file and a callback
+// checking keys against it
+func knownHostsCheck() (string, ssh.HostKeyCallback, error) {
+	path, err := expandPath(KnownHostsFile)
+	if err != nil {
+		return "", nil, err
+	}
+	var files []string
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	check, err := knownhosts.New(files...)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return path, check, nil
+}
+
+// hostKeyCallback checks server keys according to policy, tofu when empty
+// Only a recorded key of the offered type makes a key a changed one; keys
+// of other types are new. A changed key is only accepted when approve, if
+// any, says so; it then replaces the recorded one.
+func hostKeyCallback(policy models.HostKeyPolicy, approve approveFunc) ssh.HostKeyCallback {
+	if policy == models.HostKeyInsecure {
+		return ssh.InsecureIgnoreHostKey()
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		path, check, err := knownHostsCheck()
+		if err != nil {
+			return err
+		}
+
+		err = check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		var same []knownhosts.KnownKey
+		for _, k := range keyErr.Want {
+			if k.Key.Type() == key.Type() {
+				same = append(same, k)
+			}
+		}
+		if len(same) > 0 {
+			want := same[0]
+			if approve != nil && approve(knownhosts.Normalize(hostname), want.Key, key) {
+				return replaceHostKey(path, hostname, same, key)
+			}
+			return fmt.Errorf("%w for %s: got %s, %s:%d has %s (remove it with \"ssh-keygen -R %s\" if the change is expected)",
+				ErrHostKeyChanged, hostname, ssh.FingerprintSHA256(key), want.Filename, want.Line,
+				ssh.FingerprintSHA256(want.Key), knownhosts.Normalize(hostname))
+		}
+		if policy == models.HostKeyStrict {
+			return fmt.Errorf("%w: %s %s (host_key_policy strict)", ErrHostKeyUnknown, hostname, ssh.FingerprintSHA256(key))
+		}
+		return recordHostKey(path, hostname, key)
+	}
+}
+
+// probeKey is a key no known_hosts file holds; checking it lists the keys
+// recorded for a host
+type probeKey struct{}
+
+func (probeKey) Type() string                        { return "sshm-probe" }
+func (probeKey) Marshal() []byte                     { return []byte("sshm-probe") }
+func (probeKey) Verify([]byte, *ssh.Signature) error { return errors.New("probe key") }
+
+// hostKeyAlgorithms returns the host key algorithms to offer when
+// connecting to addr: those of the key types recorded for it in known_hosts
+// first, so the server presents a key that can be checked, then the rest of
+// the defaults; nil when nothing is recorded
+func hostKeyAlgorithms(addr string) []string {
+	_, check, err := knownHostsCheck()
+	if err != nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if !errors.As(check(addr, &net.TCPAddr{}, probeKey{}), &keyErr) || len(keyErr.Want) == 0 {
+		return nil
+	}
+
+	var algorithms []string
+	add := func(algorithm string) {
+		if !slices.Contains(algorithms, algorithm) {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	for _, k := range keyErr.Want {
+		if k.Key.Type() == ssh.KeyAlgoRSA {
+			add(ssh.KeyAlgoRSASHA512)
+			add(ssh.KeyAlgoRSASHA256)
+		}
+		add(k.Key.Type())
+	}
+	for _, algorithm := range ssh.SupportedAlgorithms().HostKeys {
+		add(algorithm)
+	}
+	for _, algorithm := range ssh.InsecureAlgorithms().HostKeys {
+		add(algorithm)
+	}
+	return algorithms
+}
+
+// approveHostKey returns the approval of changed host keys for connections
+// to the host called name: the new key is shown next to the old one and only
+// accepted once name is typed back on the terminal
+// Changes are kept for the connection history, accepted or not.
+func (c *Connector) approveHostKey(name string) approveFunc {
+	return func(hostname string, old, key ssh.PublicKey) bool {
+		c.hostKeyChanges = append(c.hostKeyChanges, models.HostKeyChange{
+			Host:           hostname,
+			OldFingerprint: ssh.FingerprintSHA256(old),
+			NewFingerprint: ssh.FingerprintSHA256(key),
+		})
+		change := &c.hostKeyChanges[len(c.hostKeyChanges)-1]
+		if c.noPrompt || !term.IsTerminal(int(os.Stdin.Fd())) {
+			return false
+		}
+
+		fmt.Fprintf(os.Stderr, "WARNING: the host key of %s (%s) has changed!\n", name, hostname)
+		fmt.Fprintf(os.Stderr, "  recorded: %s %s\n", old.Type(), change.OldFingerprint)
+		fmt.Fprintf(os.Stderr, "  offered:  %s %s\n", key.Type(), change.NewFingerprint)
+		fmt.Fprintln(os.Stderr, "Someone could be intercepting the connection, or the host was reinstalled.")
+		answer, err := promptTerminal(fmt.Sprintf("Type %s to trust the new key, anything else to refuse: ", name), true)
+		change.Approved = err == nil && strings.TrimSpace(answer) == name
+		return change.Approved
+	}
+}
+
+// replaceHostKey drops the host from the known_hosts lines at path holding
+// its old keys, all of the offered key's type, and records its new key
+// Other names and addresses sharing a line keep it, as do the host's keys
+// of other types; lines naming the host only through a wildcard are left
+// alone. The file is replaced through a temporary file.
+func replaceHostKey(path, hostname string, old []knownhosts.KnownKey, key ssh.PublicKey) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	stale := make(map[int]bool)
+	for _, k := range old {
+		if k.Filename == path {
+			stale[k.Line] = true
+		}
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	var kept strings.Builder
+	for i, line := range lines {
+		if stale[i+1] {
+			var ok bool
+			if line, ok = withoutHost(line, hostname); !ok {
+				continue
+			}
+		}
+		kept.WriteString(line)
+	}
+	if err := config.WriteFileAtomic(path, []byte(kept.String())); err != nil {
+		return fmt.Errorf("failed to replace host key: %w", err)
+	}
+	return recordHostKey(path, hostname, key)
+}
+
+// withoutHost removes the patterns naming hostname, plain or hashed, from a
+// known_hosts line, reporting false when no other pattern is left
+func withoutHost(line, hostname string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return line, true
+	}
+	want := knownhosts.Normalize(hostname)
+	var patterns []string
+	for _, pattern := range strings.Split(fields[0], ",") {
+		if knownhosts.Normalize(pattern) != want && !hashedHostMatches(pattern, want) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(patterns) == 0 {
+		return "", false
+	}
+	rest := strings.TrimLeft(strings.TrimPrefix(strings.TrimLeft(line, " \t"), fields[0]), " \t")
+	return strings.Join(patterns, ",") + " " + rest, true
+}
+
+// hashedHostMatches reports whether a hashed known_hosts pattern,
+// |1|salt|hash, stands for the normalized host name
+func hashedHostMatches(pattern, host string) bool {
+	parts := strings.Split(pattern, "|")
+	if len(parts) != 4 || parts[0] != "" || parts[1] != "1" {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	hash, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(host))
+	return hmac.Equal(mac.Sum(nil), hash)
+}
+
+// recordHostKey appends the host's key to the known_hosts file at path
+func recordHostKey(path, hostname string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to record host key: %w", err)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+	return err
+}
+
+// strictHostKeyChecking returns the OpenSSH StrictHostKeyChecking value for
+// policy, or "" to leave ssh's own default
+func strictHostKeyChecking(policy models.HostKeyPolicy) string {
+	switch policy {
+	case models.HostKeyStrict:
+		return "yes"
+	case models.HostKeyTOFU:
+		return "accept-new"
+	case models.HostKeyInsecure:
+		return "no"
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key types supported by GenerateKey
+const (
+	KeyTypeEd25519 = "ed25519"
+	KeyTypeRSA     = "rsa"
+	KeyTypeECDSA   = "ecdsa"
+)
+
+// KeyPair is a generated private key and its public half, both encoded the
+// way ssh-keygen writes them
+type KeyPair struct {
+	Private []byte // OpenSSH PEM, encrypted when a passphrase was given
+	Public  []byte // authorized_keys line including the comment
+}
+
+// GenerateKey creates a new key pair
+// bits selects the RSA modulus size (default 4096) or the ECDSA curve
+// (256, 384 or 521, default 256) and is ignored for ed25519
+func GenerateKey(keyType string, bits int, comment string, passphrase []byte) (*KeyPair, error) {
+	var private crypto.PrivateKey
+	var public crypto.PublicKey
+
+	switch keyType {
+	case KeyTypeEd25519, "":
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private, public = priv, pub
+	case KeyTypeRSA:
+		if bits == 0 {
+			bits = 4096
+		}
+		if bits < 2048 {
+			return nil, fmt.Errorf("RSA keys must be at least 2048 bits")
+		}
+		priv, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, err
+		}
+		private, public = priv, &priv.PublicKey
+	case KeyTypeECDSA:
+		var curve elliptic.Curve
+		switch bits {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("ECDSA keys must be 256, 384 or 521 bits")
+		}
+		priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private, public = priv, &priv.PublicKey
+	default:
+		return nil, fmt.Errorf("unknown key type %q (use ed25519, rsa or ecdsa)", keyType)
+	}
+
+	var block *pem.Block
+	var err error
+	if len(passphrase) > 0 {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, comment, passphrase)
+	} else {
+		block, err = ssh.MarshalPrivateKey(private, comment)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	publicLine := bytes.TrimSpace(ssh.MarshalAuthorizedKey(sshPublic))
+	if comment != "" {
+		publicLine = append(publicLine, ' ')
+		publicLine = append(publicLine, comment...)
+	}
+
+	return &KeyPair{
+		Private: pem.EncodeToMemory(block),
+		Public:  append(publicLine, '\n'),
+	}, nil
+}
+
+// WriteKeyPair saves the key pair to path and path.pub with the permissions
+// ssh expects, refusing to overwrite existing files
+func WriteKeyPair(kp *KeyPair, path string) error {
+	expanded, err := expandPath(path)
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{expanded, expanded + ".pub"} {
+		if _, err := os.Stat(p); err == nil {
+			return fmt.Errorf("%s already exists", p)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(expanded), 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+	if err := os.WriteFile(expanded, kp.Private, 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(expanded+".pub", kp.Public, 0644); err != nil {
+		os.Remove(expanded)
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+	return nil
+}
+
+// Fingerprint returns the SHA256 fingerprint of an authorized_keys line
+func Fingerprint(public []byte) (string, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey(public)
+	if err != nil {
+		return "", err
+	}
+	return ssh.FingerprintSHA256(key), nil
+}
@@ -0,0 +1,101 @@
+package ssh
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyInfo describes a key file: its algorithm, size and age
+type KeyInfo struct {
+	Path        string // expanded path of the private key
+	Type        string // ssh-ed25519, ssh-rsa, ecdsa-sha2-nistp256, ...
+	Bits        int    // RSA modulus or ECDSA curve size, 0 for ed25519
+	Fingerprint string
+	// Created is when the public key file was written, or the private key
+	// without one; OpenSSH keys carry no date of their own
+	Created time.Time
+}
+
+// InspectKey reads the key at path from its .pub file or, without one,
+// from the private key, which works for encrypted OpenSSH keys too
+func InspectKey(path string) (KeyInfo, error) {
+	expanded, err := expandPath(path)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	info := KeyInfo{Path: expanded}
+
+	var public ssh.PublicKey
+	stat, err := os.Stat(expanded + ".pub")
+	if err == nil {
+		data, err := os.ReadFile(expanded + ".pub")
+		if err != nil {
+			return info, err
+		}
+		if public, _, _, _, err = ssh.ParseAuthorizedKey(data); err != nil {
+			return info, fmt.Errorf("%s.pub is not a valid public key: %w", expanded, err)
+		}
+	} else {
+		if stat, err = os.Stat(expanded); err != nil {
+			return info, err
+		}
+		data, err := os.ReadFile(expanded)
+		if err != nil {
+			return info, err
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		var missing *ssh.PassphraseMissingError
+		switch {
+		case err == nil:
+			public = signer.PublicKey()
+		case errors.As(err, &missing) && missing.PublicKey != nil:
+			public = missing.PublicKey
+		default:
+			return info, fmt.Errorf("%s: no .pub file and %w", expanded, err)
+		}
+	}
+
+	info.Type = public.Type()
+	info.Bits = keyBits(public)
+	info.Fingerprint = ssh.FingerprintSHA256(public)
+	info.Created = stat.ModTime()
+	return info, nil
+}
+
+// Age returns how old the key is at now
+func (k KeyInfo) Age(now time.Time) time.Duration {
+	return now.Sub(k.Created)
+}
+
+// keyBits returns the RSA modulus or ECDSA curve size of a public key
+func keyBits(key ssh.PublicKey) int {
+	crypto, ok := key.(ssh.CryptoPublicKey)
+	if !ok {
+		return 0
+	}
+	switch k := crypto.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	}
+	return 0
+}
+
+// GenerateKeyType returns the GenerateKey type matching a public key type,
+// ed25519 for types it can't generate
+func GenerateKeyType(keyType string) string {
+	switch keyType {
+	case ssh.KeyAlgoRSA:
+		return KeyTypeRSA
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		return KeyTypeECDSA
+	}
+	return KeyTypeEd25519
+}
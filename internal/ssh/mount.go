@@ -0,0 +1,161 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/secret"
+)
+
+// ErrSSHFSNotFound is returned when the sshfs binary is not installed
+var ErrSSHFSNotFound = errors.New("sshfs not found in PATH (install sshfs, or macFUSE and sshfs on macOS)")
+
+// MountOptions tune an sshfs mount
+type MountOptions struct {
+	ReadOnly bool
+	// Extra are passed to sshfs as additional -o options
+	Extra []string
+}
+
+// SSHFSArgs builds the sshfs command line that mounts remote on mountpoint
+// using the host's address, port, identity and jump host; an empty remote
+// mounts the login directory
+func SSHFSArgs(host models.Host, profile models.Profile, remote, mountpoint string, opts MountOptions) []string {
+	args := []string{fmt.Sprintf("%s@%s:%s", host.User, host.Host, remote), mountpoint}
+
+	if host.Port != 0 && host.Port != 22 {
+		args = append(args, "-p", fmt.Sprintf("%d", host.Port))
+	}
+
+	options := []string{"reconnect"}
+	identity := host.Identity
+	if identity == "" {
+		identity = profile.IdentityFile
+	}
+	if identity != "" && host.AuthType != models.AuthTypePassword {
+		if path, err := expandPath(identity); err == nil {
+			options = append(options, "IdentityFile="+path)
+		}
+	}
+	if host.Proxy != "" {
+		options = append(options, "ProxyJump="+host.Proxy)
+	} else if profile.ProxyCommand != "" {
+		options = append(options, "ProxyCommand="+profile.ProxyCommand)
+	}
+	if checking := strictHostKeyChecking(host.HostKeyPolicy); checking != "" {
+		options = append(options, "StrictHostKeyChecking="+checking)
+	}
+	if profile.Timeout > 0 {
+		options = append(options, fmt.Sprintf("ConnectTimeout=%d", profile.Timeout))
+	}
+	if profile.ServerAliveEnabled && profile.KeepAliveInterval > 0 {
+		options = append(options, fmt.Sprintf("ServerAliveInterval=%d", profile.KeepAliveInterval))
+		if profile.KeepAliveCountMax > 0 {
+			options = append(options, fmt.Sprintf("ServerAliveCountMax=%d", profile.KeepAliveCountMax))
+		}
+	}
+	if usesStoredPassword(host) || host.AuthType == models.AuthTypeVaultOTP {
+		options = append(options, "password_stdin")
+	}
+	if opts.ReadOnly {
+		options = append(options, "ro")
+	}
+	options = append(options, opts.Extra...)
+
+	for _, o := range options {
+		args = append(args, "-o", o)
+	}
+	return args
+}
+
+// usesStoredPassword reports whether the host authenticates with a saved password
+func usesStoredPassword(host models.Host) bool {
+	return host.AuthType == models.AuthTypePassword && host.Password != ""
+}
+
+// Mount mounts remote from the host on mountpoint with sshfs
+// sshfs daemonizes once the mount is up, so Mount returns when the
+// filesystem is ready. A stored password, one kept in the keyring or a Vault
+// OTP is handed over on sshfs's stdin. vault-cert hosts can't be mounted since their key only
+// lives in sshm's memory.
+func Mount(host models.Host, profile models.Profile, remote, mountpoint string, opts MountOptions) error {
+	if host.AuthType == models.AuthTypeVaultCert {
+		return fmt.Errorf("%s: mounting is not supported with vault-cert auth", host.Name)
+	}
+	sshfs, err := exec.LookPath("sshfs")
+	if err != nil {
+		return ErrSSHFSNotFound
+	}
+
+	args := SSHFSArgs(host, profile, remote, mountpoint, opts)
+	saved := KeyringPassword(host)
+	if saved != "" {
+		args = append(args, "-o", "password_stdin")
+	}
+	cmd := exec.Command(sshfs, args...)
+	switch {
+	case saved != "":
+		cmd.Stdin = strings.NewReader(saved + "\n")
+	case usesStoredPassword(host):
+		password, err := secret.Reveal(host.Password)
+		if err != nil {
+			return err
+		}
+		cmd.Stdin = strings.NewReader(password + "\n")
+	case host.AuthType == models.AuthTypeVaultOTP:
+		otp, err := vaultOTP(host)
+		if err != nil {
+			return err
+		}
+		cmd.Stdin = strings.NewReader(otp + "\n")
+	default:
+		cmd.Stdin = os.Stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("sshfs: %s", msg)
+		}
+		return fmt.Errorf("sshfs: %w", err)
+	}
+	return nil
+}
+
+// Unmount detaches a FUSE mount created by Mount
+func Unmount(mountpoint string) error {
+	var candidates [][]string
+	switch runtime.GOOS {
+	case "linux":
+		candidates = [][]string{{"fusermount3", "-u"}, {"fusermount", "-u"}, {"umount"}}
+	case "windows":
+		return fmt.Errorf("unmounting is not supported on windows, disconnect the drive in Explorer instead")
+	default:
+		candidates = [][]string{{"umount"}}
+	}
+
+	var names []string
+	for _, c := range candidates {
+		names = append(names, c[0])
+		path, err := exec.LookPath(c[0])
+		if err != nil {
+			continue
+		}
+		out, err := exec.Command(path, append(c[1:], mountpoint)...).CombinedOutput()
+		if err != nil {
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				return fmt.Errorf("%s: %s", c[0], msg)
+			}
+			return fmt.Errorf("%s: %w", c[0], err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no unmount tool found (looked for %s)", strings.Join(names, ", "))
+}
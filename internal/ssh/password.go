@@ -0,0 +1,67 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/secret"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// KeyringPasswords lets password hosts without a saved password use one
+// kept in the system keyring; when there is none it is asked for on the
+// terminal and, after a successful login, offered to be kept there
+var KeyringPasswords bool
+
+// addKeyringPasswordAuth authenticates with the host's password from the
+// keyring, asking for it on the terminal when it is missing or rejected
+func (c *Connector) addKeyringPasswordAuth(config *ssh.ClientConfig, host models.Host) error {
+	stored, _ := secret.HostPassword(host.ID)
+	if stored == "" && (c.noPrompt || !term.IsTerminal(int(os.Stdin.Fd()))) {
+		return errPasswordRequired
+	}
+
+	attempt := 0
+	ask := func() (string, error) {
+		attempt++
+		if attempt == 1 && stored != "" {
+			return stored, nil
+		}
+		if c.noPrompt {
+			return "", errPasswordRequired
+		}
+		if attempt == 2 && stored != "" {
+			fmt.Fprintf(os.Stderr, "The password of %s in the keyring was rejected\n", host.Name)
+		}
+		password, err := promptTerminal(fmt.Sprintf("%s@%s's password: ", host.User, host.Host), false)
+		c.typedPassword = password
+		return password, err
+	}
+	config.Auth = append(config.Auth, ssh.RetryableAuthMethod(ssh.PasswordCallback(ask), 3))
+	return nil
+}
+
+// offerToSavePassword asks whether to keep the password typed for host in
+// the keyring, for the next connections and sudo prompts
+func offerToSavePassword(host models.Host, password string) {
+	answer, _ := promptTerminal(fmt.Sprintf("Save the password of %s in the system keyring? [y/N] ", host.Name), true)
+	if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		return
+	}
+	if err := secret.SaveHostPassword(host.ID, password); err != nil {
+		fmt.Fprintf(os.Stderr, "Password not saved: %v\n", err)
+	}
+}
+
+// KeyringPassword returns the keyring password of a password host without
+// one in the hosts file, or "" when there is none
+func KeyringPassword(host models.Host) string {
+	if !KeyringPasswords || host.AuthType != models.AuthTypePassword || host.Password != "" {
+		return ""
+	}
+	password, _ := secret.HostPassword(host.ID)
+	return password
+}
@@ -0,0 +1,213 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/muesli/cancelreader"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/recording"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// Session is an interactive shell on a host
+// It satisfies the tea.ExecCommand interface so the TUI can release the
+// terminal while the session runs and resume afterwards
+type Session struct {
+	host    models.Host
+	profile models.Profile
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	command string
+	record  string // asciicast file to record the session in, if any
+	options recording.Options
+	ctx     context.Context // ends the session when done, nil for never
+
+	// ConnectTime is how long establishing the connection took
+	ConnectTime time.Duration
+	// Warnings are the weak keys and algorithms seen while connecting
+	Warnings []string
+	// HostKeyChanges are the changed host keys met while connecting
+	HostKeyChanges []models.HostKeyChange
+}
+
+// NewSession creates an interactive session for the host using the terminal's stdio
+func NewSession(host models.Host, profile models.Profile) *Session {
+	return &Session{
+		host:    host,
+		profile: profile,
+		stdin:   os.Stdin,
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
+	}
+}
+
+// SetStdin sets the session input
+func (s *Session) SetStdin(r io.Reader) {
+	s.stdin = r
+}
+
+// SetStdout sets the session output
+func (s *Session) SetStdout(w io.Writer) {
+	s.stdout = w
+}
+
+// SetStderr sets the session error output
+func (s *Session) SetStderr(w io.Writer) {
+	s.stderr = w
+}
+
+// SetCommand runs command on a terminal instead of a login shell, like
+// "ssh -t host command"
+func (s *Session) SetCommand(command string) {
+	s.command = command
+}
+
+// SetContext ends the session when ctx is done, on SIGTERM for instance; the
+// terminal is restored as when the shell exits
+func (s *Session) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// Record saves the session as an asciicast v2 file at path
+func (s *Session) Record(path string, opts recording.Options) {
+	s.record = path
+	s.options = opts
+}
+
+// Run connects to the host and runs an interactive shell, or the command set
+// with SetCommand, until it exits
+func (s *Session) Run() error {
+	connector := NewConnector()
+	defer connector.Close()
+
+	start := time.Now()
+	err := connector.Connect(s.host, s.profile)
+	s.Warnings = connector.Warnings()
+	s.HostKeyChanges = connector.HostKeyChanges()
+	for _, warning := range s.Warnings {
+		fmt.Fprintf(s.stderr, "warning: %s\n", warning)
+	}
+	if err != nil {
+		return err
+	}
+	s.ConnectTime = time.Since(start)
+
+	session, err := connector.newSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	if s.ctx != nil {
+		// Dropping the connection ends Wait even when the server doesn't answer
+		stop := context.AfterFunc(s.ctx, func() { connector.client.Close() })
+		defer stop()
+	}
+
+	// Set up terminal
+	stdin, stopStdin := cancelableStdin(s.stdin)
+	defer stopStdin()
+	session.Stdout = s.stdout
+	session.Stderr = s.stderr
+	session.Stdin = stdin
+
+	// Put the local terminal in raw mode so keystrokes go straight to the remote
+	if f, ok := s.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		restore, err := makeRaw(f, s.stdout)
+		if err != nil {
+			return fmt.Errorf("failed to set terminal to raw mode: %w", err)
+		}
+		defer restore()
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+
+	termType := os.Getenv("TERM")
+	if termType == "" {
+		termType = defaultTermType
+	}
+
+	// Get terminal dimensions
+	width, height := getTerminalSize()
+	err = session.RequestPty(termType, height, width, modes)
+	if err != nil {
+		return fmt.Errorf("request for pseudo terminal failed: %w", err)
+	}
+
+	var recorder *recording.Recorder
+	if s.record != "" {
+		recorder, err = recording.Create(s.record, width, height, s.host.Name, s.options)
+		if err != nil {
+			return err
+		}
+		defer recorder.Close()
+		session.Stdout = recorder.Output(s.stdout)
+		session.Stderr = recorder.Output(s.stderr)
+		session.Stdin = recorder.Input(stdin)
+	}
+
+	// Pass terminal resizes on to the remote
+	stopResize := watchResize(func() {
+		width, height := getTerminalSize()
+		session.WindowChange(height, width)
+		if recorder != nil {
+			recorder.Resize(width, height)
+		}
+	})
+	defer stopResize()
+
+	if s.command != "" {
+		err = session.Start(s.command)
+	} else {
+		err = session.Shell()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start shell: %w", err)
+	}
+
+	err = session.Wait()
+	if s.ctx != nil && s.ctx.Err() != nil {
+		return s.ctx.Err()
+	}
+	return err
+}
+
+// cancelableStdin returns a reader of the terminal in whose pending read
+// ends when stop is called
+// x/crypto/ssh copies Session.Stdin in a goroutine that stays blocked in Read
+// after the shell exits and would take the first key press meant for the
+// TUI. Other readers are returned as they are.
+func cancelableStdin(in io.Reader) (io.Reader, func()) {
+	f, ok := in.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return in, func() {}
+	}
+	r, err := cancelreader.NewReader(f)
+	if err != nil {
+		return in, func() {}
+	}
+	return r, func() {
+		r.Cancel()
+		r.Close()
+	}
+}
+
+// ExitStatus returns the remote exit status carried by err, if any
+// A non-zero remote exit status still means the session itself worked
+func ExitStatus(err error) (int, bool) {
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,142 @@
+package ssh
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// signerCache keeps the keys parsed from identity files for the life of the
+// process, so bulk operations don't read and parse them per host
+// An entry is dropped once its file's size or modification time changes.
+var signerCache = struct {
+	sync.Mutex
+	entries map[string]cachedSigner // by expanded path
+}{entries: make(map[string]cachedSigner)}
+
+// cachedSigner is a parsed key file, or why it could not be parsed
+type cachedSigner struct {
+	modTime time.Time
+	size    int64
+	signer  ssh.Signer
+	err     error
+}
+
+// loadSigner returns the key of the private key file at expandedPath,
+// parsing it only when it changed since the last call
+func loadSigner(expandedPath string) (ssh.Signer, error) {
+	info, err := os.Stat(expandedPath)
+	if err != nil {
+		return nil, err
+	}
+	signerCache.Lock()
+	entry, ok := signerCache.entries[expandedPath]
+	signerCache.Unlock()
+	if ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.signer, entry.err
+	}
+
+	data, err := os.ReadFile(expandedPath)
+	if err != nil {
+		return nil, err
+	}
+	entry = cachedSigner{modTime: info.ModTime(), size: info.Size()}
+	entry.signer, entry.err = ssh.ParsePrivateKey(data)
+	signerCache.Lock()
+	signerCache.entries[expandedPath] = entry
+	signerCache.Unlock()
+	return entry.signer, entry.err
+}
+
+// errNoAgent is returned when SSH_AUTH_SOCK is not set
+var errNoAgent = errors.New("SSH_AUTH_SOCK is not set")
+
+// sharedAgent is the one connection to the SSH agent of the process; the
+// agent client serializes requests, so connectors share it
+// It is dialed again when SSH_AUTH_SOCK changes or the agent went away.
+var sharedAgent struct {
+	sync.Mutex
+	socket string // SSH_AUTH_SOCK, or the relay to the Windows agent
+	conn   io.ReadWriteCloser
+	client agent.ExtendedAgent
+}
+
+// agentClient returns the shared agent connection, dialing it if needed
+// In WSL without SSH_AUTH_SOCK it may be the Windows agent, see
+// WindowsAgentRelay.
+func agentClient() (agent.ExtendedAgent, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	relay := ""
+	if socket == "" {
+		if relay = windowsAgent(); relay == "" {
+			return nil, errNoAgent
+		}
+		socket = relay
+	}
+	sharedAgent.Lock()
+	defer sharedAgent.Unlock()
+	if sharedAgent.client != nil && sharedAgent.socket == socket {
+		return sharedAgent.client, nil
+	}
+	if sharedAgent.conn != nil {
+		sharedAgent.conn.Close()
+	}
+	var conn io.ReadWriteCloser
+	var err error
+	if relay != "" {
+		conn, err = dialRelay(relay)
+	} else {
+		conn, err = net.Dial("unix", socket)
+	}
+	if err != nil {
+		sharedAgent.conn, sharedAgent.client = nil, nil
+		return nil, err
+	}
+	sharedAgent.socket, sharedAgent.conn, sharedAgent.client = socket, conn, agent.NewClient(conn)
+	return sharedAgent.client, nil
+}
+
+// dropAgent forgets the shared agent connection after it failed
+func dropAgent(client agent.ExtendedAgent) {
+	sharedAgent.Lock()
+	defer sharedAgent.Unlock()
+	if sharedAgent.client != client {
+		return
+	}
+	sharedAgent.conn.Close()
+	sharedAgent.conn, sharedAgent.client = nil, nil
+}
+
+// withAgent calls fn with the shared agent, once more on a new connection
+// if the first one failed, for an agent that restarted
+func withAgent(fn func(agent.ExtendedAgent) error) error {
+	var err error
+	for range 2 {
+		var client agent.ExtendedAgent
+		if client, err = agentClient(); err != nil {
+			return err
+		}
+		if err = fn(client); err == nil {
+			return nil
+		}
+		dropAgent(client)
+	}
+	return err
+}
+
+// agentSigners returns the keys the agent holds
+func agentSigners() ([]ssh.Signer, error) {
+	var signers []ssh.Signer
+	err := withAgent(func(a agent.ExtendedAgent) error {
+		var err error
+		signers, err = a.Signers()
+		return err
+	})
+	return signers, err
+}
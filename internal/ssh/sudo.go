@@ -0,0 +1,187 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sshm/sshm/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// sudoPrompt is the prompt sudo is told to print, so it can be recognised
+// in the output regardless of the remote locale
+const sudoPrompt = "[sshm] sudo password required:"
+
+// sudoStarted is printed on a line of its own to stderr once sudo let the
+// command run, telling that no prompt is coming any more
+const sudoStarted = "[sshm] sudo started"
+
+// ErrSudoPassword is returned when sudo rejected the supplied password
+var ErrSudoPassword = errors.New("sudo: incorrect password")
+
+// PasswordFunc supplies a password when it is first needed
+type PasswordFunc func() (string, error)
+
+// SudoCommand wraps command so it runs as root through sudo, reading the
+// password from stdin when sudo asks for one and announcing the start of
+// the command on stderr
+func SudoCommand(command string) string {
+	return "sudo -S -p '" + sudoPrompt + "' sh -c " + ShellQuote("echo '"+sudoStarted+"' >&2\n"+command)
+}
+
+// ShellQuote quotes s as a single POSIX shell word
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RunSudo runs command on the host through sudo
+// If sudo asks for a password it is taken from password and written to the
+// session; the prompt itself is removed from stderr. The command gets EOF on
+// stdin once sudo lets it run, as without sudo.
+func RunSudo(ctx context.Context, host models.Host, profile models.Profile, command string, password PasswordFunc, stdout, stderr io.Writer) error {
+	sw := &sudoWriter{w: stderr, password: password}
+	err := runCommand(ctx, host, profile, SudoCommand(command), stdout, sw, func(session *ssh.Session) error {
+		stdin, err := session.StdinPipe()
+		if err != nil {
+			return err
+		}
+		sw.stdin = stdin
+		session.Stderr = sw
+		return nil
+	})
+	if ctx.Err() == nil {
+		// A cancelled run doesn't wait for someone to type the password
+		sw.answering.Wait()
+	}
+	sw.Flush()
+	if sudoErr := sw.Err(); sudoErr != nil {
+		return sudoErr
+	}
+	return err
+}
+
+// sudoWriter passes stderr through, answering sudo's password prompt on
+// stdin the first time it appears and closing stdin once sudo started the
+// command
+// The password is asked for in the background, so stderr isn't held up.
+type sudoWriter struct {
+	w        io.Writer
+	stdin    io.WriteCloser
+	password PasswordFunc
+
+	mu        sync.Mutex
+	pending   []byte
+	asked     bool // the prompt was seen
+	closed    bool // stdin was closed
+	err       error
+	answering sync.WaitGroup
+}
+
+func (s *sudoWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, p...)
+	startedLine := sudoStarted + "\n"
+	for {
+		prompt := bytes.Index(s.pending, []byte(sudoPrompt))
+		started := bytes.Index(s.pending, []byte(startedLine))
+		if prompt < 0 && started < 0 {
+			break
+		}
+		if started < 0 || prompt >= 0 && prompt < started {
+			if _, err := s.w.Write(s.pending[:prompt]); err != nil {
+				return len(p), err
+			}
+			s.pending = s.pending[prompt+len(sudoPrompt):]
+			s.prompted()
+			continue
+		}
+		if _, err := s.w.Write(s.pending[:started]); err != nil {
+			return len(p), err
+		}
+		s.pending = s.pending[started+len(startedLine):]
+		// No prompt comes any more; an answer being typed closes stdin itself
+		if !s.asked {
+			s.closeStdin()
+		}
+	}
+
+	// Hold back a partial prompt or start notice until the rest arrives
+	keep := 0
+	for _, token := range []string{sudoPrompt, startedLine} {
+		for n := len(token) - 1; n > keep; n-- {
+			if bytes.HasSuffix(s.pending, []byte(token[:n])) {
+				keep = n
+				break
+			}
+		}
+	}
+	out := s.pending[:len(s.pending)-keep]
+	s.pending = append([]byte(nil), s.pending[len(s.pending)-keep:]...)
+	if _, err := s.w.Write(out); err != nil {
+		return len(p), err
+	}
+	return len(p), nil
+}
+
+// prompted answers the first prompt in the background; a second prompt
+// means the password was wrong, so stdin is closed and sudo gives up
+func (s *sudoWriter) prompted() {
+	if s.stdin == nil {
+		return
+	}
+	if s.asked {
+		s.err = ErrSudoPassword
+		s.closeStdin()
+		return
+	}
+	s.asked = true
+	s.answering.Add(1)
+	go s.answer()
+}
+
+// answer asks for the password and writes it to sudo
+func (s *sudoWriter) answer() {
+	defer s.answering.Done()
+	password, err := s.password()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.err = err
+	} else if !s.closed {
+		io.WriteString(s.stdin, password+"\n")
+	}
+	// The command itself gets EOF, as without --sudo
+	s.closeStdin()
+}
+
+// closeStdin closes the session's stdin once; called with mu held
+func (s *sudoWriter) closeStdin() {
+	if s.stdin != nil && !s.closed {
+		s.stdin.Close()
+		s.closed = true
+	}
+}
+
+// Err returns why sudo couldn't be given a password, if it couldn't
+func (s *sudoWriter) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Flush writes any output held back while looking for the prompt
+func (s *sudoWriter) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) > 0 {
+		s.w.Write(s.pending)
+		s.pending = nil
+	}
+}
@@ -0,0 +1,47 @@
+//go:build !windows
+
+package ssh
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// defaultTermType is the terminal type requested when $TERM is unset
+const defaultTermType = "xterm"
+
+// makeRaw puts the terminal in raw mode so keystrokes go straight to the
+// remote, returning the function restoring it
+func makeRaw(in *os.File, _ io.Writer) (func(), error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	return func() { term.Restore(int(in.Fd()), state) }, nil
+}
+
+// watchResize calls resized whenever the terminal changes size (SIGWINCH)
+// until the returned function is called
+func watchResize(resized func()) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+				resized()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package ssh
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatchResize(t *testing.T) {
+	resized := make(chan struct{}, 1)
+	stop := watchResize(func() {
+		select {
+		case resized <- struct{}{}:
+		default:
+		}
+	})
+	defer stop()
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGWINCH); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-resized:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected SIGWINCH to report a resize")
+	}
+}
@@ -0,0 +1,82 @@
+//go:build windows
+
+package ssh
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/term"
+)
+
+// defaultTermType is the terminal type requested when $TERM is unset, as it
+// usually is on Windows; Windows Terminal and conhost in virtual terminal
+// mode handle 256 colors
+const defaultTermType = "xterm-256color"
+
+// resizePollInterval is how often the console size is checked; Windows has
+// no signal for it
+const resizePollInterval = 250 * time.Millisecond
+
+// makeRaw switches the console to virtual terminal mode both ways, as
+// ConPTY does for the programs it hosts: keystrokes go to the remote as VT
+// sequences (arrows, Ctrl+C, ...) and its escape sequences (colors, cursor
+// movement) are interpreted instead of printed
+// It returns the function restoring the previous console modes.
+func makeRaw(in *os.File, out io.Writer) (func(), error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	restore := func() { term.Restore(int(in.Fd()), state) }
+
+	f, ok := out.(*os.File)
+	if !ok {
+		return restore, nil
+	}
+	console := windows.Handle(f.Fd())
+	var mode uint32
+	if windows.GetConsoleMode(console, &mode) != nil {
+		// Redirected output, nothing to interpret
+		return restore, nil
+	}
+	vt := mode | windows.ENABLE_PROCESSED_OUTPUT | windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING | windows.DISABLE_NEWLINE_AUTO_RETURN
+	if err := windows.SetConsoleMode(console, vt); err != nil {
+		// Consoles before Windows 10 1809 lack some of the flags
+		if err := windows.SetConsoleMode(console, mode|windows.ENABLE_PROCESSED_OUTPUT|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+			restore()
+			return nil, err
+		}
+	}
+	return func() {
+		windows.SetConsoleMode(console, mode)
+		restore()
+	}, nil
+}
+
+// watchResize calls resized whenever the console changes size until the
+// returned function is called
+// The console size is polled; input events would carry it too, but reading
+// them would take keystrokes from the session.
+func watchResize(resized func()) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(resizePollInterval)
+		defer ticker.Stop()
+		width, height := getTerminalSize()
+		for {
+			select {
+			case <-ticker.C:
+				if w, h := getTerminalSize(); w != width || h != height {
+					width, height = w, h
+					resized()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
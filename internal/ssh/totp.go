@@ -0,0 +1,89 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sshm/sshm/internal/clipboard"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/secret"
+	"github.com/sshm/sshm/internal/totp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// codePrompt matches keyboard-interactive questions asking for a 2FA code
+var codePrompt = regexp.MustCompile(`(?i)verification code|one[- ]time|authenticator|2fa|otp|token code|code:`)
+
+// passwordPrompt matches keyboard-interactive questions asking for the
+// password
+var passwordPrompt = regexp.MustCompile(`(?i)password`)
+
+// totpChallenge answers keyboard-interactive prompts for hosts with a TOTP
+// secret: code prompts from the keyring secret, password prompts with the
+// saved password, and anything else on the terminal
+func totpChallenge(host models.Host) ssh.KeyboardInteractiveChallenge {
+	return func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+		if instruction != "" && len(questions) > 0 {
+			fmt.Fprintln(os.Stderr, instruction)
+		}
+		answers := make([]string, len(questions))
+		for i, q := range questions {
+			var err error
+			switch {
+			case codePrompt.MatchString(q):
+				answers[i], err = totpAnswer(host, q)
+			case passwordPrompt.MatchString(q) && host.Password != "":
+				answers[i], err = secret.Reveal(host.Password)
+			default:
+				answers[i], err = promptTerminal(q, echos[i])
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return answers, nil
+	}
+}
+
+// totpAnswer returns the current code, or in show mode prints it, copies it
+// to the clipboard and asks the user to paste it
+func totpAnswer(host models.Host, question string) (string, error) {
+	key, err := totp.Load(host.ID)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", host.Name, err)
+	}
+	now := time.Now()
+	code := key.Code(now)
+	if host.TOTP != models.TOTPShow {
+		return code, nil
+	}
+
+	note := ""
+	if clipboard.CopyToClipboard(code) == nil {
+		note = ", copied to the clipboard"
+	}
+	fmt.Fprintf(os.Stderr, "Verification code for %s: %s (valid %s%s)\n", host.Name, code, key.Remaining(now), note)
+	return promptTerminal(question, false)
+}
+
+// promptTerminal asks question on the terminal, hiding the answer unless
+// echo is set
+func promptTerminal(question string, echo bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("server asked %q and there is no terminal to answer", strings.TrimSpace(question))
+	}
+	fmt.Fprint(os.Stderr, question)
+	if echo {
+		var answer string
+		_, err := fmt.Fscanln(os.Stdin, &answer)
+		return answer, err
+	}
+	answer, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(answer), err
+}
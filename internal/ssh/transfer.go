@@ -0,0 +1,255 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/sftp"
+	"github.com/sshm/sshm/internal/models"
+)
+
+// ProgressFunc is called as a file is transferred with the bytes done so far
+type ProgressFunc func(name string, done, total int64)
+
+// TransferOptions controls how files are copied
+type TransferOptions struct {
+	Recursive bool         // copy directories and their contents
+	Resume    bool         // continue partial files instead of starting over
+	Progress  ProgressFunc // optional progress callback
+}
+
+// Transfer is an SFTP session for copying files to and from a host
+type Transfer struct {
+	connector *Connector
+	client    *sftp.Client
+}
+
+// OpenTransfer connects to the host and starts an SFTP session
+func OpenTransfer(host models.Host, profile models.Profile) (*Transfer, error) {
+	connector := NewConnector()
+	if err := connector.Connect(host, profile); err != nil {
+		connector.Close()
+		return nil, err
+	}
+
+	client, err := sftp.NewClient(connector.client)
+	if err != nil {
+		connector.Close()
+		return nil, fmt.Errorf("failed to start SFTP session: %w", err)
+	}
+	return &Transfer{connector: connector, client: client}, nil
+}
+
+// Close ends the SFTP session and disconnects
+func (t *Transfer) Close() error {
+	t.client.Close()
+	return t.connector.Close()
+}
+
+// fileSystem abstracts the local disk and the SFTP server so the copy logic
+// is shared by both directions
+type fileSystem interface {
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.FileInfo, error)
+	Mkdir(name string, perm os.FileMode) error
+	Open(name string) (io.ReadSeekCloser, error)
+	OpenWrite(name string, perm os.FileMode, appendMode bool) (io.WriteCloser, error)
+	Join(elem ...string) string
+}
+
+type localFS struct{}
+
+func (localFS) Stat(name string) (os.FileInfo, error) { return os.Stat(name) }
+
+func (localFS) ReadDir(name string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (localFS) Mkdir(name string, perm os.FileMode) error { return os.MkdirAll(name, perm) }
+
+func (localFS) Open(name string) (io.ReadSeekCloser, error) { return os.Open(name) }
+
+func (localFS) OpenWrite(name string, perm os.FileMode, appendMode bool) (io.WriteCloser, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendMode {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	return os.OpenFile(name, flags, perm)
+}
+
+func (localFS) Join(elem ...string) string { return filepath.Join(elem...) }
+
+type remoteFS struct {
+	client *sftp.Client
+}
+
+func (r remoteFS) Stat(name string) (os.FileInfo, error) { return r.client.Stat(name) }
+
+func (r remoteFS) ReadDir(name string) ([]os.FileInfo, error) { return r.client.ReadDir(name) }
+
+func (r remoteFS) Mkdir(name string, perm os.FileMode) error {
+	if err := r.client.MkdirAll(name); err != nil {
+		return err
+	}
+	return r.client.Chmod(name, perm)
+}
+
+func (r remoteFS) Open(name string) (io.ReadSeekCloser, error) { return r.client.Open(name) }
+
+func (r remoteFS) OpenWrite(name string, perm os.FileMode, appendMode bool) (io.WriteCloser, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendMode {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := r.client.OpenFile(name, flags)
+	if err != nil {
+		return nil, err
+	}
+	f.Chmod(perm)
+	return f, nil
+}
+
+func (remoteFS) Join(elem ...string) string { return path.Join(elem...) }
+
+// Upload copies a local file or directory to the host
+// Cancelling ctx stops the copy, leaving a partial file that can be resumed.
+func (t *Transfer) Upload(ctx context.Context, local, remote string, opts TransferOptions) error {
+	return copyTree(ctx, localFS{}, local, remoteFS{t.client}, remote, opts)
+}
+
+// Download copies a file or directory from the host
+func (t *Transfer) Download(ctx context.Context, remote, local string, opts TransferOptions) error {
+	return copyTree(ctx, remoteFS{t.client}, remote, localFS{}, local, opts)
+}
+
+// copyTree copies src to dst like cp: when dst is an existing directory the
+// source is placed inside it under its own name
+func copyTree(ctx context.Context, srcFS fileSystem, src string, dstFS fileSystem, dst string, opts TransferOptions) error {
+	info, err := srcFS.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() && !opts.Recursive {
+		return fmt.Errorf("%s is a directory (use -r to copy it)", src)
+	}
+
+	if dstInfo, err := dstFS.Stat(dst); err == nil && dstInfo.IsDir() {
+		dst = dstFS.Join(dst, info.Name())
+	}
+	return copyEntry(ctx, srcFS, src, info, dstFS, dst, opts)
+}
+
+func copyEntry(ctx context.Context, srcFS fileSystem, src string, info os.FileInfo, dstFS fileSystem, dst string, opts TransferOptions) error {
+	if !info.IsDir() {
+		return copyFile(ctx, srcFS, src, info, dstFS, dst, opts)
+	}
+
+	if err := dstFS.Mkdir(dst, info.Mode().Perm()|0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	entries, err := srcFS.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && !entry.Mode().IsRegular() {
+			// Skip symlinks, sockets and devices
+			continue
+		}
+		if err := copyEntry(ctx, srcFS, srcFS.Join(src, entry.Name()), entry, dstFS, dstFS.Join(dst, entry.Name()), opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(ctx context.Context, srcFS fileSystem, src string, info os.FileInfo, dstFS fileSystem, dst string, opts TransferOptions) error {
+	total := info.Size()
+
+	// Resume from the size already present at the destination
+	var offset int64
+	if opts.Resume {
+		if dstInfo, err := dstFS.Stat(dst); err == nil && !dstInfo.IsDir() && dstInfo.Size() <= total {
+			offset = dstInfo.Size()
+		}
+	}
+
+	in, err := srcFS.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if offset > 0 {
+		if offset == total {
+			if opts.Progress != nil {
+				opts.Progress(info.Name(), total, total)
+			}
+			return nil
+		}
+		if _, err := in.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to resume %s: %w", src, err)
+		}
+	}
+
+	out, err := dstFS.OpenWrite(dst, info.Mode().Perm(), offset > 0)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+
+	var w io.Writer = out
+	if opts.Progress != nil {
+		w = &progressWriter{w: out, name: info.Name(), done: offset, total: total, fn: opts.Progress}
+		opts.Progress(info.Name(), offset, total)
+	}
+	if _, err := io.Copy(w, &contextReader{ctx: ctx, r: in}); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return out.Close()
+}
+
+// progressWriter reports the bytes written through it
+type progressWriter struct {
+	w     io.Writer
+	name  string
+	done  int64
+	total int64
+	fn    ProgressFunc
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	p.fn(p.name, p.done, p.total)
+	return n, err
+}
+
+// contextReader stops reading once its context is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
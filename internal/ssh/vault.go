@@ -0,0 +1,93 @@
+package ssh
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/vault"
+	"golang.org/x/crypto/ssh"
+)
+
+// Vault issues the credentials of hosts with vault-cert and vault-otp auth;
+// without settings it uses $VAULT_ADDR and $VAULT_TOKEN
+var Vault = &vault.Client{}
+
+// addVaultCertAuth signs a key generated for this connection with Vault
+// and authenticates with the resulting certificate, so no long-lived key is
+// involved
+func (c *Connector) addVaultCertAuth(config *ssh.ClientConfig, host models.Host) error {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		return err
+	}
+	cert, err := Vault.SignKey(context.Background(), host.VaultRole, signer.PublicKey(), host.User)
+	if err != nil {
+		return fmt.Errorf("failed to sign key for %s: %w", host.Name, err)
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return err
+	}
+	config.Auth = append(config.Auth, ssh.PublicKeys(certSigner))
+	return nil
+}
+
+// addVaultOTPAuth asks Vault for a one-time password and offers it both as
+// a password and as the answer to keyboard-interactive prompts, which is how
+// PAM-based OTP helpers ask for it
+func (c *Connector) addVaultOTPAuth(config *ssh.ClientConfig, host models.Host) error {
+	otp, err := vaultOTP(host)
+	if err != nil {
+		return err
+	}
+	config.Auth = append(config.Auth,
+		ssh.Password(otp),
+		ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range answers {
+				answers[i] = otp
+			}
+			return answers, nil
+		}),
+	)
+	return nil
+}
+
+// vaultOTP returns a one-time password for the host, which Vault issues per
+// IP address
+func vaultOTP(host models.Host) (string, error) {
+	ip, err := resolveIP(host.Host)
+	if err != nil {
+		return "", err
+	}
+	otp, err := Vault.OTP(context.Background(), host.VaultRole, ip, host.User)
+	if err != nil {
+		return "", fmt.Errorf("failed to get an OTP for %s: %w", host.Name, err)
+	}
+	return otp, nil
+}
+
+// resolveIP returns addr as an IP address, looking up host names
+func resolveIP(addr string) (string, error) {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip.String(), nil
+	}
+	ips, err := net.LookupIP(addr)
+	if err != nil {
+		return "", err
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip.String(), nil
+		}
+	}
+	return ips[0].String(), nil
+}
@@ -0,0 +1,160 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// WindowsKeys makes sshm running in WSL also try the default keys in the
+// .ssh folder of the Windows user
+var WindowsKeys bool
+
+// WindowsAgentRelay, when set, makes sshm running in WSL without an agent of
+// its own use the Windows OpenSSH agent, through this relay program
+var WindowsAgentRelay string
+
+// windowsAgentPipe is the named pipe the Windows OpenSSH agent listens on
+const windowsAgentPipe = "//./pipe/openssh-ssh-agent"
+
+// wslMountRoot is where WSL mounts the Windows drives by default
+const wslMountRoot = "/mnt/"
+
+var (
+	// drivePath matches Windows paths with a drive letter: C:\Users, c:/Users
+	drivePath = regexp.MustCompile(`^([A-Za-z]):[\\/]`)
+	// mountPath matches paths on a drive mounted by WSL: /mnt/c/Users
+	mountPath = regexp.MustCompile(`^/mnt/([a-z])(/|$)`)
+)
+
+// inWSL reports whether sshm runs in the Windows Subsystem for Linux
+var inWSL = sync.OnceValue(func() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	_, err := os.Stat("/proc/sys/fs/binfmt_misc/WSLInterop")
+	return err == nil
+})
+
+// translatePath returns where an identity path written for the other side
+// of WSL is on this one: C:\Users\me\.ssh\id becomes /mnt/c/Users/me/.ssh/id
+// in WSL and /mnt/c/Users/me/.ssh/id becomes C:\Users\me\.ssh\id on Windows
+func translatePath(path string) string {
+	switch {
+	case inWSL():
+		return wslPath(path)
+	case runtime.GOOS == "windows":
+		return windowsPath(path)
+	}
+	return path
+}
+
+// wslPath turns a Windows path with a drive letter into its WSL mount
+func wslPath(path string) string {
+	m := drivePath.FindStringSubmatch(path)
+	if m == nil {
+		return path
+	}
+	rest := strings.ReplaceAll(path[len(m[0]):], `\`, "/")
+	return wslMountRoot + strings.ToLower(m[1]) + "/" + rest
+}
+
+// windowsPath turns a path on a WSL drive mount into a Windows path
+func windowsPath(path string) string {
+	m := mountPath.FindStringSubmatch(path)
+	if m == nil {
+		return path
+	}
+	rest := strings.ReplaceAll(path[len(m[0]):], "/", `\`)
+	return strings.ToUpper(m[1]) + `:\` + rest
+}
+
+// windowsHome returns the Windows user's profile folder as seen from WSL,
+// or "" outside WSL or when Windows can't be asked
+var windowsHome = sync.OnceValue(func() string {
+	if !inWSL() {
+		return ""
+	}
+	cmd := exec.Command("cmd.exe", "/c", "echo %USERPROFILE%")
+	// cmd.exe complains about starting in a Linux folder
+	cmd.Dir = wslMountRoot + "c"
+	out, err := cmd.Output()
+	home := strings.TrimSpace(string(out))
+	if err != nil || !drivePath.MatchString(home) {
+		return ""
+	}
+	return wslPath(home)
+})
+
+// WindowsIdentities returns the default keys present in the Windows user's
+// .ssh folder when sshm runs in WSL with WindowsKeys set
+func WindowsIdentities() []string {
+	home := ""
+	if WindowsKeys {
+		home = windowsHome()
+	}
+	if home == "" {
+		return nil
+	}
+	var paths []string
+	for _, name := range []string{"id_ed25519", "id_rsa", "id_ecdsa", "id_dsa"} {
+		path := filepath.Join(home, ".ssh", name)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// windowsAgent returns the relay to the Windows agent to use instead of
+// SSH_AUTH_SOCK, or ""
+func windowsAgent() string {
+	if WindowsAgentRelay == "" || !inWSL() {
+		return ""
+	}
+	return WindowsAgentRelay
+}
+
+// relayConn talks to the Windows agent through the standard input and
+// output of a relay process
+type relayConn struct {
+	io.Reader
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+// dialRelay starts the relay to the Windows agent's named pipe
+func dialRelay(relay string) (*relayConn, error) {
+	// -ei ends the relay when sshm closes its input, -s passes that on to
+	// the pipe
+	cmd := exec.Command(relay, "-ei", "-s", windowsAgentPipe)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start agent relay %s: %w", relay, err)
+	}
+	return &relayConn{Reader: stdout, WriteCloser: stdin, cmd: cmd}, nil
+}
+
+// Close stops the relay
+func (c *relayConn) Close() error {
+	c.WriteCloser.Close()
+	c.cmd.Process.Kill()
+	c.cmd.Wait()
+	return nil
+}
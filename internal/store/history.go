@@ -8,6 +8,7 @@ import (
 	"sort"
 	"time"
 
+	"github.com/sshm/sshm/internal/config"
 	"github.com/sshm/sshm/internal/models"
 )
 
@@ -20,8 +21,7 @@ type HistoryStore struct {
 // NewHistoryStore creates a new HistoryStore instance
 func NewHistoryStore(path string) *HistoryStore {
 	if path == "" {
-		home, _ := os.UserHomeDir()
-		path = filepath.Join(home, ".sshm_history.json")
+		path = config.DefaultPaths().History
 	}
 	s := &HistoryStore{
 		path:    path,
@@ -72,14 +72,18 @@ func (s *HistoryStore) save() error {
 
 // AddConnection records a new connection attempt
 func (s *HistoryStore) AddConnection(hostID string, success bool, errMsg string, durationMs int64) error {
-	entry := models.ConnectionHistory{
-		HostID:    hostID,
-		Timestamp: time.Now(),
-		Success:   success,
-		Error:     errMsg,
-		Duration:  durationMs,
-	}
+	return s.Add(models.ConnectionHistory{
+		HostID:   hostID,
+		Success:  success,
+		Error:    errMsg,
+		Duration: durationMs,
+	})
+}
 
+// Add records a connection attempt with its warnings and host key changes,
+// stamped with the current time
+func (s *HistoryStore) Add(entry models.ConnectionHistory) error {
+	entry.Timestamp = time.Now()
 	s.history = append(s.history, entry)
 	return s.save()
 }
@@ -118,6 +122,24 @@ func (s *HistoryStore) GetRecentHistory(limit int) []models.ConnectionHistory {
 	return sorted[:limit]
 }
 
+// RecentHosts returns the latest connection attempt for each of the limit
+// most recently used hosts, newest first; a limit <= 0 returns every host
+func (s *HistoryStore) RecentHosts(limit int) []models.ConnectionHistory {
+	var results []models.ConnectionHistory
+	seen := make(map[string]bool)
+	for _, h := range s.GetRecentHistory(len(s.history)) {
+		if seen[h.HostID] {
+			continue
+		}
+		seen[h.HostID] = true
+		results = append(results, h)
+		if len(results) == limit {
+			break
+		}
+	}
+	return results
+}
+
 // GetStatsForHost returns connection statistics for a specific host
 func (s *HistoryStore) GetStatsForHost(hostID string) models.HistoryStats {
 	history := s.GetHistoryForHost(hostID)
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+// searchIndex keeps the searchable fields of the hosts lowercased, so
+// queries don't lowercase the whole inventory each time, and indexes their
+// trigrams to narrow queries of three or more characters down to the hosts
+// that can match
+// It is updated as hosts are added, changed and removed; trigrams don't span
+// fields, so every candidate is still checked for the whole query.
+type searchIndex struct {
+	hosts    map[string]indexedHost         // by host ID
+	trigrams map[string]map[string]struct{} // trigram -> IDs of hosts having it
+	names    map[string]string              // exact name -> host ID
+}
+
+// indexedHost is what the index keeps of a host
+type indexedHost struct {
+	name   string
+	fields []string // see searchFields
+}
+
+// proxyField is the position of the jump host in searchFields
+const proxyField = 3
+
+// newSearchIndex creates an index of hosts
+func newSearchIndex(hosts map[string]models.Host) *searchIndex {
+	x := &searchIndex{
+		hosts:    make(map[string]indexedHost, len(hosts)),
+		trigrams: make(map[string]map[string]struct{}),
+		names:    make(map[string]string, len(hosts)),
+	}
+	for _, h := range hosts {
+		x.add(h)
+	}
+	return x
+}
+
+// searchFields returns the fields of a host a search looks at, lowercased
+func searchFields(h models.Host) []string {
+	fields := []string{lower(h.Name), lower(h.Host), lower(h.User), lower(h.Proxy), lower(h.Group)}
+	for _, tag := range h.Tags {
+		fields = append(fields, lower(tag))
+	}
+	return fields
+}
+
+// add indexes a host, replacing what was indexed under its ID
+func (x *searchIndex) add(h models.Host) {
+	if old, ok := x.hosts[h.ID]; ok && old.name == h.Name {
+		x.unindex(h.ID)
+	} else {
+		x.remove(h.ID)
+	}
+	fields := searchFields(h)
+	x.hosts[h.ID] = indexedHost{name: h.Name, fields: fields}
+	x.names[h.Name] = h.ID
+	for _, field := range fields {
+		for _, t := range trigramsOf(field) {
+			ids, ok := x.trigrams[t]
+			if !ok {
+				ids = make(map[string]struct{})
+				x.trigrams[t] = ids
+			}
+			ids[h.ID] = struct{}{}
+		}
+	}
+}
+
+// remove drops a host from the index
+func (x *searchIndex) remove(id string) {
+	indexed, ok := x.unindex(id)
+	if !ok || x.names[indexed.name] != id {
+		return
+	}
+	// Hand the name over to another host carrying it, if any
+	delete(x.names, indexed.name)
+	for other, h := range x.hosts {
+		if h.name == indexed.name {
+			x.names[h.name] = other
+			break
+		}
+	}
+}
+
+// unindex drops a host's fields and trigrams, leaving its name
+func (x *searchIndex) unindex(id string) (indexedHost, bool) {
+	indexed, ok := x.hosts[id]
+	if !ok {
+		return indexed, false
+	}
+	delete(x.hosts, id)
+	for _, field := range indexed.fields {
+		for _, t := range trigramsOf(field) {
+			delete(x.trigrams[t], id)
+			if len(x.trigrams[t]) == 0 {
+				delete(x.trigrams, t)
+			}
+		}
+	}
+	return indexed, true
+}
+
+// byName returns the ID of the host with exactly this name
+func (x *searchIndex) byName(name string) (string, bool) {
+	id, ok := x.names[name]
+	return id, ok
+}
+
+// search returns the IDs of the hosts with a field containing the
+// lowercased query; withProxy includes the jump host among the fields
+func (x *searchIndex) search(query string, withProxy bool) map[string]bool {
+	matches := make(map[string]bool)
+	check := func(id string) {
+		for i, field := range x.hosts[id].fields {
+			if i == proxyField && !withProxy {
+				continue
+			}
+			if strings.Contains(field, query) {
+				matches[id] = true
+				return
+			}
+		}
+	}
+
+	grams := trigramsOf(query)
+	if len(grams) == 0 {
+		for id := range x.hosts {
+			check(id)
+		}
+		return matches
+	}
+	// Start from the rarest trigram; hosts missing any of them can't match
+	smallest := x.trigrams[grams[0]]
+	for _, t := range grams[1:] {
+		if ids := x.trigrams[t]; len(ids) < len(smallest) {
+			smallest = ids
+		}
+	}
+	for id := range smallest {
+		candidate := true
+		for _, t := range grams {
+			if _, ok := x.trigrams[t][id]; !ok {
+				candidate = false
+				break
+			}
+		}
+		if candidate {
+			check(id)
+		}
+	}
+	return matches
+}
+
+// trigramsOf returns the distinct three-byte substrings of s
+func trigramsOf(s string) []string {
+	if len(s) < 3 {
+		return nil
+	}
+	seen := make(map[string]bool, len(s)-2)
+	grams := make([]string, 0, len(s)-2)
+	for i := 0; i+3 <= len(s); i++ {
+		if t := s[i : i+3]; !seen[t] {
+			seen[t] = true
+			grams = append(grams, t)
+		}
+	}
+	return grams
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+// ResolveHost finds the hosts a user most likely means by query
+// It tries an exact name or ID match, then a case-insensitive exact name,
+// then a name prefix and finally a fuzzy (in-order characters) match.
+// Only the matches of the first stage that finds any are returned, with
+// fuzzy matches ordered best first.
+func (s *FileStore) ResolveHost(query string) []models.Host {
+	return resolveAmong(s.ListHosts(), query)
+}
+
+// resolveAmong is ResolveHost over the given hosts
+func resolveAmong(hosts []models.Host, query string) []models.Host {
+	if query == "" {
+		return nil
+	}
+
+	var matches []models.Host
+	for _, h := range hosts {
+		if h.Name == query || h.ID == query {
+			matches = append(matches, h)
+		}
+	}
+	if len(matches) > 0 {
+		return matches
+	}
+
+	lowerQuery := strings.ToLower(query)
+	for _, h := range hosts {
+		if strings.ToLower(h.Name) == lowerQuery {
+			matches = append(matches, h)
+		}
+	}
+	if len(matches) > 0 {
+		return matches
+	}
+
+	for _, h := range hosts {
+		if strings.HasPrefix(strings.ToLower(h.Name), lowerQuery) {
+			matches = append(matches, h)
+		}
+	}
+	if len(matches) > 0 {
+		return matches
+	}
+
+	scores := make(map[string]int)
+	for _, h := range hosts {
+		if score, ok := fuzzyScore(strings.ToLower(h.Name), lowerQuery); ok {
+			matches = append(matches, h)
+			scores[h.ID] = score
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return scores[matches[i].ID] < scores[matches[j].ID]
+	})
+	return matches
+}
+
+// fuzzyScore reports whether all characters of query appear in s in order
+// The score is the length of the matched span; tighter matches score lower
+func fuzzyScore(s, query string) (int, bool) {
+	first, last := -1, -1
+	qi := 0
+	q := []rune(query)
+	for i, r := range []rune(s) {
+		if qi < len(q) && r == q[qi] {
+			if first < 0 {
+				first = i
+			}
+			last = i
+			qi++
+		}
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return last - first, true
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+// SearchFilter narrows a host search
+// Empty fields don't filter; every tag in Tags must be present on the host.
+type SearchFilter struct {
+	Query string
+	Tags  []string
+	Group string
+	User  string
+}
+
+// Matches reports whether a host passes the tag, group and user filters
+func (f SearchFilter) Matches(h models.Host) bool {
+	if f.Group != "" && !strings.EqualFold(h.Group, f.Group) {
+		return false
+	}
+	if f.User != "" && !strings.EqualFold(h.User, f.User) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		found := false
+		for _, t := range h.Tags {
+			if strings.EqualFold(t, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterHosts searches hosts with SearchHosts semantics and applies the
+// filter's tag, group and user constraints. When the query matches no host
+// as a substring, names are fuzzy matched instead and ordered best first.
+func (s *FileStore) FilterHosts(f SearchFilter) []models.Host {
+	var candidates []models.Host
+	if f.Query == "" {
+		candidates = s.ListHosts()
+	} else {
+		candidates = s.SearchHosts(f.Query)
+	}
+
+	results := filterHosts(candidates, f)
+	if len(results) > 0 || f.Query == "" {
+		return results
+	}
+
+	query := lower(f.Query)
+	scores := make(map[string]int)
+	for _, h := range s.ListHosts() {
+		if score, ok := fuzzyScore(lower(h.Name), query); ok && f.Matches(h) {
+			results = append(results, h)
+			scores[h.ID] = score
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return scores[results[i].ID] < scores[results[j].ID]
+	})
+	return results
+}
+
+func filterHosts(hosts []models.Host, f SearchFilter) []models.Host {
+	var results []models.Host
+	for _, h := range hosts {
+		if f.Matches(h) {
+			results = append(results, h)
+		}
+	}
+	return results
+}
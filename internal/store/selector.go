@@ -0,0 +1,368 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/sshm/sshm/internal/models"
+)
+
+// ErrNoMatch is wrapped by selector errors for terms that matched no host
+var ErrNoMatch = errors.New("not found")
+
+// AmbiguousError is returned when a name resolves to several hosts
+type AmbiguousError struct {
+	Query   string
+	Matches []models.Host
+}
+
+func (e *AmbiguousError) Error() string {
+	names := make([]string, 0, len(e.Matches))
+	for _, h := range e.Matches {
+		names = append(names, h.Name)
+	}
+	return fmt.Sprintf("%q is ambiguous, it matches: %s", e.Query, strings.Join(names, ", "))
+}
+
+// Selector picks hosts with a small expression language:
+//
+//	web1                 a host name, resolved like connect (exact, prefix, fuzzy)
+//	prod-web-*           a name pattern with *, ? and [...]
+//	tag:db  group:eu     hosts with the tag or in the group (patterns allowed)
+//	user:deploy          hosts logging in as the user (patterns allowed)
+//	a AND b, NOT a, (a)  intersection, complement and grouping
+//	a b, a OR b          union
+//
+// AND binds tighter than OR, and the operators must be written in capitals.
+type Selector struct {
+	expr string
+	root selNode
+}
+
+// ParseSelector parses a selector expression
+func ParseSelector(expr string) (*Selector, error) {
+	tokens, err := tokenizeSelector(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	p := &selParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in selector", p.tokens[p.pos])
+	}
+	return &Selector{expr: expr, root: root}, nil
+}
+
+// IsSelector reports whether text uses selector syntax rather than being a
+// plain search string: a field prefix, a pattern or an operator
+func IsSelector(text string) bool {
+	tokens, err := tokenizeSelector(text)
+	if err != nil {
+		return false
+	}
+	for _, tok := range tokens {
+		switch tok {
+		case "AND", "OR", "NOT", "(", ")":
+			return true
+		}
+		if _, _, ok := fieldTerm(tok); ok || hasPattern(tok) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the expression the selector was parsed from
+func (s *Selector) String() string {
+	return s.expr
+}
+
+// Select returns the hosts the selector picks out of hosts, in the order the
+// terms name them
+// Terms that match nothing or names that are ambiguous are reported in err;
+// they count as matching no host, so the result is still usable by callers
+// that don't want to fail on them.
+func (s *Selector) Select(hosts []models.Host) ([]models.Host, error) {
+	ctx := &selContext{hosts: hosts}
+	return s.root.eval(ctx), ctx.err
+}
+
+// SelectHosts parses expr and applies it to the hosts in the store
+func (s *FileStore) SelectHosts(expr string) ([]models.Host, error) {
+	sel, err := ParseSelector(expr)
+	if err != nil {
+		return nil, err
+	}
+	return sel.Select(s.ListHosts())
+}
+
+// selContext carries the hosts being selected from and the first error
+type selContext struct {
+	hosts []models.Host
+	err   error
+}
+
+func (c *selContext) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+type selNode interface {
+	eval(ctx *selContext) []models.Host
+}
+
+type selOr struct{ left, right selNode }
+type selAnd struct{ left, right selNode }
+type selNot struct{ operand selNode }
+
+// selTerm is a single name, pattern or field:value term
+type selTerm struct {
+	field string // "", "tag", "group" or "user"
+	value string
+}
+
+func (n selOr) eval(ctx *selContext) []models.Host {
+	left := n.left.eval(ctx)
+	seen := hostIDs(left)
+	for _, h := range n.right.eval(ctx) {
+		if !seen[h.ID] {
+			seen[h.ID] = true
+			left = append(left, h)
+		}
+	}
+	return left
+}
+
+func (n selAnd) eval(ctx *selContext) []models.Host {
+	left := n.left.eval(ctx)
+	right := hostIDs(n.right.eval(ctx))
+	var result []models.Host
+	for _, h := range left {
+		if right[h.ID] {
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+func (n selNot) eval(ctx *selContext) []models.Host {
+	excluded := hostIDs(n.operand.eval(ctx))
+	var result []models.Host
+	for _, h := range ctx.hosts {
+		if !excluded[h.ID] {
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+func (n selTerm) eval(ctx *selContext) []models.Host {
+	if n.field == "" && !hasPattern(n.value) {
+		matches := resolveAmong(ctx.hosts, n.value)
+		switch len(matches) {
+		case 0:
+			ctx.fail(fmt.Errorf("host %q %w", n.value, ErrNoMatch))
+			return nil
+		case 1:
+			return matches
+		}
+		ctx.fail(&AmbiguousError{Query: n.value, Matches: matches})
+		return nil
+	}
+
+	var result []models.Host
+	for _, h := range ctx.hosts {
+		if n.matches(h) {
+			result = append(result, h)
+		}
+	}
+	if len(result) == 0 {
+		kind := n.field
+		if kind == "" {
+			kind = "hosts matching"
+		}
+		ctx.fail(fmt.Errorf("%s %q %w", kind, n.value, ErrNoMatch))
+	}
+	return result
+}
+
+// matches reports whether the host satisfies a pattern or field term
+func (n selTerm) matches(h models.Host) bool {
+	switch n.field {
+	case "tag":
+		for _, t := range h.Tags {
+			if matchPattern(n.value, t) {
+				return true
+			}
+		}
+		return false
+	case "group":
+		return h.Group != "" && matchPattern(n.value, h.Group)
+	case "user":
+		return matchPattern(n.value, h.User)
+	}
+	return matchPattern(n.value, h.Name)
+}
+
+// matchPattern compares case-insensitively, as a glob when pattern has one
+func matchPattern(pattern, s string) bool {
+	if !hasPattern(pattern) {
+		return strings.EqualFold(pattern, s)
+	}
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(s))
+	return ok
+}
+
+// hasPattern reports whether s contains glob characters
+func hasPattern(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// fieldTerm splits "tag:db" style tokens
+func fieldTerm(tok string) (field, value string, ok bool) {
+	field, value, found := strings.Cut(tok, ":")
+	if !found || value == "" {
+		return "", "", false
+	}
+	switch field {
+	case "tag", "group", "user":
+		return field, value, true
+	}
+	return "", "", false
+}
+
+func hostIDs(hosts []models.Host) map[string]bool {
+	ids := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		ids[h.ID] = true
+	}
+	return ids
+}
+
+// tokenizeSelector splits on whitespace and parentheses; double quotes keep
+// names with spaces together
+func tokenizeSelector(expr string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes, quoted := false, false
+	flush := func() {
+		if current.Len() > 0 || quoted {
+			tokens = append(tokens, current.String())
+		}
+		current.Reset()
+		quoted = false
+	}
+
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case inQuotes:
+			current.WriteRune(r)
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in selector")
+	}
+	flush()
+	return tokens, nil
+}
+
+// selParser is a recursive descent parser over selector tokens
+type selParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *selParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// parseOr handles explicit OR and adjacent terms, which are also a union
+func (p *selParser) parseOr() (selNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.pos < len(p.tokens) && p.peek() != ")" {
+		if p.peek() == "OR" {
+			p.pos++
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = selOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *selParser) parseAnd() (selNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "AND" {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = selAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *selParser) parseUnary() (selNode, error) {
+	tok := p.peek()
+	switch tok {
+	case "":
+		return nil, fmt.Errorf("selector ends unexpectedly")
+	case "NOT":
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return selNot{operand}, nil
+	case "(":
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ) in selector")
+		}
+		p.pos++
+		return inner, nil
+	case ")", "AND", "OR":
+		return nil, fmt.Errorf("unexpected %q in selector", tok)
+	}
+
+	p.pos++
+	if field, value, ok := fieldTerm(tok); ok {
+		return selTerm{field: field, value: value}, nil
+	}
+	return selTerm{value: tok}, nil
+}
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/sshm/sshm/internal/config"
 	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/secret"
 )
 
 // ErrHostNotFound is returned when a host is not found
@@ -17,6 +20,13 @@ var ErrHostNotFound = errors.New("host not found")
 // ErrHostExists is returned when adding a host that already exists
 var ErrHostExists = errors.New("host already exists")
 
+// ErrIncluded is returned when changing a host read from an included file
+var ErrIncluded = errors.New("host is included from")
+
+// ErrNothingEncrypted is returned when checking a passphrase against a
+// store without encrypted values
+var ErrNothingEncrypted = errors.New("no encrypted values to check the passphrase against")
+
 // StoreInterface defines the interface for host storage
 type StoreInterface interface {
 	AddHost(host models.Host) error
@@ -29,69 +39,301 @@ type StoreInterface interface {
 
 // FileStore manages host data persistence in a file
 type FileStore struct {
-	path    string
-	hosts   map[string]models.Host
-	config  *models.Config
+	path       string
+	configPath string // where profiles live, usually path itself
+	hosts      map[string]models.Host
+	index      *searchIndex // kept in step with hosts
+	config     *models.Config
+	defaults   models.Defaults // applied on load, stripped again on save
+	included   map[string]string // host ID -> included file it was read from
+	files      []string          // included files read, hosts or not
+	encrypted  []string          // host fields saved encrypted
+	passphrase func() ([]byte, error)
+	batching   bool // writes wait for Flush
+	dirty      bool // changes not written yet while batching
+}
+
+// NewFileStore creates a new FileStore instance keeping hosts and profiles
+// in the same file
+func NewFileStore(path string) (*FileStore, error) {
+	return NewFileStoreWithConfig(path, path)
 }
 
-// NewFileStore creates a new FileStore instance
-func NewFileStore(path string) *FileStore {
+// NewFileStoreWithConfig creates a FileStore keeping hosts in path and
+// profiles in the settings file at configPath
+// A hosts file that can't be read or parsed is an error: an empty store in
+// its place would overwrite every host on the next change. Broken included
+// files only lose their own hosts; Reload reports them.
+func NewFileStoreWithConfig(path, configPath string) (*FileStore, error) {
 	s := &FileStore{
-		path:    path,
-		hosts:   make(map[string]models.Host),
-		config:  &models.Config{},
+		path:       path,
+		configPath: configPath,
+		hosts:      make(map[string]models.Host),
+		index:      newSearchIndex(nil),
+		config:     &models.Config{},
+		included:   make(map[string]string),
+		passphrase: secret.Passphrase,
 	}
-	s.load()
-	return s
+	include, ownInclude, err := s.loadOwn()
+	if err != nil {
+		return nil, err
+	}
+	s.loadIncludes(include, ownInclude)
+	return s, nil
 }
 
 // load reads data from the storage file
+// Both JSON and YAML files are understood, see config.DetectFormat. Hosts of
+// files named by include directives in the settings or the storage file are
+// added after the file's own, skipping names and IDs already taken.
 func (s *FileStore) load() error {
-	data, err := os.ReadFile(s.path)
+	include, ownInclude, err := s.loadOwn()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return fmt.Errorf("failed to read store: %w", err)
+		return err
 	}
+	return s.loadIncludes(include, ownInclude)
+}
 
-	// Try to parse as new Config format first (object with hosts array)
-	var cfg models.Config
-	if err := json.Unmarshal(data, &cfg); err == nil && len(cfg.Hosts) > 0 {
-		// New format - Config with hosts
-		s.hosts = make(map[string]models.Host)
-		for _, host := range cfg.Hosts {
-			s.hosts[host.ID] = host
-		}
-		return nil
+// loadOwn reads the settings and the hosts of the storage file itself,
+// returning the include lists of the settings and of the storage file
+func (s *FileStore) loadOwn() (include, ownInclude []string, err error) {
+	if cfg, err := s.LoadConfig(); err == nil {
+		s.defaults = cfg.Defaults
+		include = cfg.Include
+		s.encrypted = cfg.EncryptedFields
 	}
 
-	// Try legacy array format
-	var hosts []models.Host
-	if err := json.Unmarshal(data, &hosts); err != nil {
-		return fmt.Errorf("failed to parse store data: %w", err)
+	hosts, ownInclude, err := config.ReadHosts(s.path)
+	if err != nil {
+		return nil, nil, err
 	}
-
 	s.hosts = make(map[string]models.Host)
 	for _, host := range hosts {
+		host = s.withDefaults(host)
 		s.hosts[host.ID] = host
 	}
+	s.index = newSearchIndex(s.hosts)
+	return include, ownInclude, nil
+}
+
+// loadIncludes adds the hosts of the included files
+func (s *FileStore) loadIncludes(include, ownInclude []string) error {
+	files, err := config.LoadIncludes(s.configPath, include)
+	if s.path != s.configPath {
+		more, moreErr := config.LoadIncludes(s.path, ownInclude)
+		files = append(files, more...)
+		err = errors.Join(err, moreErr)
+	}
+	s.included = make(map[string]string)
+	s.files = nil
+	for _, file := range files {
+		s.files = append(s.files, file.Path)
+		for _, host := range file.Hosts {
+			host = s.withDefaults(host)
+			if _, taken := s.hosts[host.ID]; taken {
+				continue
+			}
+			if _, taken := s.GetHostByName(host.Name); taken == nil {
+				continue
+			}
+			s.hosts[host.ID] = host
+			s.index.add(host)
+			s.included[host.ID] = file.Path
+		}
+	}
+	return err
+}
+
+// Reload reads the hosts and settings files again, picking up changes to
+// the defaults and include lists made outside the store
+// Pending changes are written first; if that fails nothing is reloaded.
+func (s *FileStore) Reload() error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	return s.load()
+}
+
+// IncludedFrom returns the included file a host was read from, or "" for
+// hosts of the store's own file
+func (s *FileStore) IncludedFrom(id string) string {
+	return s.included[id]
+}
+
+// IncludedFiles returns the paths of the included files read
+func (s *FileStore) IncludedFiles() []string {
+	return append([]string(nil), s.files...)
+}
 
+// checkOwn rejects changes to hosts of included files
+func (s *FileStore) checkOwn(id string) error {
+	if file, ok := s.included[id]; ok {
+		return fmt.Errorf("%w %s, edit it there", ErrIncluded, file)
+	}
 	return nil
 }
 
-// save writes data to the storage file
+// withDefaults fills in what hand-written hosts may leave out, see
+// ApplyDefaults, plus an ID derived from the name, so it stays the same
+// across loads until the file is saved with it
+func (s *FileStore) withDefaults(host models.Host) models.Host {
+	host = s.ApplyDefaults(host)
+	if host.ID == "" {
+		host.ID = config.HostID(host.Name)
+	}
+	return host
+}
+
+// ApplyDefaults fills the empty fields of host from the defaults block of
+// the settings, falling back to port 22
+// Hosts are stored without the fields that equal their default, so they
+// follow later changes to the defaults.
+func (s *FileStore) ApplyDefaults(host models.Host) models.Host {
+	host = s.defaults.Apply(host)
+	if host.Port == 0 {
+		host.Port = 22
+	}
+	return host
+}
+
+// SetPassphraseFunc sets where the passphrase for encrypted fields comes
+// from, secret.Passphrase by default
+// The TUI can't prompt on the terminal and uses secret.StoredPassphrase.
+func (s *FileStore) SetPassphraseFunc(passphrase func() ([]byte, error)) {
+	s.passphrase = passphrase
+}
+
+// CheckPassphrase reports whether passphrase opens the encrypted values of
+// the store's own hosts, trying the first one it finds
+func (s *FileStore) CheckPassphrase(passphrase []byte) error {
+	for _, value := range s.encryptedValues() {
+		_, err := secret.Decrypt(value, passphrase)
+		return err
+	}
+	return ErrNothingEncrypted
+}
+
+// HasEncrypted reports whether any of the store's own hosts has an
+// encrypted value
+func (s *FileStore) HasEncrypted() bool {
+	return len(s.encryptedValues()) > 0
+}
+
+// encryptedValues returns the encrypted values of the store's own hosts
+func (s *FileStore) encryptedValues() []string {
+	var values []string
+	for id, host := range s.hosts {
+		if _, ok := s.included[id]; !ok && secret.IsEncrypted(host.Password) {
+			values = append(values, host.Password)
+		}
+	}
+	return values
+}
+
+// Encrypts reports whether the settings ask for field to be saved encrypted
+func (s *FileStore) Encrypts(field string) bool {
+	for _, f := range s.encrypted {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Seal encrypts the plain text values of the fields named by
+// encrypted_fields and saves the store, returning how many were encrypted
+func (s *FileStore) Seal() (int, error) {
+	sealed, err := s.seal()
+	if err != nil || sealed == 0 {
+		return sealed, err
+	}
+	return sealed, s.save()
+}
+
+// seal encrypts the plain text passwords of the store's own hosts in place
+// when encrypted_fields names them
+// The passphrase has to open the values already encrypted, so one store
+// never mixes passphrases; a wrong one is forgotten so the next attempt
+// asks again.
+func (s *FileStore) seal() (int, error) {
+	if !s.Encrypts("password") {
+		return 0, nil
+	}
+	sealed := 0
+	var passphrase []byte
+	for id, host := range s.hosts {
+		if _, ok := s.included[id]; ok || host.Password == "" || secret.IsEncrypted(host.Password) {
+			continue
+		}
+		var err error
+		if passphrase == nil {
+			if passphrase, err = s.passphrase(); err != nil {
+				return sealed, fmt.Errorf("failed to encrypt password of %s: %w", host.Name, err)
+			}
+			if err = s.CheckPassphrase(passphrase); err != nil && !errors.Is(err, ErrNothingEncrypted) {
+				secret.SetPassphrase(nil)
+				return sealed, fmt.Errorf("failed to encrypt password of %s: %w", host.Name, err)
+			}
+		}
+		if host.Password, err = secret.Encrypt(host.Password, passphrase); err != nil {
+			return sealed, fmt.Errorf("failed to encrypt password of %s: %w", host.Name, err)
+		}
+		s.hosts[id] = host
+		sealed++
+	}
+	return sealed, nil
+}
+
+// Batch makes changes wait for Flush instead of writing the file each time,
+// so bulk operations and quick successive edits write it once
+func (s *FileStore) Batch() {
+	s.batching = true
+}
+
+// Pending reports whether there are changes waiting for Flush
+func (s *FileStore) Pending() bool {
+	return s.dirty
+}
+
+// Flush writes the changes made since the last write, if any; batching
+// goes on
+// When the write fails the changes stay pending for the next Flush.
+func (s *FileStore) Flush() error {
+	if !s.dirty {
+		return nil
+	}
+	if err := s.write(); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// save writes data to the storage file, or marks it pending while batching
 func (s *FileStore) save() error {
-	hosts := s.ListHosts()
-	data, err := json.MarshalIndent(hosts, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal hosts: %w", err)
+	if s.batching {
+		s.dirty = true
+		return nil
 	}
+	return s.write()
+}
 
-	if err := os.WriteFile(s.path, data, 0600); err != nil {
+// write writes data to the storage file
+// Other top-level settings sharing the file (theme, profiles, ...) are
+// preserved. Fields named by encrypted_fields are encrypted first.
+func (s *FileStore) write() error {
+	if _, err := s.seal(); err != nil {
+		return err
+	}
+	hosts := []models.Host{}
+	for _, host := range s.ListHosts() {
+		if _, ok := s.included[host.ID]; !ok {
+			hosts = append(hosts, s.defaults.Strip(host))
+		}
+	}
+	if err := config.SetKey(s.path, "hosts", hosts); err != nil {
 		return fmt.Errorf("failed to write store: %w", err)
 	}
-
 	return nil
 }
 
@@ -105,7 +347,8 @@ func (s *FileStore) AddHost(host models.Host) error {
 		return ErrHostExists
 	}
 
-	s.hosts[host.ID] = host
+	s.hosts[host.ID] = s.ApplyDefaults(host)
+	s.index.add(s.hosts[host.ID])
 	return s.save()
 }
 
@@ -118,49 +361,106 @@ func (s *FileStore) UpdateHost(host models.Host) error {
 	if _, exists := s.hosts[host.ID]; !exists {
 		return ErrHostNotFound
 	}
+	if err := s.checkOwn(host.ID); err != nil {
+		return err
+	}
 
-	s.hosts[host.ID] = host
+	s.hosts[host.ID] = s.ApplyDefaults(host)
+	s.index.add(s.hosts[host.ID])
 	return s.save()
 }
 
+// ReplaceHosts swaps the whole inventory for hosts in a single write
+// Hosts without an ID get a new one. Duplicate IDs or names, and changed or
+// missing hosts of included files, are rejected and leave the store
+// unchanged.
+func (s *FileStore) ReplaceHosts(hosts []models.Host) error {
+	replaced := make(map[string]models.Host, len(hosts))
+	names := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		if host.ID == "" {
+			host.ID = uuid.New().String()
+		}
+		if _, exists := replaced[host.ID]; exists {
+			return fmt.Errorf("duplicate host ID %q", host.ID)
+		}
+		if names[host.Name] {
+			return fmt.Errorf("%w: %s", ErrHostExists, host.Name)
+		}
+		names[host.Name] = true
+		replaced[host.ID] = s.ApplyDefaults(host)
+
+		if _, ok := s.included[host.ID]; ok {
+			if !sameHost(s.hosts[host.ID], replaced[host.ID]) {
+				return s.checkOwn(host.ID)
+			}
+		}
+	}
+	for id := range s.included {
+		if _, kept := replaced[id]; !kept {
+			return s.checkOwn(id)
+		}
+	}
+
+	previous, previousIndex := s.hosts, s.index
+	s.hosts, s.index = replaced, newSearchIndex(replaced)
+	if err := s.save(); err != nil {
+		s.hosts, s.index = previous, previousIndex
+		return err
+	}
+	return nil
+}
+
+// sameHost reports whether two hosts have the same stored fields, treating
+// empty and missing lists alike
+func sameHost(a, b models.Host) bool {
+	a.Online, b.Online = nil, nil
+	aData, _ := json.Marshal(a)
+	bData, _ := json.Marshal(b)
+	return string(aData) == string(bData)
+}
+
 // DeleteHost removes a host by ID
 func (s *FileStore) DeleteHost(id string) error {
 	if _, exists := s.hosts[id]; !exists {
 		return ErrHostNotFound
 	}
+	if err := s.checkOwn(id); err != nil {
+		return err
+	}
 
 	delete(s.hosts, id)
+	s.index.remove(id)
 	return s.save()
 }
 
-// ListHosts returns all hosts
+// ListHosts returns all hosts sorted by name
 func (s *FileStore) ListHosts() []models.Host {
 	hosts := make([]models.Host, 0, len(s.hosts))
 	for _, host := range s.hosts {
 		hosts = append(hosts, host)
 	}
+	sortHosts(hosts)
 	return hosts
 }
 
-// SearchHosts searches hosts by query string
+// SearchHosts returns the hosts whose name, address, user, proxy, group or
+// tags contain query, ignoring case, sorted by name
 func (s *FileStore) SearchHosts(query string) []models.Host {
-	query = lower(query)
 	var results []models.Host
-
-	for _, host := range s.hosts {
-		if contains(lower(host.Name), query) ||
-			contains(lower(host.Host), query) ||
-			contains(lower(host.User), query) ||
-			contains(lower(host.Proxy), query) ||
-			contains(lower(host.Group), query) ||
-			containsAny(host.Tags, query) {
-			results = append(results, host)
-		}
+	for id := range s.index.search(lower(query), true) {
+		results = append(results, s.hosts[id])
 	}
-
+	sortHosts(results)
 	return results
 }
 
+// MatchingHosts returns the IDs of the hosts whose name, address, user,
+// group or tags contain query, ignoring case: what the TUI filters on
+func (s *FileStore) MatchingHosts(query string) map[string]bool {
+	return s.index.search(lower(query), false)
+}
+
 // GetHost returns a host by ID
 func (s *FileStore) GetHost(id string) (models.Host, error) {
 	host, exists := s.hosts[id]
@@ -170,6 +470,14 @@ func (s *FileStore) GetHost(id string) (models.Host, error) {
 	return host, nil
 }
 
+// GetHostByName returns a host by its exact name, falling back to its ID
+func (s *FileStore) GetHostByName(name string) (models.Host, error) {
+	if id, ok := s.index.byName(name); ok {
+		return s.hosts[id], nil
+	}
+	return s.GetHost(name)
+}
+
 // FilterByTag returns hosts that have the specified tag
 func (s *FileStore) FilterByTag(tag string) []models.Host {
 	tag = lower(tag)
@@ -205,19 +513,20 @@ func (s *FileStore) Count() int {
 
 // LoadConfig loads the full configuration including profiles
 func (s *FileStore) LoadConfig() (*models.Config, error) {
-	data, err := os.ReadFile(s.path)
+	data, err := os.ReadFile(s.configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return &models.Config{}, nil
 		}
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
+	format := config.DetectFormat(s.configPath, data)
 
 	var cfg models.Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	if err := config.Unmarshal(format, data, &cfg); err != nil {
 		// Try legacy format (just hosts array)
 		var hosts []models.Host
-		if jsonErr := json.Unmarshal(data, &hosts); jsonErr == nil {
+		if legacyErr := config.Unmarshal(format, data, &hosts); legacyErr == nil {
 			cfg.Hosts = hosts
 			return &cfg, nil
 		}
@@ -270,14 +579,9 @@ func (s *FileStore) DeleteProfile(name string) error {
 	return s.saveConfig(cfg)
 }
 
-// saveConfig saves the full config to file
+// saveConfig saves the profiles of cfg, leaving the rest of the file as is
 func (s *FileStore) saveConfig(cfg *models.Config) error {
-	data, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(s.path, data, 0600); err != nil {
+	if err := config.SetKey(s.configPath, "profiles", cfg.Profiles); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
 
@@ -286,6 +590,16 @@ func (s *FileStore) saveConfig(cfg *models.Config) error {
 }
 
 // helper functions
+// sortHosts orders hosts by name, then ID, so listings are stable
+func sortHosts(hosts []models.Host) {
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Name != hosts[j].Name {
+			return hosts[i].Name < hosts[j].Name
+		}
+		return hosts[i].ID < hosts[j].ID
+	})
+}
+
 func lower(s string) string {
 	return strings.ToLower(s)
 }
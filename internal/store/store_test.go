@@ -1,20 +1,37 @@
 package store
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/secret"
 )
 
+// openTestStore opens the store at path, failing the test on errors
+func openTestStore(t *testing.T, path string) *FileStore {
+	t.Helper()
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	return s
+}
+
 func TestFileStore(t *testing.T) {
 	// Create a temporary file for testing
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "test_hosts.json")
 
 	// Test NewFileStore
-	store := NewFileStore(tmpFile)
+	store, err := NewFileStore(tmpFile)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
 	if store == nil {
 		t.Fatal("NewFileStore returned nil")
 	}
@@ -35,7 +52,7 @@ func TestFileStore(t *testing.T) {
 		Tags:   []string{"web", "production"},
 	}
 
-	err := store.AddHost(host)
+	err = store.AddHost(host)
 	if err != nil {
 		t.Errorf("AddHost failed: %v", err)
 	}
@@ -99,7 +116,7 @@ func TestSearchHosts(t *testing.T) {
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "test_search.json")
 
-	store := NewFileStore(tmpFile)
+	store := openTestStore(t, tmpFile)
 
 	// Add test hosts
 	hosts := []models.Host{
@@ -144,11 +161,144 @@ func TestSearchHosts(t *testing.T) {
 	}
 }
 
+func TestSearchIndexFollowsChanges(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	store.AddHost(models.Host{ID: "1", Name: "web1", Host: "10.0.0.1", Proxy: "bastion-eu"})
+	store.AddHost(models.Host{ID: "2", Name: "db1", Host: "10.0.0.2", Tags: []string{"Postgres"}})
+
+	names := func(hosts []models.Host) string {
+		var names []string
+		for _, h := range hosts {
+			names = append(names, h.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	if got := names(store.SearchHosts("BASTION")); got != "web1" {
+		t.Errorf("expected the proxy to be searched, got %q", got)
+	}
+	if got := store.MatchingHosts("bastion"); len(got) != 0 {
+		t.Errorf("expected MatchingHosts to leave the proxy out, got %v", got)
+	}
+	if got := names(store.SearchHosts("10")); got != "db1,web1" {
+		t.Errorf("expected a short query to match both, got %q", got)
+	}
+
+	// Updates replace what was indexed for the host
+	store.UpdateHost(models.Host{ID: "2", Name: "pg1", Host: "10.0.0.2", Tags: []string{"postgres"}})
+	if got := names(store.SearchHosts("db1")); got != "" {
+		t.Errorf("expected the old name to be gone, got %q", got)
+	}
+	if got := names(store.SearchHosts("gres")); got != "pg1" {
+		t.Errorf("expected the new host, got %q", got)
+	}
+	if _, err := store.GetHostByName("db1"); err == nil {
+		t.Error("expected the old name not to resolve")
+	}
+	if h, err := store.GetHostByName("pg1"); err != nil || h.ID != "2" {
+		t.Errorf("expected pg1 by name, got %+v (%v)", h, err)
+	}
+
+	// Trigrams of the query must line up within a single field
+	if got := names(store.SearchHosts("web110.0")); got != "" {
+		t.Errorf("expected no match across fields, got %q", got)
+	}
+
+	store.DeleteHost("1")
+	if got := names(store.SearchHosts("bastion")); got != "" {
+		t.Errorf("expected the deleted host to be gone, got %q", got)
+	}
+
+	// Another store sees the saved hosts indexed on load
+	if got := names(openTestStore(t, store.path).SearchHosts("PG")); got != "pg1" {
+		t.Errorf("expected the loaded hosts to be indexed, got %q", got)
+	}
+}
+
+func TestReplaceHosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test_replace.json")
+	store := openTestStore(t, path)
+	store.AddHost(models.Host{ID: "1", Name: "old", Host: "10.0.0.1", Port: 22})
+
+	err := store.ReplaceHosts([]models.Host{
+		{ID: "1", Name: "renamed", Host: "10.0.0.1", Port: 22},
+		{Name: "new", Host: "10.0.0.2", Port: 22},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceHosts failed: %v", err)
+	}
+
+	reloaded := openTestStore(t, path)
+	if reloaded.Count() != 2 {
+		t.Fatalf("expected 2 hosts after reload, got %d", reloaded.Count())
+	}
+	if h, _ := reloaded.GetHost("1"); h.Name != "renamed" {
+		t.Errorf("expected host 1 to be renamed, got %q", h.Name)
+	}
+	if h, err := reloaded.GetHostByName("new"); err != nil || h.ID == "" {
+		t.Errorf("expected new host to get an ID, got %+v, %v", h, err)
+	}
+
+	// Duplicate names are rejected without touching the store
+	err = store.ReplaceHosts([]models.Host{
+		{Name: "a", Host: "10.0.0.1", Port: 22},
+		{Name: "a", Host: "10.0.0.2", Port: 22},
+	})
+	if err == nil {
+		t.Error("expected duplicate names to be rejected")
+	}
+	if store.Count() != 2 {
+		t.Errorf("expected store to be unchanged, got %d hosts", store.Count())
+	}
+	if tmp, _ := filepath.Glob(path + ".*.tmp"); len(tmp) > 0 {
+		t.Errorf("expected no temporary file to be left behind, got %v", tmp)
+	}
+}
+
+func TestFilterHosts(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "test_filter_hosts.json"))
+
+	hosts := []models.Host{
+		{ID: "1", Name: "prod-database", Host: "10.0.0.1", User: "postgres", Group: "production", Tags: []string{"db", "prod"}},
+		{ID: "2", Name: "prod-web", Host: "10.0.0.2", User: "deploy", Group: "production", Tags: []string{"web", "prod"}},
+		{ID: "3", Name: "staging-db", Host: "10.0.1.1", User: "postgres", Group: "staging", Tags: []string{"db"}},
+	}
+	for _, h := range hosts {
+		store.AddHost(h)
+	}
+
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   []string
+	}{
+		{"query only", SearchFilter{Query: "db"}, []string{"prod-database", "staging-db"}},
+		{"query and tag", SearchFilter{Query: "db", Tags: []string{"PROD"}}, []string{"prod-database"}},
+		{"all tags required", SearchFilter{Tags: []string{"db", "prod"}}, []string{"prod-database"}},
+		{"group without query", SearchFilter{Group: "production"}, []string{"prod-database", "prod-web"}},
+		{"user", SearchFilter{User: "postgres", Group: "staging"}, []string{"staging-db"}},
+		{"fuzzy fallback", SearchFilter{Query: "pdb"}, []string{"prod-web", "prod-database"}},
+		{"fuzzy with tag", SearchFilter{Query: "pdb", Tags: []string{"db"}}, []string{"prod-database"}},
+		{"fuzzy respects filters", SearchFilter{Query: "pdb", Group: "staging"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, h := range store.FilterHosts(tt.filter) {
+				got = append(got, h.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("FilterHosts(%+v) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFilterByTag(t *testing.T) {
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "test_filter_tag.json")
 
-	store := NewFileStore(tmpFile)
+	store := openTestStore(t, tmpFile)
 
 	hosts := []models.Host{
 		{ID: "1", Name: "server-1", Tags: []string{"web", "prod"}},
@@ -177,7 +327,7 @@ func TestFilterByGroup(t *testing.T) {
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "test_filter_group.json")
 
-	store := NewFileStore(tmpFile)
+	store := openTestStore(t, tmpFile)
 
 	hosts := []models.Host{
 		{ID: "1", Name: "server-1", Group: "production"},
@@ -206,7 +356,7 @@ func TestAddHostGeneratesID(t *testing.T) {
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "test_auto_id.json")
 
-	store := NewFileStore(tmpFile)
+	store := openTestStore(t, tmpFile)
 
 	// Add host without ID
 	host := models.Host{
@@ -235,7 +385,7 @@ func TestDuplicateHost(t *testing.T) {
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "test_dup.json")
 
-	store := NewFileStore(tmpFile)
+	store := openTestStore(t, tmpFile)
 
 	host := models.Host{
 		ID:   "same-id",
@@ -260,7 +410,7 @@ func TestLoadNonExistentFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "nonexistent.json")
 
-	store := NewFileStore(tmpFile)
+	store := openTestStore(t, tmpFile)
 
 	// Should not error, just create empty store
 	if store.Count() != 0 {
@@ -268,12 +418,28 @@ func TestLoadNonExistentFile(t *testing.T) {
 	}
 }
 
+func TestLoadDamagedFile(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "hosts.json")
+	damaged := `{"hosts": [{"name": "web1", "host": "10.0.0.1"`
+	if err := os.WriteFile(tmpFile, []byte(damaged), 0600); err != nil {
+		t.Fatalf("failed to write hosts: %v", err)
+	}
+
+	// An empty store in its place would overwrite the hosts on the next change
+	if _, err := NewFileStore(tmpFile); err == nil {
+		t.Fatal("expected NewFileStore to fail on a damaged file")
+	}
+	if data, _ := os.ReadFile(tmpFile); string(data) != damaged {
+		t.Errorf("damaged file was changed to %q", data)
+	}
+}
+
 func TestPersistence(t *testing.T) {
 	tmpDir := t.TempDir()
 	tmpFile := filepath.Join(tmpDir, "test_persist.json")
 
 	// Create store and add hosts
-	store1 := NewFileStore(tmpFile)
+	store1 := openTestStore(t, tmpFile)
 	host := models.Host{
 		ID:   "persist-test",
 		Name: "persistent-server",
@@ -283,7 +449,7 @@ func TestPersistence(t *testing.T) {
 	store1.AddHost(host)
 
 	// Create new store from same file
-	store2 := NewFileStore(tmpFile)
+	store2 := openTestStore(t, tmpFile)
 
 	// Verify data persisted
 	if store2.Count() != 1 {
@@ -301,3 +467,353 @@ func TestPersistence(t *testing.T) {
 	// Cleanup
 	os.Remove(tmpFile)
 }
+
+func TestBatchedWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	store := openTestStore(t, path)
+	store.Batch()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := store.AddHost(models.Host{Name: name, Host: name + ".example.com"}); err != nil {
+			t.Fatalf("AddHost failed: %v", err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no write before Flush, got %v", err)
+	}
+	if !store.Pending() {
+		t.Error("expected pending changes")
+	}
+
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if store.Pending() {
+		t.Error("expected nothing pending after Flush")
+	}
+	if count := openTestStore(t, path).Count(); count != 3 {
+		t.Errorf("expected 3 hosts written, got %d", count)
+	}
+
+	// Reloading writes pending changes rather than dropping them
+	host, _ := store.GetHostByName("a")
+	store.DeleteHost(host.ID)
+	if err := store.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if count := store.Count(); count != 2 {
+		t.Errorf("expected 2 hosts after reload, got %d", count)
+	}
+}
+
+func TestSavePreservesSettings(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "test_settings.json")
+	if err := os.WriteFile(tmpFile, []byte(`{"theme": "light", "hosts": []}`), 0600); err != nil {
+		t.Fatalf("failed to write store: %v", err)
+	}
+
+	store := openTestStore(t, tmpFile)
+	if err := store.AddHost(models.Host{ID: "1", Name: "server-1"}); err != nil {
+		t.Fatalf("AddHost failed: %v", err)
+	}
+
+	data, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to read store: %v", err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("store is not a JSON object: %v", err)
+	}
+	if string(doc["theme"]) != `"light"` {
+		t.Errorf("expected theme to be preserved, got %s", doc["theme"])
+	}
+
+	reloaded := openTestStore(t, tmpFile)
+	if reloaded.Count() != 1 {
+		t.Errorf("expected 1 host after reload, got %d", reloaded.Count())
+	}
+}
+
+func TestYAMLStore(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "hosts.yaml")
+	data := "# written by hand\ntheme: light\nhosts:\n  - name: web1\n    host: 10.0.0.1\n"
+	if err := os.WriteFile(tmpFile, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write store: %v", err)
+	}
+
+	store := openTestStore(t, tmpFile)
+	web, err := store.GetHostByName("web1")
+	if err != nil {
+		t.Fatalf("expected web1 to load: %v", err)
+	}
+	if web.ID == "" || web.Port != 22 {
+		t.Errorf("expected an ID and the default port, got %+v", web)
+	}
+	if again, _ := openTestStore(t, tmpFile).GetHostByName("web1"); again.ID != web.ID {
+		t.Errorf("expected a stable ID, got %s and %s", web.ID, again.ID)
+	}
+
+	if err := store.AddHost(models.Host{ID: "2", Name: "db1", Host: "10.0.0.2", Port: 22}); err != nil {
+		t.Fatalf("AddHost failed: %v", err)
+	}
+
+	out, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to read store: %v", err)
+	}
+	for _, want := range []string{"# written by hand", "theme: light", "name: db1"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if openTestStore(t, tmpFile).Count() != 2 {
+		t.Errorf("expected 2 hosts after reload")
+	}
+}
+
+func TestStoreDefaults(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "hosts.yaml")
+	data := "defaults:\n  user: deploy\n  port: 2222\nhosts:\n  - name: web1\n    host: 10.0.0.1\n  - name: db1\n    host: 10.0.0.2\n    user: postgres\n"
+	if err := os.WriteFile(tmpFile, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write store: %v", err)
+	}
+
+	store := openTestStore(t, tmpFile)
+	web, _ := store.GetHostByName("web1")
+	db, _ := store.GetHostByName("db1")
+	if web.User != "deploy" || web.Port != 2222 || db.User != "postgres" {
+		t.Errorf("expected defaults to apply, got %+v and %+v", web, db)
+	}
+
+	if err := store.AddHost(models.Host{Name: "web2", Host: "10.0.0.3"}); err != nil {
+		t.Fatalf("AddHost failed: %v", err)
+	}
+	if web2, _ := store.GetHostByName("web2"); web2.Port != 2222 {
+		t.Errorf("expected the default port for a new host, got %d", web2.Port)
+	}
+
+	// Saved hosts keep following the defaults instead of copying them
+	out, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to read store: %v", err)
+	}
+	if strings.Count(string(out), "user: deploy") != 1 || strings.Count(string(out), "port: 2222") != 1 {
+		t.Errorf("expected defaults to be stripped from hosts:\n%s", out)
+	}
+}
+
+func TestEncryptedPasswords(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "hosts.yaml")
+	data := "encrypted_fields: [password]\nhosts:\n  - name: web1\n    host: 10.0.0.1\n    password: hunter2\n"
+	if err := os.WriteFile(tmpFile, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write store: %v", err)
+	}
+
+	store := openTestStore(t, tmpFile)
+	store.SetPassphraseFunc(func() ([]byte, error) { return []byte("correct horse"), nil })
+	if sealed, err := store.Seal(); err != nil || sealed != 1 {
+		t.Fatalf("expected 1 password encrypted, got %d (%v)", sealed, err)
+	}
+	if err := store.AddHost(models.Host{Name: "web2", Host: "10.0.0.2", Password: "swordfish"}); err != nil {
+		t.Fatalf("AddHost failed: %v", err)
+	}
+
+	out, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("failed to read store: %v", err)
+	}
+	if strings.Contains(string(out), "hunter2") || strings.Contains(string(out), "swordfish") {
+		t.Errorf("expected no plain text passwords in:\n%s", out)
+	}
+	web2, _ := openTestStore(t, tmpFile).GetHostByName("web2")
+	if plain, err := secret.Decrypt(web2.Password, []byte("correct horse")); err != nil || plain != "swordfish" {
+		t.Errorf("expected swordfish, got %q (%v)", plain, err)
+	}
+
+	if err := store.CheckPassphrase([]byte("correct horse")); err != nil {
+		t.Errorf("expected the passphrase to check out, got %v", err)
+	}
+	if err := store.CheckPassphrase([]byte("battery staple")); !errors.Is(err, secret.ErrWrongPassphrase) {
+		t.Errorf("expected a wrong passphrase to be rejected, got %v", err)
+	}
+	if err := openTestStore(t, filepath.Join(t.TempDir(), "empty.yaml")).CheckPassphrase(nil); !errors.Is(err, ErrNothingEncrypted) {
+		t.Errorf("expected ErrNothingEncrypted for a store without encrypted values, got %v", err)
+	}
+
+	// New values are never encrypted with another passphrase than the old ones
+	store.SetPassphraseFunc(func() ([]byte, error) { return []byte("battery staple"), nil })
+	if err := store.AddHost(models.Host{Name: "web4", Host: "10.0.0.4", Password: "qwerty"}); !errors.Is(err, secret.ErrWrongPassphrase) {
+		t.Errorf("expected a wrong passphrase to be refused, got %v", err)
+	}
+	if out, _ := os.ReadFile(tmpFile); strings.Contains(string(out), "web4") {
+		t.Errorf("expected nothing saved with a wrong passphrase:\n%s", out)
+	}
+
+	store.SetPassphraseFunc(func() ([]byte, error) { return nil, secret.ErrLocked })
+	if err := store.AddHost(models.Host{Name: "web3", Host: "10.0.0.3", Password: "letmein"}); !errors.Is(err, secret.ErrLocked) {
+		t.Errorf("expected ErrLocked without a passphrase, got %v", err)
+	}
+	if out, _ := os.ReadFile(tmpFile); strings.Contains(string(out), "letmein") {
+		t.Errorf("expected the password not to be saved in plain text:\n%s", out)
+	}
+}
+
+func TestIncludedHosts(t *testing.T) {
+	dir := t.TempDir()
+	tmpFile := filepath.Join(dir, "hosts.yaml")
+	shared := filepath.Join(dir, "shared.yaml")
+	if err := os.WriteFile(tmpFile, []byte("include: [shared.yaml]\nhosts:\n  - name: mine\n    host: 10.0.0.1\n"), 0600); err != nil {
+		t.Fatalf("failed to write store: %v", err)
+	}
+	if err := os.WriteFile(shared, []byte("hosts:\n  - name: team\n    host: 10.0.0.2\n  - name: mine\n    host: 10.0.0.9\n"), 0600); err != nil {
+		t.Fatalf("failed to write included file: %v", err)
+	}
+
+	store := openTestStore(t, tmpFile)
+	if store.Count() != 2 {
+		t.Fatalf("expected own and included hosts without the duplicate, got %d", store.Count())
+	}
+	team, err := store.GetHostByName("team")
+	if err != nil || store.IncludedFrom(team.ID) != shared {
+		t.Fatalf("expected team to come from %s, got %q (%v)", shared, store.IncludedFrom(team.ID), err)
+	}
+	if mine, _ := store.GetHostByName("mine"); mine.Host != "10.0.0.1" {
+		t.Errorf("expected the own host to win, got %s", mine.Host)
+	}
+
+	if err := store.DeleteHost(team.ID); !errors.Is(err, ErrIncluded) {
+		t.Errorf("expected ErrIncluded deleting an included host, got %v", err)
+	}
+	team.User = "root"
+	if err := store.UpdateHost(team); !errors.Is(err, ErrIncluded) {
+		t.Errorf("expected ErrIncluded updating an included host, got %v", err)
+	}
+
+	// Saving leaves included hosts in their own file
+	if err := store.AddHost(models.Host{Name: "new", Host: "10.0.0.3"}); err != nil {
+		t.Fatalf("AddHost failed: %v", err)
+	}
+	out, _ := os.ReadFile(tmpFile)
+	if strings.Contains(string(out), "team") {
+		t.Errorf("expected the included host not to be copied:\n%s", out)
+	}
+	if openTestStore(t, tmpFile).Count() != 3 {
+		t.Errorf("expected 3 hosts after reload")
+	}
+}
+
+func TestGetHostByName(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "test_by_name.json"))
+	store.AddHost(models.Host{ID: "id-b", Name: "beta"})
+	store.AddHost(models.Host{ID: "id-a", Name: "alpha"})
+
+	host, err := store.GetHostByName("alpha")
+	if err != nil || host.ID != "id-a" {
+		t.Errorf("expected alpha by name, got %v (%v)", host, err)
+	}
+	host, err = store.GetHostByName("id-b")
+	if err != nil || host.Name != "beta" {
+		t.Errorf("expected beta by ID, got %v (%v)", host, err)
+	}
+	if _, err := store.GetHostByName("gamma"); err != ErrHostNotFound {
+		t.Errorf("expected ErrHostNotFound, got %v", err)
+	}
+
+	hosts := store.ListHosts()
+	if hosts[0].Name != "alpha" || hosts[1].Name != "beta" {
+		t.Errorf("expected hosts sorted by name, got %s, %s", hosts[0].Name, hosts[1].Name)
+	}
+}
+
+func TestResolveHost(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "test_resolve.json"))
+	for _, name := range []string{"web", "web-1", "web-2", "prod-database", "db-staging"} {
+		store.AddHost(models.Host{ID: "id-" + name, Name: name})
+	}
+
+	names := func(hosts []models.Host) []string {
+		var result []string
+		for _, h := range hosts {
+			result = append(result, h.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"web", []string{"web"}},
+		{"WEB-1", []string{"web-1"}},
+		{"id-web-2", []string{"web-2"}},
+		{"web-", []string{"web-1", "web-2"}},
+		{"prod", []string{"prod-database"}},
+		{"dbstg", []string{"db-staging"}},
+		{"db", []string{"db-staging"}},
+		{"pdb", []string{"prod-database"}},
+		{"dtb", []string{"prod-database"}},
+		{"zzz", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		got := names(store.ResolveHost(tt.query))
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ResolveHost(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSelectHosts(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "test_select.json"))
+	for _, h := range []models.Host{
+		{ID: "1", Name: "prod-web-1", User: "deploy", Group: "eu", Tags: []string{"web", "prod"}},
+		{ID: "2", Name: "prod-web-2", User: "deploy", Group: "us", Tags: []string{"web", "prod"}},
+		{ID: "3", Name: "prod-db", User: "postgres", Group: "eu", Tags: []string{"db", "prod"}},
+		{ID: "4", Name: "staging-db", User: "postgres", Group: "eu", Tags: []string{"db"}},
+	} {
+		store.AddHost(h)
+	}
+
+	tests := []struct {
+		expr string
+		want string
+		err  string
+	}{
+		{"prod-web-*", "prod-web-1,prod-web-2", ""},
+		{"tag:db AND group:eu", "prod-db,staging-db", ""},
+		{"tag:DB AND NOT tag:prod", "staging-db", ""},
+		{"staging-db prod-web-2", "staging-db,prod-web-2", ""},
+		{"staging-db OR tag:db", "staging-db,prod-db", ""},
+		{"(tag:web OR tag:db) AND group:us", "prod-web-2", ""},
+		{"user:post* AND prod-*", "prod-db", ""},
+		{"group:us AND tag:db", "", ""},
+		{"tag:cache", "", `tag "cache" not found`},
+		{"prod-web", "", `"prod-web" is ambiguous, it matches: prod-web-1, prod-web-2`},
+		{"tag:db AND", "", "selector ends unexpectedly"},
+		{"(tag:db", "", "missing ) in selector"},
+	}
+
+	for _, tt := range tests {
+		hosts, err := store.SelectHosts(tt.expr)
+		var got []string
+		for _, h := range hosts {
+			got = append(got, h.Name)
+		}
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("SelectHosts(%q) error = %v, want %q", tt.expr, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || strings.Join(got, ",") != tt.want {
+			t.Errorf("SelectHosts(%q) = %v, %v; want %s", tt.expr, got, err, tt.want)
+		}
+	}
+
+	for text, want := range map[string]bool{"web": false, "prod db": false, "tag:db": true, "web-*": true, "a AND b": true} {
+		if IsSelector(text) != want {
+			t.Errorf("IsSelector(%q) = %v, want %v", text, !want, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Presets are the built-in themes by name
+var Presets = map[string]*Theme{
+	"dark":  &DarkTheme,
+	"light": &LightTheme,
+}
+
+// Custom is a theme defined in the settings file: a preset with some colors
+// and styles replaced
+type Custom struct {
+	// Extends names the preset to start from; it defaults to the preset of
+	// the same name, so a custom "light" tweaks the light theme, or dark
+	Extends string `json:"extends,omitempty" yaml:"extends,omitempty"`
+	Colors  Colors `json:"colors,omitempty" yaml:"colors,omitempty"`
+	Styles  Styles `json:"styles,omitempty" yaml:"styles,omitempty"`
+}
+
+// Colors replaces the colors of a preset; empty fields keep the preset's
+// Colors are ANSI numbers ("0"-"255") or hex ("#5fd7af").
+type Colors struct {
+	Primary       string `json:"primary,omitempty" yaml:"primary,omitempty"`
+	Secondary     string `json:"secondary,omitempty" yaml:"secondary,omitempty"`
+	Success       string `json:"success,omitempty" yaml:"success,omitempty"`
+	Error         string `json:"error,omitempty" yaml:"error,omitempty"`
+	Background    string `json:"background,omitempty" yaml:"background,omitempty"`
+	Surface       string `json:"surface,omitempty" yaml:"surface,omitempty"`
+	Border        string `json:"border,omitempty" yaml:"border,omitempty"`
+	Text          string `json:"text,omitempty" yaml:"text,omitempty"`
+	TextDim       string `json:"text_dim,omitempty" yaml:"text_dim,omitempty"`
+	SelectedBg    string `json:"selected_bg,omitempty" yaml:"selected_bg,omitempty"`
+	TagBackground string `json:"tag_background,omitempty" yaml:"tag_background,omitempty"`
+	StatusOnline  string `json:"status_online,omitempty" yaml:"status_online,omitempty"`
+	StatusOffline string `json:"status_offline,omitempty" yaml:"status_offline,omitempty"`
+	StatusUnknown string `json:"status_unknown,omitempty" yaml:"status_unknown,omitempty"`
+}
+
+// Styles overrides the TUI styles built from the theme colors
+type Styles struct {
+	Title     *Style `json:"title,omitempty" yaml:"title,omitempty"`
+	Header    *Style `json:"header,omitempty" yaml:"header,omitempty"`
+	Body      *Style `json:"body,omitempty" yaml:"body,omitempty"`
+	Help      *Style `json:"help,omitempty" yaml:"help,omitempty"`
+	Selected  *Style `json:"selected,omitempty" yaml:"selected,omitempty"`
+	Normal    *Style `json:"normal,omitempty" yaml:"normal,omitempty"`
+	Border    *Style `json:"border,omitempty" yaml:"border,omitempty"`
+	Input     *Style `json:"input,omitempty" yaml:"input,omitempty"`
+	Error     *Style `json:"error,omitempty" yaml:"error,omitempty"`
+	StatusBar *Style `json:"status_bar,omitempty" yaml:"status_bar,omitempty"`
+}
+
+// Style overrides the colors and attributes of one style
+// For the border style the foreground is the border color.
+type Style struct {
+	Foreground string `json:"foreground,omitempty" yaml:"foreground,omitempty"`
+	Background string `json:"background,omitempty" yaml:"background,omitempty"`
+	Bold       *bool  `json:"bold,omitempty" yaml:"bold,omitempty"`
+	Italic     *bool  `json:"italic,omitempty" yaml:"italic,omitempty"`
+	Underline  *bool  `json:"underline,omitempty" yaml:"underline,omitempty"`
+}
+
+// Apply returns base with the overrides of s; a nil s changes nothing
+func (s *Style) Apply(base lipgloss.Style) lipgloss.Style {
+	if s == nil {
+		return base
+	}
+	if s.Foreground != "" {
+		base = base.Foreground(lipgloss.Color(s.Foreground))
+	}
+	if s.Background != "" {
+		base = base.Background(lipgloss.Color(s.Background))
+	}
+	if s.Bold != nil {
+		base = base.Bold(*s.Bold)
+	}
+	if s.Italic != nil {
+		base = base.Italic(*s.Italic)
+	}
+	if s.Underline != nil {
+		base = base.Underline(*s.Underline)
+	}
+	return base
+}
+
+// ValidColor reports whether s is an ANSI color number or a hex color
+func ValidColor(s string) bool {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n >= 0 && n <= 255
+	}
+	if len(s) != 4 && len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	_, err := strconv.ParseUint(s[1:], 16, 32)
+	return err == nil
+}
+
+// Build makes the theme called name from its definition
+func Build(name string, c Custom) (*Theme, error) {
+	base := c.Extends
+	if base == "" {
+		base = "dark"
+		if _, ok := Presets[name]; ok {
+			base = name
+		}
+	}
+	preset, ok := Presets[base]
+	if !ok {
+		return nil, fmt.Errorf("theme %s extends unknown preset %q (use dark or light)", name, base)
+	}
+
+	t := *preset
+	t.Name = name
+	for _, f := range colorFields(&t, &c.Colors) {
+		if *f.value == "" {
+			continue
+		}
+		if !ValidColor(*f.value) {
+			return nil, fmt.Errorf("theme %s: invalid %s color %q", name, f.name, *f.value)
+		}
+		*f.color = lipgloss.Color(*f.value)
+	}
+	for _, s := range c.Styles.list() {
+		if s.style == nil {
+			continue
+		}
+		for _, v := range []string{s.style.Foreground, s.style.Background} {
+			if v != "" && !ValidColor(v) {
+				return nil, fmt.Errorf("theme %s: invalid %s style color %q", name, s.name, v)
+			}
+		}
+	}
+	t.Styles = c.Styles
+	return &t, nil
+}
+
+// Resolve returns the presets and the custom themes by name, with the names
+// in switching order: the presets, then the custom themes alphabetically
+// Themes that fail to build are left out and their errors joined.
+func Resolve(custom map[string]Custom) (map[string]*Theme, []string, error) {
+	themes := make(map[string]*Theme, len(Presets)+len(custom))
+	names := []string{"dark", "light"}
+	for name, t := range Presets {
+		themes[name] = t
+	}
+
+	customNames := make([]string, 0, len(custom))
+	for name := range custom {
+		customNames = append(customNames, name)
+	}
+	sort.Strings(customNames)
+
+	var errs []error
+	for _, name := range customNames {
+		t, err := Build(name, custom[name])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, preset := Presets[name]; !preset {
+			names = append(names, name)
+		}
+		themes[name] = t
+	}
+	return themes, names, errors.Join(errs...)
+}
+
+type colorField struct {
+	name  string
+	value *string
+	color *lipgloss.Color
+}
+
+// colorFields pairs the configurable colors with the theme colors they set
+func colorFields(t *Theme, c *Colors) []colorField {
+	return []colorField{
+		{"primary", &c.Primary, &t.Primary},
+		{"secondary", &c.Secondary, &t.Secondary},
+		{"success", &c.Success, &t.Success},
+		{"error", &c.Error, &t.Error},
+		{"background", &c.Background, &t.Background},
+		{"surface", &c.Surface, &t.Surface},
+		{"border", &c.Border, &t.Border},
+		{"text", &c.Text, &t.Text},
+		{"text_dim", &c.TextDim, &t.TextDim},
+		{"selected_bg", &c.SelectedBg, &t.SelectedBg},
+		{"tag_background", &c.TagBackground, &t.TagBackground},
+		{"status_online", &c.StatusOnline, &t.StatusOnline},
+		{"status_offline", &c.StatusOffline, &t.StatusOffline},
+		{"status_unknown", &c.StatusUnknown, &t.StatusUnknown},
+	}
+}
+
+type namedStyle struct {
+	name  string
+	style *Style
+}
+
+func (s Styles) list() []namedStyle {
+	return []namedStyle{
+		{"title", s.Title},
+		{"header", s.Header},
+		{"body", s.Body},
+		{"help", s.Help},
+		{"selected", s.Selected},
+		{"normal", s.Normal},
+		{"border", s.Border},
+		{"input", s.Input},
+		{"error", s.Error},
+		{"status_bar", s.StatusBar},
+	}
+}
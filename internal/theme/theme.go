@@ -21,6 +21,8 @@ type Theme struct {
 	StatusOnline    lipgloss.Color
 	StatusOffline   lipgloss.Color
 	StatusUnknown   lipgloss.Color
+	// Styles overrides the styles built from the colors above
+	Styles          Styles
 }
 
 // DarkTheme is the default dark theme
@@ -0,0 +1,122 @@
+// Package tmux opens commands in new windows and panes of the tmux session
+// sshm runs in
+package tmux
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Command is what a new window or pane runs
+type Command struct {
+	Title string   // window or pane title
+	Argv  []string // run directly, without a shell
+	Env   []string // KEY=value set on top of the tmux session's environment
+}
+
+// ErrNotInside is returned when sshm doesn't run inside tmux
+var ErrNotInside = errors.New("not running inside tmux")
+
+// Inside reports whether sshm runs inside a tmux session
+func Inside() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// NewWindow opens a window named after the command's title running it
+func NewWindow(c Command) error {
+	return run(open([]string{"new-window", "-n", c.Title}, c))
+}
+
+// SplitPane splits the current pane, running the command in the new one
+func SplitPane(c Command) error {
+	return run(open([]string{"split-window"}, c))
+}
+
+// Tile opens a window named title with a pane per command, arranged by
+// layout (tiled, even-horizontal, ...)
+func Tile(title string, commands []Command, layout string) error {
+	if len(commands) == 0 {
+		return nil
+	}
+	args := open([]string{"new-window", "-n", title}, commands[0])
+	for _, c := range commands[1:] {
+		args = append(args, ";")
+		args = append(args, open([]string{"split-window"}, c)...)
+		// Re-arranging after each split leaves room for the next one
+		args = append(args, ";", "select-layout", layout)
+	}
+	return run(args)
+}
+
+// open returns the tmux command creating a window or pane for c, which
+// becomes the current one, followed by those titling the pane and keeping
+// it open when the command fails
+// The commands go to tmux in one go, so the pane is set up before a quick
+// failure could close it.
+func open(create []string, c Command) []string {
+	args := append([]string(nil), create...)
+	for _, env := range c.Env {
+		args = append(args, "-e", env)
+	}
+	args = append(args, c.Argv...)
+	args = append(args, ";", "select-pane", "-T", c.Title)
+	if remainOnFailure() {
+		// Errors stay on screen until the pane is closed
+		args = append(args, ";", "set-option", "-p", "remain-on-exit", "failed")
+	}
+	return args
+}
+
+// run runs a tmux command sequence, reporting what tmux said on failure
+func run(args []string) error {
+	if !Inside() {
+		return ErrNotInside
+	}
+	out, err := exec.Command("tmux", args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("tmux: %s", msg)
+		}
+		return fmt.Errorf("tmux: %w", err)
+	}
+	return nil
+}
+
+// versionPattern matches the version tmux -V prints: "tmux 3.4",
+// "tmux next-3.5", "tmux 3.3a"
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)`)
+
+var (
+	versionOnce sync.Once
+	remainFails bool
+)
+
+// remainOnFailure reports whether tmux keeps panes whose command failed open
+// (remain-on-exit failed, tmux 3.2 and later)
+func remainOnFailure() bool {
+	versionOnce.Do(func() {
+		out, err := exec.Command("tmux", "-V").Output()
+		if err == nil {
+			remainFails = atLeast(string(out), 3, 2)
+		}
+	})
+	return remainFails
+}
+
+// atLeast reports whether the output of tmux -V names version major.minor
+// or a later one
+func atLeast(version string, major, minor int) bool {
+	m := versionPattern.FindStringSubmatch(version)
+	if m == nil {
+		return false
+	}
+	gotMajor, _ := strconv.Atoi(m[1])
+	gotMinor, _ := strconv.Atoi(m[2])
+	return gotMajor > major || (gotMajor == major && gotMinor >= minor)
+}
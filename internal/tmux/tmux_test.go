@@ -0,0 +1,58 @@
+package tmux
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	// Pretend tmux 3.2 or later
+	versionOnce.Do(func() {})
+	remainFails = true
+	defer func() { remainFails = false }()
+
+	c := Command{Title: "web1", Argv: []string{"/usr/bin/sshm", "connect", "--exact", "id1"}, Env: []string{"XDG_CONFIG_HOME=/cfg"}}
+	got := open([]string{"split-window"}, c)
+	want := []string{
+		"split-window", "-e", "XDG_CONFIG_HOME=/cfg", "/usr/bin/sshm", "connect", "--exact", "id1",
+		";", "select-pane", "-T", "web1",
+		";", "set-option", "-p", "remain-on-exit", "failed",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("open() = %q, want %q", got, want)
+	}
+
+	remainFails = false
+	got = open([]string{"new-window", "-n", "web1"}, Command{Title: "web1", Argv: []string{"sshm"}})
+	want = []string{"new-window", "-n", "web1", "sshm", ";", "select-pane", "-T", "web1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("open() = %q, want %q", got, want)
+	}
+}
+
+func TestNotInside(t *testing.T) {
+	t.Setenv("TMUX", "")
+	if err := NewWindow(Command{Title: "web1", Argv: []string{"true"}}); err != ErrNotInside {
+		t.Errorf("NewWindow outside tmux = %v, want ErrNotInside", err)
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"tmux 3.2\n", true},
+		{"tmux 3.3a", true},
+		{"tmux 4.0", true},
+		{"tmux next-3.5", true},
+		{"tmux 3.1c", false},
+		{"tmux 2.9", false},
+		{"tmux master", false},
+	}
+	for _, tt := range tests {
+		if got := atLeast(tt.version, 3, 2); got != tt.want {
+			t.Errorf("atLeast(%q, 3, 2) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sshm/sshm/internal/keyring"
+)
+
+// Key is a TOTP secret with the parameters of RFC 6238
+type Key struct {
+	Secret []byte
+	// Digits is the length of codes, 6 by default
+	Digits int
+	// Period is how long a code is valid, 30s by default
+	Period time.Duration
+	// Algorithm is SHA1, SHA256 or SHA512, SHA1 by default
+	Algorithm string
+}
+
+// Parse reads a base32 secret as shown by authenticator setup pages, or an
+// otpauth://totp/ URI as encoded in their QR codes
+func Parse(s string) (Key, error) {
+	key := Key{Digits: 6, Period: 30 * time.Second, Algorithm: "SHA1"}
+	s = strings.TrimSpace(s)
+	secret := s
+
+	if strings.HasPrefix(strings.ToLower(s), "otpauth://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return key, fmt.Errorf("invalid otpauth URI: %w", err)
+		}
+		if !strings.EqualFold(u.Host, "totp") {
+			return key, fmt.Errorf("unsupported otpauth type %q (only totp is supported)", u.Host)
+		}
+		q := u.Query()
+		secret = q.Get("secret")
+		if d := q.Get("digits"); d != "" {
+			if key.Digits, err = strconv.Atoi(d); err != nil || key.Digits < 6 || key.Digits > 8 {
+				return key, fmt.Errorf("invalid digits %q (use 6 to 8)", d)
+			}
+		}
+		if p := q.Get("period"); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 1 {
+				return key, fmt.Errorf("invalid period %q", p)
+			}
+			key.Period = time.Duration(n) * time.Second
+		}
+		if a := q.Get("algorithm"); a != "" {
+			key.Algorithm = strings.ToUpper(a)
+		}
+	}
+	if newHash(key.Algorithm) == nil {
+		return key, fmt.Errorf("unsupported algorithm %q (use SHA1, SHA256 or SHA512)", key.Algorithm)
+	}
+
+	secret = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(secret))
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(decoded) == 0 {
+		return key, fmt.Errorf("invalid TOTP secret (expected base32 or an otpauth:// URI)")
+	}
+	key.Secret = decoded
+	return key, nil
+}
+
+// Code returns the code valid at t
+func (k Key) Code(t time.Time) string {
+	counter := uint64(t.Unix() / int64(k.Period/time.Second))
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(newHash(k.Algorithm), k.Secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < k.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", k.Digits, value%mod)
+}
+
+// Remaining returns how long the code valid at t stays valid
+func (k Key) Remaining(t time.Time) time.Duration {
+	period := int64(k.Period / time.Second)
+	return time.Duration(period-t.Unix()%period) * time.Second
+}
+
+func newHash(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case "SHA1":
+		return sha1.New
+	case "SHA256":
+		return sha256.New
+	case "SHA512":
+		return sha512.New
+	}
+	return nil
+}
+
+// keyringAccount is the keyring account holding the secret of a host
+func keyringAccount(hostID string) string {
+	return "totp:" + hostID
+}
+
+// Save stores the TOTP secret of a host in the system keyring
+func Save(hostID, secret string) error {
+	if _, err := Parse(secret); err != nil {
+		return err
+	}
+	return keyring.Set(keyringAccount(hostID), strings.TrimSpace(secret))
+}
+
+// Load returns the TOTP key of a host from the system keyring
+func Load(hostID string) (Key, error) {
+	secret, err := keyring.Get(keyringAccount(hostID))
+	if err != nil {
+		return Key{}, fmt.Errorf("TOTP secret: %w", err)
+	}
+	return Parse(secret)
+}
+
+// Delete removes the TOTP secret of a host from the system keyring
+func Delete(hostID string) error {
+	return keyring.Delete(keyringAccount(hostID))
+}
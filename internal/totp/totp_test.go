@@ -0,0 +1,58 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// Test vectors of RFC 6238 appendix B
+func TestCode(t *testing.T) {
+	secrets := map[string]string{
+		"SHA1":   "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"SHA256": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA",
+		"SHA512": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA",
+	}
+	tests := []struct {
+		unix      int64
+		algorithm string
+		want      string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1234567890, "SHA256", "91819424"},
+		{20000000000, "SHA512", "47863826"},
+	}
+	for _, tt := range tests {
+		key, err := Parse("otpauth://totp/test?digits=8&algorithm=" + tt.algorithm + "&secret=" + secrets[tt.algorithm])
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if got := key.Code(time.Unix(tt.unix, 0)); got != tt.want {
+			t.Errorf("Code(%d, %s) = %s, want %s", tt.unix, tt.algorithm, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	key, err := Parse("gezd gnbv gy3t qojq gezd gnbv gy3t qojq")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if key.Digits != 6 || key.Period != 30*time.Second || key.Algorithm != "SHA1" {
+		t.Errorf("unexpected defaults %+v", key)
+	}
+	if got := key.Code(time.Unix(59, 0)); got != "287082" {
+		t.Errorf("Code() = %s, want 287082", got)
+	}
+	if got := key.Remaining(time.Unix(59, 0)); got != time.Second {
+		t.Errorf("Remaining() = %s, want 1s", got)
+	}
+
+	for _, bad := range []string{"", "not base32!", "otpauth://hotp/x?secret=GEZDGNBV", "otpauth://totp/x?secret=GEZDGNBV&digits=4", "otpauth://totp/x?secret=GEZDGNBV&algorithm=MD5"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
@@ -1,13 +1,24 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/sshm/sshm/internal/clipboard"
 	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/editor"
+	"github.com/sshm/sshm/internal/i18n"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/notify"
+	"github.com/sshm/sshm/internal/secret"
+	"github.com/sshm/sshm/internal/ssh"
 	"github.com/sshm/sshm/internal/store"
 )
 
@@ -19,63 +30,236 @@ type App struct {
 	editView    *EditView
 	historyView *HistoryView
 	helpView    *HelpView
-	view        string // "list", "add", "edit", "detail", "history", "help"
+	quickEdit   *QuickEditView // inline single-field editor, nil when closed
+	toasts      *Toasts        // notifications shared by all views
+	onboarding  *OnboardingView
+	tagPicker   *FilterPicker // tag/group filter popup, nil when closed
+	snippetPicker *SnippetPicker // snippet popup, nil when closed
+	shareView     *ShareView     // host being shared in the "share" view
+	lock        *LockView // idle lock screen, nil when unlocked
+	lastInput   time.Time // last key press, for idle_lock
+	view        string // "list", "add", "edit", "detail", "history", "help", "onboarding"
 	quitting    bool
 	err         error
-	configPath  string
+	paths       config.Paths
+	startupWarning string // shown as a toast once the program starts
+	configStamp fileStamp // version of the settings file last applied
+	includeStamps    map[string]fileStamp // versions of the included files, nil until first seen
+	includesChecked  time.Time            // when includeStamps were last taken
+	checkingIncludes bool                 // a look at the included files is under way
+	flushScheduled   bool                 // a write of pending host changes is due
+	flushFailed      bool                 // the last write failed, retried after the next key
 	pendingDelete string // host ID waiting for delete confirmation
+	pickMode    bool         // Enter selects a host and quits instead of connecting
+	picked      *models.Host // host chosen in pick mode
 }
 
-// New creates a new TUI application
-func New(storePath string) (*App, error) {
-	s := store.NewFileStore(storePath)
-	h := store.NewHistoryStore("")
+// New creates a new TUI application for the settings and hosts at paths
+func New(paths config.Paths) (*App, error) {
+	s, err := store.NewFileStoreWithConfig(paths.Hosts, paths.Config)
+	if err != nil {
+		return nil, err
+	}
+	// The alt screen is up while hosts are saved, so don't ask for a passphrase
+	s.SetPassphraseFunc(secret.StoredPassphrase)
+	// Edits in quick succession are written together, see storeFlushDelay
+	s.Batch()
+	h := store.NewHistoryStore(paths.History)
 
 	// Load config to get theme preference
-	cfgPath := config.GetDefaultConfigPath()
-	cfg, err := config.LoadConfig(cfgPath)
-	if err == nil && cfg != nil && cfg.Theme != "" {
-		InitTheme(cfg.Theme)
-	} else {
-		// Default to dark theme
-		InitTheme("dark")
+	cfg, err := config.LoadConfig(paths.Config)
+	if err != nil || cfg == nil {
+		cfg = &config.Config{}
 	}
-
-	return &App{
+	ssh.Vault = cfg.VaultClient()
+	ssh.KeyringPasswords = cfg.KeyringPasswords
+	ssh.WindowsKeys = cfg.WSL.WindowsKeys
+	ssh.WindowsAgentRelay = cfg.WSL.AgentRelay()
+	themeErr := InitTheme(cfg.Theme, cfg.Themes)
+	keymapErr := InitKeymap(cfg.Keybindings)
+	languageErr := i18n.Init(cfg.Language)
+	listView := NewListView(s)
+	listView.SetConfig(cfg)
+	listView.SetHistory(h)
+	listView.SetPresenting(cfg.Presentation)
+	listView.sshm, listView.sshmEnv = sshmCommand(paths)
+
+	app := &App{
 		store:      s,
 		history:    h,
-		listView:   NewListView(s),
+		listView:   listView,
 		helpView:   NewHelpView(),
+		toasts:     NewToasts(),
 		view:       "list",
-		configPath: cfgPath,
-	}, nil
+		paths:      paths,
+		configStamp: stampFile(paths.Config),
+		includeStamps: stampFiles(s.IncludedFiles()),
+		includesChecked: time.Now(),
+		lastInput:  time.Now(),
+	}
+	var warnings []string
+	if themeErr != nil {
+		warnings = append(warnings, fmt.Sprintf("Theme: %v", themeErr))
+	}
+	if keymapErr != nil {
+		warnings = append(warnings, fmt.Sprintf("Ignoring keybindings: %v", keymapErr))
+	}
+	if languageErr != nil {
+		warnings = append(warnings, fmt.Sprintf("Language: %v", languageErr))
+	}
+	for _, file := range []string{paths.Config, paths.Hosts} {
+		if changes, _ := config.CheckUpgrade(file); len(changes) > 0 {
+			warnings = append(warnings, fmt.Sprintf("%s uses an older layout, run \"sshm config migrate\"", file))
+		}
+		if paths.Hosts == paths.Config {
+			break
+		}
+	}
+	app.startupWarning = strings.Join(warnings, "; ")
+
+	// Greet first-time users with a wizard instead of an empty list
+	if s.Count() == 0 {
+		app.onboarding = NewOnboardingView()
+		app.view = "onboarding"
+	}
+
+	return app, nil
 }
 
 // Init initializes the TUI application
 func (m *App) Init() tea.Cmd {
-	return nil
+	// Start probing hosts for status and latency
+	cmd := tea.Batch(m.listView.Init(), watchConfig(), watchIdle())
+	if m.startupWarning != "" {
+		cmd = tea.Batch(cmd, m.notify(ToastError, m.startupWarning))
+	}
+	return cmd
 }
 
-// Update handles incoming messages
+// storeFlushDelay is how long host changes wait to be written with those
+// following them
+const storeFlushDelay = 500 * time.Millisecond
+
+// storeFlushMsg writes the pending host changes
+type storeFlushMsg struct{}
+
+// Update handles incoming messages, then schedules the write of the host
+// changes they made
 func (m *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(tea.KeyMsg); ok {
+		m.flushFailed = false
+	}
+	model, cmd := m.update(msg)
+	if m.store.Pending() && !m.flushScheduled && !m.flushFailed {
+		m.flushScheduled = true
+		cmd = tea.Batch(cmd, tea.Tick(storeFlushDelay, func(time.Time) tea.Msg {
+			return storeFlushMsg{}
+		}))
+	}
+	return model, cmd
+}
+
+// update handles incoming messages
+func (m *App) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case storeFlushMsg:
+		m.flushScheduled = false
+		if err := m.store.Flush(); err != nil {
+			m.flushFailed = true
+			return m, m.notify(ToastError, i18n.T("toast.save_hosts_failed", err))
+		}
+		return m, nil
 	case tea.KeyMsg:
 		return m.handleKeyMsg(msg)
 	case tea.WindowSizeMsg:
 		return m, nil
+	case toastExpiredMsg:
+		m.toasts.Dismiss(msg.id)
+		return m, nil
+	case configCheckMsg:
+		return m, m.checkConfig()
+	case includesCheckedMsg:
+		m.applyIncludes(msg)
+		return m, nil
+	case idleCheckMsg:
+		return m, m.checkIdle(msg)
+	case connectMsg:
+		// Let the list track connection state and report failures as notifications
+		model, cmd := m.listView.Update(msg)
+		m.listView = model.(*ListView)
+		if !msg.success {
+			return m, tea.Batch(cmd, m.notify(ToastError, i18n.T("toast.connection_failed", msg.host.Name, msg.err)))
+		}
+		return m, cmd
+	case sessionEndedMsg:
+		// Back from an interactive session: record it and resume where we left off
+		errMsg := ""
+		if msg.Failed() {
+			errMsg = msg.err.Error()
+		}
+		RecordConnection(m.history, m.store, models.ConnectionHistory{
+			HostID:         msg.host.ID,
+			Success:        !msg.Failed(),
+			Error:          errMsg,
+			Duration:       msg.connectTime.Milliseconds(),
+			Warnings:       msg.warnings,
+			HostKeyChanges: msg.hostKeyChanges,
+		})
+
+		model, cmd := m.listView.Update(msg)
+		m.listView = model.(*ListView)
+		if msg.Failed() {
+			return m, tea.Batch(cmd, m.notify(ToastError, i18n.T("toast.connection_failed", msg.host.Name, msg.err)))
+		}
+		if len(msg.warnings) > 0 {
+			return m, tea.Batch(cmd, m.notify(ToastError, i18n.T("toast.disconnected_weak", msg.host.Name)))
+		}
+		return m, tea.Batch(cmd, m.notify(ToastInfo, i18n.T("toast.disconnected", msg.host.Name)))
+	case tmuxOpenedMsg:
+		return m, m.notify(msg.describe())
+	case rawEditMsg:
+		return m, m.applyRawEdit(msg)
+	case aliasEndedMsg:
+		kind, text := msg.describe()
+		if m.listView.config.Notify.Due(msg.elapsed) {
+			return m, tea.Batch(m.notify(kind, text), notifyDesktop(text))
+		}
+		return m, m.notify(kind, text)
+	default:
+		// Forward background results (probes, ...) to the list
+		model, cmd := m.listView.Update(msg)
+		m.listView = model.(*ListView)
+		return m, cmd
 	}
-	return m, nil
+}
+
+// notify shows a notification and returns the command that dismisses it
+func (m *App) notify(kind ToastKind, text string) tea.Cmd {
+	return m.toasts.Push(kind, text)
 }
 
 // View renders the TUI
 func (m *App) View() string {
+	view := m.renderView()
+	if !m.toasts.Empty() && m.lock == nil {
+		view += "\n\n" + m.toasts.View()
+	}
+	return view
+}
+
+// renderView renders the active view without notifications
+func (m *App) renderView() string {
+	if m.lock != nil {
+		return m.lock.View()
+	}
 	if m.err != nil {
-		return ErrorStyle.Render(fmt.Sprintf("Error: %v", m.err))
+		return ErrorStyle.Render(i18n.T("error.generic", m.err))
 	}
 
 	// Show delete confirmation if pending
 	if m.pendingDelete != "" {
-		confirmMsg := fmt.Sprintf("Delete this host? Press 'x' or 'y' to confirm, 'n' or 'esc' to cancel.")
+		confirmMsg := i18n.T("list.delete_confirm",
+			keymap.Key(ActionDelete), keymap.Key(ActionConfirm), keymap.Key(ActionCancel), keymap.Key(ActionBack))
 		confirmDisplay := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("214")). // Orange
 			Bold(true).
@@ -87,6 +271,15 @@ func (m *App) View() string {
 
 	switch m.view {
 	case "list":
+		if m.quickEdit != nil {
+			return m.listView.View() + "\n\n" + m.quickEdit.View()
+		}
+		if m.tagPicker != nil {
+			return m.listView.View() + "\n\n" + m.tagPicker.View()
+		}
+		if m.snippetPicker != nil {
+			return m.listView.View() + "\n\n" + m.snippetPicker.View()
+		}
 		return m.listView.View()
 	case "add":
 		if m.editView != nil {
@@ -107,12 +300,38 @@ func (m *App) View() string {
 		return m.renderHistory()
 	case "help":
 		return m.helpView.View()
+	case "share":
+		if m.shareView != nil {
+			return m.shareView.View(m.listView.width, m.listView.height)
+		}
+		return m.listView.View()
+	case "onboarding":
+		if m.onboarding != nil {
+			return m.onboarding.View()
+		}
+		return m.listView.View()
 	default:
 		return m.listView.View()
 	}
 }
 
 func (m *App) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.lastInput = time.Now()
+
+	// While locked keys only go to the lock screen
+	if m.lock != nil {
+		if msg.String() == "ctrl+c" {
+			m.quitting = true
+			return m, tea.Quit
+		}
+		model, cmd := m.lock.Update(msg)
+		m.lock = model.(*LockView)
+		if m.lock.done {
+			m.lock = nil
+		}
+		return m, cmd
+	}
+
 	// Delegate to edit view if active
 	if m.view == "add" || m.view == "edit" {
 		if m.editView != nil {
@@ -120,43 +339,170 @@ func (m *App) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.editView = model.(*EditView)
 
 			// Check if edit view signaled quit (save completed or cancel)
+			// Its quit command only closes the form, not the whole app
 			if m.editView.saved || msg.String() == "esc" {
+				saved := m.editView.saved
 				m.view = "list"
 				m.editView = nil
 				m.listView.Refresh()
+				if saved {
+					return m, m.notify(ToastSuccess, i18n.T("toast.host_saved"))
+				}
+				return m, nil
 			}
 			return m, cmd
 		}
 	}
 
+	// Delegate to the first-run wizard if active
+	if m.view == "onboarding" && m.onboarding != nil {
+		if msg.String() == "ctrl+c" {
+			m.quitting = true
+			return m, tea.Quit
+		}
+		m.onboarding.Update(msg)
+		return m.handleOnboardingChoice(m.onboarding.TakeChoice())
+	}
+
+	// Delegate to the tag picker popup if open
+	if m.tagPicker != nil {
+		model, cmd := m.tagPicker.Update(msg)
+		m.tagPicker = model.(*FilterPicker)
+		if m.tagPicker.done {
+			if m.tagPicker.applied {
+				if m.tagPicker.single {
+					group := ""
+					if selected := m.tagPicker.Selected(); len(selected) > 0 {
+						group = selected[0]
+					}
+					m.listView.SetGroupFilter(group)
+				} else {
+					m.listView.SetTagFilters(m.tagPicker.Selected())
+				}
+			}
+			m.tagPicker = nil
+		}
+		return m, cmd
+	}
+
+	// Delegate to the snippet popup if open
+	if m.snippetPicker != nil {
+		model, cmd := m.snippetPicker.Update(msg)
+		m.snippetPicker = model.(*SnippetPicker)
+		if m.snippetPicker.done {
+			picker := m.snippetPicker
+			m.snippetPicker = nil
+			if picker.command != "" {
+				return m, m.runCommand(picker.host, picker.Snippet().Name, picker.command)
+			}
+		}
+		return m, cmd
+	}
+
+	// Delegate to the inline quick edit popup if open
+	if m.quickEdit != nil {
+		model, cmd := m.quickEdit.Update(msg)
+		m.quickEdit = model.(*QuickEditView)
+		if m.quickEdit.done {
+			if m.quickEdit.saved {
+				m.listView.Refresh()
+				cmd = tea.Batch(cmd, m.notify(ToastSuccess, i18n.T("toast.host_updated", m.quickEdit.host.Name)))
+			}
+			m.quickEdit = nil
+		}
+		return m, cmd
+	}
+
+	// Let the list handle its own modal input (filter text, connect confirmation)
+	if m.view == "list" && (m.listView.IsFiltering() || m.listView.IsConfirmingConnect()) {
+		model, cmd := m.listView.Update(msg)
+		m.listView = model.(*ListView)
+		return m, cmd
+	}
+
+	// In pick mode Enter chooses the host instead of connecting to it and
+	// actions that modify hosts or leave the list are disabled
+	if m.pickMode && m.view == "list" {
+		switch keymap.Action(msg) {
+		case ActionConnect:
+			if host := m.listView.GetSelectedHost(); host != nil {
+				m.picked = host
+				m.quitting = true
+				return m, tea.Quit
+			}
+			return m, nil
+		case ActionAdd, ActionEdit, ActionEditRaw, ActionDelete, ActionConfirm, ActionDetail,
+			ActionHistory, ActionHostHistory, ActionImport, ActionRename, ActionEditUser,
+			ActionEditPort, ActionCopy, ActionCopyConfig, ActionTheme, ActionHelp, ActionSnippets, ActionSplit,
+			ActionShare:
+			return m, nil
+		}
+	}
+
 	// Handle help view
 	if m.view == "help" {
-		if msg.String() == "esc" || msg.String() == "q" || msg.String() == "?" {
+		switch keymap.Action(msg) {
+		case ActionBack, ActionQuit, ActionHelp:
 			m.view = "list"
 		}
 		return m, nil
 	}
 
-	switch msg.String() {
-	case "q", "ctrl+c":
+	// Handle share view
+	if m.view == "share" {
+		switch keymap.Action(msg) {
+		case ActionCopy:
+			if err := clipboard.CopyToClipboard(m.shareView.URI()); err != nil {
+				return m, m.notify(ToastError, i18n.T("toast.copy_failed", err))
+			}
+			return m, m.notify(ToastSuccess, i18n.T("toast.copied_link"))
+		case ActionBack, ActionQuit, ActionShare:
+			m.view = "list"
+			m.shareView = nil
+		}
+		return m, nil
+	}
+
+	// In the detail view number keys run the host's command aliases
+	if m.view == "detail" {
+		if cmd := m.runAliasKey(msg.String()); cmd != nil {
+			return m, cmd
+		}
+	}
+
+	if msg.String() == "ctrl+c" {
 		m.quitting = true
 		return m, tea.Quit
-	case "?":
+	}
+
+	switch action := keymap.Action(msg); action {
+	case ActionQuit:
+		m.quitting = true
+		return m, tea.Quit
+	case ActionHelp:
 		// Show help view
 		m.helpView = NewHelpView()
 		m.view = "help"
-	case "t":
+	case ActionTheme:
 		// Toggle theme
 		newTheme := ToggleTheme()
 		m.saveThemePreference(newTheme)
-	case "i":
+		return m, m.notify(ToastInfo, i18n.T("toast.theme", newTheme))
+	case ActionPresent:
+		// Mask hosts and users for screen sharing
+		m.listView.SetPresenting(!m.listView.Presenting())
+		if m.listView.Presenting() {
+			return m, m.notify(ToastInfo, i18n.T("toast.presentation_on"))
+		}
+		return m, m.notify(ToastInfo, i18n.T("toast.presentation_off"))
+	case ActionImport:
 		// Import from SSH config
 		return m.handleSSHConfigImport()
-	case "a":
+	case ActionAdd:
 		// Start add mode
 		m.editView = NewAddView(m.store)
 		m.view = "add"
-	case "e":
+	case ActionEdit:
 		// Start edit mode with selected host
 		selectedHost := m.listView.GetSelectedHost()
 		if selectedHost != nil {
@@ -168,56 +514,105 @@ func (m *App) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.editView = editView
 			m.view = "edit"
 		}
-	case "d":
+	case ActionEditRaw:
+		// Edit the selected host as YAML in $EDITOR
+		selectedHost := m.listView.GetSelectedHost()
+		if selectedHost != nil && m.view == "list" {
+			return m, m.editInEditor(*selectedHost)
+		}
+	case ActionRename, ActionEditUser, ActionEditPort:
+		// Quick edit a single field of the selected host
+		selectedHost := m.listView.GetSelectedHost()
+		if selectedHost != nil && m.view == "list" {
+			m.quickEdit = NewQuickEditView(m.store, *selectedHost, quickEditFields[action])
+		}
+	case ActionTags:
+		// Pick tags to filter the list by
+		if m.view == "list" {
+			m.tagPicker = NewTagPicker(m.listView.Hosts(), m.listView.TagFilters())
+		}
+	case ActionGroups:
+		// Pick a group to filter the list by
+		if m.view == "list" {
+			m.tagPicker = NewGroupPicker(m.listView.Hosts(), m.listView.GroupFilter())
+		}
+	case ActionSnippets:
+		// Pick a snippet to run on the selected host
+		selectedHost := m.listView.GetSelectedHost()
+		if selectedHost != nil && m.view == "list" {
+			m.snippetPicker = NewSnippetPicker(*selectedHost, m.listView.config.SnippetsFor(*selectedHost))
+		}
+	case ActionDetail:
 		m.view = "detail"
-	case "h":
+	case ActionHistory:
 		// Show history view
 		m.historyView = NewHistoryView(m.store, m.history, "")
 		m.view = "history"
-	case "H":
+	case ActionHostHistory:
 		// Show history for selected host
 		selectedHost := m.listView.GetSelectedHost()
 		if selectedHost != nil {
 			m.historyView = NewHistoryView(m.store, m.history, selectedHost.ID)
 			m.view = "history"
 		}
-	case "c":
+	case ActionCopy:
 		// Copy SSH command to clipboard
 		selectedHost := m.listView.GetSelectedHost()
 		if selectedHost != nil {
 			sshCmd := selectedHost.GenerateSSHCommand()
 			if err := clipboard.CopyToClipboard(sshCmd); err != nil {
-				m.err = fmt.Errorf("failed to copy to clipboard: %w", err)
+				return m, m.notify(ToastError, i18n.T("toast.copy_failed", err))
 			}
+			return m, m.notify(ToastSuccess, i18n.T("toast.copied_command"))
 		}
-	case "x":
+	case ActionCopyConfig:
+		// Copy the host as an ssh_config block to clipboard
+		selectedHost := m.listView.GetSelectedHost()
+		if selectedHost != nil {
+			options := m.listView.config.ApplyConfigs(*selectedHost, models.Profile{})
+			block := strings.Join(selectedHost.SSHConfigLines(options), "\n") + "\n"
+			if err := clipboard.CopyToClipboard(block); err != nil {
+				return m, m.notify(ToastError, i18n.T("toast.copy_failed", err))
+			}
+			return m, m.notify(ToastSuccess, i18n.T("toast.copied_config"))
+		}
+	case ActionShare:
+		// Show the selected host as a QR code and link
+		selectedHost := m.listView.GetSelectedHost()
+		if selectedHost != nil && m.view == "list" {
+			// The code would show what presentation mode masks
+			if m.listView.Presenting() {
+				return m, m.notify(ToastError, i18n.T("toast.share_presenting"))
+			}
+			m.shareView = NewShareView(*selectedHost)
+			m.view = "share"
+		}
+	case ActionDelete:
 		// Delete selected host (with confirmation)
 		selectedHost := m.listView.GetSelectedHost()
 		if selectedHost != nil {
 			if m.pendingDelete == selectedHost.ID {
 				// Second press - confirm delete
-				if err := m.store.DeleteHost(selectedHost.ID); err != nil {
-					m.err = fmt.Errorf("failed to delete host: %w", err)
-				} else {
-					m.listView.Refresh()
-				}
 				m.pendingDelete = ""
+				return m, m.deleteHost(selectedHost.ID)
 			} else {
 				// First press - ask for confirmation
 				m.pendingDelete = selectedHost.ID
 			}
 		}
-	case "y":
+	case ActionConfirm:
 		// Confirm delete when pending
 		if m.pendingDelete != "" {
-			if err := m.store.DeleteHost(m.pendingDelete); err != nil {
-				m.err = fmt.Errorf("failed to delete host: %w", err)
-			} else {
-				m.listView.Refresh()
-			}
+			id := m.pendingDelete
 			m.pendingDelete = ""
+			return m, m.deleteHost(id)
+		}
+	case ActionCancel, ActionBack:
+		// Back on the list pops the most recent filter
+		if action == ActionBack && m.pendingDelete == "" && m.view == "list" {
+			m.listView.PopFilter()
+			return m, nil
 		}
-	case "n", "esc":
 		// Cancel delete confirmation or go back
 		m.pendingDelete = ""
 		if m.view != "list" {
@@ -242,17 +637,156 @@ func (m *App) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// handleOnboardingChoice acts on the option selected in the first-run wizard
+func (m *App) handleOnboardingChoice(choice string) (tea.Model, tea.Cmd) {
+	switch choice {
+	case onboardImport:
+		m.finishOnboarding()
+		return m.handleSSHConfigImport()
+	case onboardAdd:
+		m.finishOnboarding()
+		m.editView = NewAddView(m.store)
+		m.view = "add"
+	case onboardTheme:
+		// Stay in the wizard so the new theme can be previewed
+		newTheme := ToggleTheme()
+		m.saveThemePreference(newTheme)
+	case onboardSkip:
+		m.finishOnboarding()
+	}
+	return m, nil
+}
+
+// finishOnboarding closes the wizard and returns to the host list
+func (m *App) finishOnboarding() {
+	m.onboarding = nil
+	m.view = "list"
+}
+
+// aliasEndedMsg is sent when a command alias or snippet started from the
+// TUI exits
+type aliasEndedMsg struct {
+	host    models.Host
+	alias   string
+	err     error
+	elapsed time.Duration
+}
+
+// describe returns the notification for the outcome
+func (m aliasEndedMsg) describe() (ToastKind, string) {
+	if code, remote := ssh.ExitStatus(m.err); remote {
+		return ToastError, i18n.T("toast.alias_status", m.alias, m.host.Name, code)
+	} else if m.err != nil {
+		return ToastError, i18n.T("toast.alias_failed", m.alias, m.host.Name, m.err)
+	}
+	return ToastInfo, i18n.T("toast.alias_finished", m.alias, m.host.Name)
+}
+
+// notifyDesktop shows text as a desktop notification, for jobs that ran long
+// enough for the terminal to be out of sight
+// It is best effort: the toast already tells what happened.
+func notifyDesktop(text string) tea.Cmd {
+	return func() tea.Msg {
+		notify.Send(text)
+		return nil
+	}
+}
+
+// runAliasKey runs the selected host's n-th alias (in name order) for the
+// keys 1-9; it returns nil for other keys
+func (m *App) runAliasKey(key string) tea.Cmd {
+	host := m.listView.GetSelectedHost()
+	if host == nil || len(key) != 1 || key[0] < '1' || key[0] > '9' {
+		return nil
+	}
+	names := host.AliasNames()
+	i := int(key[0] - '1')
+	if i >= len(names) {
+		return nil
+	}
+
+	return m.runCommand(*host, names[i], host.Aliases[names[i]])
+}
+
+// runCommand runs command on host under the given name, releasing the
+// terminal like a connect so interactive commands work
+func (m *App) runCommand(host models.Host, name, command string) tea.Cmd {
+	session, err := m.listView.newSession(host)
+	if err != nil {
+		return m.notify(ToastError, err.Error())
+	}
+	session.SetCommand(command)
+	start := time.Now()
+	return tea.Exec(session, func(err error) tea.Msg {
+		return aliasEndedMsg{host: host, alias: name, err: err, elapsed: time.Since(start)}
+	})
+}
+
+// rawEditMsg is sent when the external editor opened by editInEditor exits
+type rawEditMsg struct {
+	session *editor.Session
+	host    models.Host
+	err     error
+}
+
+// editInEditor suspends the TUI and opens host in $EDITOR
+func (m *App) editInEditor(host models.Host) tea.Cmd {
+	session, err := editor.NewSession([]models.Host{host}, editor.FormatYAML, true)
+	if err != nil {
+		return m.notify(ToastError, i18n.T("toast.editor_open_failed", err))
+	}
+	return tea.ExecProcess(session.Cmd(), func(err error) tea.Msg {
+		return rawEditMsg{session: session, host: host, err: err}
+	})
+}
+
+// applyRawEdit validates and saves the host edited in $EDITOR
+// Invalid input is discarded and reported, leaving the host untouched.
+func (m *App) applyRawEdit(msg rawEditMsg) tea.Cmd {
+	defer msg.session.Close()
+	if msg.err != nil {
+		return m.notify(ToastError, i18n.T("toast.editor_failed", msg.err))
+	}
+
+	edited, changed, err := msg.session.Result()
+	if !changed {
+		return nil
+	}
+	if err != nil {
+		return m.notify(ToastError, i18n.T("toast.changes_discarded", err))
+	}
+
+	host := edited[0]
+	host.ID = msg.host.ID
+	if other, err := m.store.GetHostByName(host.Name); err == nil && other.ID != host.ID {
+		return m.notify(ToastError, i18n.T("toast.host_exists", host.Name))
+	}
+	if err := m.store.UpdateHost(host); err != nil {
+		return m.notify(ToastError, i18n.T("toast.save_host_failed", err))
+	}
+	m.listView.Refresh()
+	return m.notify(ToastSuccess, i18n.T("toast.host_updated", host.Name))
+}
+
+// deleteHost removes a host from the store and reports the outcome
+func (m *App) deleteHost(id string) tea.Cmd {
+	host, _ := m.store.GetHost(id)
+	if err := m.store.DeleteHost(id); err != nil {
+		return m.notify(ToastError, i18n.T("toast.delete_failed", err))
+	}
+	m.listView.Refresh()
+	return m.notify(ToastSuccess, i18n.T("toast.host_deleted", host.Name))
+}
+
 // handleSSHConfigImport imports hosts from ~/.ssh/config
 func (m *App) handleSSHConfigImport() (tea.Model, tea.Cmd) {
-	hosts, err := config.ImportFromSSHConfig("")
+	hosts, err := config.ImportFromSSHConfig(m.paths.Hosts)
 	if err != nil {
-		m.err = fmt.Errorf("failed to import SSH config: %w", err)
-		return m, nil
+		return m, m.notify(ToastError, i18n.T("toast.import_failed", err))
 	}
 
 	if len(hosts) == 0 {
-		fmt.Println("No new hosts found in ~/.ssh/config")
-		return m, nil
+		return m, m.notify(ToastInfo, i18n.T("toast.import_none"))
 	}
 
 	// Add imported hosts to store
@@ -263,21 +797,20 @@ func (m *App) handleSSHConfigImport() (tea.Model, tea.Cmd) {
 		}
 	}
 
-	fmt.Printf("Imported %d hosts from ~/.ssh/config\n", imported)
 	m.listView.Refresh()
-	return m, nil
+	return m, m.notify(ToastSuccess, i18n.T("toast.import_done", imported))
 }
 
 func (m *App) renderList() string {
 	hosts := m.store.ListHosts()
 
 	header := BorderStyle.Width(60).Render(
-		HeaderStyle.Render("SSH Host Manager"),
+		HeaderStyle.Render(i18n.T("title")),
 	)
 
 	var body string
 	if len(hosts) == 0 {
-		body = BodyStyle.Render("No hosts configured. Press 'a' to add a host.")
+		body = BodyStyle.Render(i18n.T("list.no_hosts", keymap.Key(ActionAdd)))
 	} else {
 		body = ""
 		for _, h := range hosts {
@@ -285,7 +818,7 @@ func (m *App) renderList() string {
 		}
 	}
 
-	footer := StatusBar("↑↓ Navigate | a: Add | e: Edit | d: Detail | q: Quit")
+	footer := StatusBar(i18n.T("app.list_help"))
 
 	return header + "\n\n" + body + "\n\n" + footer
 }
@@ -296,12 +829,12 @@ func (m *App) renderAdd() string {
 	}
 
 	header := BorderStyle.Width(60).Render(
-		HeaderStyle.Render("Add New Host"),
+		HeaderStyle.Render(i18n.T("app.add_title")),
 	)
 
-	body := BodyStyle.Render("Form to add new host (coming soon)")
+	body := BodyStyle.Render(i18n.T("app.add_soon"))
 
-	footer := StatusBar("esc: Back | Enter: Save")
+	footer := StatusBar(i18n.T("app.form_help"))
 
 	return header + "\n\n" + body + "\n\n" + footer
 }
@@ -312,12 +845,12 @@ func (m *App) renderEdit() string {
 	}
 
 	header := BorderStyle.Width(60).Render(
-		HeaderStyle.Render("Edit Host"),
+		HeaderStyle.Render(i18n.T("form.edit_title")),
 	)
 
-	body := BodyStyle.Render("Form to edit host (coming soon)")
+	body := BodyStyle.Render(i18n.T("app.edit_soon"))
 
-	footer := StatusBar("esc: Back | Enter: Save")
+	footer := StatusBar(i18n.T("app.form_help"))
 
 	return header + "\n\n" + body + "\n\n" + footer
 }
@@ -326,32 +859,56 @@ func (m *App) renderDetail() string {
 	selectedHost := m.listView.GetSelectedHost()
 
 	header := BorderStyle.Width(60).Render(
-		HeaderStyle.Render("Host Details"),
+		HeaderStyle.Render(i18n.T("detail.title")),
 	)
 
 	var body string
 	if selectedHost == nil {
-		body = BodyStyle.Render("No host selected")
+		body = BodyStyle.Render(i18n.T("detail.none"))
 	} else {
 		stats := GetHistoryStatsForHost(m.store, m.history, selectedHost.ID)
+		aliases := ""
+		for i, name := range selectedHost.AliasNames() {
+			if i == 0 {
+				aliases = "\n\n" + i18n.T("detail.aliases")
+			}
+			if i < 9 {
+				aliases += fmt.Sprintf("\n  %d) %s: %s", i+1, name, selectedHost.Aliases[name])
+			} else {
+				aliases += fmt.Sprintf("\n     %s: %s", name, selectedHost.Aliases[name])
+			}
+		}
+		// Weak keys and algorithms seen on the last connection
+		warnings := ""
+		if recent := m.history.GetHistoryForHost(selectedHost.ID); len(recent) > 0 {
+			for i, warning := range recent[0].Warnings {
+				if i == 0 {
+					warnings = "\n\n" + i18n.T("detail.warnings")
+				}
+				warnings += "\n  " + m.listView.redact(warning)
+			}
+		}
 		body = BodyStyle.Render(
-			fmt.Sprintf("Name: %s\nHost: %s\nPort: %d\nUser: %s\nIdentity: %s\nProxy: %s\nGroup: %s\n\nConnection Stats:\n  Total: %d\n  Successful: %d\n  Failed: %d\n  Last: %s",
+			i18n.T("detail.body",
 				selectedHost.Name,
-				selectedHost.Host,
+				m.listView.redact(selectedHost.Host),
 				selectedHost.Port,
-				selectedHost.User,
-				selectedHost.Identity,
-				selectedHost.Proxy,
+				m.listView.redact(selectedHost.User),
+				m.listView.redact(selectedHost.Identity),
+				m.listView.redact(selectedHost.Proxy),
 				selectedHost.Group,
 				stats.TotalConnections,
 				stats.SuccessfulConns,
 				stats.FailedConns,
 				stats.LastConnected.Format("2006-01-02 15:04"),
-			),
+			) + warnings + aliases,
 		)
 	}
 
-	footer := StatusBar("esc: Back")
+	footer := StatusBar(i18n.T("detail.help"))
+	if selectedHost != nil && len(selectedHost.Aliases) > 0 {
+		footer = StatusBar(i18n.T("detail.alias_help"))
+	}
 
 	return header + "\n\n" + body + "\n\n" + footer
 }
@@ -362,46 +919,70 @@ func (m *App) renderHistory() string {
 	}
 
 	header := BorderStyle.Width(60).Render(
-		HeaderStyle.Render("Connection History"),
+		HeaderStyle.Render(i18n.T("history.title")),
 	)
 
-	body := BodyStyle.Render("Loading history...")
+	body := BodyStyle.Render(i18n.T("history.loading"))
 
-	footer := StatusBar("↑↓ Navigate | r: Refresh | c: Clear | esc: Back")
+	footer := StatusBar(i18n.T("history.help"))
 
 	return header + "\n\n" + body + "\n\n" + footer
 }
 
 // saveThemePreference saves the theme preference to config file
 func (m *App) saveThemePreference(themeName string) {
-	cfg, err := config.LoadConfig(m.configPath)
-	if err != nil {
-		return // Silently fail - theme will work for this session
-	}
-	if cfg == nil {
-		cfg = &config.Config{}
-	}
-	cfg.Theme = themeName
-	_ = config.SaveConfig(cfg, m.configPath)
+	// Silently fail - theme will work for this session
+	_ = config.SetKey(m.paths.Config, "theme", themeName)
+	// Our own write is not a change to reload
+	m.listView.config.Theme = themeName
+	m.configStamp = stampFile(m.paths.Config)
 }
 
 // Run starts the TUI application
-func Run(storePath string) error {
-	app, err := New(storePath)
+// Cancelling ctx ends it like quitting does, closing an interactive session
+// under way, restoring the terminal and writing pending host changes.
+func Run(ctx context.Context, paths config.Paths) error {
+	app, err := New(paths)
 	if err != nil {
 		return err
 	}
+	app.listView.ctx = ctx
 
-	p := tea.NewProgram(app, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		return err
+	defer app.listView.Close()
+
+	p := tea.NewProgram(app, tea.WithAltScreen(), tea.WithContext(ctx))
+	_, err = p.Run()
+	// Changes made just before quitting are still pending
+	return errors.Join(err, app.store.Flush())
+}
+
+// Pick shows the host list for choosing a host and returns the choice, or
+// nil if the user quit without choosing
+// The interface is drawn on output and reads the terminal directly, so
+// stdout stays free for the caller to print the result
+func Pick(ctx context.Context, paths config.Paths, output io.Writer) (*models.Host, error) {
+	app, err := New(paths)
+	if err != nil {
+		return nil, err
+	}
+	app.listView.ctx = ctx
+	app.pickMode = true
+	app.listView.SetPickMode(true)
+	app.onboarding = nil
+	app.view = "list"
+	defer app.listView.Close()
+
+	p := tea.NewProgram(app, tea.WithAltScreen(), tea.WithOutput(output), tea.WithInputTTY(), tea.WithContext(ctx))
+	_, err = p.Run()
+	if err := errors.Join(err, app.store.Flush()); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return app.picked, nil
 }
 
 func Main() {
-	if err := Run(""); err != nil {
+	if err := Run(context.Background(), config.DefaultPaths()); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,205 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/sshm/sshm/internal/i18n"
+	"github.com/sshm/sshm/internal/models"
+)
+
+// Column identifiers usable in the "columns" config setting
+const (
+	columnName     = "name"
+	columnUserHost = "user@host"
+	columnPort     = "port"
+	columnGroup    = "group"
+	columnTags     = "tags"
+	columnLastUsed = "last_used"
+	columnLatency  = "latency"
+)
+
+// defaultColumns is the table layout used when none is configured
+var defaultColumns = []string{columnName, columnUserHost, columnPort, columnGroup, columnTags}
+
+// column describes how a table column is titled, sized and filled
+type column struct {
+	id       string
+	title    string // in the active language, set by columns
+	maxWidth int    // upper bound for content-sized columns
+	flex     bool   // flexible columns share the remaining width
+	value    func(v *ListView, h models.Host) string
+}
+
+// columnDefs holds all known columns by identifier
+var columnDefs = map[string]column{
+	columnName: {
+		id: columnName, flex: true,
+		value: func(v *ListView, h models.Host) string { return h.Name },
+	},
+	columnUserHost: {
+		id: columnUserHost, maxWidth: 32,
+		value: func(v *ListView, h models.Host) string {
+			return fmt.Sprintf("%s@%s", v.redact(h.User), v.redact(h.Host))
+		},
+	},
+	columnPort: {
+		id: columnPort, maxWidth: 5,
+		value: func(v *ListView, h models.Host) string { return strconv.Itoa(h.Port) },
+	},
+	columnGroup: {
+		id: columnGroup, maxWidth: 16,
+		value: func(v *ListView, h models.Host) string { return h.Group },
+	},
+	columnTags: {
+		id: columnTags, flex: true,
+		value: func(v *ListView, h models.Host) string { return strings.Join(h.Tags, " ") },
+	},
+	columnLastUsed: {
+		id: columnLastUsed, maxWidth: 10,
+		value: func(v *ListView, h models.Host) string { return FormatLastUsed(v.lastUsed[h.ID]) },
+	},
+	columnLatency: {
+		id: columnLatency, maxWidth: 8,
+		value: func(v *ListView, h models.Host) string { return v.formatLatency(h) },
+	},
+}
+
+// ValidColumn returns whether name is a known column identifier
+func ValidColumn(name string) bool {
+	_, ok := columnDefs[name]
+	return ok
+}
+
+// columns returns the configured table columns, skipping unknown identifiers
+func (v *ListView) columns() []column {
+	ids := defaultColumns
+	if len(v.config.Columns) > 0 {
+		ids = v.config.Columns
+	}
+
+	var cols []column
+	for _, id := range ids {
+		if col, ok := columnDefs[strings.ToLower(id)]; ok {
+			cols = append(cols, col)
+		}
+	}
+	if len(cols) == 0 {
+		for _, id := range defaultColumns {
+			cols = append(cols, columnDefs[id])
+		}
+	}
+	for i := range cols {
+		cols[i].title = i18n.T("column." + cols[i].id)
+	}
+	return cols
+}
+
+// columnWidths sizes each column to fit its content within the available width
+// Fixed columns take what they need (capped), flexible columns share the rest
+func (v *ListView) columnWidths(cols []column, hosts []models.Host, available int) []int {
+	widths := make([]int, len(cols))
+	used := 0
+	flexCount := 0
+
+	for i, col := range cols {
+		if col.flex {
+			flexCount++
+			continue
+		}
+		w := textWidth(col.title)
+		for _, h := range hosts {
+			if l := textWidth(col.value(v, h)); l > w {
+				w = l
+			}
+		}
+		if w > col.maxWidth && col.maxWidth >= textWidth(col.title) {
+			w = col.maxWidth
+		}
+		widths[i] = w
+		used += w
+	}
+
+	// One space between columns
+	used += len(cols) - 1
+	if flexCount > 0 {
+		remaining := available - used
+		share := remaining / flexCount
+		if share < 10 {
+			share = 10
+		}
+		for i, col := range cols {
+			if col.flex {
+				widths[i] = share
+			}
+		}
+	}
+
+	return widths
+}
+
+// cells measures text in terminal cells: CJK characters and emoji take two
+// Ambiguous characters count as one whatever the locale, as lipgloss does.
+var cells = &runewidth.Condition{StrictEmojiNeutral: true}
+
+// textWidth returns how many terminal cells s takes
+func textWidth(s string) int {
+	return cells.StringWidth(s)
+}
+
+// truncate shortens s to at most width cells, marking the cut with ".."
+// A wide character that would straddle the edge is cut too.
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if textWidth(s) <= width {
+		return s
+	}
+	if width <= 2 {
+		return cells.Truncate(s, width, "")
+	}
+	return cells.Truncate(s, width, "..")
+}
+
+// padRight pads s with spaces to the given width in cells
+func padRight(s string, width int) string {
+	return cells.FillRight(s, width)
+}
+
+// trimLastRune removes the last character of typed text
+func trimLastRune(s string) string {
+	_, size := utf8.DecodeLastRuneInString(s)
+	return s[:len(s)-size]
+}
+
+// FormatLastUsed renders a last connection time relative to now
+func FormatLastUsed(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return i18n.T("time.just_now")
+	case d < time.Hour:
+		return i18n.T("time.minutes_ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return i18n.T("time.hours_ago", int(d.Hours()))
+	default:
+		return i18n.T("time.days_ago", int(d.Hours()/24))
+	}
+}
+
+// formatLatency renders the last measured ping latency for a host
+func (v *ListView) formatLatency(h models.Host) string {
+	result, ok := v.status(h)
+	if !ok || !result.Online {
+		return "-"
+	}
+	return fmt.Sprintf("%dms", result.Latency.Milliseconds())
+}
@@ -9,7 +9,10 @@ import (
 
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/sshm/sshm/internal/i18n"
 	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/secret"
+	"github.com/sshm/sshm/internal/ssh"
 	"github.com/sshm/sshm/internal/store"
 )
 
@@ -155,7 +158,7 @@ func (fb *FileBrowser) View(width int) string {
 	if len(rows) == 1 {
 		rows = append(rows, lipgloss.NewStyle().
 			Foreground(secondaryColor).
-			Render("  (empty)"))
+			Render("  " + i18n.T("form.browser_empty")))
 	}
 	
 	body := lipgloss.JoinVertical(lipgloss.Left, rows...)
@@ -374,7 +377,7 @@ func (v *EditView) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		v.nextField()
 	case "backspace", "b", "delete", "ctrl+h": // Support backspace, b, delete, and ctrl+h
 		if len(v.values[v.field]) > 0 {
-			v.values[v.field] = v.values[v.field][:len(v.values[v.field])-1]
+			v.values[v.field] = trimLastRune(v.values[v.field])
 		}
 		// Don't validate on each keystroke - validate only on save
 	case "esc":
@@ -495,40 +498,38 @@ func (v *EditView) validate() {
 
 	// Name validation
 	if v.values[fieldName] == "" {
-		v.errors[fieldName] = "Name is required"
+		v.errors[fieldName] = i18n.T("form.name_required")
 	} else if len(v.values[fieldName]) > 50 {
-		v.errors[fieldName] = "Name too long (max 50 chars)"
+		v.errors[fieldName] = i18n.T("form.name_too_long")
 	}
 
 	// Host validation
 	if v.values[fieldHost] == "" {
-		v.errors[fieldHost] = "Host is required"
+		v.errors[fieldHost] = i18n.T("form.host_required")
 	}
 
 	// Port validation
 	if v.values[fieldPort] == "" {
-		v.errors[fieldPort] = "Port is required"
+		v.errors[fieldPort] = i18n.T("form.port_required")
 	} else {
 		port, err := strconv.Atoi(v.values[fieldPort])
 		if err != nil {
-			v.errors[fieldPort] = "Port must be a number"
+			v.errors[fieldPort] = i18n.T("form.port_not_number")
 		} else if port < 1 || port > 65535 {
-			v.errors[fieldPort] = "Port must be 1-65535"
+			v.errors[fieldPort] = i18n.T("form.port_range")
 		}
 	}
 
-	// User validation
-	if v.values[fieldUser] == "" {
-		v.errors[fieldUser] = "User is required"
-	}
+	// An empty user takes the default one
 
 	// Auth type specific validation
 	authType := v.values[fieldAuthType]
 	if authType == string(AuthKey) && v.values[fieldIdentity] == "" {
-		v.errors[fieldIdentity] = "Key file required for key auth"
+		v.errors[fieldIdentity] = i18n.T("form.key_required")
 	}
-	if authType == string(AuthPassword) && v.securePassword == "" {
-		v.errors[fieldPassword] = "Password required for password auth"
+	// With keyring_passwords the password is asked for on the first login
+	if authType == string(AuthPassword) && v.securePassword == "" && !ssh.KeyringPasswords {
+		v.errors[fieldPassword] = i18n.T("form.password_required")
 	}
 }
 
@@ -610,9 +611,9 @@ func (v *EditView) View() string {
 		return v.renderFileBrowser()
 	}
 
-	title := "Add Host"
+	title := i18n.T("form.add_title")
 	if v.mode == "edit" {
-		title = "Edit Host"
+		title = i18n.T("form.edit_title")
 	}
 
 	header := BorderStyle.Width(60).Render(
@@ -628,67 +629,58 @@ func (v *EditView) View() string {
 	body := lipgloss.JoinVertical(lipgloss.Left, fields...)
 	form := BorderStyle.Width(60).Render(body)
 
-	help := HelpStyle.Render("↑↓ move | type to edit | backspace/delete/b/ctrl+h: delete | ← select key file/password | enter: save | esc: cancel")
+	help := HelpStyle.Render(i18n.T("form.help"))
 
 	return header + "\n\n" + form + "\n\n" + help
 }
 
 func (v *EditView) renderPasswordEntry() string {
 	header := BorderStyle.Width(60).Render(
-		TitleStyle.Render(" Enter Password "),
+		TitleStyle.Render(" " + i18n.T("form.password_title") + " "),
 	)
 
 	body := lipgloss.NewStyle().
 		Width(56).
-		Render("Password: " + v.passwordMasked + "_")
+		Render(i18n.T("form.password") + v.passwordMasked + "_")
 
 	form := BorderStyle.Width(60).Render(body)
 
-	help := HelpStyle.Render("type to enter password | enter: confirm | esc: cancel")
+	help := HelpStyle.Render(i18n.T("form.password_help"))
 
 	return header + "\n\n" + form + "\n\n" + help
 }
 
 func (v *EditView) renderField(f string) string {
-	label := f
+	label := i18n.T("field." + f)
 	value := v.values[f]
 	
 	switch f {
 	case fieldPort:
-		label = "Port"
 		if value == "" {
 			value = "22"
 		}
 	case fieldIdentity:
-		label = "Identity File"
 		if value == "" {
-			value = "(default: ~/.ssh/id_rsa)"
+			value = i18n.T("form.identity_default")
 		}
 	case fieldPassword:
-		label = "Password"
 		if v.enterPassword {
 			value = v.passwordMasked + "_"
+		} else if secret.IsEncrypted(v.securePassword) {
+			value = i18n.T("form.password_encrypted")
 		} else if v.securePassword != "" {
-			value = "•••••••• (← to edit)"
+			value = i18n.T("form.password_set")
 		} else {
-			value = "(empty) (← to set)"
+			value = i18n.T("form.password_unset")
 		}
-	case fieldProxy:
-		label = "Proxy Jump"
 	case fieldAuthType:
-		label = "Auth Type"
 		if value == "" {
 			value = string(AuthKey)
 		}
-		value = "[ " + value + " ] (← → to change)"
-	case fieldGroup:
-		label = "Group"
-	case fieldTags:
-		label = "Tags"
+		value = i18n.T("form.auth_type", value)
 	case fieldProfile:
-		label = "Profile"
 		if value == "" {
-			value = "(default)"
+			value = i18n.T("form.profile_default")
 		}
 	}
 
@@ -711,7 +703,7 @@ func (v *EditView) renderField(f string) string {
 	if f == fieldGroup && len(v.existingGroups) > 0 && value == "" {
 		suggestions := lipgloss.NewStyle().
 			Foreground(secondaryColor).
-			Render(fmt.Sprintf("    Suggestions: %v", v.existingGroups[:min(3, len(v.existingGroups))]))
+			Render("    " + i18n.T("form.suggestions", v.existingGroups[:min(3, len(v.existingGroups))]))
 		row += "\n" + suggestions
 	}
 
@@ -719,7 +711,7 @@ func (v *EditView) renderField(f string) string {
 	if f == fieldProfile && len(v.existingProfiles) > 0 {
 		suggestions := lipgloss.NewStyle().
 			Foreground(secondaryColor).
-			Render(fmt.Sprintf("    Available: %v", v.existingProfiles))
+			Render("    " + i18n.T("form.available", v.existingProfiles))
 		row += "\n" + suggestions
 	}
 
@@ -728,12 +720,12 @@ func (v *EditView) renderField(f string) string {
 
 func (v *EditView) renderFileBrowser() string {
 	header := BorderStyle.Width(60).Render(
-		TitleStyle.Render(" Select SSH Key File "),
+		TitleStyle.Render(" " + i18n.T("form.browser_title") + " "),
 	)
 
 	browser := v.fileBrowser.View(56)
 
-	help := HelpStyle.Render("↑↓ navigate | enter: select | esc: cancel")
+	help := HelpStyle.Render(i18n.T("form.browser_help"))
 
 	return header + "\n\n" + browser + "\n\n" + help
 }
@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/store"
+)
+
+// Filter kinds that can be stacked on the host list
+const (
+	filterGroup = "group"
+	filterTag   = "tag"
+	filterText  = "text"
+	// filterSelector is a selector expression such as "tag:db AND group:eu"
+	filterSelector = "selector"
+)
+
+// listFilter is one entry of the filter stack; all entries must match (AND)
+type listFilter struct {
+	kind  string
+	value string
+
+	// selector filters keep the parsed expression; selector and text
+	// filters keep the IDs they picked the last time the host list changed
+	selector *store.Selector
+	selected map[string]bool
+}
+
+// newSelectorFilter parses text as a selector filter
+func newSelectorFilter(text string) (listFilter, bool) {
+	sel, err := store.ParseSelector(text)
+	if err != nil {
+		return listFilter{}, false
+	}
+	return listFilter{kind: filterSelector, value: text, selector: sel}, true
+}
+
+// refresh re-evaluates a selector filter against hosts, and a text filter
+// against the search index of s
+// Unknown names or tags just match nothing, as a typed filter would.
+func (f *listFilter) refresh(s *store.FileStore, hosts []models.Host) {
+	if f.kind == filterText {
+		f.selected = s.MatchingHosts(f.value)
+		return
+	}
+	if f.selector == nil {
+		return
+	}
+	picked, _ := f.selector.Select(hosts)
+	f.selected = make(map[string]bool, len(picked))
+	for _, h := range picked {
+		f.selected[h.ID] = true
+	}
+}
+
+// matches returns whether the host satisfies the filter
+func (f listFilter) matches(h models.Host) bool {
+	switch f.kind {
+	case filterGroup:
+		return strings.EqualFold(h.Group, f.value)
+	case filterTag:
+		for _, t := range h.Tags {
+			if t == f.value {
+				return true
+			}
+		}
+		return false
+	case filterText, filterSelector:
+		return f.selected[h.ID]
+	}
+	return true
+}
+
+// label returns the breadcrumb label of the filter
+func (f listFilter) label() string {
+	switch f.kind {
+	case filterText:
+		return fmt.Sprintf("%q", f.value)
+	case filterSelector:
+		return f.value
+	}
+	return f.kind + "=" + f.value
+}
+
+// matchesAll returns whether the host satisfies every filter of the stack
+func matchesAll(h models.Host, filters []listFilter) bool {
+	for _, f := range filters {
+		if !f.matches(h) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterValues returns the values of all filters of the given kind, in stack order
+func filterValues(filters []listFilter, kind string) []string {
+	var values []string
+	for _, f := range filters {
+		if f.kind == kind {
+			values = append(values, f.value)
+		}
+	}
+	return values
+}
+
+// replaceFilters swaps the filters of one kind for the given values
+// Filters that stay selected keep their position, new ones are pushed on top
+func replaceFilters(filters []listFilter, kind string, values []string) []listFilter {
+	wanted := make(map[string]bool)
+	for _, v := range values {
+		wanted[v] = true
+	}
+
+	kept := make(map[string]bool)
+	var result []listFilter
+	for _, f := range filters {
+		if f.kind != kind {
+			result = append(result, f)
+		} else if wanted[f.value] && !kept[f.value] {
+			result = append(result, f)
+			kept[f.value] = true
+		}
+	}
+	for _, v := range values {
+		if !kept[v] {
+			result = append(result, listFilter{kind: kind, value: v})
+			kept[v] = true
+		}
+	}
+	return result
+}
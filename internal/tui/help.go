@@ -2,9 +2,11 @@ package tui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/sshm/sshm/internal/i18n"
 )
 
 // HelpView displays help and usage information
@@ -49,26 +51,11 @@ func (v *HelpView) View() string {
 			Bold(true).
 			Width(width).
 			Align(lipgloss.Center).
-			Render(" SSH Host Manager - Help "),
+			Render(" " + i18n.T("help.title") + " "),
 	)
 
-	// Keyboard shortcuts
-	shortcuts := [][]string{
-		{"↑↓ or j/k", "Navigate host list"},
-		{"Enter", "Connect to selected host"},
-		{"a", "Add new host"},
-		{"e", "Edit selected host"},
-		{"x", "Delete selected host"},
-		{"d", "View host details"},
-		{"c", "Copy SSH command to clipboard"},
-		{"h", "View connection history (all)"},
-		{"H", "View history for selected host"},
-		{"t", "Toggle light/dark theme"},
-		{"/", "Filter/search hosts"},
-		{"backspace/delete", "Delete character in filter"},
-		{"esc", "Clear filter / Go back"},
-		{"q, Ctrl+C", "Quit application"},
-	}
+	// Keyboard shortcuts, as configured
+	shortcuts := keymap.Help()
 
 	var shortcutContent string
 	for _, s := range shortcuts {
@@ -91,22 +78,13 @@ func (v *HelpView) View() string {
 			Foreground(primaryColor).
 			Bold(true).
 			Width(width).
-			Render(" Keyboard Shortcuts "),
+			Render(" " + i18n.T("help.shortcuts") + " "),
 	)
 
 	// Tips section
-	tips := []string{
-		"• Hosts are saved to ~/.sshm.json",
-		"• SSH config can be imported from ~/.ssh/config",
-		"• Connection history is tracked in ~/.sshm_history.json",
-		"• Use groups to organize hosts (production, staging, etc.)",
-		"• Use tags to label hosts (database, web, backup, etc.)",
-		"• Use identity files for key-based authentication",
-	}
-
 	var tipsContent string
-	for _, t := range tips {
-		tipsContent += "  " + lipgloss.NewStyle().Foreground(secondaryColor).Render(t) + "\n"
+	for _, t := range strings.Split(i18n.T("help.tips"), "\n") {
+		tipsContent += "  " + lipgloss.NewStyle().Foreground(secondaryColor).Render("• "+t) + "\n"
 	}
 
 	tipsBox := lipgloss.NewStyle().
@@ -118,10 +96,10 @@ func (v *HelpView) View() string {
 			Foreground(primaryColor).
 			Bold(true).
 			Width(width).
-			Render(" Tips "),
+			Render(" " + i18n.T("help.tips_title") + " "),
 	)
 
-	footer := StatusBar("esc: Back to list")
+	footer := StatusBar(i18n.T("help.back", keymap.Key(ActionBack)))
 
 	return header + "\n\n" + shortcutsBorder + "\n" + shortcutsBox + "\n" + tipsBorder + "\n" + tipsBox + "\n\n" + footer
 }
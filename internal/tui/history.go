@@ -6,6 +6,7 @@ import (
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sshm/sshm/internal/i18n"
 	"github.com/sshm/sshm/internal/models"
 	"github.com/sshm/sshm/internal/store"
 )
@@ -109,9 +110,9 @@ func (h *HistoryView) View() string {
 	var title string
 	if h.hostID != "" {
 		host, _ := h.store.GetHost(h.hostID)
-		title = fmt.Sprintf("Connection History: %s", host.Name)
+		title = i18n.T("history.host_title", host.Name)
 	} else {
-		title = "All Connection History"
+		title = i18n.T("history.all_title")
 	}
 
 	header := BorderStyle.Width(60).Render(
@@ -120,7 +121,7 @@ func (h *HistoryView) View() string {
 
 	body := h.list.View()
 
-	footer := StatusBar("↑↓ Navigate | r: Refresh | c: Clear | esc: Back")
+	footer := StatusBar(i18n.T("history.help"))
 
 	return header + "\n\n" + body + "\n\n" + footer
 }
@@ -137,7 +138,7 @@ func (i historyItem) FilterValue() string {
 
 func (i historyItem) Title() string {
 	if i.entry.Timestamp.IsZero() {
-		return "No connection history"
+		return i18n.T("history.none")
 	}
 	status := "✓"
 	if !i.entry.Success {
@@ -148,7 +149,7 @@ func (i historyItem) Title() string {
 
 func (i historyItem) Description() string {
 	if i.entry.Timestamp.IsZero() {
-		return "No connections recorded yet"
+		return i18n.T("history.none_desc")
 	}
 	timestamp := i.entry.Timestamp.Format("2006-01-02 15:04:05")
 	desc := timestamp
@@ -158,6 +159,11 @@ func (i historyItem) Description() string {
 	if !i.entry.Success && i.entry.Error != "" {
 		desc += " - " + i.entry.Error
 	}
+	for _, change := range i.entry.HostKeyChanges {
+		if change.Approved {
+			desc += i18n.T("history.key_approved", change.NewFingerprint, change.OldFingerprint)
+		}
+	}
 	return desc
 }
 
@@ -176,13 +182,13 @@ func GetHistoryStatsForHost(store *store.FileStore, history *store.HistoryStore,
 }
 
 // RecordConnection records a connection attempt
-func RecordConnection(history *store.HistoryStore, store *store.FileStore, hostID string, success bool, errMsg string, durationMs int64) {
+func RecordConnection(history *store.HistoryStore, store *store.FileStore, entry models.ConnectionHistory) {
 	// Record in history
-	history.AddConnection(hostID, success, errMsg, durationMs)
+	history.Add(entry)
 
 	// Update host connection count if successful
-	if success {
-		host, err := store.GetHost(hostID)
+	if entry.Success {
+		host, err := store.GetHost(entry.HostID)
 		if err == nil {
 			host.ConnectionCount++
 			store.UpdateHost(host)
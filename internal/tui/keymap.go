@@ -0,0 +1,226 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sshm/sshm/internal/i18n"
+)
+
+// Action is something a key does in the host list and the views around it
+// Text inputs (filter, forms, popups) keep their own fixed keys.
+type Action string
+
+const (
+	ActionUp          Action = "up"
+	ActionDown        Action = "down"
+	ActionTop         Action = "top"
+	ActionBottom      Action = "bottom"
+	ActionPageUp      Action = "page_up"
+	ActionPageDown    Action = "page_down"
+	ActionConnect     Action = "connect"
+	ActionAdd         Action = "add"
+	ActionEdit        Action = "edit"
+	ActionEditRaw     Action = "edit_raw"
+	ActionRename      Action = "rename"
+	ActionEditUser    Action = "edit_user"
+	ActionEditPort    Action = "edit_port"
+	ActionDelete      Action = "delete"
+	ActionConfirm     Action = "confirm"
+	ActionCancel      Action = "cancel"
+	ActionBack        Action = "back"
+	ActionDetail      Action = "detail"
+	ActionCopy        Action = "copy"
+	ActionCopyConfig  Action = "copy_config"
+	ActionShare       Action = "share"
+	ActionHistory     Action = "history"
+	ActionHostHistory Action = "host_history"
+	ActionTheme       Action = "theme"
+	ActionPresent     Action = "presentation"
+	ActionImport      Action = "import"
+	ActionFilter      Action = "filter"
+	ActionTags        Action = "tags"
+	ActionGroups      Action = "groups"
+	ActionPopFilter   Action = "pop_filter"
+	ActionSnippets    Action = "snippets"
+	ActionSplit       Action = "split"
+	ActionHelp        Action = "help"
+	ActionQuit        Action = "quit"
+)
+
+// binding is the keys of an action
+// Its line in the help overlay is the message "action.<action>".
+type binding struct {
+	action Action
+	keys   []string
+}
+
+// defaultBindings lists every action in help overlay order
+var defaultBindings = []binding{
+	{ActionUp, []string{"up", "k"}},
+	{ActionDown, []string{"down", "j"}},
+	{ActionTop, []string{"home", "g"}},
+	{ActionBottom, []string{"end", "G"}},
+	{ActionPageUp, []string{"pgup"}},
+	{ActionPageDown, []string{"pgdown"}},
+	{ActionConnect, []string{"enter"}},
+	{ActionAdd, []string{"a"}},
+	{ActionEdit, []string{"e"}},
+	{ActionEditRaw, []string{"E"}},
+	{ActionRename, []string{"r"}},
+	{ActionEditUser, []string{"u"}},
+	{ActionEditPort, []string{"p"}},
+	{ActionDelete, []string{"x"}},
+	{ActionConfirm, []string{"y"}},
+	{ActionCancel, []string{"n"}},
+	{ActionBack, []string{"esc"}},
+	{ActionDetail, []string{"d"}},
+	{ActionSplit, []string{"S"}},
+	{ActionCopy, []string{"c"}},
+	{ActionCopyConfig, []string{"C"}},
+	{ActionShare, []string{"Q"}},
+	{ActionSnippets, []string{"s"}},
+	{ActionHistory, []string{"h"}},
+	{ActionHostHistory, []string{"H"}},
+	{ActionTheme, []string{"t"}},
+	{ActionPresent, []string{"P"}},
+	{ActionImport, []string{"i"}},
+	{ActionFilter, []string{"/"}},
+	{ActionTags, []string{"T"}},
+	{ActionGroups, []string{"o"}},
+	{ActionPopFilter, []string{"backspace", "delete", "ctrl+h"}},
+	{ActionHelp, []string{"?"}},
+	{ActionQuit, []string{"q"}},
+}
+
+// Keymap maps keys to actions
+type Keymap struct {
+	bindings []binding
+	actions  map[string]Action
+}
+
+// keymap is the active keymap, replaced by InitKeymap
+var keymap = DefaultKeymap()
+
+// DefaultKeymap returns the built-in bindings
+func DefaultKeymap() *Keymap {
+	km, _ := NewKeymap(nil)
+	return km
+}
+
+// NewKeymap returns the default bindings with the actions in overrides
+// bound to the given keys instead
+// Unknown actions, actions without keys and keys bound to two actions are
+// errors.
+func NewKeymap(overrides map[string][]string) (*Keymap, error) {
+	km := &Keymap{actions: make(map[string]Action)}
+	known := make(map[Action]bool, len(defaultBindings))
+	for _, b := range defaultBindings {
+		known[b.action] = true
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[Action(name)] {
+			return nil, fmt.Errorf("unknown key binding action %q", name)
+		}
+		if len(overrides[name]) == 0 {
+			return nil, fmt.Errorf("key binding %q has no keys", name)
+		}
+	}
+
+	for _, b := range defaultBindings {
+		if keys, ok := overrides[string(b.action)]; ok {
+			b.keys = keys
+		}
+		for _, key := range b.keys {
+			if other, taken := km.actions[key]; taken {
+				return nil, fmt.Errorf("key %q is bound to both %s and %s", key, other, b.action)
+			}
+			km.actions[key] = b.action
+		}
+		km.bindings = append(km.bindings, b)
+	}
+	return km, nil
+}
+
+// InitKeymap activates the defaults with the configured overrides
+// On error the default bindings stay active.
+func InitKeymap(overrides map[string][]string) error {
+	km, err := NewKeymap(overrides)
+	if err != nil {
+		keymap = DefaultKeymap()
+		return err
+	}
+	keymap = km
+	return nil
+}
+
+// Action returns the action bound to a key, or "" for none
+func (k *Keymap) Action(msg tea.KeyMsg) Action {
+	return k.actions[msg.String()]
+}
+
+// Is reports whether the key is bound to the action
+func (k *Keymap) Is(msg tea.KeyMsg, action Action) bool {
+	return k.Action(msg) == action
+}
+
+// Key returns the first key of an action, as shown in hints
+func (k *Keymap) Key(action Action) string {
+	for _, b := range k.bindings {
+		if b.action == action {
+			return displayKey(b.keys[0])
+		}
+	}
+	return ""
+}
+
+// Help returns the keys and description of every action for the help overlay
+func (k *Keymap) Help() [][]string {
+	rows := make([][]string, 0, len(k.bindings))
+	for _, b := range k.bindings {
+		keys := make([]string, len(b.keys))
+		for i, key := range b.keys {
+			keys[i] = displayKey(key)
+		}
+		rows = append(rows, []string{strings.Join(keys, " / "), i18n.T("action." + string(b.action))})
+	}
+	return rows
+}
+
+// keyHint is an action named in the hints above the status bar
+type keyHint struct {
+	action Action
+	label  string // message ID
+}
+
+// keyHints renders hints with the keys currently bound to their actions
+func keyHints(hints []keyHint) string {
+	parts := []string{keymap.Key(ActionUp) + keymap.Key(ActionDown) + " " + i18n.T("hint.navigate")}
+	for _, h := range hints {
+		parts = append(parts, keymap.Key(h.action)+": "+i18n.T(h.label))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// displayKey turns key names into what is printed on keycaps
+func displayKey(key string) string {
+	switch key {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "enter":
+		return "Enter"
+	case "esc":
+		return "Esc"
+	}
+	return key
+}
@@ -1,12 +1,16 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/health"
+	"github.com/sshm/sshm/internal/i18n"
 	"github.com/sshm/sshm/internal/models"
 	"github.com/sshm/sshm/internal/ssh"
 	"github.com/sshm/sshm/internal/store"
@@ -40,28 +44,108 @@ type ListView struct {
 	connecting  bool
 	connectHost string
 	connectErr  string
-	pinging     bool // Whether we're currently pinging hosts
-	pingMu      sync.Mutex
+	health      *health.Scheduler // background reachability probes
+	ctx         context.Context   // ends interactive sessions when done
+	agentStatus ssh.AgentStatus // SSH agent reachability, refreshed with the host list
+	config      *config.Config
+	pendingConnect *models.Host // guarded host waiting for connect confirmation
+	pendingTag     string       // guarded tag that triggered the confirmation
+	pendingSplit   []models.Host // hosts to split into tmux panes once confirmed
+	sshm           []string      // command line running sshm, for tmux windows
+	sshmEnv        []string      // environment it needs, see sshmCommand
+	filters        []listFilter // stacked group/tag/text filters, most recent last
+	stacked        []models.Host // hosts passing the filter stack, before the typed text
+	filterSeq      int           // bumped per keystroke, see scheduleFilter
+	history        *store.HistoryStore
+	lastUsed       map[string]time.Time     // last connection time per host ID
+	pickMode       bool                     // list is used to choose a host, not connect
+	presenting     bool                     // hosts and users are masked for screen sharing
+	identityHost   string                   // selected host whose identity file was checked last
+	identityPath   string                   // its identity file
+	identityGone   bool                     // whether that file was missing
 }
 
 // NewListView creates a new list view
 func NewListView(s *store.FileStore) *ListView {
 	hosts := s.ListHosts()
+	scheduler := health.NewScheduler(func(h models.Host) error {
+		return ssh.Ping(h.Host, h.Port)
+	}, healthTimings(config.HealthCheck{}))
+	scheduler.SetHosts(hosts)
 	return &ListView{
 		store:    s,
 		hosts:    hosts,
 		filtered: hosts,
+		stacked:  hosts,
 		selected: 0,
 		filterText: "",
 		cursor:   0,
 		filtering: false,
+		agentStatus: ssh.CheckAgent(),
+		config:   &config.Config{},
+		lastUsed: make(map[string]time.Time),
+		health:   scheduler,
+		ctx:      context.Background(),
 	}
 }
 
+// SetHistory sets the connection history used for the "last used" column
+func (v *ListView) SetHistory(h *store.HistoryStore) {
+	v.history = h
+	v.refreshLastUsed()
+}
+
+// refreshLastUsed recomputes the last connection time of each host
+func (v *ListView) refreshLastUsed() {
+	if v.history == nil {
+		return
+	}
+	lastUsed := make(map[string]time.Time)
+	for id, stats := range v.history.GetAllStats() {
+		lastUsed[id] = stats.LastConnected
+	}
+	v.lastUsed = lastUsed
+}
+
+// SetConfig sets the application config used for list behaviour (e.g. guarded tags)
+func (v *ListView) SetConfig(cfg *config.Config) {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	v.config = cfg
+	v.health.SetTimings(healthTimings(cfg.HealthCheck))
+}
+
+// healthTimings returns the scheduler timings of the health_check settings
+func healthTimings(h config.HealthCheck) health.Timings {
+	return health.Timings{Interval: h.Period(), Jitter: h.Spread(), TTL: h.Lifetime()}
+}
+
 // Init initializes the list view
 func (v *ListView) Init() tea.Cmd {
-	// Start pinging hosts in background
-	return v.pingHostsCmd()
+	// Start probing hosts in the background and follow the results
+	v.health.Start()
+	return v.waitForHealth()
+}
+
+// Close stops the background probes
+func (v *ListView) Close() {
+	v.health.Stop()
+}
+
+// newSession creates an interactive session to host, recorded when the
+// settings ask for it, unless bastion_policy forbids connecting directly
+func (v *ListView) newSession(host models.Host) (*ssh.Session, error) {
+	cfg := v.config
+	if err := cfg.CheckBastion(host); err != nil {
+		return nil, err
+	}
+	session := ssh.NewSession(host, cfg.GetProfile(host))
+	session.SetContext(v.ctx)
+	if path := cfg.RecordingPath(host, time.Now()); path != "" {
+		session.Record(path, cfg.RecordingOptions())
+	}
+	return session, nil
 }
 
 // connectMsg is used to signal connection result
@@ -71,63 +155,54 @@ type connectMsg struct {
 	success bool
 }
 
-// pingResultMsg is used to signal ping result for a host
-type pingResultMsg struct {
-	hostID string
-	online bool
-	err    error
+// sessionEndedMsg is sent when an interactive session started from the list ends
+type sessionEndedMsg struct {
+	host        models.Host
+	err         error
+	connectTime    time.Duration
+	warnings       []string
+	hostKeyChanges []models.HostKeyChange
 }
 
-// pingHostsCmd returns a command that pings all hosts in the background
-func (v *ListView) pingHostsCmd() tea.Cmd {
-	return func() tea.Msg {
-		hosts := v.store.ListHosts()
-		var wg sync.WaitGroup
-		results := make(chan pingResultMsg, len(hosts))
-
-		for _, h := range hosts {
-			wg.Add(1)
-			go func(host models.Host) {
-				defer wg.Done()
-				online := true
-				err := ssh.Ping(host.Host, host.Port)
-				if err != nil {
-					online = false
-				}
-				results <- pingResultMsg{hostID: host.ID, online: online, err: err}
-			}(h)
-		}
+// Failed returns whether the session could not be established
+// A non-zero remote exit status is not considered a failure
+func (m sessionEndedMsg) Failed() bool {
+	if m.err == nil {
+		return false
+	}
+	_, remoteExit := ssh.ExitStatus(m.err)
+	return !remoteExit
+}
 
-		go func() {
-			wg.Wait()
-			close(results)
-		}()
+// healthMsg carries a probe result from the health scheduler
+type healthMsg health.Result
 
-		// Collect results
-		for result := range results {
-			// Update host status in background (don't block)
-			v.updateHostOnlineStatus(result.hostID, result.online)
+// waitForHealth waits for the next probe result
+func (v *ListView) waitForHealth() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case r := <-v.health.Updates():
+			return healthMsg(r)
+		case <-v.health.Done():
+			return nil
 		}
-
-		return tea.Msg(pingResultMsg{hostID: "", online: false}) // Signal ping complete
 	}
 }
 
-// updateHostOnlineStatus updates the online status for a host
-func (v *ListView) updateHostOnlineStatus(hostID string, online bool) {
-	v.pingMu.Lock()
-	defer v.pingMu.Unlock()
-
-	for i := range v.hosts {
-		if v.hosts[i].ID == hostID {
-			v.hosts[i].Online = &online
-		}
+// agentStatusText is the agent part of the status bar
+func agentStatusText(s ssh.AgentStatus) string {
+	if !s.Available {
+		return i18n.T("agent.unavailable")
 	}
-	for i := range v.filtered {
-		if v.filtered[i].ID == hostID {
-			v.filtered[i].Online = &online
-		}
+	if s.Keys == 1 {
+		return i18n.T("agent.one_key")
 	}
+	return i18n.T("agent.keys", s.Keys)
+}
+
+// status returns the fresh probe result for a host, if any
+func (v *ListView) status(h models.Host) (health.Result, bool) {
+	return v.health.Result(h.ID)
 }
 
 // Update handles messages
@@ -142,143 +217,359 @@ func (v *ListView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case connectMsg:
 		// Handle connection result
 		if msg.success {
-			if err := ssh.LaunchSSH(msg.host); err != nil {
-				v.connectErr = fmt.Sprintf("Failed to connect: %v", err)
+			if where := v.tmuxTarget(); where != "" {
+				v.connecting = false
+				return v, v.openInTmux(msg.host, where)
+			}
+			// Release the terminal for the interactive session and resume afterwards
+			session, err := v.newSession(msg.host)
+			if err != nil {
+				v.connectErr = err.Error()
 				v.connecting = false
+				return v, nil
 			}
-			// SSH launched - quit the TUI
-			return v, tea.Quit
+			host := msg.host
+			return v, tea.Exec(session, func(err error) tea.Msg {
+				return sessionEndedMsg{
+					host:           host,
+					err:            err,
+					connectTime:    session.ConnectTime,
+					warnings:       session.Warnings,
+					hostKeyChanges: session.HostKeyChanges,
+				}
+			})
 		}
 		// Connection failed
 		v.connectErr = msg.err.Error()
 		v.connecting = false
 		return v, nil
-	case pingResultMsg:
-		// Ping completed - refresh filtered list to show updated status
-		if msg.hostID == "" {
-			// This is the completion signal
-			v.updateFiltered()
-			return v, nil
+	case sessionEndedMsg:
+		v.connecting = false
+		if msg.Failed() {
+			v.connectErr = i18n.T("error.connect", msg.err)
+		}
+		v.Refresh()
+		return v, nil
+	case filterDebounceMsg:
+		// Only the last keystroke of a burst filters the list
+		if msg.seq == v.filterSeq && v.filtering {
+			v.applyTyped()
+			v.cursor = 0
 		}
+		return v, nil
+	case healthMsg:
+		// Rows read the results when drawn; wait for the next one
+		return v, v.waitForHealth()
 	}
 	return v, nil
 }
 
 func (v *ListView) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// If a guarded connect is pending, only accept confirm or cancel
+	if v.pendingConnect != nil {
+		switch keymap.Action(msg) {
+		case ActionConfirm:
+			host, hosts := *v.pendingConnect, v.pendingSplit
+			v.cancelConnect()
+			if hosts != nil {
+				return v, v.split(hosts)
+			}
+			return v, v.connect(host)
+		case ActionCancel, ActionBack, ActionQuit:
+			v.cancelConnect()
+		}
+		if msg.String() == "ctrl+c" {
+			v.cancelConnect()
+		}
+		return v, nil
+	}
+
 	// If filtering, handle filter input
 	if v.filtering {
 		switch msg.String() {
 		case "esc":
 			v.filtering = false
 			v.filterText = ""
-			v.updateFiltered()
+			v.filterSeq++
+			v.applyTyped()
 			v.cursor = 0
 		case "enter":
+			// Push the typed text onto the filter stack
 			v.filtering = false
+			v.filterSeq++
+			if v.filterText != "" {
+				f := listFilter{kind: filterText, value: v.filterText}
+				if store.IsSelector(v.filterText) {
+					if sel, ok := newSelectorFilter(v.filterText); ok {
+						f = sel
+					}
+				}
+				v.filters = append(v.filters, f)
+				v.filterText = ""
+				v.updateFiltered()
+			}
 		case "backspace", "delete", "ctrl+h":
 			if len(v.filterText) > 0 {
-				v.filterText = v.filterText[:len(v.filterText)-1]
-				v.updateFiltered()
-				if v.cursor >= len(v.filtered) {
-					v.cursor = max(0, len(v.filtered)-1)
-				}
+				v.filterText = trimLastRune(v.filterText)
+				return v, v.scheduleFilter()
 			}
 		default:
 			// Add character to filter
-			if len(msg.String()) == 1 {
-				v.filterText += msg.String()
-				v.updateFiltered()
-				v.cursor = 0
+			if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+				v.filterText += string(msg.Runes)
+				return v, v.scheduleFilter()
 			}
 		}
 		return v, nil
 	}
 
 	// Normal navigation
-	switch msg.String() {
-	case "up", "k":
+	if msg.String() == "ctrl+c" {
+		return v, tea.Quit
+	}
+	switch keymap.Action(msg) {
+	case ActionUp:
 		if v.cursor > 0 {
 			v.cursor--
 		}
-	case "down", "j":
+	case ActionDown:
 		if v.cursor < len(v.filtered)-1 {
 			v.cursor++
 		}
-	case "home", "g":
+	case ActionTop:
 		v.cursor = 0
-	case "end", "G":
+	case ActionBottom:
 		v.cursor = max(0, len(v.filtered)-1)
-	case "pageup":
+	case ActionPageUp:
 		v.cursor = max(0, v.cursor-5)
-	case "pagedown":
-		v.cursor = min(len(v.filtered)-1, v.cursor+5)
-	case "/":
+	case ActionPageDown:
+		v.cursor = max(0, min(len(v.filtered)-1, v.cursor+5))
+	case ActionFilter:
 		v.filtering = true
 		v.filterText = ""
-	case "enter":
+	case ActionPopFilter:
+		v.PopFilter()
+	case ActionConnect:
 		// Quick Connect: Connect to selected host
 		if len(v.filtered) > 0 && v.cursor < len(v.filtered) {
 			host := v.filtered[v.cursor]
-			// Set connecting state to show progress
-			v.connecting = true
-			v.connectHost = host.Name
-			v.connectErr = ""
-			// Return a command to test connection in background
-			return v, func() tea.Msg {
-				// Test connection first
-				if err := ssh.Ping(host.Host, host.Port); err != nil {
-					return connectMsg{host: host, err: err, success: false}
-				}
-				// Connection OK, return success to launch SSH
-				return connectMsg{host: host, success: true}
+			// Refuse before asking to confirm a connection that can't happen
+			if err := v.config.CheckBastion(host); err != nil {
+				v.connectErr = err.Error()
+				return v, nil
+			}
+			// Guarded hosts need an explicit confirmation first
+			if tag := v.config.GuardedTag(host); tag != "" {
+				v.pendingConnect = &host
+				v.pendingTag = tag
+				return v, nil
 			}
+			return v, v.connect(host)
 		}
-	case "a":
-		// Handled by parent App
-	case "e":
-		// Handled by parent App
-	case "d":
-		// Handled by parent App
-	case "q", "ctrl+c":
+	case ActionSplit:
+		return v, v.splitListed()
+	case ActionQuit:
 		return v, tea.Quit
 	}
 	return v, nil
 }
 
+// cancelConnect drops a connection waiting for confirmation
+func (v *ListView) cancelConnect() {
+	v.pendingConnect = nil
+	v.pendingTag = ""
+	v.pendingSplit = nil
+}
+
+// connect starts connecting to the host, testing reachability in the background
+func (v *ListView) connect(host models.Host) tea.Cmd {
+	// Set connecting state to show progress
+	v.connecting = true
+	v.connectHost = host.Name
+	v.connectErr = ""
+	// Return a command to test connection in background
+	return func() tea.Msg {
+		// Test connection first, updating the host's status while at it
+		if r := v.health.Check(host); r.Err != nil {
+			return connectMsg{host: host, err: r.Err, success: false}
+		}
+		// Connection OK, return success to launch SSH
+		return connectMsg{host: host, success: true}
+	}
+}
+
+// SetPickMode switches the key hints to choosing a host instead of connecting
+func (v *ListView) SetPickMode(pick bool) {
+	v.pickMode = pick
+}
+
+// SetPresenting turns presentation mode, which masks host addresses and
+// users, on or off
+func (v *ListView) SetPresenting(presenting bool) {
+	v.presenting = presenting
+}
+
+// Presenting reports whether presentation mode is on
+func (v *ListView) Presenting() bool {
+	return v.presenting
+}
+
+// redacted replaces sensitive values in presentation mode
+const redacted = "•••••"
+
+// redact returns value, or a mask of fixed length in presentation mode
+func (v *ListView) redact(value string) string {
+	if !v.presenting || value == "" {
+		return value
+	}
+	return redacted
+}
+
+// helpText returns the key hints shown above the status bar
+func (v *ListView) helpText() string {
+	if v.pickMode {
+		return keyHints([]keyHint{
+			{ActionConnect, "hint.pick"}, {ActionFilter, "hint.filter"}, {ActionTags, "hint.tags"},
+			{ActionGroups, "hint.group"}, {ActionQuit, "hint.cancel"},
+		})
+	}
+	return keyHints([]keyHint{
+		{ActionConnect, "hint.connect"}, {ActionAdd, "hint.add"}, {ActionEdit, "hint.edit"},
+		{ActionRename, "hint.rename"}, {ActionDelete, "hint.delete"}, {ActionTags, "hint.tags"},
+		{ActionDetail, "hint.detail"}, {ActionHistory, "hint.history"}, {ActionImport, "hint.import"},
+		{ActionFilter, "hint.filter"}, {ActionHelp, "hint.help"}, {ActionQuit, "hint.quit"},
+	})
+}
+
+// IsConfirmingConnect returns whether a guarded connect is awaiting confirmation
+func (v *ListView) IsConfirmingConnect() bool {
+	return v.pendingConnect != nil
+}
+
+// filterDebounce is how long typing in the filter pauses before the list is
+// filtered again
+const filterDebounce = 80 * time.Millisecond
+
+// filterDebounceMsg filters the list once typing paused
+type filterDebounceMsg struct {
+	seq int
+}
+
+// scheduleFilter filters the list with the typed text after filterDebounce,
+// unless another key comes first
+func (v *ListView) scheduleFilter() tea.Cmd {
+	v.filterSeq++
+	seq := v.filterSeq
+	return tea.Tick(filterDebounce, func(time.Time) tea.Msg {
+		return filterDebounceMsg{seq: seq}
+	})
+}
+
+// updateFiltered re-evaluates the filter stack, after the hosts or the stack
+// changed, then applies the typed text
 func (v *ListView) updateFiltered() {
-	if v.filterText == "" {
-		v.filtered = v.hosts
+	if len(v.filters) == 0 {
+		v.stacked = v.hosts
 	} else {
-		lowerFilter := strings.ToLower(v.filterText)
-		v.filtered = nil
+		v.stacked = nil
+		for i := range v.filters {
+			v.filters[i].refresh(v.store, v.hosts)
+		}
 		for _, h := range v.hosts {
-			if strings.Contains(strings.ToLower(h.Name), lowerFilter) ||
-				strings.Contains(strings.ToLower(h.Host), lowerFilter) ||
-				strings.Contains(strings.ToLower(h.User), lowerFilter) ||
-				strings.Contains(strings.ToLower(h.Group), lowerFilter) ||
-				stringsContainsAny(h.Tags, lowerFilter) {
-				v.filtered = append(v.filtered, h)
+			if matchesAll(h, v.filters) {
+				v.stacked = append(v.stacked, h)
 			}
 		}
 	}
+	v.applyTyped()
 }
 
-func stringsContainsAny(tags []string, query string) bool {
-	for _, tag := range tags {
-		if strings.Contains(strings.ToLower(tag), query) {
-			return true
+// applyTyped narrows the hosts passing the filter stack down to the typed
+// text, which applies live on top of the stack
+func (v *ListView) applyTyped() {
+	defer func() {
+		if v.cursor >= len(v.filtered) {
+			v.cursor = max(0, len(v.filtered)-1)
+		}
+	}()
+	if v.filterText == "" {
+		v.filtered = v.stacked
+		return
+	}
+	// A selector being typed applies as soon as it parses
+	var match func(models.Host) bool
+	if typing, ok := newSelectorFilter(v.filterText); ok && store.IsSelector(v.filterText) {
+		typing.refresh(v.store, v.hosts)
+		match = typing.matches
+	} else {
+		typed := v.store.MatchingHosts(v.filterText)
+		match = func(h models.Host) bool { return typed[h.ID] }
+	}
+	v.filtered = nil
+	for _, h := range v.stacked {
+		if match(h) {
+			v.filtered = append(v.filtered, h)
 		}
 	}
-	return false
 }
 
-// View renders the list
-func (v *ListView) View() string {
-	// Ensure filtered is up to date
-	if v.filterText != "" {
-		v.updateFiltered()
+// hasFilters returns whether any stacked or in-progress filter is active
+func (v *ListView) hasFilters() bool {
+	return v.filterText != "" || len(v.filters) > 0
+}
+
+// SetTagFilters replaces the active tag filters
+func (v *ListView) SetTagFilters(tags []string) {
+	v.filters = replaceFilters(v.filters, filterTag, tags)
+	v.updateFiltered()
+	v.cursor = 0
+}
+
+// TagFilters returns the active tag filters
+func (v *ListView) TagFilters() []string {
+	return filterValues(v.filters, filterTag)
+}
+
+// SetGroupFilter replaces the active group filter; an empty group removes it
+func (v *ListView) SetGroupFilter(group string) {
+	var groups []string
+	if group != "" {
+		groups = []string{group}
+	}
+	v.filters = replaceFilters(v.filters, filterGroup, groups)
+	v.updateFiltered()
+	v.cursor = 0
+}
+
+// GroupFilter returns the active group filter, if any
+func (v *ListView) GroupFilter() string {
+	if groups := filterValues(v.filters, filterGroup); len(groups) > 0 {
+		return groups[0]
 	}
+	return ""
+}
 
+// PopFilter removes the most recently added filter
+// Returns false if no filter was active
+func (v *ListView) PopFilter() bool {
+	if len(v.filters) == 0 {
+		return false
+	}
+	v.filters = v.filters[:len(v.filters)-1]
+	v.updateFiltered()
+	if v.cursor >= len(v.filtered) {
+		v.cursor = max(0, len(v.filtered)-1)
+	}
+	return true
+}
+
+// Hosts returns all hosts known to the list, regardless of filters
+func (v *ListView) Hosts() []models.Host {
+	return v.hosts
+}
+
+// View renders the list
+func (v *ListView) View() string {
 	hosts := v.filtered
 
 	// Calculate dimensions
@@ -310,6 +601,11 @@ func (v *ListView) View() string {
 	// Status bar
 	statusBar := v.renderStatusBar(width, hosts)
 
+	// Active filters shown as a breadcrumb
+	if breadcrumb := v.renderBreadcrumb(); breadcrumb != "" {
+		filterBar += "\n" + breadcrumb
+	}
+
 	return titleBar + "\n" + filterBar + "\n\n" + listContent + "\n\n" + statusBar
 }
 
@@ -319,7 +615,7 @@ func (v *ListView) renderTitleBar(width int) string {
 		Bold(true).
 		Width(width).
 		Align(lipgloss.Center).
-		Render(" SSH Host Manager ")
+		Render(" " + i18n.T("title") + " ")
 
 	border := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
@@ -340,7 +636,7 @@ func (v *ListView) renderFilterBar(width int) string {
 		
 		filterLabel := lipgloss.NewStyle().
 			Foreground(secondaryColor).
-			Render("Filter: ")
+			Render(i18n.T("list.filter"))
 		
 		filterInput := inputStyle.Render(v.filterText + "_")
 		
@@ -351,24 +647,59 @@ func (v *ListView) renderFilterBar(width int) string {
 	hint := lipgloss.NewStyle().
 		Foreground(secondaryColor).
 		Width(width).
-		Render("/ to filter | esc to clear")
+		Render(i18n.T("list.filter_hint",
+			keymap.Key(ActionFilter), keymap.Key(ActionTags), keymap.Key(ActionGroups),
+			keymap.Key(ActionPopFilter), keymap.Key(ActionBack)))
 
 	return hint
 }
 
+// renderBreadcrumb renders the filter stack as a breadcrumb of chips
+func (v *ListView) renderBreadcrumb() string {
+	if len(v.filters) == 0 {
+		return ""
+	}
+
+	tagBg := GetTagBackground()
+	separator := lipgloss.NewStyle().Foreground(secondaryColor).Render(" › ")
+	crumbs := []string{lipgloss.NewStyle().Foreground(secondaryColor).Render(i18n.T("list.filters"))}
+	for i, f := range v.filters {
+		color := primaryColor
+		if f.kind == filterTag {
+			if c, ok := tagColors[f.value]; ok {
+				color = c
+			} else {
+				color = tagColors["default"]
+			}
+		}
+		if i > 0 {
+			crumbs = append(crumbs, separator)
+		}
+		crumbs = append(crumbs, lipgloss.NewStyle().
+			Foreground(color).
+			Background(tagBg).
+			Bold(true).
+			Padding(0, 1).
+			Render(f.label()))
+	}
+	crumbs = append(crumbs, lipgloss.NewStyle().Foreground(secondaryColor).Render("  "+i18n.T("list.pop_hint")))
+	return lipgloss.JoinHorizontal(lipgloss.Top, crumbs...)
+}
+
 func (v *ListView) renderHostList(width, height int) string {
 	hosts := v.filtered
 
 	var content string
 	if len(hosts) == 0 {
 		emptyMsg := BodyStyle.Width(width).Align(lipgloss.Center).Render(
-			"No hosts found.\nPress 'a' to add a host.",
+			i18n.T("list.empty", keymap.Key(ActionAdd)),
 		)
 		content = BorderStyle.Width(width).Height(height).Render(emptyMsg)
 		return content
 	}
 
-	// Calculate visible range
+	// Calculate visible range (one line is used by the header)
+	height--
 	start := v.cursor - height/2
 	if start < 0 {
 		start = 0
@@ -379,10 +710,15 @@ func (v *ListView) renderHostList(width, height int) string {
 		start = max(0, end-height)
 	}
 
-	var rows []string
+	// Table layout: header row followed by aligned host rows
+	// The row prefix (cursor and status indicator) takes 5 columns
+	cols := v.columns()
+	widths := v.columnWidths(cols, hosts, width-2-5)
+
+	rows := []string{v.renderHeaderRow(cols, widths, width-2)}
 	for i := start; i < end; i++ {
 		h := hosts[i]
-		row := v.renderHostRow(h, width-2, i == v.cursor)
+		row := v.renderHostRow(h, cols, widths, width-2, i == v.cursor)
 		rows = append(rows, row)
 	}
 
@@ -394,7 +730,7 @@ func (v *ListView) renderHostList(width, height int) string {
 	return BorderStyle.Width(width).Height(height).Render(listContent)
 }
 
-func (v *ListView) renderHostRow(h models.Host, width int, selected bool) string {
+func (v *ListView) renderHostRow(h models.Host, cols []column, widths []int, width int, selected bool) string {
 	// Cursor indicator
 	cursor := " "
 	if selected {
@@ -403,8 +739,9 @@ func (v *ListView) renderHostRow(h models.Host, width int, selected bool) string
 
 	// Online/offline status indicator
 	var statusIndicator string
-	if h.Online != nil {
-		if *h.Online {
+	result, known := v.status(h)
+	if known {
+		if result.Online {
 			statusIndicator = "●" // Green dot for online
 		} else {
 			statusIndicator = "○" // Gray circle for offline
@@ -413,35 +750,11 @@ func (v *ListView) renderHostRow(h models.Host, width int, selected bool) string
 		statusIndicator = "◌" // Dotted circle for unknown
 	}
 
-	// Host info
-	hostInfo := fmt.Sprintf("%s@%s:%d", h.User, h.Host, h.Port)
-
-	// Group info
-	groupInfo := ""
-	if h.Group != "" {
-		groupInfo = "[" + h.Group + "]"
-	}
-
-	// Calculate available width for name (subtract status indicator space)
-	availableWidth := width - len(cursor) - len(statusIndicator) - len(hostInfo) - len(groupInfo) - 5
-	if availableWidth < 10 {
-		availableWidth = 10
-	}
-
-	// Truncate name if needed
-	name := h.Name
-	if len(name) > availableWidth {
-		name = name[:availableWidth-2] + ".."
-	}
-
-	// Render tags
-	tagsStr := v.renderTags(h.Tags, availableWidth)
-
 	// Determine status color
 	var statusColor lipgloss.Color
 	onlineColor, offlineColor, unknownColor := GetStatusColors()
-	if h.Online != nil {
-		if *h.Online {
+	if known {
+		if result.Online {
 			statusColor = onlineColor
 		} else {
 			statusColor = offlineColor
@@ -450,17 +763,36 @@ func (v *ListView) renderHostRow(h models.Host, width int, selected bool) string
 		statusColor = unknownColor
 	}
 
-	// Build the row
-	var row string
+	// Build the cells, aligned to the column widths
+	cells := make([]string, len(cols))
+	for i, col := range cols {
+		if col.id == columnTags {
+			tags := v.renderTags(h.Tags, widths[i]+10)
+			cells[i] = tags + strings.Repeat(" ", max(0, widths[i]-lipgloss.Width(tags)))
+			continue
+		}
+		cells[i] = padRight(truncate(col.value(v, h), widths[i]), widths[i])
+	}
+
+	row := fmt.Sprintf(" %s %s %s", cursor, lipgloss.NewStyle().Foreground(statusColor).Render(statusIndicator), strings.Join(cells, " "))
 	if selected {
-		row = fmt.Sprintf(" %s %s %-*s %s %s %s", cursor, lipgloss.NewStyle().Foreground(statusColor).Render(statusIndicator), availableWidth, name, groupInfo, hostInfo, tagsStr)
-		row = SelectedStyle.Width(width).Render(row)
-	} else {
-		row = fmt.Sprintf(" %s %s %-*s %s %s %s", cursor, lipgloss.NewStyle().Foreground(statusColor).Render(statusIndicator), availableWidth, name, groupInfo, hostInfo, tagsStr)
-		row = NormalStyle.Width(width).Render(row)
+		return SelectedStyle.Width(width).Render(row)
 	}
+	return NormalStyle.Width(width).Render(row)
+}
 
-	return row
+// renderHeaderRow renders the column titles aligned with the host rows
+func (v *ListView) renderHeaderRow(cols []column, widths []int, width int) string {
+	titles := make([]string, len(cols))
+	for i, col := range cols {
+		titles[i] = padRight(truncate(col.title, widths[i]), widths[i])
+	}
+
+	return lipgloss.NewStyle().
+		Foreground(secondaryColor).
+		Bold(true).
+		Width(width).
+		Render("     " + strings.Join(titles, " "))
 }
 
 func (v *ListView) renderTags(tags []string, availableWidth int) string {
@@ -485,7 +817,7 @@ func (v *ListView) renderTags(tags []string, availableWidth int) string {
 			Padding(0, 1).
 			Render(tag)
 
-		tagWidth := len(tag) + 2
+		tagWidth := textWidth(tag) + 2
 		if currentWidth+tagWidth > availableWidth-10 {
 			break // Don't overflow
 		}
@@ -497,15 +829,44 @@ func (v *ListView) renderTags(tags []string, availableWidth int) string {
 	return lipgloss.JoinHorizontal(0, tagViews...)
 }
 
+// identityMissing reports whether the host's identity file doesn't exist
+// The disk is only checked again once another host is selected, its
+// identity changes or the list is refreshed, not on every render.
+func (v *ListView) identityMissing(h models.Host) bool {
+	if h.ID != v.identityHost || h.Identity != v.identityPath {
+		v.identityHost, v.identityPath = h.ID, h.Identity
+		v.identityGone = !ssh.IdentityExists(h.Identity)
+	}
+	return v.identityGone
+}
+
 func (v *ListView) renderStatusBar(width int, hosts []models.Host) string {
+	// Ask for confirmation before connecting to a guarded host
+	if v.pendingConnect != nil {
+		warn := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")). // Orange
+			Bold(true)
+		hostName := lipgloss.NewStyle().
+			Foreground(errorColor).
+			Bold(true).
+			Render(v.pendingConnect.Name)
+		keys := []any{v.pendingTag, keymap.Key(ActionConfirm), keymap.Key(ActionCancel), keymap.Key(ActionBack)}
+		prompt := warn.Render("⚠ "+i18n.T("guarded.before")) + hostName + warn.Render(i18n.T("guarded.after", keys...))
+		if v.pendingSplit != nil {
+			prompt = warn.Render("⚠ "+i18n.T("guarded.split_before", len(v.pendingSplit))) + hostName +
+				warn.Render(i18n.T("guarded.split_after", keys...))
+		}
+		return HelpStyle.Width(width).Render(i18n.T("guarded.title")) + "\n" + StatusBar(prompt)
+	}
+
 	// Show connection status if connecting or error
 	if v.connecting {
-		connectMsg := fmt.Sprintf("Connecting to %s...", v.connectHost)
+		connectMsg := i18n.T("list.connecting", v.connectHost)
 		connectingStatus := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("82")). // Green
 			Render(connectMsg)
 		
-		helpText := "↑↓ Navigate | Enter: Connect | a: Add | e: Edit | x: Delete | d: Detail | h: History | i: Import | /: Filter | ?: Help | q: Quit"
+		helpText := v.helpText()
 		help := HelpStyle.Width(width).Render(helpText)
 		return help + "\n" + StatusBar(connectingStatus)
 	}
@@ -515,72 +876,79 @@ func (v *ListView) renderStatusBar(width int, hosts []models.Host) string {
 			Foreground(lipgloss.Color("203")). // Red
 			Render("✗ " + v.connectErr)
 		
-		helpText := "↑↓ Navigate | Enter: Connect | a: Add | e: Edit | x: Delete | d: Detail | h: History | i: Import | /: Filter | ?: Help | q: Quit"
+		helpText := v.helpText()
 		help := HelpStyle.Width(width).Render(helpText)
 		return help + "\n" + StatusBar(errorStatus)
 	}
 
 	// Status text
-	hostCount := fmt.Sprintf("%d hosts", len(hosts))
-	if v.filterText != "" {
-		hostCount = fmt.Sprintf("%d / %d hosts", len(hosts), len(v.hosts))
+	hostCount := i18n.T("list.hosts", len(hosts))
+	if v.hasFilters() {
+		hostCount = i18n.T("list.hosts_filtered", len(hosts), len(v.hosts))
+	}
+	statusLeftText := hostCount + " | " + agentStatusText(v.agentStatus)
+	if v.presenting {
+		statusLeftText += " | " + i18n.T("list.presentation")
 	}
 
 	statusLeft := lipgloss.NewStyle().
 		Foreground(secondaryColor).
-		Render(hostCount)
+		Render(statusLeftText)
+
+	// Warn when the selected host points at an identity file that does not exist
+	if len(hosts) > 0 && v.cursor < len(hosts) && v.identityMissing(hosts[v.cursor]) {
+		warning := " | ⚠ " + i18n.T("list.identity_missing")
+		statusLeftText += warning
+		statusLeft += lipgloss.NewStyle().
+			Foreground(errorColor).
+			Render(warning)
+	}
 
-	// Selected host info
+	// Selected host info, cut rather than wrapped when the name is long
 	var statusRight string
+	rightWidth := max(0, width-lipgloss.Width(statusLeftText)-5)
 	if len(hosts) > 0 && v.cursor < len(hosts) {
 		h := hosts[v.cursor]
-		statusRight = fmt.Sprintf("[%d/%d] %s", v.cursor+1, len(hosts), h.Name)
+		statusRight = truncate(fmt.Sprintf("[%d/%d] %s", v.cursor+1, len(hosts), h.Name), rightWidth)
 	}
 
 	statusRight = lipgloss.NewStyle().
 		Foreground(secondaryColor).
-		Width(width - len(hostCount) - 5).
+		Width(rightWidth).
 		Align(lipgloss.Right).
 		Render(statusRight)
 
 	status := statusLeft + statusRight
 
-	helpText := "↑↓ Navigate | Enter: Connect | a: Add | e: Edit | x: Delete | d: Detail | h: History | i: Import | /: Filter | ?: Help | q: Quit"
+	helpText := v.helpText()
 	
 	help := HelpStyle.Width(width).Render(helpText)
 
 	return help + "\n" + StatusBar(status)
 }
 
-// Refresh reloads hosts from store and re-pings all hosts
+// Refresh reloads hosts from store, staying on the selected host; new hosts
+// are probed right away
 func (v *ListView) Refresh() {
+	var selected string
+	if h := v.GetSelectedHost(); h != nil {
+		selected = h.ID
+	}
 	v.hosts = v.store.ListHosts()
+	v.agentStatus = ssh.CheckAgent()
+	v.identityHost = ""
+	v.refreshLastUsed()
 	v.updateFiltered()
+	for i, h := range v.filtered {
+		if h.ID == selected {
+			v.cursor = i
+			break
+		}
+	}
 	if v.cursor >= len(v.filtered) {
 		v.cursor = max(0, len(v.filtered)-1)
 	}
-	// Re-ping hosts in background
-	go v.pingHostsBackground()
-}
-
-// pingHostsBackground pings all hosts without blocking (for Refresh)
-func (v *ListView) pingHostsBackground() {
-	hosts := v.store.ListHosts()
-	var wg sync.WaitGroup
-
-	for _, h := range hosts {
-		wg.Add(1)
-		go func(host models.Host) {
-			defer wg.Done()
-			online := true
-			err := ssh.Ping(host.Host, host.Port)
-			if err != nil {
-				online = false
-			}
-			v.updateHostOnlineStatus(host.ID, online)
-		}(h)
-	}
-	wg.Wait()
+	v.health.SetHosts(v.hosts)
 }
 
 // GetSelectedHost returns the currently selected host
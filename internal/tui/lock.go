@@ -0,0 +1,157 @@
+package tui
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sshm/sshm/internal/i18n"
+	"github.com/sshm/sshm/internal/secret"
+	"github.com/sshm/sshm/internal/store"
+)
+
+// idleCheckInterval is how often the app looks for idle time to lock on
+const idleCheckInterval = time.Second
+
+// idleCheckMsg asks the app whether it has been idle long enough to lock
+type idleCheckMsg struct {
+	at time.Time
+}
+
+// watchIdle schedules the next idle check
+func watchIdle() tea.Cmd {
+	return tea.Tick(idleCheckInterval, func(t time.Time) tea.Msg {
+		return idleCheckMsg{at: t}
+	})
+}
+
+// checkIdle locks the app once idle_lock's timeout passed without a key
+// press and schedules the next check
+func (m *App) checkIdle(msg idleCheckMsg) tea.Cmd {
+	// A check held up by an interactive session or editor counts as use,
+	// or coming back from a long session would lock right away
+	if time.Since(msg.at) > 2*idleCheckInterval {
+		m.lastInput = time.Now()
+	}
+	timeout := m.listView.config.IdleLock.Timeout()
+	if m.lock == nil && timeout > 0 && time.Since(m.lastInput) >= timeout {
+		m.lockScreen()
+	}
+	return watchIdle()
+}
+
+// lockScreen hides the app behind the lock view and forgets the passphrase,
+// so encrypted passwords need it again after unlocking
+// Without a passphrase to check against, any key unlocks.
+func (m *App) lockScreen() {
+	var check func([]byte) error
+	if m.listView.config.IdleLock.Passphrase {
+		if _, err := secret.StoredPassphrase(); err == nil || m.store.HasEncrypted() {
+			check = m.checkPassphrase
+		}
+	}
+	m.lock = NewLockView(check)
+	m.pendingDelete = ""
+	// Encrypting pending changes may need the passphrase about to be forgotten
+	m.store.Flush()
+	secret.Forget()
+}
+
+// checkPassphrase checks a passphrase typed on the lock view against the
+// encrypted passwords of the store or, without any, the passphrase in
+// $SSHM_PASSPHRASE or the keyring
+func (m *App) checkPassphrase(passphrase []byte) error {
+	err := m.store.CheckPassphrase(passphrase)
+	if !errors.Is(err, store.ErrNothingEncrypted) {
+		return err
+	}
+	stored, err := secret.StoredPassphrase()
+	secret.Forget()
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(stored, passphrase) != 1 {
+		return secret.ErrWrongPassphrase
+	}
+	return nil
+}
+
+// LockView covers the app after idle_lock's timeout until a key is pressed
+// or, with a check function, the store passphrase is typed
+type LockView struct {
+	check func([]byte) error
+	value []byte
+	err   string
+	done  bool // true once unlocked
+}
+
+// NewLockView creates a lock view; a nil check unlocks on any key
+func NewLockView(check func([]byte) error) *LockView {
+	return &LockView{check: check}
+}
+
+// Init initializes the lock view
+func (v *LockView) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages
+func (v *LockView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+	if v.check == nil {
+		v.done = true
+		return v, nil
+	}
+
+	switch keyMsg.Type {
+	case tea.KeyEnter:
+		if err := v.check(v.value); err != nil {
+			clear(v.value)
+			v.value = nil
+			v.err = i18n.T("lock.wrong")
+			break
+		}
+		secret.SetPassphrase(v.value)
+		v.done = true
+	case tea.KeyBackspace, tea.KeyDelete, tea.KeyCtrlH:
+		if len(v.value) > 0 {
+			_, size := utf8.DecodeLastRune(v.value)
+			clear(v.value[len(v.value)-size:])
+			v.value = v.value[:len(v.value)-size]
+		}
+	case tea.KeyCtrlU, tea.KeyEsc:
+		clear(v.value)
+		v.value = nil
+	case tea.KeyRunes, tea.KeySpace:
+		v.value = append(v.value, string(keyMsg.Runes)...)
+	}
+	return v, nil
+}
+
+// View renders the lock screen without anything of the inventory
+func (v *LockView) View() string {
+	title := lipgloss.NewStyle().
+		Foreground(primaryColor).
+		Bold(true).
+		Render(i18n.T("lock.title"))
+
+	var body string
+	if v.check == nil {
+		body = title + "\n" + HelpStyle.Render(i18n.T("lock.any_key"))
+	} else {
+		input := InputStyle.Width(40).Render(strings.Repeat("•", len([]rune(string(v.value)))) + "_")
+		body = title + "\n" + i18n.T("lock.passphrase") + "\n" + input
+		if v.err != "" {
+			body += "\n" + ErrorStyle.Render(v.err)
+		}
+		body += "\n" + HelpStyle.Render(i18n.T("lock.help"))
+	}
+	return BorderStyle.Padding(0, 1).Render(body)
+}
@@ -0,0 +1,107 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sshm/sshm/internal/i18n"
+)
+
+// Onboarding choices
+const (
+	onboardImport = "import"
+	onboardAdd    = "add"
+	onboardTheme  = "theme"
+	onboardSkip   = "skip"
+)
+
+// onboardingOption is a single entry of the first-run wizard
+type onboardingOption struct {
+	id    string
+	title string
+	desc  string
+}
+
+// OnboardingView is the first-run wizard shown when the store is empty
+type OnboardingView struct {
+	options []onboardingOption
+	cursor  int
+	choice  string // selected option id, consumed by the App
+}
+
+// NewOnboardingView creates the first-run wizard
+func NewOnboardingView() *OnboardingView {
+	return &OnboardingView{
+		options: []onboardingOption{
+			{onboardImport, i18n.T("onboarding.import"), i18n.T("onboarding.import_desc")},
+			{onboardAdd, i18n.T("onboarding.add"), i18n.T("onboarding.add_desc")},
+			{onboardTheme, i18n.T("onboarding.theme"), i18n.T("onboarding.theme_desc")},
+			{onboardSkip, i18n.T("onboarding.skip"), i18n.T("onboarding.skip_desc")},
+		},
+	}
+}
+
+// Init initializes the onboarding view
+func (v *OnboardingView) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages
+func (v *OnboardingView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if v.cursor > 0 {
+			v.cursor--
+		}
+	case "down", "j", "tab":
+		if v.cursor < len(v.options)-1 {
+			v.cursor++
+		}
+	case "enter", " ":
+		v.choice = v.options[v.cursor].id
+	case "esc":
+		v.choice = onboardSkip
+	}
+	return v, nil
+}
+
+// TakeChoice returns the selected option and resets it
+func (v *OnboardingView) TakeChoice() string {
+	choice := v.choice
+	v.choice = ""
+	return choice
+}
+
+// View renders the wizard
+func (v *OnboardingView) View() string {
+	header := BorderStyle.Width(60).Render(
+		HeaderStyle.Render(i18n.T("onboarding.title")),
+	)
+
+	intro := BodyStyle.Render(i18n.T("onboarding.intro"))
+
+	var rows []string
+	for i, opt := range v.options {
+		title := opt.title
+		if opt.id == onboardTheme {
+			title = i18n.T("onboarding.current_theme", title, GetCurrentThemeName())
+		}
+
+		if i == v.cursor {
+			rows = append(rows, SelectedStyle.Render(" › "+title))
+		} else {
+			rows = append(rows, NormalStyle.Render("   "+title))
+		}
+		rows = append(rows, HelpStyle.Render("     "+opt.desc))
+	}
+
+	body := BorderStyle.Width(60).Render(lipgloss.JoinVertical(lipgloss.Left, rows...))
+
+	footer := StatusBar(i18n.T("onboarding.help"))
+
+	return header + "\n\n" + intro + "\n\n" + body + "\n\n" + footer
+}
@@ -0,0 +1,168 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sshm/sshm/internal/models"
+)
+
+// pickerItem is a selectable value with the number of hosts carrying it
+type pickerItem struct {
+	value string
+	count int
+}
+
+// FilterPicker is a popup multi-select list used to choose filter values
+type FilterPicker struct {
+	title    string
+	items    []pickerItem
+	selected map[string]bool
+	cursor   int
+	done     bool // true once applied or cancelled
+	applied  bool // true if the selection should be used
+	single   bool // only one value can be selected
+	cleared  bool // selection was explicitly cleared with 'c'
+}
+
+// NewTagPicker creates a picker listing all tags of the given hosts with counts
+func NewTagPicker(hosts []models.Host, active []string) *FilterPicker {
+	counts := make(map[string]int)
+	for _, h := range hosts {
+		for _, t := range h.Tags {
+			counts[t]++
+		}
+	}
+	return newFilterPicker("Filter by tag", counts, active)
+}
+
+// NewGroupPicker creates a single-choice picker listing all groups with counts
+func NewGroupPicker(hosts []models.Host, active string) *FilterPicker {
+	counts := make(map[string]int)
+	for _, h := range hosts {
+		if h.Group != "" {
+			counts[h.Group]++
+		}
+	}
+	var activeGroups []string
+	if active != "" {
+		activeGroups = []string{active}
+	}
+	p := newFilterPicker("Filter by group", counts, activeGroups)
+	p.single = true
+	return p
+}
+
+func newFilterPicker(title string, counts map[string]int, active []string) *FilterPicker {
+	items := make([]pickerItem, 0, len(counts))
+	for value, count := range counts {
+		items = append(items, pickerItem{value: value, count: count})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return strings.ToLower(items[i].value) < strings.ToLower(items[j].value)
+	})
+
+	selected := make(map[string]bool)
+	for _, a := range active {
+		selected[a] = true
+	}
+
+	return &FilterPicker{
+		title:    title,
+		items:    items,
+		selected: selected,
+	}
+}
+
+// Init initializes the picker
+func (p *FilterPicker) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages
+func (p *FilterPicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.items)-1 {
+			p.cursor++
+		}
+	case " ", "x":
+		if p.cursor < len(p.items) {
+			value := p.items[p.cursor].value
+			if p.single {
+				selected := p.selected[value]
+				p.selected = make(map[string]bool)
+				p.selected[value] = !selected
+			} else {
+				p.selected[value] = !p.selected[value]
+			}
+		}
+	case "enter":
+		// Enter without an explicit selection picks the highlighted value,
+		// unless the selection was deliberately cleared
+		if len(p.Selected()) == 0 && !p.cleared && p.cursor < len(p.items) {
+			p.selected[p.items[p.cursor].value] = true
+		}
+		p.done = true
+		p.applied = true
+	case "c":
+		p.selected = make(map[string]bool)
+		p.cleared = true
+	case "esc", "q":
+		p.done = true
+	}
+	return p, nil
+}
+
+// Selected returns the selected values in display order
+func (p *FilterPicker) Selected() []string {
+	var values []string
+	for _, item := range p.items {
+		if p.selected[item.value] {
+			values = append(values, item.value)
+		}
+	}
+	return values
+}
+
+// View renders the picker
+func (p *FilterPicker) View() string {
+	title := lipgloss.NewStyle().
+		Foreground(primaryColor).
+		Bold(true).
+		Render(p.title)
+
+	var rows []string
+	if len(p.items) == 0 {
+		rows = append(rows, HelpStyle.Render("(nothing to filter by)"))
+	}
+	for i, item := range p.items {
+		check := "[ ]"
+		if p.selected[item.value] {
+			check = "[x]"
+		}
+		row := fmt.Sprintf("%s %s (%d)", check, item.value, item.count)
+		if i == p.cursor {
+			rows = append(rows, SelectedStyle.Render("› "+row))
+		} else {
+			rows = append(rows, NormalStyle.Render("  "+row))
+		}
+	}
+
+	help := HelpStyle.Render("space: toggle | c: clear | enter: apply | esc: cancel")
+
+	body := title + "\n" + strings.Join(rows, "\n") + "\n" + help
+	return BorderStyle.Padding(0, 1).Render(body)
+}
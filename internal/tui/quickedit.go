@@ -0,0 +1,151 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sshm/sshm/internal/i18n"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/store"
+)
+
+// quickEditFields maps list actions to the single field they edit inline
+var quickEditFields = map[Action]string{
+	ActionRename:   fieldName,
+	ActionEditUser: fieldUser,
+	ActionEditPort: fieldPort,
+}
+
+// QuickEditView is a small popup for editing one field of a host without the full form
+type QuickEditView struct {
+	store *store.FileStore
+	host  models.Host
+	field string
+	value string
+	err   string
+	done  bool // true once the edit was saved or cancelled
+	saved bool // true if the edit was written to the store
+}
+
+// NewQuickEditView creates a popup editing the given field of a host
+func NewQuickEditView(s *store.FileStore, host models.Host, field string) *QuickEditView {
+	v := &QuickEditView{
+		store: s,
+		host:  host,
+		field: field,
+	}
+
+	switch field {
+	case fieldName:
+		v.value = host.Name
+	case fieldUser:
+		v.value = host.User
+	case fieldPort:
+		v.value = strconv.Itoa(host.Port)
+	}
+
+	return v
+}
+
+// Init initializes the quick edit view
+func (v *QuickEditView) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages
+func (v *QuickEditView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+
+	switch keyMsg.Type {
+	case tea.KeyEsc:
+		v.done = true
+	case tea.KeyEnter:
+		v.save()
+	case tea.KeyBackspace, tea.KeyDelete, tea.KeyCtrlH:
+		if len(v.value) > 0 {
+			v.value = trimLastRune(v.value)
+		}
+	case tea.KeyCtrlU:
+		v.value = ""
+	case tea.KeyRunes, tea.KeySpace:
+		v.value += string(keyMsg.Runes)
+	}
+	return v, nil
+}
+
+// save validates the value and writes the updated host to the store
+func (v *QuickEditView) save() {
+	host := v.host
+
+	switch v.field {
+	case fieldName:
+		if v.value == "" {
+			v.err = i18n.T("form.name_required")
+			return
+		}
+		if len(v.value) > 50 {
+			v.err = i18n.T("form.name_too_long")
+			return
+		}
+		if other, err := v.store.GetHostByName(v.value); err == nil && other.ID != host.ID {
+			v.err = i18n.T("form.name_taken", v.value)
+			return
+		}
+		host.Name = v.value
+	case fieldUser:
+		// An empty user takes the default one
+		host.User = v.value
+	case fieldPort:
+		port, err := strconv.Atoi(v.value)
+		if err != nil {
+			v.err = i18n.T("form.port_not_number")
+			return
+		}
+		if port < 1 || port > 65535 {
+			v.err = i18n.T("form.port_range")
+			return
+		}
+		host.Port = port
+	}
+
+	if err := v.store.UpdateHost(host); err != nil {
+		v.err = i18n.T("quick.save_failed", err)
+		return
+	}
+
+	v.host = host
+	v.saved = true
+	v.done = true
+}
+
+// label returns the display label for the field being edited
+func (v *QuickEditView) label() string {
+	switch v.field {
+	case fieldName:
+		return i18n.T("quick.rename")
+	}
+	return i18n.T("field." + v.field)
+}
+
+// View renders the popup
+func (v *QuickEditView) View() string {
+	title := lipgloss.NewStyle().
+		Foreground(primaryColor).
+		Bold(true).
+		Render(fmt.Sprintf("%s %s", v.label(), v.host.Name))
+
+	input := InputStyle.Width(40).Render(v.value + "_")
+
+	body := title + "\n" + input
+	if v.err != "" {
+		body += "\n" + ErrorStyle.Render(v.err)
+	}
+	body += "\n" + HelpStyle.Render(i18n.T("quick.help"))
+
+	return BorderStyle.Padding(0, 1).Render(body)
+}
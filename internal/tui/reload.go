@@ -0,0 +1,190 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/i18n"
+	"github.com/sshm/sshm/internal/ssh"
+)
+
+// configPollInterval is how often the settings file is checked for changes
+const configPollInterval = time.Second
+
+// configCheckMsg asks the app to look for changes to the settings file
+type configCheckMsg struct{}
+
+// fileStamp identifies a version of a file; a missing file has the zero stamp
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// watchConfig schedules the next check of the settings file
+func watchConfig() tea.Cmd {
+	return tea.Tick(configPollInterval, func(time.Time) tea.Msg {
+		return configCheckMsg{}
+	})
+}
+
+// checkConfig reloads the settings if the file changed since it was last
+// read and schedules the next check
+func (m *App) checkConfig() tea.Cmd {
+	stamp := stampFile(m.paths.Config)
+	if stamp == m.configStamp {
+		return tea.Batch(m.checkIncludes(), watchConfig())
+	}
+	m.configStamp = stamp
+	return tea.Batch(m.reloadConfig(), watchConfig())
+}
+
+// includesCheckedMsg carries the stamps of the included files, taken in the
+// background
+type includesCheckedMsg struct {
+	stamps map[string]fileStamp
+}
+
+// stampFiles returns the stamps of files
+func stampFiles(paths []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		stamps[path] = stampFile(path)
+	}
+	return stamps
+}
+
+// checkIncludes stats the included files in the background once
+// include_refresh has passed since the last look
+func (m *App) checkIncludes() tea.Cmd {
+	interval := m.listView.config.IncludeRefreshInterval()
+	if interval == 0 || m.checkingIncludes || time.Since(m.includesChecked) < interval {
+		return nil
+	}
+	m.checkingIncludes = true
+	paths := m.store.IncludedFiles()
+	return func() tea.Msg {
+		return includesCheckedMsg{stamps: stampFiles(paths)}
+	}
+}
+
+// applyIncludes reloads the hosts when an included file changed, keeping
+// the selected host and the filters of the list
+// Files newly matching an include pattern show up with the next change to
+// the settings or to a file already included.
+func (m *App) applyIncludes(msg includesCheckedMsg) {
+	m.checkingIncludes = false
+	m.includesChecked = time.Now()
+	if m.includeStamps != nil && !reflect.DeepEqual(msg.stamps, m.includeStamps) {
+		m.store.Reload()
+		m.listView.Refresh()
+		msg.stamps = stampFiles(m.store.IncludedFiles())
+	}
+	m.includeStamps = msg.stamps
+}
+
+// reloadConfig applies the theme, key bindings, defaults and other list
+// settings of the settings file to the running app
+// A file with errors is not applied at all. Hosts are left to the store, so
+// saving hosts into a shared settings file reloads nothing.
+func (m *App) reloadConfig() tea.Cmd {
+	path := m.paths.Config
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return m.notify(ToastError, i18n.T("toast.reload_failed", err))
+	}
+	var errors []config.Problem
+	for _, p := range config.Validate(path, data) {
+		if !p.Warning {
+			errors = append(errors, p)
+		}
+	}
+	if len(errors) > 0 {
+		if len(errors) > 1 {
+			return m.notify(ToastError, i18n.T("toast.reload_invalid_more", errors[0].String(), len(errors)-1))
+		}
+		return m.notify(ToastError, i18n.T("toast.reload_failed", errors[0].String()))
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return m.notify(ToastError, i18n.T("toast.reload_failed", err))
+	}
+
+	old := m.listView.config
+	var reloaded, failed []string
+	if cfg.Theme != old.Theme || !reflect.DeepEqual(cfg.Themes, old.Themes) {
+		reloaded = append(reloaded, "theme")
+		if err := InitTheme(cfg.Theme, cfg.Themes); err != nil {
+			failed = append(failed, fmt.Sprintf("theme: %v", err))
+		}
+	}
+	if !reflect.DeepEqual(cfg.Keybindings, old.Keybindings) {
+		reloaded = append(reloaded, "key bindings")
+		if err := InitKeymap(cfg.Keybindings); err != nil {
+			failed = append(failed, fmt.Sprintf("ignoring keybindings: %v", err))
+		}
+	}
+	if cfg.Language != old.Language {
+		reloaded = append(reloaded, "language")
+		if err := i18n.Init(cfg.Language); err != nil {
+			failed = append(failed, fmt.Sprintf("language: %v", err))
+		}
+	}
+	if !reflect.DeepEqual(cfg.Defaults, old.Defaults) || !reflect.DeepEqual(cfg.Include, old.Include) {
+		reloaded = append(reloaded, "defaults")
+		if err := m.store.Reload(); err != nil {
+			failed = append(failed, err.Error())
+		}
+		m.includeStamps = nil
+		m.listView.Refresh()
+	}
+	if cfg.Presentation != old.Presentation {
+		reloaded = append(reloaded, "presentation mode")
+		m.listView.SetPresenting(cfg.Presentation)
+	}
+	if !reflect.DeepEqual(listSettings(cfg), listSettings(old)) {
+		reloaded = append(reloaded, "settings")
+	}
+	m.listView.SetConfig(cfg)
+	ssh.Vault = cfg.VaultClient()
+	ssh.KeyringPasswords = cfg.KeyringPasswords
+	ssh.WindowsKeys = cfg.WSL.WindowsKeys
+	ssh.WindowsAgentRelay = cfg.WSL.AgentRelay()
+
+	switch {
+	case len(failed) > 0:
+		return m.notify(ToastError, i18n.T("toast.reload_errors", strings.Join(failed, "; ")))
+	case len(reloaded) > 0:
+		return m.notify(ToastSuccess, i18n.T("toast.reloaded", strings.Join(reloaded, ", ")))
+	}
+	return nil
+}
+
+// listSettings returns the settings other than hosts that reloadConfig
+// doesn't apply itself, for comparison
+func listSettings(cfg *config.Config) config.Config {
+	return config.Config{
+		Profiles:         cfg.Profiles,
+		Configs:          cfg.Configs,
+		GuardedTags:      cfg.GuardedTags,
+		BastionPolicy:    cfg.BastionPolicy,
+		Columns:          cfg.Columns,
+		Vault:            cfg.Vault,
+		KeyringPasswords: cfg.KeyringPasswords,
+		Snippets:         cfg.Snippets,
+		IdleLock:         cfg.IdleLock,
+		HealthCheck:      cfg.HealthCheck,
+	}
+}
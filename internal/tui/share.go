@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sshm/sshm/internal/i18n"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/qr"
+)
+
+// quietZone is the light border around the QR code scanners need
+const quietZone = 2
+
+// ShareView shows a host as an sshm:// link and its QR code, for a
+// teammate to scan or to paste into "sshm import-uri"
+type ShareView struct {
+	host models.Host
+	uri  string
+	code *qr.Code // nil when the link doesn't fit a QR code
+}
+
+// NewShareView creates a view sharing host
+func NewShareView(host models.Host) *ShareView {
+	v := &ShareView{host: host, uri: host.URI()}
+	v.code, _ = qr.Encode([]byte(v.uri))
+	return v
+}
+
+// URI returns the link to the host
+func (v *ShareView) URI() string {
+	return v.uri
+}
+
+// View renders the link, and the QR code when the terminal is large enough
+// for it; 0 means the size is unknown
+func (v *ShareView) View(width, height int) string {
+	title := lipgloss.NewStyle().Foreground(primaryColor).Bold(true).
+		Render(i18n.T("share.title", v.host.Name))
+	textWidth := max(width-4, 40)
+
+	var code string
+	switch {
+	case v.code == nil:
+		code = HelpStyle.Render(i18n.T("share.too_long"))
+	case width > 0 && v.code.Size+2*quietZone > width-4,
+		height > 0 && (v.code.Size+2*quietZone+1)/2 > height-8:
+		code = HelpStyle.Render(i18n.T("share.too_small"))
+	default:
+		// Light on dark whatever the theme, the way scanners expect it
+		style := lipgloss.NewStyle().Foreground(lipgloss.Color("15")).Background(lipgloss.Color("0"))
+		lines := v.code.Blocks(quietZone)
+		for i, line := range lines {
+			lines[i] = style.Render(line)
+		}
+		code = strings.Join(lines, "\n")
+	}
+
+	command := "sshm import-uri '" + v.uri + "'"
+	body := title + "\n\n" + code + "\n\n" +
+		lipgloss.NewStyle().Foreground(secondaryColor).Width(textWidth).Render(i18n.T("share.scan")) + "\n" +
+		lipgloss.NewStyle().Foreground(successColor).Width(textWidth).Render(command)
+	help := StatusBar(i18n.T("share.help", keymap.Key(ActionCopy), keymap.Key(ActionBack)))
+	return BorderStyle.Padding(0, 1).Render(body) + "\n" + help
+}
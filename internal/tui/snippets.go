@@ -0,0 +1,166 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sshm/sshm/internal/i18n"
+	"github.com/sshm/sshm/internal/models"
+)
+
+// SnippetPicker is a popup for choosing a snippet to run on a host and
+// filling in its parameters, one at a time
+type SnippetPicker struct {
+	host     models.Host
+	snippets []models.Snippet
+	cursor   int
+	chosen   *models.Snippet // snippet whose parameters are being asked for
+	params   []models.SnippetParam
+	values   map[string]string
+	param    int    // index of the parameter being edited
+	value    string // input of the current parameter
+	err      string
+	done     bool   // true once a command is ready or the picker was cancelled
+	command  string // expanded command to run, empty when cancelled
+}
+
+// NewSnippetPicker creates a picker listing the snippets for host
+func NewSnippetPicker(host models.Host, snippets []models.Snippet) *SnippetPicker {
+	return &SnippetPicker{host: host, snippets: snippets}
+}
+
+// Init initializes the picker
+func (p *SnippetPicker) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages
+func (p *SnippetPicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+	if p.chosen != nil {
+		p.updateParam(keyMsg)
+		return p, nil
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.snippets)-1 {
+			p.cursor++
+		}
+	case "enter":
+		if p.cursor < len(p.snippets) {
+			p.choose(p.snippets[p.cursor])
+		}
+	case "esc", "q":
+		p.done = true
+	}
+	return p, nil
+}
+
+// choose starts asking for the parameters of a snippet, or expands it
+// right away when it has none
+func (p *SnippetPicker) choose(s models.Snippet) {
+	p.chosen = &s
+	p.params = s.Params()
+	p.values = make(map[string]string, len(p.params))
+	p.param = 0
+	p.startParam()
+}
+
+// startParam prefills the input with the current parameter's default, or
+// expands the command once every parameter has a value
+func (p *SnippetPicker) startParam() {
+	if p.param < len(p.params) {
+		p.value = p.params[p.param].Default
+		return
+	}
+	command, err := p.chosen.Expand(p.values)
+	if err != nil {
+		p.err = err.Error()
+		return
+	}
+	p.command = command
+	p.done = true
+}
+
+// updateParam edits the value of the current parameter
+func (p *SnippetPicker) updateParam(keyMsg tea.KeyMsg) {
+	switch keyMsg.Type {
+	case tea.KeyEsc:
+		// Back to the list of snippets
+		p.chosen = nil
+		p.err = ""
+	case tea.KeyEnter:
+		p.values[p.params[p.param].Name] = p.value
+		p.param++
+		p.startParam()
+	case tea.KeyBackspace, tea.KeyDelete, tea.KeyCtrlH:
+		if len(p.value) > 0 {
+			p.value = trimLastRune(p.value)
+		}
+	case tea.KeyCtrlU:
+		p.value = ""
+	case tea.KeyRunes, tea.KeySpace:
+		p.value += string(keyMsg.Runes)
+	}
+}
+
+// Snippet returns the chosen snippet, or nil before one was chosen
+func (p *SnippetPicker) Snippet() *models.Snippet {
+	return p.chosen
+}
+
+// View renders the picker
+func (p *SnippetPicker) View() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(primaryColor).
+		Bold(true)
+
+	if p.chosen != nil && p.param < len(p.params) {
+		title := titleStyle.Render(i18n.T("snippets.param_title", p.chosen.Name, p.host.Name))
+		label := fmt.Sprintf("%s (%d/%d)", p.params[p.param].Name, p.param+1, len(p.params))
+		input := InputStyle.Width(40).Render(p.value + "_")
+		body := title + "\n" + label + "\n" + input
+		if p.err != "" {
+			body += "\n" + ErrorStyle.Render(p.err)
+		}
+		body += "\n" + HelpStyle.Render(i18n.T("snippets.param_help"))
+		return BorderStyle.Padding(0, 1).Render(body)
+	}
+
+	title := titleStyle.Render(i18n.T("snippets.title", p.host.Name))
+
+	var rows []string
+	if len(p.snippets) == 0 {
+		rows = append(rows, HelpStyle.Render(i18n.T("snippets.none")))
+	}
+	for i, s := range p.snippets {
+		description := s.Description
+		if description == "" {
+			description = s.Command
+		}
+		row := fmt.Sprintf("%s  %s", s.Name, HelpStyle.Render(description))
+		if i == p.cursor {
+			rows = append(rows, SelectedStyle.Render("› ")+row)
+		} else {
+			rows = append(rows, NormalStyle.Render("  ")+row)
+		}
+	}
+	if p.err != "" {
+		rows = append(rows, ErrorStyle.Render(p.err))
+	}
+
+	help := HelpStyle.Render(i18n.T("snippets.help"))
+
+	body := title + "\n" + strings.Join(rows, "\n") + "\n" + help
+	return BorderStyle.Padding(0, 1).Render(body)
+}
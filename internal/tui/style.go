@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/charmbracelet/lipgloss"
@@ -10,17 +12,36 @@ import (
 // ThemeManager handles theme switching and provides theme-aware styles
 type ThemeManager struct {
 	current *theme.Theme
+	themes  map[string]*theme.Theme // presets and custom themes by name
+	names   []string                // theme names in switching order
 	mu      sync.RWMutex
 }
 
 // Global theme manager instance
 var themeManager = &ThemeManager{
 	current: &theme.DarkTheme,
+	themes:  theme.Presets,
+	names:   []string{"dark", "light"},
 }
 
-// InitTheme initializes the theme manager with saved preference
-func InitTheme(savedTheme string) {
-	themeManager.SetTheme(savedTheme)
+// InitTheme loads the custom themes and activates the named one
+// It can be called again to reload the themes while the TUI runs. Custom
+// themes that fail to build and unknown names are reported; the dark theme
+// stands in for an unknown name.
+func InitTheme(name string, custom map[string]theme.Custom) error {
+	themes, names, err := theme.Resolve(custom)
+	themeManager.mu.Lock()
+	themeManager.themes = themes
+	themeManager.names = names
+	themeManager.mu.Unlock()
+
+	if name == "" {
+		name = "dark"
+	}
+	if themeManager.SetTheme(name) != name {
+		err = errors.Join(err, fmt.Errorf("unknown theme %q", name))
+	}
+	return err
 }
 
 // GetTheme returns the current theme
@@ -33,13 +54,21 @@ func SetTheme(name string) string {
 	return themeManager.SetTheme(name)
 }
 
-// ToggleTheme toggles between light and dark themes
+// ToggleTheme switches to the next theme: dark, light, then custom themes
 func ToggleTheme() string {
-	current := themeManager.GetCurrent()
-	if current.Name == "dark" {
-		return themeManager.SetTheme("light")
+	themeManager.mu.RLock()
+	names := themeManager.names
+	themeManager.mu.RUnlock()
+
+	current := themeManager.GetCurrent().Name
+	next := names[0]
+	for i, name := range names {
+		if name == current {
+			next = names[(i+1)%len(names)]
+			break
+		}
 	}
-	return themeManager.SetTheme("dark")
+	return themeManager.SetTheme(next)
 }
 
 // GetCurrentThemeName returns the name of the current theme
@@ -49,11 +78,11 @@ func GetCurrentThemeName() string {
 
 // SetTheme sets the current theme
 func (tm *ThemeManager) SetTheme(name string) string {
-	t := theme.GetTheme(name)
-	if t == nil {
-		t = &theme.DarkTheme
-	}
 	tm.mu.Lock()
+	t, ok := tm.themes[name]
+	if !ok {
+		t = tm.themes["dark"]
+	}
 	tm.current = t
 	tm.mu.Unlock()
 	// Update global style variables
@@ -83,7 +112,8 @@ func updateStyles(t *theme.Theme) {
 	updateStyleFuncs(t)
 }
 
-// updateStyleFuncs updates the style functions with the current theme
+// updateStyleFuncs updates the style functions with the current theme and
+// applies the style overrides of custom themes
 func updateStyleFuncs(t *theme.Theme) {
 	TitleStyle = lipgloss.NewStyle().
 		Foreground(t.Primary).
@@ -123,6 +153,25 @@ func updateStyleFuncs(t *theme.Theme) {
 	ErrorStyle = lipgloss.NewStyle().
 		Foreground(t.Error).
 		Bold(true)
+
+	o := t.Styles
+	TitleStyle = o.Title.Apply(TitleStyle)
+	HeaderStyle = o.Header.Apply(HeaderStyle)
+	BodyStyle = o.Body.Apply(BodyStyle)
+	HelpStyle = o.Help.Apply(HelpStyle)
+	SelectedStyle = o.Selected.Apply(SelectedStyle)
+	NormalStyle = o.Normal.Apply(NormalStyle)
+	InputStyle = o.Input.Apply(InputStyle)
+	ErrorStyle = o.Error.Apply(ErrorStyle)
+	if o.Border != nil {
+		// The border style draws no text, its foreground colors the border
+		border := *o.Border
+		if border.Foreground != "" {
+			BorderStyle = BorderStyle.BorderForeground(lipgloss.Color(border.Foreground))
+			border.Foreground = ""
+		}
+		BorderStyle = border.Apply(BorderStyle)
+	}
 }
 
 // GetStatusColors returns the status colors for the current theme
@@ -167,9 +216,9 @@ func init() {
 // StatusBar returns a status bar with given text
 func StatusBar(text string) string {
 	t := themeManager.GetCurrent()
-	return lipgloss.NewStyle().
+	style := lipgloss.NewStyle().
 		Background(t.Surface).
 		Foreground(t.Secondary).
-		Padding(0, 1).
-		Render(text)
+		Padding(0, 1)
+	return t.Styles.StatusBar.Apply(style).Render(text)
 }
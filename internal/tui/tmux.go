@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/i18n"
+	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/tmux"
+)
+
+// maxPanes bounds the hosts split into panes at once; more don't fit a
+// window
+const maxPanes = 16
+
+// tmuxOpenedMsg reports the outcome of opening hosts in tmux
+type tmuxOpenedMsg struct {
+	hosts []models.Host
+	where string // config.TmuxWindow or config.TmuxPane, "" for split panes
+	err   error
+}
+
+// sshmCommand returns the command line running sshm on the files at paths,
+// and the environment it needs to find them, for new tmux windows
+// These start from the tmux session's environment, not sshm's.
+func sshmCommand(paths config.Paths) (argv, env []string) {
+	self, err := os.Executable()
+	if err != nil {
+		self = "sshm"
+	}
+	argv = []string{self}
+	if paths.Config == paths.Hosts {
+		argv = append(argv, "--config", paths.Config)
+	}
+	for _, name := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME"} {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return argv, env
+}
+
+// tmuxCommand returns the command connecting to host in a window or pane
+func (v *ListView) tmuxCommand(host models.Host) tmux.Command {
+	argv := append(append([]string(nil), v.sshm...), "connect", "--exact", host.ID)
+	return tmux.Command{Title: host.Name, Argv: argv, Env: v.sshmEnv}
+}
+
+// tmuxTarget returns where connecting opens hosts: a tmux window or pane
+// when the settings ask for it and sshm runs inside tmux, "" in place
+func (v *ListView) tmuxTarget() string {
+	if v.config.Tmux.Open == "" || v.sshm == nil || !tmux.Inside() {
+		return ""
+	}
+	return v.config.Tmux.Open
+}
+
+// openInTmux connects to host in a new tmux window or pane
+func (v *ListView) openInTmux(host models.Host, where string) tea.Cmd {
+	c := v.tmuxCommand(host)
+	return func() tea.Msg {
+		var err error
+		if where == config.TmuxPane {
+			err = tmux.SplitPane(c)
+		} else {
+			err = tmux.NewWindow(c)
+		}
+		return tmuxOpenedMsg{hosts: []models.Host{host}, where: where, err: err}
+	}
+}
+
+// splitListed checks that every listed host can be connected to in a pane
+// of its own, asking to confirm first when some are guarded
+func (v *ListView) splitListed() tea.Cmd {
+	hosts := v.filtered
+	switch {
+	case len(hosts) == 0:
+		return nil
+	case v.sshm == nil || !tmux.Inside():
+		v.connectErr = i18n.T("tmux.not_inside")
+		return nil
+	case len(hosts) > maxPanes:
+		v.connectErr = i18n.T("tmux.too_many", len(hosts), maxPanes)
+		return nil
+	}
+	for _, host := range hosts {
+		if err := v.config.CheckBastion(host); err != nil {
+			v.connectErr = err.Error()
+			return nil
+		}
+	}
+	for _, host := range hosts {
+		if tag := v.config.GuardedTag(host); tag != "" {
+			v.pendingConnect = &host
+			v.pendingTag = tag
+			v.pendingSplit = hosts
+			return nil
+		}
+	}
+	return v.split(hosts)
+}
+
+// split connects to hosts in the panes of a new tmux window
+func (v *ListView) split(hosts []models.Host) tea.Cmd {
+	v.connectErr = ""
+	commands := make([]tmux.Command, len(hosts))
+	for i, host := range hosts {
+		commands[i] = v.tmuxCommand(host)
+	}
+	title := fmt.Sprintf("sshm (%d)", len(hosts))
+	layout := v.config.Tmux.PaneLayout()
+	return func() tea.Msg {
+		return tmuxOpenedMsg{hosts: hosts, err: tmux.Tile(title, commands, layout)}
+	}
+}
+
+// describe returns the notification for the outcome
+func (m tmuxOpenedMsg) describe() (ToastKind, string) {
+	if m.err != nil {
+		return ToastError, i18n.T("tmux.failed", m.err)
+	}
+	switch {
+	case m.where == config.TmuxPane:
+		return ToastInfo, i18n.T("tmux.opened_pane", m.hosts[0].Name)
+	case m.where == config.TmuxWindow:
+		return ToastInfo, i18n.T("tmux.opened_window", m.hosts[0].Name)
+	}
+	return ToastInfo, i18n.T("tmux.opened_split", len(m.hosts))
+}
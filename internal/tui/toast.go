@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ToastKind represents the severity of a notification
+type ToastKind int
+
+const (
+	ToastInfo ToastKind = iota
+	ToastSuccess
+	ToastError
+)
+
+// toastDuration is how long a notification stays visible
+const toastDuration = 4 * time.Second
+
+// maxToasts is the maximum number of notifications shown at once
+const maxToasts = 3
+
+// toast is a single notification
+type toast struct {
+	id   int
+	kind ToastKind
+	text string
+}
+
+// toastExpiredMsg signals that a notification should be dismissed
+type toastExpiredMsg struct {
+	id int
+}
+
+// Toasts manages auto-dismissing notifications shared by all views
+type Toasts struct {
+	items  []toast
+	nextID int
+}
+
+// NewToasts creates an empty notification queue
+func NewToasts() *Toasts {
+	return &Toasts{}
+}
+
+// Push adds a notification and returns a command that dismisses it later
+func (t *Toasts) Push(kind ToastKind, text string) tea.Cmd {
+	t.nextID++
+	id := t.nextID
+	t.items = append(t.items, toast{id: id, kind: kind, text: text})
+	if len(t.items) > maxToasts {
+		t.items = t.items[len(t.items)-maxToasts:]
+	}
+
+	return tea.Tick(toastDuration, func(time.Time) tea.Msg {
+		return toastExpiredMsg{id: id}
+	})
+}
+
+// Dismiss removes the notification with the given id
+func (t *Toasts) Dismiss(id int) {
+	for i, item := range t.items {
+		if item.id == id {
+			t.items = append(t.items[:i], t.items[i+1:]...)
+			return
+		}
+	}
+}
+
+// Empty returns whether there are no visible notifications
+func (t *Toasts) Empty() bool {
+	return len(t.items) == 0
+}
+
+// View renders the visible notifications, newest last
+func (t *Toasts) View() string {
+	if t.Empty() {
+		return ""
+	}
+
+	th := GetTheme()
+	var rows []string
+	for _, item := range t.items {
+		icon := "ℹ"
+		color := th.Primary
+		switch item.kind {
+		case ToastSuccess:
+			icon = "✓"
+			color = th.Success
+		case ToastError:
+			icon = "✗"
+			color = th.Error
+		}
+
+		rows = append(rows, lipgloss.NewStyle().
+			Foreground(color).
+			Background(th.Surface).
+			Bold(true).
+			Padding(0, 1).
+			Render(icon+" "+item.text))
+	}
+
+	return strings.Join(rows, "\n")
+}
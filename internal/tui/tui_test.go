@@ -1,12 +1,40 @@
 package tui
 
 import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sshm/sshm/internal/config"
+	"github.com/sshm/sshm/internal/editor"
+	"github.com/sshm/sshm/internal/i18n"
 	"github.com/sshm/sshm/internal/models"
+	"github.com/sshm/sshm/internal/secret"
 	"github.com/sshm/sshm/internal/store"
+	"github.com/sshm/sshm/internal/theme"
 )
 
+// TestMain runs the tests in English, whatever the locale
+// openTestStore opens the store at path, failing the test on errors
+func openTestStore(t *testing.T, path string) *store.FileStore {
+	t.Helper()
+	s, err := store.NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	return s
+}
+
+func TestMain(m *testing.M) {
+	os.Setenv("LC_ALL", "C")
+	os.Exit(m.Run())
+}
+
 func TestTagColors(t *testing.T) {
 	// Test that predefined tag colors exist
 	expectedTags := []string{"production", "staging", "development", "local", "database", "web", "backup", "storage", "admin", "default"}
@@ -20,7 +48,7 @@ func TestTagColors(t *testing.T) {
 
 func TestGetHistoryStatsForHost(t *testing.T) {
 	// Test with empty store and history
-	fileStore := store.NewFileStore("")
+	fileStore := openTestStore(t, "")
 	historyStore := store.NewHistoryStore("")
 	stats := GetHistoryStatsForHost(fileStore, historyStore, "test-id")
 	if stats.TotalConnections != 0 {
@@ -49,3 +77,794 @@ func TestAuthType(t *testing.T) {
 		t.Errorf("AuthTypeAgent should be 'agent'")
 	}
 }
+
+func TestQuickEditSavesField(t *testing.T) {
+	fileStore := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	host := models.Host{ID: "1", Name: "web", Host: "10.0.0.1", Port: 22, User: "admin"}
+	if err := fileStore.AddHost(host); err != nil {
+		t.Fatalf("AddHost failed: %v", err)
+	}
+
+	v := NewQuickEditView(fileStore, host, fieldPort)
+	v.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2222")})
+	v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if !v.saved {
+		t.Fatalf("expected quick edit to save, got error %q", v.err)
+	}
+	updated, _ := fileStore.GetHost("1")
+	if updated.Port != 2222 {
+		t.Errorf("expected port 2222, got %d", updated.Port)
+	}
+}
+
+func TestQuickEditRejectsInvalidPort(t *testing.T) {
+	fileStore := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	host := models.Host{ID: "1", Name: "web", Host: "10.0.0.1", Port: 22, User: "admin"}
+	fileStore.AddHost(host)
+
+	v := NewQuickEditView(fileStore, host, fieldPort)
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("99999")})
+	v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if v.saved || v.err == "" {
+		t.Error("expected invalid port to be rejected")
+	}
+}
+
+func TestQuickEditRename(t *testing.T) {
+	fileStore := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	web := models.Host{ID: "1", Name: "web", Host: "10.0.0.1", Port: 22, User: "admin"}
+	fileStore.AddHost(web)
+	fileStore.AddHost(models.Host{ID: "2", Name: "db", Host: "10.0.0.2", Port: 22, User: "admin"})
+
+	v := NewQuickEditView(fileStore, web, fieldName)
+	v.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("db")})
+	v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if v.saved || v.err == "" {
+		t.Error("expected a rename to an existing name to be rejected")
+	}
+
+	// Keeping its own name is fine
+	v = NewQuickEditView(fileStore, web, fieldName)
+	v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !v.saved {
+		t.Errorf("expected the host to keep its name, got error %q", v.err)
+	}
+}
+
+func TestQuickEditClearsUser(t *testing.T) {
+	fileStore := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	host := models.Host{ID: "1", Name: "web", Host: "10.0.0.1", Port: 22, User: "admin"}
+	fileStore.AddHost(host)
+
+	v := NewQuickEditView(fileStore, host, fieldUser)
+	v.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !v.saved {
+		t.Fatalf("expected an empty user to be saved, got error %q", v.err)
+	}
+	if updated, _ := fileStore.GetHost("1"); updated.User != "" {
+		t.Errorf("expected the user to be cleared, got %q", updated.User)
+	}
+}
+
+func TestQuickEditTranslated(t *testing.T) {
+	if err := i18n.Init("de"); err != nil {
+		t.Fatal(err)
+	}
+	defer i18n.Init(i18n.Fallback)
+
+	fileStore := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	host := models.Host{ID: "1", Name: "web", Host: "10.0.0.1", Port: 22}
+	fileStore.AddHost(host)
+
+	v := NewQuickEditView(fileStore, host, fieldPort)
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if out := v.View(); !strings.Contains(out, "Port muss eine Zahl sein") || !strings.Contains(out, "Enter: speichern") {
+		t.Errorf("expected the popup in German, got:\n%s", out)
+	}
+}
+
+func TestToastsPushAndDismiss(t *testing.T) {
+	toasts := NewToasts()
+	if !toasts.Empty() {
+		t.Fatal("expected no toasts initially")
+	}
+
+	for i := 0; i < maxToasts+2; i++ {
+		if cmd := toasts.Push(ToastInfo, "message"); cmd == nil {
+			t.Fatal("expected Push to return a dismiss command")
+		}
+	}
+	if len(toasts.items) != maxToasts {
+		t.Errorf("expected %d visible toasts, got %d", maxToasts, len(toasts.items))
+	}
+
+	for _, item := range append([]toast(nil), toasts.items...) {
+		toasts.Dismiss(item.id)
+	}
+	if !toasts.Empty() {
+		t.Error("expected all toasts to be dismissed")
+	}
+}
+
+func TestOnboardingChoice(t *testing.T) {
+	v := NewOnboardingView()
+	v.Update(tea.KeyMsg{Type: tea.KeyDown})
+	v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if choice := v.TakeChoice(); choice != onboardAdd {
+		t.Errorf("expected choice %q, got %q", onboardAdd, choice)
+	}
+	if choice := v.TakeChoice(); choice != "" {
+		t.Errorf("expected choice to be reset, got %q", choice)
+	}
+
+	v.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if choice := v.TakeChoice(); choice != onboardSkip {
+		t.Errorf("expected esc to skip, got %q", choice)
+	}
+}
+
+func TestTagPickerCountsAndSelection(t *testing.T) {
+	hosts := []models.Host{
+		{ID: "1", Name: "web-1", Tags: []string{"web", "prod"}},
+		{ID: "2", Name: "db-1", Tags: []string{"db", "prod"}},
+	}
+
+	p := NewTagPicker(hosts, []string{"db"})
+	if len(p.items) != 3 {
+		t.Fatalf("expected 3 tags, got %d", len(p.items))
+	}
+	for _, item := range p.items {
+		if item.value == "prod" && item.count != 2 {
+			t.Errorf("expected prod count 2, got %d", item.count)
+		}
+	}
+
+	// Items are sorted: db, prod, web - toggle prod and apply
+	p.Update(tea.KeyMsg{Type: tea.KeyDown})
+	p.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	selected := p.Selected()
+	if !p.applied || len(selected) != 2 || selected[0] != "db" || selected[1] != "prod" {
+		t.Errorf("expected [db prod] applied, got %v (applied=%v)", selected, p.applied)
+	}
+}
+
+func TestListTagFilters(t *testing.T) {
+	fileStore := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	fileStore.AddHost(models.Host{ID: "1", Name: "web-1", Tags: []string{"web", "prod"}})
+	fileStore.AddHost(models.Host{ID: "2", Name: "db-1", Tags: []string{"db", "prod"}})
+
+	v := NewListView(fileStore)
+	v.SetTagFilters([]string{"prod", "web"})
+	if len(v.filtered) != 1 || v.filtered[0].Name != "web-1" {
+		t.Errorf("expected only web-1, got %v", v.filtered)
+	}
+
+	v.SetTagFilters(nil)
+	if len(v.filtered) != 2 {
+		t.Errorf("expected 2 hosts without filters, got %d", len(v.filtered))
+	}
+}
+
+func TestConfiguredColumns(t *testing.T) {
+	v := NewListView(openTestStore(t, filepath.Join(t.TempDir(), "hosts.json")))
+
+	cols := v.columns()
+	if len(cols) != len(defaultColumns) {
+		t.Errorf("expected %d default columns, got %d", len(defaultColumns), len(cols))
+	}
+
+	v.SetConfig(&config.Config{Columns: []string{"latency", "bogus", "Name"}})
+	cols = v.columns()
+	if len(cols) != 2 || cols[0].id != columnLatency || cols[1].id != columnName {
+		t.Errorf("expected [latency name], got %v", cols)
+	}
+}
+
+func TestIdentityMissingCached(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "id_deploy")
+	fileStore := openTestStore(t, filepath.Join(dir, "hosts.json"))
+	fileStore.AddHost(models.Host{ID: "1", Name: "web", Host: "10.0.0.1", Port: 22, Identity: key})
+	v := NewListView(fileStore)
+	host := v.hosts[0]
+
+	if !v.identityMissing(host) {
+		t.Fatal("expected the identity file to be missing")
+	}
+	if err := os.WriteFile(key, []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !v.identityMissing(host) {
+		t.Error("expected the result to be cached while the host stays selected")
+	}
+	v.Refresh()
+	if v.identityMissing(host) {
+		t.Error("expected a refresh to check the identity file again")
+	}
+}
+
+func TestTruncateAndPad(t *testing.T) {
+	if got := truncate("database-primary", 10); got != "database.." {
+		t.Errorf("truncate() = %q", got)
+	}
+	if got := truncate("web", 10); got != "web" {
+		t.Errorf("truncate() = %q", got)
+	}
+	if got := padRight("web", 5); got != "web  " {
+		t.Errorf("padRight() = %q", got)
+	}
+
+	// Wide characters take two cells
+	if got := truncate("東京サーバー", 7); got != "東京.." {
+		t.Errorf("truncate() = %q", got)
+	}
+	if got := padRight(truncate("🚀-deploy", 6), 6); got != "🚀-d.." {
+		t.Errorf("truncate() = %q", got)
+	}
+	if got := padRight("東京", 5); got != "東京 " {
+		t.Errorf("padRight() = %q", got)
+	}
+	if got := trimLastRune("web-東"); got != "web-" {
+		t.Errorf("trimLastRune() = %q", got)
+	}
+}
+
+func TestStackedFilters(t *testing.T) {
+	fileStore := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	fileStore.AddHost(models.Host{ID: "1", Name: "web-eu", Group: "prod", Tags: []string{"web"}})
+	fileStore.AddHost(models.Host{ID: "2", Name: "db-eu", Group: "prod", Tags: []string{"db"}})
+	fileStore.AddHost(models.Host{ID: "3", Name: "db-us", Group: "prod", Tags: []string{"db"}})
+	fileStore.AddHost(models.Host{ID: "4", Name: "db-eu-dev", Group: "dev", Tags: []string{"db"}})
+
+	v := NewListView(fileStore)
+	v.SetGroupFilter("prod")
+	v.SetTagFilters([]string{"db"})
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if len(v.filters) != 3 {
+		t.Fatalf("expected 3 stacked filters, got %d", len(v.filters))
+	}
+	if len(v.filtered) != 1 || v.filtered[0].Name != "db-eu" {
+		t.Errorf("expected only db-eu, got %v", v.filtered)
+	}
+
+	// Popping the text filter widens the result to all prod db hosts
+	if !v.PopFilter() {
+		t.Fatal("expected PopFilter to remove a filter")
+	}
+	if len(v.filtered) != 2 {
+		t.Errorf("expected 2 hosts after pop, got %d", len(v.filtered))
+	}
+
+	v.PopFilter()
+	v.PopFilter()
+	if v.PopFilter() {
+		t.Error("expected PopFilter to report an empty stack")
+	}
+	if len(v.filtered) != 4 {
+		t.Errorf("expected all hosts without filters, got %d", len(v.filtered))
+	}
+}
+
+func TestPickModeSelectsHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	fileStore := openTestStore(t, path)
+	fileStore.AddHost(models.Host{ID: "1", Name: "alpha", Host: "10.0.0.1", Port: 22})
+	fileStore.AddHost(models.Host{ID: "2", Name: "beta", Host: "10.0.0.2", Port: 22})
+
+	app, err := New(config.SingleFile(path))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	app.pickMode = true
+
+	// Destructive actions are disabled while picking
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if app.store.Count() != 2 {
+		t.Fatalf("expected delete to be disabled in pick mode")
+	}
+
+	app.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, cmd := app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Error("expected picking to quit")
+	}
+	if app.picked == nil || app.picked.Name != "beta" {
+		t.Errorf("expected beta to be picked, got %v", app.picked)
+	}
+}
+
+func TestApplyRawEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	fileStore := openTestStore(t, path)
+	fileStore.AddHost(models.Host{ID: "1", Name: "alpha", Host: "10.0.0.1", Port: 22})
+	fileStore.AddHost(models.Host{ID: "2", Name: "beta", Host: "10.0.0.2", Port: 22})
+
+	app, err := New(config.SingleFile(path))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	edit := func(host models.Host, content string) {
+		t.Helper()
+		session, err := editor.NewSession([]models.Host{host}, editor.FormatYAML, true)
+		if err != nil {
+			t.Fatalf("NewSession failed: %v", err)
+		}
+		os.WriteFile(session.Path(), []byte(content), 0600)
+		app.Update(rawEditMsg{session: session, host: host})
+	}
+
+	alpha, _ := app.store.GetHost("1")
+	edit(alpha, "id: changed\nname: alpha\nhost: 10.0.0.9\nport: 2222\n")
+	if got, _ := app.store.GetHost("1"); got.Host != "10.0.0.9" || got.Port != 2222 {
+		t.Errorf("expected edit to be saved under the original ID, got %+v", got)
+	}
+
+	// Invalid or conflicting edits leave the host untouched
+	edit(alpha, "name: alpha\nhost: \"\"\n")
+	edit(alpha, "name: beta\nhost: 10.0.0.9\n")
+	if got, _ := app.store.GetHost("1"); got.Name != "alpha" || got.Host != "10.0.0.9" {
+		t.Errorf("expected invalid edits to be discarded, got %+v", got)
+	}
+}
+
+func TestSelectorFilter(t *testing.T) {
+	fileStore := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	fileStore.AddHost(models.Host{ID: "1", Name: "web-eu", Group: "eu", Tags: []string{"web"}})
+	fileStore.AddHost(models.Host{ID: "2", Name: "db-eu", Group: "eu", Tags: []string{"db"}})
+	fileStore.AddHost(models.Host{ID: "3", Name: "db-us", Group: "us", Tags: []string{"db"}})
+
+	v := NewListView(fileStore)
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	var first, last tea.Cmd
+	for _, r := range "tag:db AND group:eu" {
+		_, last = v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		if first == nil {
+			first = last
+		}
+	}
+	// Filtering waits for typing to pause, and only the last key counts
+	if len(v.filtered) != 3 {
+		t.Errorf("expected the list unfiltered while typing, got %v", v.filtered)
+	}
+	v.Update(first())
+	if len(v.filtered) != 3 {
+		t.Errorf("expected an earlier keystroke not to filter, got %v", v.filtered)
+	}
+	v.Update(last())
+	if len(v.filtered) != 1 || v.filtered[0].Name != "db-eu" {
+		t.Errorf("expected the typed selector to apply live, got %v", v.filtered)
+	}
+
+	v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(v.filters) != 1 || v.filters[0].kind != filterSelector {
+		t.Fatalf("expected a selector filter on the stack, got %v", v.filters)
+	}
+	if len(v.filtered) != 1 || v.filtered[0].Name != "db-eu" {
+		t.Errorf("expected only db-eu, got %v", v.filtered)
+	}
+}
+
+func TestKeymap(t *testing.T) {
+	km, err := NewKeymap(map[string][]string{"add": {"+"}, "up": {"up", "w"}})
+	if err != nil {
+		t.Fatalf("NewKeymap failed: %v", err)
+	}
+	if km.Action(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("+")}) != ActionAdd {
+		t.Error("expected + to add")
+	}
+	if km.Action(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")}) != "" {
+		t.Error("expected a to be unbound")
+	}
+	if km.Action(tea.KeyMsg{Type: tea.KeyUp}) != ActionUp || km.Key(ActionUp) != "↑" {
+		t.Error("expected the up arrow to stay bound")
+	}
+
+	if _, err := NewKeymap(map[string][]string{"connect": {"q"}}); err == nil {
+		t.Error("expected a key bound twice to fail")
+	}
+	if _, err := NewKeymap(map[string][]string{"teleport": {"z"}}); err == nil {
+		t.Error("expected an unknown action to fail")
+	}
+}
+
+func TestConfiguredKeybindings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	data := `{"keybindings": {"add": ["+"], "help": ["F1"]}, "hosts": [{"id": "1", "name": "alpha", "host": "10.0.0.1", "port": 22}]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	app, err := New(config.SingleFile(path))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer InitKeymap(nil)
+
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	if app.view != "list" {
+		t.Errorf("expected a to do nothing once add is rebound, got view %s", app.view)
+	}
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("+")})
+	if app.view != "add" {
+		t.Errorf("expected + to open the add form, got view %s", app.view)
+	}
+
+	help := NewHelpView()
+	help.width = 100
+	if !strings.Contains(help.View(), "F1") {
+		t.Error("expected the help overlay to show the configured key")
+	}
+}
+
+func TestCustomThemes(t *testing.T) {
+	bold := false
+	custom := map[string]theme.Custom{
+		"light":   {Colors: theme.Colors{Primary: "#ff8800"}},
+		"solar":   {Extends: "light", Colors: theme.Colors{Error: "160"}, Styles: theme.Styles{Title: &theme.Style{Foreground: "33", Bold: &bold}}},
+		"broken":  {Colors: theme.Colors{Primary: "orange"}},
+		"nowhere": {Extends: "sepia"},
+	}
+	err := InitTheme("solar", custom)
+	defer InitTheme("dark", nil)
+	if err == nil || !strings.Contains(err.Error(), "broken") || !strings.Contains(err.Error(), "sepia") {
+		t.Errorf("expected errors for the broken themes, got %v", err)
+	}
+
+	if GetCurrentThemeName() != "solar" {
+		t.Fatalf("expected solar to be active, got %s", GetCurrentThemeName())
+	}
+	if errorColor != lipgloss.Color("160") || primaryColor != theme.LightTheme.Primary {
+		t.Errorf("expected solar to be light with a new error color, got %v %v", errorColor, primaryColor)
+	}
+	if TitleStyle.GetForeground() != lipgloss.Color("33") || TitleStyle.GetBold() {
+		t.Error("expected the title style override to apply")
+	}
+
+	if SetTheme("light"); primaryColor != lipgloss.Color("#ff8800") {
+		t.Errorf("expected the light preset to be tweaked, got %v", primaryColor)
+	}
+	if next := ToggleTheme(); next != "solar" {
+		t.Errorf("expected light to switch to solar, got %s", next)
+	}
+	if next := ToggleTheme(); next != "dark" {
+		t.Errorf("expected solar to wrap around to dark, got %s", next)
+	}
+
+	if err := InitTheme("missing", nil); err == nil || GetCurrentThemeName() != "dark" {
+		t.Errorf("expected an unknown theme to fall back to dark with an error, got %v", err)
+	}
+}
+
+func TestLiveConfigReload(t *testing.T) {
+	dir := t.TempDir()
+	paths := config.Paths{Config: filepath.Join(dir, "config.yaml"), Hosts: filepath.Join(dir, "hosts.json")}
+	write := func(data string) {
+		if err := os.WriteFile(paths.Config, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("theme: dark\n")
+	if err := os.WriteFile(paths.Hosts, []byte(`{"hosts": [{"name": "web", "host": "10.0.0.1"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	app, err := New(paths)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer InitTheme("dark", nil)
+	defer InitKeymap(nil)
+	lastToast := func() string {
+		return app.toasts.items[len(app.toasts.items)-1].text
+	}
+
+	write("theme: light\nkeybindings:\n  add: [\"+\"]\ndefaults:\n  user: deploy\n")
+	app.configStamp = fileStamp{}
+	app.checkConfig()
+	if GetCurrentThemeName() != "light" || keymap.Key(ActionAdd) != "+" {
+		t.Errorf("expected the theme and key bindings to be applied, got %s and %s", GetCurrentThemeName(), keymap.Key(ActionAdd))
+	}
+	if host, _ := app.store.GetHostByName("web"); host.User != "deploy" {
+		t.Errorf("expected the new default user, got %q", host.User)
+	}
+	if lastToast() != "Reloaded theme, key bindings, defaults" {
+		t.Errorf("unexpected notification %q", lastToast())
+	}
+
+	write("theme: dark\ncolour: red\n")
+	app.configStamp = fileStamp{}
+	app.checkConfig()
+	if GetCurrentThemeName() != "light" || !strings.HasPrefix(lastToast(), "Settings not reloaded: "+paths.Config+`:2:1: unknown field "colour"`) {
+		t.Errorf("expected an invalid file to be rejected, got theme %s and %q", GetCurrentThemeName(), lastToast())
+	}
+
+	// Writing the theme ourselves is not a change to announce
+	count := len(app.toasts.items)
+	app.saveThemePreference("dark")
+	app.checkConfig()
+	if len(app.toasts.items) != count {
+		t.Errorf("expected no notification for our own write, got %q", lastToast())
+	}
+}
+
+func TestIncludeRefresh(t *testing.T) {
+	dir := t.TempDir()
+	paths := config.Paths{Config: filepath.Join(dir, "config.yaml"), Hosts: filepath.Join(dir, "hosts.json")}
+	shared := filepath.Join(dir, "shared.yaml")
+	writeShared := func(data string) {
+		if err := os.WriteFile(shared, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(paths.Config, []byte("include: [shared.yaml]\ninclude_refresh: 5\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths.Hosts, []byte(`{"hosts": [{"id": "1", "name": "web", "host": "10.0.0.1"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeShared("hosts:\n  - {id: s1, name: db, host: 10.0.0.2}\n")
+	app, err := New(paths)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	app.listView.cursor = 1 // web, after db
+
+	check := func() {
+		t.Helper()
+		cmd := app.checkIncludes()
+		if cmd == nil {
+			t.Fatal("expected the included files to be checked")
+		}
+		app.Update(cmd())
+	}
+
+	if app.checkIncludes() != nil {
+		t.Fatal("expected no check before include_refresh passed")
+	}
+	writeShared("hosts:\n  - {id: s1, name: db, host: 10.0.0.2}\n  - {id: s2, name: cache, host: 10.0.0.3}\n")
+	app.includesChecked = time.Now().Add(-time.Minute)
+	check()
+	if _, err := app.store.GetHostByName("cache"); err != nil {
+		t.Fatalf("expected the new included host, got %v", err)
+	}
+	if h := app.listView.GetSelectedHost(); h == nil || h.Name != "web" {
+		t.Errorf("expected the selection to stay on web, got %v", h)
+	}
+
+	app.listView.config.IncludeRefresh = -1
+	app.includesChecked = time.Time{}
+	if app.checkIncludes() != nil {
+		t.Error("expected no check with include_refresh -1")
+	}
+}
+
+func TestSnippetPickerAsksForParams(t *testing.T) {
+	host := models.Host{Name: "web-1"}
+	p := NewSnippetPicker(host, []models.Snippet{
+		{Name: "uptime", Command: "uptime"},
+		{Name: "tail", Command: "tail -n {{lines=100}} {{file}}"},
+	})
+
+	// Pick tail, keep the default line count and type the file
+	p.Update(tea.KeyMsg{Type: tea.KeyDown})
+	p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if p.done {
+		t.Fatal("expected the picker to ask for the file")
+	}
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("app.log")})
+	p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if !p.done || p.command != "tail -n 100 app.log" {
+		t.Errorf("expected the expanded command, got %q (done=%v)", p.command, p.done)
+	}
+
+	// A snippet without parameters is ready right away; esc cancels
+	p = NewSnippetPicker(host, []models.Snippet{{Name: "uptime", Command: "uptime"}})
+	p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !p.done || p.command != "uptime" {
+		t.Errorf("expected uptime to run without asking, got %q", p.command)
+	}
+	p = NewSnippetPicker(host, nil)
+	p.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if !p.done || p.command != "" {
+		t.Error("expected esc to cancel the picker")
+	}
+}
+
+func TestLockBackspace(t *testing.T) {
+	var got []byte
+	v := NewLockView(func(p []byte) error {
+		got = append([]byte(nil), p...)
+		return nil
+	})
+	defer secret.Forget()
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("grüß")})
+	v.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	v.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("üße")})
+	v.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if string(got) != "grüße" {
+		t.Errorf("expected backspace to remove whole characters, got %q", got)
+	}
+}
+
+func TestIdleLock(t *testing.T) {
+	t.Setenv("SSHM_PASSPHRASE", "correct horse")
+	dir := t.TempDir()
+	paths := config.Paths{Config: filepath.Join(dir, "config.yaml"), Hosts: filepath.Join(dir, "hosts.json")}
+	if err := os.WriteFile(paths.Config, []byte("idle_lock:\n  after: 60\n  passphrase: true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths.Hosts, []byte(`{"hosts": [{"name": "secret-db", "host": "10.0.0.1"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	app, err := New(paths)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	typeText := func(text string) {
+		app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+		app.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+
+	app.checkIdle(idleCheckMsg{at: time.Now()})
+	if app.lock != nil {
+		t.Fatal("expected no lock before the timeout")
+	}
+	app.lastInput = time.Now().Add(-2 * time.Minute)
+	app.checkIdle(idleCheckMsg{at: time.Now()})
+	if app.lock == nil || strings.Contains(app.View(), "secret-db") {
+		t.Fatalf("expected the host list to be hidden after the timeout, got:\n%s", app.View())
+	}
+
+	typeText("battery staple")
+	if app.lock == nil {
+		t.Fatal("expected a wrong passphrase to keep the lock")
+	}
+	typeText("correct horse")
+	if app.lock != nil || !strings.Contains(app.View(), "secret-db") {
+		t.Error("expected the passphrase to unlock")
+	}
+
+	// A check held up by a session counts as use
+	app.lastInput = time.Now().Add(-2 * time.Minute)
+	app.checkIdle(idleCheckMsg{at: time.Now().Add(-time.Minute)})
+	if app.lock != nil {
+		t.Error("expected no lock right after coming back from a session")
+	}
+
+	// Without passphrase any key unlocks
+	app.listView.config.IdleLock.Passphrase = false
+	app.lockScreen()
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if app.lock != nil {
+		t.Error("expected any key to unlock")
+	}
+}
+
+func TestPresentationMode(t *testing.T) {
+	fileStore := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	fileStore.AddHost(models.Host{Name: "db-1", Host: "10.1.2.3", User: "alice", Port: 22, Identity: "/home/alice/.ssh/id_ed25519"})
+	app := &App{store: fileStore, history: store.NewHistoryStore(""), listView: NewListView(fileStore), toasts: NewToasts(), view: "list"}
+	app.listView.width, app.listView.height = 120, 20
+
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("P")})
+	if !app.listView.Presenting() {
+		t.Fatal("expected P to turn presentation mode on")
+	}
+	for _, view := range []string{"list", "detail"} {
+		app.view = view
+		out := app.View()
+		if !strings.Contains(out, "db-1") || strings.Contains(out, "10.1.2.3") || strings.Contains(out, "alice") {
+			t.Errorf("expected the %s view to mask the host and user, got:\n%s", view, out)
+		}
+	}
+
+	app.view = "list"
+	app.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("P")})
+	if out := app.View(); !strings.Contains(out, "alice@10.1.2.3") {
+		t.Errorf("expected the host and user back, got:\n%s", out)
+	}
+}
+
+func TestSplitInTmux(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tmux is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "tmux.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "tmux"), []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	fileStore := openTestStore(t, filepath.Join(dir, "hosts.json"))
+	fileStore.AddHost(models.Host{ID: "1", Name: "web1", Host: "10.0.0.1", Port: 22})
+	fileStore.AddHost(models.Host{ID: "2", Name: "web2", Host: "10.0.0.2", Port: 22, Tags: []string{"production"}})
+	v := NewListView(fileStore)
+	v.SetConfig(&config.Config{GuardedTags: []string{"production"}})
+	v.sshm = []string{"/bin/sshm"}
+	split := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("S")}
+
+	t.Setenv("TMUX", "")
+	v.Update(split)
+	if !strings.Contains(v.connectErr, "inside tmux") {
+		t.Errorf("expected splitting outside tmux to fail, got %q", v.connectErr)
+	}
+
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	v.connectErr = ""
+	if _, cmd := v.Update(split); cmd != nil || !v.IsConfirmingConnect() {
+		t.Fatal("expected the guarded host to ask for confirmation")
+	}
+	_, cmd := v.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if cmd == nil {
+		t.Fatal("expected confirming to split")
+	}
+	msg, ok := cmd().(tmuxOpenedMsg)
+	if !ok || msg.err != nil || len(msg.hosts) != 2 {
+		t.Fatalf("unexpected result %+v", msg)
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{"new-window -n sshm (2) /bin/sshm connect --exact 1 ; select-pane -T web1", "split-window /bin/sshm connect --exact 2 ; select-pane -T web2", "select-layout tiled"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected tmux to be run with %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestShareHost(t *testing.T) {
+	fileStore := openTestStore(t, filepath.Join(t.TempDir(), "hosts.json"))
+	fileStore.AddHost(models.Host{Name: "db-1", Host: "10.1.2.3", User: "alice", Port: 22, Password: "s3cret"})
+	app := &App{store: fileStore, history: store.NewHistoryStore(""), listView: NewListView(fileStore), toasts: NewToasts(), view: "list"}
+	app.listView.width, app.listView.height = 120, 60
+	share := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Q")}
+
+	app.listView.SetPresenting(true)
+	app.Update(share)
+	if app.view != "list" {
+		t.Fatal("expected sharing to be refused in presentation mode")
+	}
+
+	app.listView.SetPresenting(false)
+	app.Update(share)
+	if app.view != "share" {
+		t.Fatalf("expected Q to show the share view, got %q", app.view)
+	}
+	out := app.View()
+	if !strings.Contains(out, "sshm import-uri 'sshm://alice@10.1.2.3:22?name=db-1'") || !strings.Contains(out, "█") {
+		t.Errorf("expected the link and a QR code, got:\n%s", out)
+	}
+	if strings.Contains(out, "s3cret") {
+		t.Error("the password leaked into the share view")
+	}
+
+	app.listView.height = 10
+	if out := app.View(); strings.Contains(out, "█") {
+		t.Errorf("expected no QR code in a small terminal, got:\n%s", out)
+	}
+
+	app.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if app.view != "list" || app.shareView != nil {
+		t.Errorf("expected esc to go back to the list, got %q", app.view)
+	}
+}
@@ -0,0 +1,188 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultMount is the path the SSH secrets engine is usually mounted at
+const DefaultMount = "ssh"
+
+// ErrNoAddress is returned when neither the settings nor $VAULT_ADDR name
+// a Vault server
+var ErrNoAddress = errors.New("no Vault address (set vault.address or $VAULT_ADDR)")
+
+// ErrNoToken is returned when no Vault token is available
+var ErrNoToken = errors.New("no Vault token (set $VAULT_TOKEN or run \"vault login\")")
+
+// ErrNoRole is returned when neither the host nor the settings name a role
+var ErrNoRole = errors.New("no Vault role (set vault_role on the host or vault.role in the settings)")
+
+// Client asks the SSH secrets engine of a Vault server for short-lived
+// credentials
+type Client struct {
+	// Address is the Vault server URL, $VAULT_ADDR when empty
+	Address string
+	// Mount is the path of the SSH secrets engine, DefaultMount when empty
+	Mount string
+	// Namespace is the Vault Enterprise namespace, $VAULT_NAMESPACE when
+	// empty
+	Namespace string
+	// Role is used for hosts without a role of their own
+	Role string
+	// Token authenticates requests; $VAULT_TOKEN or ~/.vault-token when
+	// empty
+	Token string
+	// HTTP sends the requests, http.DefaultClient with a timeout when nil
+	HTTP *http.Client
+}
+
+// SignKey has Vault sign pub as a user certificate for principal
+func (c *Client) SignKey(ctx context.Context, role string, pub ssh.PublicKey, principal string) (*ssh.Certificate, error) {
+	body := map[string]string{
+		"public_key":       string(ssh.MarshalAuthorizedKey(pub)),
+		"cert_type":        "user",
+		"valid_principals": principal,
+	}
+	var data struct {
+		SignedKey string `json:"signed_key"`
+	}
+	if err := c.post(ctx, "sign", role, body, &data); err != nil {
+		return nil, err
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(data.SignedKey))
+	if err != nil {
+		return nil, fmt.Errorf("vault returned an invalid certificate: %w", err)
+	}
+	cert, ok := key.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("vault returned a %s key instead of a certificate", key.Type())
+	}
+	return cert, nil
+}
+
+// OTP asks Vault for a one-time password for user on the host at ip
+func (c *Client) OTP(ctx context.Context, role, ip, user string) (string, error) {
+	body := map[string]string{"ip": ip, "username": user}
+	var data struct {
+		Key     string `json:"key"`
+		KeyType string `json:"key_type"`
+	}
+	if err := c.post(ctx, "creds", role, body, &data); err != nil {
+		return "", err
+	}
+	if data.KeyType != "" && data.KeyType != "otp" {
+		return "", fmt.Errorf("vault role %q issues %s credentials, not OTPs", role, data.KeyType)
+	}
+	if data.Key == "" {
+		return "", fmt.Errorf("vault returned no OTP")
+	}
+	return data.Key, nil
+}
+
+// post sends body to the engine's endpoint for role and decodes the data of
+// the response into out
+func (c *Client) post(ctx context.Context, endpoint, role string, body interface{}, out interface{}) error {
+	address := c.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return ErrNoAddress
+	}
+	if role == "" {
+		role = c.Role
+	}
+	if role == "" {
+		return ErrNoRole
+	}
+	token, err := c.token()
+	if err != nil {
+		return err
+	}
+	mount := strings.Trim(c.Mount, "/")
+	if mount == "" {
+		mount = DefaultMount
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	u := strings.TrimRight(address, "/") + "/v1/" + mount + "/" + endpoint + "/" + url.PathEscape(role)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", token)
+	if ns := c.namespace(); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 15 * time.Second}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && resp.StatusCode < 300 {
+		return fmt.Errorf("vault: invalid response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("vault: %s (%s)", strings.Join(result.Errors, "; "), resp.Status)
+		}
+		return fmt.Errorf("vault: %s", resp.Status)
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("vault: invalid response data: %w", err)
+	}
+	return nil
+}
+
+// token returns the configured token, $VAULT_TOKEN or the one "vault login"
+// saved in ~/.vault-token
+func (c *Client) token() (string, error) {
+	if c.Token != "" {
+		return c.Token, nil
+	}
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", ErrNoToken
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".vault-token"))
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return "", ErrNoToken
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (c *Client) namespace() string {
+	if c.Namespace != "" {
+		return c.Namespace
+	}
+	return os.Getenv("VAULT_NAMESPACE")
+}
@@ -0,0 +1,122 @@
+package vault
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeVault answers sign and creds requests like the SSH secrets engine
+func fakeVault(t *testing.T, ca ssh.Signer) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.test" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+			return
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch r.URL.Path {
+		case "/v1/ssh-client/sign/ops":
+			pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(body["public_key"]))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			cert := &ssh.Certificate{
+				Key:             pub,
+				CertType:        ssh.UserCert,
+				ValidPrincipals: strings.Split(body["valid_principals"], ","),
+				ValidBefore:     uint64(time.Now().Add(time.Minute).Unix()),
+			}
+			if err := cert.SignCert(rand.Reader, ca); err != nil {
+				t.Errorf("failed to sign: %v", err)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"signed_key": string(ssh.MarshalAuthorizedKey(cert))},
+			})
+		case "/v1/ssh/creds/otp":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"key": "otp-for-" + body["username"] + "@" + body["ip"], "key_type": "otp"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string][]string{"errors": {"no handler for route"}})
+		}
+	}))
+}
+
+func TestSignKey(t *testing.T) {
+	_, caKey, _ := ed25519.GenerateKey(rand.Reader)
+	ca, _ := ssh.NewSignerFromKey(caKey)
+	srv := fakeVault(t, ca)
+	defer srv.Close()
+
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	key, _ := ssh.NewPublicKey(pub)
+
+	c := &Client{Address: srv.URL, Mount: "ssh-client", Role: "ops", Token: "s.test"}
+	cert, err := c.SignKey(context.Background(), "", key, "deploy")
+	if err != nil {
+		t.Fatalf("SignKey() error = %v", err)
+	}
+	if string(cert.Key.Marshal()) != string(key.Marshal()) {
+		t.Error("certificate is for another key")
+	}
+	if len(cert.ValidPrincipals) != 1 || cert.ValidPrincipals[0] != "deploy" {
+		t.Errorf("principals = %v", cert.ValidPrincipals)
+	}
+	if string(cert.SignatureKey.Marshal()) != string(ca.PublicKey().Marshal()) {
+		t.Error("certificate not signed by the CA")
+	}
+
+	c.Token = "wrong"
+	if _, err := c.SignKey(context.Background(), "", key, "deploy"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("expected Vault's error, got %v", err)
+	}
+}
+
+func TestOTP(t *testing.T) {
+	srv := fakeVault(t, nil)
+	defer srv.Close()
+
+	c := &Client{Address: srv.URL, Token: "s.test"}
+	otp, err := c.OTP(context.Background(), "otp", "10.0.0.4", "deploy")
+	if err != nil {
+		t.Fatalf("OTP() error = %v", err)
+	}
+	if otp != "otp-for-deploy@10.0.0.4" {
+		t.Errorf("OTP() = %q", otp)
+	}
+
+	if _, err := c.OTP(context.Background(), "missing", "10.0.0.4", "deploy"); err == nil || !strings.Contains(err.Error(), "no handler for route") {
+		t.Errorf("expected Vault's error, got %v", err)
+	}
+}
+
+func TestClientSettings(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := (&Client{Token: "s.test"}).OTP(context.Background(), "otp", "10.0.0.4", "deploy"); !errors.Is(err, ErrNoAddress) {
+		t.Errorf("expected ErrNoAddress, got %v", err)
+	}
+	if _, err := (&Client{Address: "http://127.0.0.1:1", Token: "s.test"}).OTP(context.Background(), "", "10.0.0.4", "deploy"); !errors.Is(err, ErrNoRole) {
+		t.Errorf("expected ErrNoRole, got %v", err)
+	}
+	if _, err := (&Client{Address: "http://127.0.0.1:1"}).OTP(context.Background(), "otp", "10.0.0.4", "deploy"); !errors.Is(err, ErrNoToken) {
+		t.Errorf("expected ErrNoToken, got %v", err)
+	}
+}